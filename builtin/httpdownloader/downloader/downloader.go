@@ -2,12 +2,20 @@ package downloader
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
 )
 
 // Type is an enum of all the available http methods
@@ -21,6 +29,21 @@ const (
 	PUT
 )
 
+const (
+	// Suffix appended to the destination path while a download
+	// is in progress
+	PartialSuffix = ".partial"
+
+	// Suffix appended to the partial file path for the file that
+	// stores the ETag or Last-Modified time of the remote file. A
+	// partial download is only resumed if the remote file still
+	// matches it.
+	ValidatorSuffix = ".validator"
+
+	// Minimum time between progress updates sent to the UI
+	progressInterval = 250 * time.Millisecond
+)
+
 type Downloader struct {
 	config DownloaderConfig
 }
@@ -44,25 +67,198 @@ func (d *Downloader) DownloadFunc() interface{} {
 	return d.Download
 }
 
-func (d *Downloader) Download() (err error) {
+// Download streams the response body into a partial file next to the
+// destination and moves it into place once complete. If the transfer
+// is interrupted, the download is resumed from the partial file using
+// a Range request with an If-Range header, so the partial file is only
+// appended to if the remote file has not changed. The partial file is
+// removed if the context is cancelled.
+func (d *Downloader) Download(ctx context.Context, ui terminal.UI, log hclog.Logger) (err error) {
+	partial := d.config.Dest + PartialSuffix
+
+	sg := ui.StepGroup()
+	defer sg.Wait()
+	step := sg.Add("Downloading %s", d.config.Src)
+	defer func() {
+		if err != nil {
+			step.Abort()
+		}
+	}()
+
+	for attempt := 0; ; attempt++ {
+		err = d.download(ctx, step, partial, log)
+		if err == nil {
+			break
+		}
+		// If the download was cancelled, remove the partial
+		// file since it will not be resumed
+		if ctx.Err() != nil {
+			log.Debug("download cancelled, removing partial file", "path", partial)
+			removePartial(partial)
+			return ctx.Err()
+		}
+		if !d.resumable() {
+			removePartial(partial)
+			return err
+		}
+		if attempt >= d.config.RetryCount {
+			return err
+		}
+		log.Warn("download interrupted, resuming", "src", d.config.Src,
+			"attempt", attempt+1, "error", err)
+	}
+
+	if err = os.Rename(partial, d.config.Dest); err != nil {
+		return err
+	}
+	os.Remove(partial + ValidatorSuffix)
+	step.Update("Downloaded %s", d.config.Src)
+	step.Done()
+	return
+}
+
+// Performs a single download attempt, appending to the partial
+// file when the server supports ranged requests.
+func (d *Downloader) download(ctx context.Context, step terminal.Step, partial string, log hclog.Logger) (err error) {
+	// A partial file can only be resumed if we know which version of
+	// the remote file it holds
+	var offset int64
+	var validator string
+	if d.resumable() {
+		if info, err := os.Stat(partial); err == nil && info.Size() > 0 {
+			if v, err := ioutil.ReadFile(partial + ValidatorSuffix); err == nil && len(v) > 0 {
+				offset, validator = info.Size(), string(v)
+			} else {
+				log.Debug("partial download can't be verified, restarting", "src", d.config.Src)
+			}
+		}
+	}
+
+	req, err := d.request(ctx, offset, validator)
+	if err != nil {
+		return err
+	}
+
 	client := retryablehttp.NewClient()
 	client.RetryMax = d.config.RetryCount
-	var req *retryablehttp.Request
+	client.Logger = nil
+	// Add headers to redirects
+	client.HTTPClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		for key, val := range via[0].Header {
+			req.Header[key] = val
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// The range must start where the partial file ends
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if offset == 0 && (err != nil || start != 0) {
+			return fmt.Errorf("download failed, %s: unexpected partial content", d.config.Src)
+		}
+		if err != nil || start != offset {
+			log.Debug("partial content does not continue the partial download, restarting",
+				"src", d.config.Src, "offset", offset, "content_range", resp.Header.Get("Content-Range"))
+			resp.Body.Close()
+			return d.restart(ctx, step, partial, log)
+		}
+		log.Debug("resuming download", "src", d.config.Src, "offset", offset)
+		flags |= os.O_APPEND
+		if size >= 0 {
+			total = size
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return fmt.Errorf("download failed, %s: %s", d.config.Src, resp.Status)
+		}
+		// The partial file is not usable with the remote file
+		// so remove it and start over
+		log.Debug("partial download does not match remote file, restarting",
+			"src", d.config.Src)
+		resp.Body.Close()
+		return d.restart(ctx, step, partial, log)
+	default:
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("download failed, %s: %s", d.config.Src, resp.Status)
+		}
+		// The server sent the full body, which is either a new
+		// download or the remote file changed since the partial
+		// download was started
+		offset = 0
+		total = resp.ContentLength
+		flags |= os.O_TRUNC
+		if d.resumable() {
+			if err = writeValidator(partial, resp.Header); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+	progress := &progressWriter{
+		step:    step,
+		src:     d.config.Src,
+		current: offset,
+		total:   total,
+	}
+
+	_, err = io.Copy(io.MultiWriter(f, progress), resp.Body)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if total >= 0 && progress.current != total {
+		return fmt.Errorf("download incomplete, received %d of %d bytes", progress.current, total)
+	}
+	return nil
+}
 
+// Removes the partial download and downloads the file from the start.
+func (d *Downloader) restart(ctx context.Context, step terminal.Step, partial string, log hclog.Logger) error {
+	if err := removePartial(partial); err != nil {
+		return err
+	}
+	return d.download(ctx, step, partial, log)
+}
+
+// Builds the request for the download. Range and If-Range headers are
+// added when the download is being resumed from the given offset.
+func (d *Downloader) request(ctx context.Context, offset int64, validator string) (req *retryablehttp.Request, err error) {
 	// Create request with request body if one is provided
 	if d.config.RequestBody != nil {
 		req, err = retryablehttp.NewRequest(
 			d.config.Method.String(), d.config.Src, bytes.NewBuffer(d.config.RequestBody),
 		)
-		if err != nil {
-			return err
-		}
 	} else {
 		// If no request body is provided then create an empty request
 		req, err = retryablehttp.NewRequest(
 			d.config.Method.String(), d.config.Src, nil,
 		)
 	}
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
 
 	// Add query params if provided
 	if d.config.UrlQueryParams != nil {
@@ -74,26 +270,114 @@ func (d *Downloader) Download() (err error) {
 	}
 
 	// Set headers
-	req.Header = d.config.Headers
-	// Add headers to redirects
-	client.HTTPClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		for key, val := range via[0].Header {
-			req.Header[key] = val
+	req.Header = d.config.Headers.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+	return
+}
+
+// Stores the validator of the remote file for the partial download.
+// Weak ETags can't be used with If-Range, so the Last-Modified time is
+// used instead. If the response has neither, the download can't be
+// resumed.
+func writeValidator(partial string, header http.Header) error {
+	validator := header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+
+	path := partial + ValidatorSuffix
+	if validator == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
 		}
-		return err
+		return nil
 	}
+	return ioutil.WriteFile(path, []byte(validator), 0644)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+// Removes the partial file and its validator.
+func removePartial(partial string) error {
+	for _, path := range []string{partial, partial + ValidatorSuffix} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
-	defer resp.Body.Close()
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	return nil
+}
+
+// Parses a Content-Range header such as "bytes 100-199/200" and returns
+// the first byte position and the complete length. The complete length
+// is -1 if it is unknown.
+func parseContentRange(value string) (start, size int64, err error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, fmt.Errorf("invalid content range %q", value)
 	}
-	err = os.WriteFile(d.config.Dest, data, 0644)
-	return
+	value = strings.TrimPrefix(value, "bytes ")
+	slash := strings.IndexByte(value, '/')
+	dash := strings.IndexByte(value, '-')
+	if slash < 0 || dash < 0 || dash > slash {
+		return 0, 0, fmt.Errorf("invalid content range %q", value)
+	}
+	if start, err = strconv.ParseInt(value[:dash], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid content range %q", value)
+	}
+	size = -1
+	if length := value[slash+1:]; length != "*" {
+		if size, err = strconv.ParseInt(length, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid content range %q", value)
+		}
+	}
+	return start, size, nil
+}
+
+// Only GET requests can be resumed
+func (d *Downloader) resumable() bool {
+	return d.config.Method == GET
+}
+
+// progressWriter reports the number of bytes written through
+// the terminal step
+type progressWriter struct {
+	step       terminal.Step
+	src        string
+	current    int64
+	total      int64
+	lastUpdate time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.current += int64(len(b))
+	if time.Since(p.lastUpdate) < progressInterval && p.current != p.total {
+		return len(b), nil
+	}
+	p.lastUpdate = time.Now()
+	if p.total > 0 {
+		p.step.Update("Downloading %s: %s / %s (%d%%)", p.src,
+			formatBytes(p.current), formatBytes(p.total), p.current*100/p.total)
+	} else {
+		p.step.Update("Downloading %s: %s", p.src, formatBytes(p.current))
+	}
+	return len(b), nil
+}
+
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
 }
 
 var (
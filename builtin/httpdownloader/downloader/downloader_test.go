@@ -0,0 +1,296 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/stretchr/testify/require"
+)
+
+var testContent = bytes.Repeat([]byte("0123456789"), 1024)
+
+// serveContent serves testContent with an ETag so that Range and
+// If-Range requests are handled.
+func serveContent(etag string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "box", time.Time{}, bytes.NewReader(testContent))
+	}
+}
+
+// recordRequests records the headers of each request before calling h.
+type recordRequests struct {
+	sync.Mutex
+	headers []http.Header
+	h       http.Handler
+}
+
+func (r *recordRequests) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	r.headers = append(r.headers, req.Header.Clone())
+	r.Unlock()
+	r.h.ServeHTTP(w, req)
+}
+
+func testDownloader(t *testing.T, src string) (*Downloader, string) {
+	dest := filepath.Join(t.TempDir(), "box")
+	return &Downloader{
+		config: DownloaderConfig{
+			Src:        src,
+			Dest:       dest,
+			Method:     GET,
+			RetryCount: 1,
+		},
+	}, dest
+}
+
+func testDownload(t *testing.T, d *Downloader) error {
+	ctx := context.Background()
+	return d.Download(ctx, terminal.NonInteractiveUI(ctx), hclog.L())
+}
+
+// writePartial writes the first n bytes of testContent as a partial
+// download of the remote file with the given validator.
+func writePartial(t *testing.T, dest string, n int, validator string) {
+	partial := dest + PartialSuffix
+	require.NoError(t, ioutil.WriteFile(partial, testContent[:n], 0644))
+	if validator != "" {
+		require.NoError(t, ioutil.WriteFile(partial+ValidatorSuffix, []byte(validator), 0644))
+	}
+}
+
+func requireDownloaded(t *testing.T, dest string) {
+	data, err := ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, testContent, data)
+
+	for _, suffix := range []string{PartialSuffix, PartialSuffix + ValidatorSuffix} {
+		_, err = os.Stat(dest + suffix)
+		require.True(t, os.IsNotExist(err), "%s should be removed", suffix)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	srv := httptest.NewServer(serveContent(`"v1"`))
+	defer srv.Close()
+
+	d, dest := testDownloader(t, srv.URL)
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+}
+
+func TestDownload_resume(t *testing.T) {
+	rec := &recordRequests{h: serveContent(`"v1"`)}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d, dest := testDownloader(t, srv.URL)
+	writePartial(t, dest, 1000, `"v1"`)
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+
+	require.Len(t, rec.headers, 1)
+	require.Equal(t, "bytes=1000-", rec.headers[0].Get("Range"))
+	require.Equal(t, `"v1"`, rec.headers[0].Get("If-Range"))
+}
+
+func TestDownload_resumeInterrupted(t *testing.T) {
+	var calls int
+	full := serveContent(`"v1"`)
+	rec := &recordRequests{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			full(w, r)
+			return
+		}
+
+		// Send half of the file and drop the connection
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", fmt.Sprint(len(testContent)))
+		w.Write(testContent[:len(testContent)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	})}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d, dest := testDownloader(t, srv.URL)
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+
+	require.Len(t, rec.headers, 2)
+	require.Equal(t, fmt.Sprintf("bytes=%d-", len(testContent)/2), rec.headers[1].Get("Range"))
+}
+
+func TestDownload_changedRemoteFile(t *testing.T) {
+	rec := &recordRequests{h: serveContent(`"v2"`)}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	// The partial file is of an older version of the remote file so
+	// the server sends the whole file
+	d, dest := testDownloader(t, srv.URL)
+	require.NoError(t, ioutil.WriteFile(dest+PartialSuffix, []byte("old content"), 0644))
+	require.NoError(t, ioutil.WriteFile(dest+PartialSuffix+ValidatorSuffix, []byte(`"v1"`), 0644))
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+
+	require.Len(t, rec.headers, 1)
+	require.Equal(t, `"v1"`, rec.headers[0].Get("If-Range"))
+}
+
+func TestDownload_partialWithoutValidator(t *testing.T) {
+	rec := &recordRequests{h: serveContent(`"v1"`)}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	// Without a validator the partial file can't be resumed
+	d, dest := testDownloader(t, srv.URL)
+	require.NoError(t, ioutil.WriteFile(dest+PartialSuffix, []byte("unknown"), 0644))
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+
+	require.Len(t, rec.headers, 1)
+	require.Empty(t, rec.headers[0].Get("Range"))
+}
+
+func TestDownload_rangeNotSatisfiable(t *testing.T) {
+	full := serveContent(`"v1"`)
+	rec := &recordRequests{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		full(w, r)
+	})}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d, dest := testDownloader(t, srv.URL)
+	writePartial(t, dest, 1000, `"v1"`)
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+
+	require.Len(t, rec.headers, 2)
+	require.Empty(t, rec.headers[1].Get("Range"))
+}
+
+func TestDownload_contentRangeMismatch(t *testing.T) {
+	full := serveContent(`"v1"`)
+	rec := &recordRequests{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			// Send a range that doesn't start at the requested offset
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-99/%d", len(testContent)))
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(testContent[:100])
+			return
+		}
+		full(w, r)
+	})}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d, dest := testDownloader(t, srv.URL)
+	writePartial(t, dest, 1000, `"v1"`)
+	require.NoError(t, testDownload(t, d))
+	requireDownloaded(t, dest)
+
+	require.Len(t, rec.headers, 2)
+	require.Empty(t, rec.headers[1].Get("Range"))
+}
+
+func TestDownload_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", fmt.Sprint(len(testContent)))
+		w.Write(testContent[:100])
+		w.(http.Flusher).Flush()
+
+		// Cancel the download once part of the file was received
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	d, dest := testDownloader(t, srv.URL)
+	err := d.Download(ctx, terminal.NonInteractiveUI(ctx), hclog.L())
+	require.Equal(t, context.Canceled, err)
+
+	for _, path := range []string{dest, dest + PartialSuffix, dest + PartialSuffix + ValidatorSuffix} {
+		_, err = os.Stat(path)
+		require.True(t, os.IsNotExist(err), "%s should not exist", path)
+	}
+}
+
+// testStep records the messages of a terminal step.
+type testStep struct {
+	updates []string
+}
+
+func (s *testStep) TermOutput() io.Writer { return ioutil.Discard }
+func (s *testStep) Status(string)         {}
+func (s *testStep) Done()                 {}
+func (s *testStep) Abort()                {}
+func (s *testStep) Update(msg string, args ...interface{}) {
+	s.updates = append(s.updates, fmt.Sprintf(msg, args...))
+}
+
+func TestProgressWriter(t *testing.T) {
+	step := &testStep{}
+	p := &progressWriter{
+		step:    step,
+		src:     "box",
+		current: 1024,
+		total:   4096,
+	}
+
+	// Updates are limited but the last write is always reported
+	for i := 0; i < 3; i++ {
+		n, err := p.Write(make([]byte, 1024))
+		require.NoError(t, err)
+		require.Equal(t, 1024, n)
+	}
+	require.Len(t, step.updates, 2)
+	require.Equal(t, "Downloading box: 2.0 KiB / 4.0 KiB (50%)", step.updates[0])
+	require.Equal(t, "Downloading box: 4.0 KiB / 4.0 KiB (100%)", step.updates[1])
+
+	// Without a total only the received bytes are reported
+	p = &progressWriter{step: step, src: "box", total: -1}
+	p.Write(make([]byte, 10))
+	require.True(t, strings.HasSuffix(step.updates[2], ": 10 B"))
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, size, err := parseContentRange("bytes 100-199/200")
+	require.NoError(t, err)
+	require.Equal(t, int64(100), start)
+	require.Equal(t, int64(200), size)
+
+	start, size, err = parseContentRange("bytes 100-199/*")
+	require.NoError(t, err)
+	require.Equal(t, int64(100), start)
+	require.Equal(t, int64(-1), size)
+
+	for _, v := range []string{"", "bytes */200", "items 0-1/2", "bytes 1-2"} {
+		_, _, err = parseContentRange(v)
+		require.Error(t, err, v)
+	}
+}
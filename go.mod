@@ -15,6 +15,7 @@ require (
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/handlers v1.4.2
 	github.com/h2non/filetype v1.1.1
+	github.com/hashicorp/cronexpr v1.1.0
 	github.com/hashicorp/go-argmapper v0.2.3
 	github.com/hashicorp/go-getter v1.5.9
 	github.com/hashicorp/go-hclog v0.16.2
@@ -104,7 +105,6 @@ require (
 	github.com/gookit/color v1.3.1 // indirect
 	github.com/gorilla/mux v1.7.4 // indirect
 	github.com/gorilla/websocket v1.4.2 // indirect
-	github.com/hashicorp/cronexpr v1.1.0 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
 	github.com/hashicorp/go-immutable-radix v1.2.0 // indirect
@@ -428,6 +428,18 @@ func (c *baseCommand) logError(log hclog.Logger, prefix string, err error) {
 	c.ui.Output("%s%s", prefix, err, terminal.WithErrorStyle())
 }
 
+// flagValue returns the value of the flag with the given name if it was
+// set on the command line.
+func (c *baseCommand) flagValue(name string) (interface{}, bool) {
+	for f, v := range c.flagData {
+		if f.LongName == name {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
 // flagSet creates the flags for this command. The callback should be used
 // to configure the set with your own custom options.
 func (c *baseCommand) flagSet(bit flagSetBit, f func([]*component.CommandFlag) []*component.CommandFlag) component.CommandFlags {
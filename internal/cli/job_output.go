@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/clierrors"
+)
+
+type JobOutputCommand struct {
+	*baseCommand
+}
+
+func (c *JobOutputCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 1 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	_, err := c.client.JobOutput(c.Ctx, c.args[0], c.ui)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	return 0
+}
+
+func (c *JobOutputCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, nil)
+}
+
+func (c *JobOutputCommand) Primary() bool {
+	return false
+}
+
+func (c *JobOutputCommand) Synopsis() string {
+	return "Show the output of a job"
+}
+
+func (c *JobOutputCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant job output JOB_ID
+  Show the terminal output of a job.
+
+  If the job is still running, the output is streamed until the job
+  completes. The output of a completed job is replayed from the server.
+`)
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/skratchdot/open-golang/open"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/clierrors"
+	"github.com/hashicorp/vagrant/internal/oidc"
+)
+
+type LoginCommand struct {
+	*baseCommand
+}
+
+func (c *LoginCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+		WithClient(false),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 0 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	cfg := &oidc.Config{}
+	if v, ok := c.flagValue("issuer"); ok {
+		cfg.Issuer = v.(string)
+	}
+	if v, ok := c.flagValue("client-id"); ok {
+		cfg.ClientID = v.(string)
+	}
+	if v, ok := c.flagValue("client-secret"); ok {
+		cfg.ClientSecret = v.(string)
+	}
+	if v, ok := c.flagValue("scope"); ok && v.(string) != "" {
+		cfg.Scopes = strings.Split(v.(string), ",")
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		c.ui.Output("The -issuer and -client-id flags are required.\n\n"+c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	device := false
+	if v, ok := c.flagValue("device"); ok {
+		device = v.(bool)
+	}
+
+	var token *oidc.Token
+	var err error
+	if device {
+		token, err = oidc.DeviceLogin(c.Ctx, cfg, func(uri, code string) {
+			c.ui.Output("To log in, visit %s and enter the code %s", uri, code,
+				terminal.WithInfoStyle())
+		})
+	} else {
+		token, err = oidc.AuthCodeLogin(c.Ctx, cfg, func(uri string) error {
+			c.ui.Output("Opening the browser to log in. If it doesn't open, visit:\n\n  %s\n", uri,
+				terminal.WithInfoStyle())
+			if err := open.Run(uri); err != nil {
+				c.Log.Warn("failed to open the browser", "error", err)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	// Store the ID token in the context we connect with so that it is
+	// presented to the server from now on.
+	name, err := c.contextStorage.Default()
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	ctxConfig := *c.clientContext
+	ctxConfig.Server.RequireAuth = true
+	ctxConfig.Server.AuthToken = token.IDToken
+	if err := c.contextStorage.Set(name, &ctxConfig); err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	user := token.Claims.String("email")
+	if user == "" {
+		user = token.Claims.String("sub")
+	}
+	c.ui.Output("Logged in as %s until %s.", user, token.Expiry.Local().Format("2006-01-02 15:04:05"),
+		terminal.WithSuccessStyle())
+	return 0
+}
+
+func (c *LoginCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, func(set []*component.CommandFlag) []*component.CommandFlag {
+		return append(set,
+			&component.CommandFlag{
+				LongName:    "issuer",
+				Description: "Issuer URL of the OIDC provider",
+				Type:        component.FlagString,
+			},
+			&component.CommandFlag{
+				LongName:    "client-id",
+				Description: "Client ID of Vagrant at the OIDC provider",
+				Type:        component.FlagString,
+			},
+			&component.CommandFlag{
+				LongName:    "client-secret",
+				Description: "Client secret of Vagrant at the OIDC provider, if any",
+				Type:        component.FlagString,
+			},
+			&component.CommandFlag{
+				LongName:    "scope",
+				Description: "Comma separated scopes to request in addition to openid",
+				Type:        component.FlagString,
+			},
+			&component.CommandFlag{
+				LongName:     "device",
+				Description:  "Log in with a code on another device instead of a browser",
+				DefaultValue: "false",
+				Type:         component.FlagBool,
+			},
+		)
+	})
+}
+
+func (c *LoginCommand) Primary() bool {
+	return false
+}
+
+func (c *LoginCommand) Synopsis() string {
+	return "Log in to the server with an OIDC provider"
+}
+
+func (c *LoginCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant login [options]
+  Log in to the server with an OpenID Connect provider.
+
+  This opens a browser to log in with the provider. With -device, a code
+  is shown to enter on another device instead, for hosts without a
+  browser. The ID token of the login is stored in the default context
+  and presented to the server until it expires.
+
+` + c.Flags().Display())
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/go-glint"
 
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
 	"github.com/hashicorp/vagrant-plugin-sdk/internal-shared/protomappers"
 	"github.com/hashicorp/vagrant-plugin-sdk/localizer"
 	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
@@ -148,6 +149,18 @@ func Commands(
 		return bc, commands, nil
 	}
 
+	// Migrating opens the local database itself so it must run before
+	// the local server is started, since that migrates the database.
+	if len(args) > 2 && args[1] == "server" && args[2] == "migrate" {
+		bc.flagData = map[*component.CommandFlag]interface{}{}
+		commands["server migrate"] = func() (cli.Command, error) {
+			return &ServerMigrateCommand{
+				baseCommand: bc,
+			}, nil
+		}
+		return bc, commands, nil
+	}
+
 	baseCommand, err := BaseCommand(ctx, log, logOutput,
 		WithArgs(args),
 	)
@@ -198,6 +211,51 @@ func Commands(
 			VersionInfo: version.GetVersion(),
 		}, nil
 	}
+	commands["login"] = func() (cli.Command, error) {
+		return &LoginCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["job output"] = func() (cli.Command, error) {
+		return &JobOutputCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["runner list"] = func() (cli.Command, error) {
+		return &RunnerListCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["runner drain"] = func() (cli.Command, error) {
+		return &RunnerDrainCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["task list"] = func() (cli.Command, error) {
+		return &TaskListCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["server migrate"] = func() (cli.Command, error) {
+		return &ServerMigrateCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["server key list"] = func() (cli.Command, error) {
+		return &ServerKeyListCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["server key rotate"] = func() (cli.Command, error) {
+		return &ServerKeyRotateCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
+	commands["server key retire"] = func() (cli.Command, error) {
+		return &ServerKeyRetireCommand{
+			baseCommand: baseCommand,
+		}, nil
+	}
 
 	// register our aliases
 	for from, to := range aliases {
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/clierrors"
+)
+
+type RunnerListCommand struct {
+	*baseCommand
+}
+
+func (c *RunnerListCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	runners, err := c.client.ListRunners(c.Ctx)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+	if len(runners) == 0 {
+		c.ui.Output("No runners are registered.")
+		return 0
+	}
+
+	tbl := terminal.NewTable("ID", "Hostname", "Version", "State", "Jobs", "Last Heartbeat", "Labels")
+	for _, r := range runners {
+		state := "online"
+		if !r.Online {
+			state = "offline"
+		}
+		if r.Draining {
+			state += " (draining)"
+		}
+
+		var heartbeat string
+		if r.LastHeartbeat != nil {
+			heartbeat = time.Since(r.LastHeartbeat.AsTime()).Truncate(time.Second).String() + " ago"
+		}
+
+		var labels []string
+		for k, v := range r.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+
+		jobs := r.JobIds
+		if len(jobs) == 0 && r.CurrentJobId != "" {
+			jobs = []string{r.CurrentJobId}
+		}
+		if r.Concurrency > 0 {
+			jobs = append(jobs, fmt.Sprintf("(%d/%d)", len(jobs), r.Concurrency))
+		}
+
+		tbl.Rich([]string{
+			r.Id,
+			r.Hostname,
+			r.Version,
+			state,
+			strings.Join(jobs, " "),
+			heartbeat,
+			strings.Join(labels, ","),
+		}, nil)
+	}
+
+	c.ui.Table(tbl)
+	return 0
+}
+
+func (c *RunnerListCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, nil)
+}
+
+func (c *RunnerListCommand) Primary() bool {
+	return false
+}
+
+func (c *RunnerListCommand) Synopsis() string {
+	return "List the registered runners"
+}
+
+func (c *RunnerListCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant runner list
+  List the runners registered with the server.
+
+  Runners which disconnected are listed as offline until they reconnect
+  or the grace period for reconnecting has passed.
+`)
+}
+
+type RunnerDrainCommand struct {
+	*baseCommand
+}
+
+func (c *RunnerDrainCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 1 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	if err := c.client.DrainRunner(c.Ctx, c.args[0]); err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	c.ui.Output("Runner %s is draining and will not be assigned new jobs.", c.args[0],
+		terminal.WithSuccessStyle())
+	return 0
+}
+
+func (c *RunnerDrainCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, nil)
+}
+
+func (c *RunnerDrainCommand) Primary() bool {
+	return false
+}
+
+func (c *RunnerDrainCommand) Synopsis() string {
+	return "Stop assigning new jobs to a runner"
+}
+
+func (c *RunnerDrainCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant runner drain RUNNER_ID
+  Stop assigning new jobs to a runner.
+
+  Any job currently running on the runner is allowed to complete.
+`)
+}
@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/clierrors"
+)
+
+type ServerKeyListCommand struct {
+	*baseCommand
+}
+
+func (c *ServerKeyListCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	keys, err := c.client.ListServerKeys(c.Ctx)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+	if len(keys) == 0 {
+		c.ui.Output("The server has no keys, it has not been bootstrapped yet.")
+		return 0
+	}
+
+	tbl := terminal.NewTable("ID", "State", "Created", "Retires")
+	for _, k := range keys {
+		state := "valid"
+		switch {
+		case k.Active:
+			state = "active"
+		case k.Retired:
+			state = "retired"
+		}
+
+		var created, retires string
+		if k.CreateTime != nil {
+			created = k.CreateTime.AsTime().Local().Format(time.RFC3339)
+		}
+		if k.RetireTime != nil {
+			retires = k.RetireTime.AsTime().Local().Format(time.RFC3339)
+		}
+
+		tbl.Rich([]string{k.Id, state, created, retires}, nil)
+	}
+
+	c.ui.Table(tbl)
+	return 0
+}
+
+func (c *ServerKeyListCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, nil)
+}
+
+func (c *ServerKeyListCommand) Primary() bool {
+	return false
+}
+
+func (c *ServerKeyListCommand) Synopsis() string {
+	return "List the keys the server signs tokens with"
+}
+
+func (c *ServerKeyListCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant server key list
+  List the keys the server signs authentication tokens with.
+
+  New tokens are signed with the active key. Tokens signed with other
+  keys are valid until the key is retired.
+`)
+}
+
+type ServerKeyRotateCommand struct {
+	*baseCommand
+}
+
+func (c *ServerKeyRotateCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 0 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	var retireAfter string
+	if v, ok := c.flagValue("retire-after"); ok {
+		retireAfter = v.(string)
+	}
+
+	key, err := c.client.RotateServerKey(c.Ctx, retireAfter)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	c.ui.Output("New tokens are now signed with key %s.", key.Id, terminal.WithSuccessStyle())
+	return 0
+}
+
+func (c *ServerKeyRotateCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, func(set []*component.CommandFlag) []*component.CommandFlag {
+		return append(set,
+			&component.CommandFlag{
+				LongName:    "retire-after",
+				Description: "Retire the previous key after this duration, such as 72h",
+				Type:        component.FlagString,
+			},
+		)
+	})
+}
+
+func (c *ServerKeyRotateCommand) Primary() bool {
+	return false
+}
+
+func (c *ServerKeyRotateCommand) Synopsis() string {
+	return "Create a new key to sign tokens with"
+}
+
+func (c *ServerKeyRotateCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant server key rotate [options]
+  Create a new key the server signs authentication tokens with.
+
+  Tokens signed with the previous key remain valid until that key is
+  retired, so nobody is logged out by rotating the key.
+
+` + c.Flags().Display())
+}
+
+type ServerKeyRetireCommand struct {
+	*baseCommand
+}
+
+func (c *ServerKeyRetireCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 1 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	var retireAfter string
+	if v, ok := c.flagValue("after"); ok {
+		retireAfter = v.(string)
+	}
+
+	key, err := c.client.RetireServerKey(c.Ctx, c.args[0], retireAfter)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	if key.Retired {
+		c.ui.Output("Key %s is retired.", key.Id, terminal.WithSuccessStyle())
+	} else {
+		c.ui.Output("Key %s retires at %s.", key.Id,
+			key.RetireTime.AsTime().Local().Format(time.RFC3339), terminal.WithSuccessStyle())
+	}
+	return 0
+}
+
+func (c *ServerKeyRetireCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, func(set []*component.CommandFlag) []*component.CommandFlag {
+		return append(set,
+			&component.CommandFlag{
+				LongName:    "after",
+				Description: "Retire the key after this duration, such as 24h",
+				Type:        component.FlagString,
+			},
+		)
+	})
+}
+
+func (c *ServerKeyRetireCommand) Primary() bool {
+	return false
+}
+
+func (c *ServerKeyRetireCommand) Synopsis() string {
+	return "Retire a key the server signed tokens with"
+}
+
+func (c *ServerKeyRetireCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant server key retire [options] KEY_ID
+  Retire a key the server signed authentication tokens with.
+
+  Tokens signed with a retired key are rejected. The active key can't be
+  retired, rotate the key first.
+
+` + c.Flags().Display())
+}
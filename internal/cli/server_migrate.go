@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/helper/paths"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/clierrors"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
+)
+
+type ServerMigrateCommand struct {
+	*baseCommand
+}
+
+func (c *ServerMigrateCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+		WithClient(false),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 0 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	opts := &state.MigrateOptions{}
+	if v, ok := c.flagValue("dry-run"); ok {
+		opts.DryRun = v.(bool)
+	}
+	if v, ok := c.flagValue("snapshot-dir"); ok {
+		opts.SnapshotDir = v.(string)
+	}
+
+	dataPath, err := paths.VagrantData()
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+	path := dataPath.Join("data.db").String()
+
+	// Opening a missing database would create it
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		c.ui.Output("There is no local server database to migrate.")
+		return 0
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		Timeout: 1 * time.Second,
+	})
+	if err != nil {
+		c.ui.Output(fmt.Sprintf(
+			"The database at %s could not be opened. Make sure no other "+
+				"Vagrant process is running and try again.\n\n%s",
+			path, clierrors.Humanize(err)), terminal.WithErrorStyle())
+		return 1
+	}
+	defer db.Close()
+
+	result, err := state.Migrate(c.Log.Named("migrate"), db, opts)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+
+	if len(result.Applied) == 0 {
+		c.ui.Output(fmt.Sprintf("The database is at version %d, no migrations are needed.", result.To))
+		return 0
+	}
+
+	if opts.DryRun {
+		c.ui.Output(fmt.Sprintf("Migrating the database from version %d to %d will apply:",
+			result.From, result.To), terminal.WithHeaderStyle())
+	} else {
+		c.ui.Output(fmt.Sprintf("Migrated the database from version %d to %d by applying:",
+			result.From, result.To), terminal.WithHeaderStyle())
+	}
+	for i, step := range result.Applied {
+		c.ui.Output(fmt.Sprintf("v%d: %s", result.From+int64(i)+1, step), terminal.WithInfoStyle())
+	}
+
+	if opts.DryRun {
+		c.ui.Output("\nThis was a dry run, the database was not changed.")
+	} else {
+		c.ui.Output(fmt.Sprintf("\nThe previous data was saved to %s", result.Snapshot))
+	}
+
+	return 0
+}
+
+func (c *ServerMigrateCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, func(set []*component.CommandFlag) []*component.CommandFlag {
+		return append(set,
+			&component.CommandFlag{
+				LongName:     "dry-run",
+				Description:  "Show the migrations that would be applied without changing the database",
+				DefaultValue: "false",
+				Type:         component.FlagBool,
+			},
+			&component.CommandFlag{
+				LongName:    "snapshot-dir",
+				Description: "Directory to save the data to before migrating, defaults to the database directory",
+				Type:        component.FlagString,
+			},
+		)
+	})
+}
+
+func (c *ServerMigrateCommand) Primary() bool {
+	return false
+}
+
+func (c *ServerMigrateCommand) Synopsis() string {
+	return "Migrate the local server database to this version of Vagrant"
+}
+
+func (c *ServerMigrateCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant server migrate [options]
+  Migrate the local server database to the data version of this Vagrant.
+
+  The database is migrated automatically when the server starts. Use
+  --dry-run to see the migrations that would be applied and verify they
+  succeed without changing the database.
+
+` + c.Flags().Display())
+}
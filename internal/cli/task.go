@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/clierrors"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+type TaskListCommand struct {
+	*baseCommand
+}
+
+func (c *TaskListCommand) Run(args []string) int {
+	flagSet := c.Flags()
+
+	// Initialize. If we fail, we just exit since Init handles the UI.
+	if err := c.Init(
+		WithArgs(args),
+		WithFlags(flagSet),
+		WithNoConfig(),
+	); err != nil {
+		return 1
+	}
+
+	if len(c.args) != 0 {
+		c.ui.Output(c.Help(), terminal.WithErrorStyle())
+		return 1
+	}
+
+	// List the tasks of the most specific scope we have
+	req := &vagrant_server.ListTasksRequest{}
+	switch {
+	case c.target != nil:
+		req.Scope = &vagrant_server.ListTasksRequest_Target{Target: c.target.Ref()}
+	case c.project != nil:
+		req.Scope = &vagrant_server.ListTasksRequest_Project{Project: c.project.Ref()}
+	default:
+		req.Scope = &vagrant_server.ListTasksRequest_Basis{Basis: c.basis.Ref()}
+	}
+
+	var limit uint32
+	if v, ok := c.flagValue("limit"); ok {
+		n, err := strconv.ParseUint(v.(string), 10, 32)
+		if err != nil {
+			c.ui.Output("The -limit flag must be a number.\n\n"+c.Help(), terminal.WithErrorStyle())
+			return 1
+		}
+		limit = uint32(n)
+	}
+
+	tasks, err := c.client.ListTasks(c.Ctx, req, limit)
+	if err != nil {
+		c.ui.Output(clierrors.Humanize(err), terminal.WithErrorStyle())
+		return 1
+	}
+	if len(tasks) == 0 {
+		c.ui.Output("No tasks have been run.")
+		return 0
+	}
+
+	tbl := terminal.NewTable("Sequence", "Task", "Target", "Status", "Requested By", "Started", "Job")
+	for _, t := range tasks {
+		var target string
+		if scope, ok := t.Scope.(*vagrant_server.Task_Target); ok {
+			target = scope.Target.Name
+		}
+
+		var state, started string
+		if t.Status != nil {
+			state = strings.ToLower(t.Status.State.String())
+			if t.Status.StartTime != nil {
+				started = t.Status.StartTime.AsTime().Local().Format(time.RFC3339)
+			}
+		}
+
+		tbl.Rich([]string{
+			strconv.FormatUint(t.Sequence, 10),
+			t.Task,
+			target,
+			state,
+			t.RequestedBy,
+			started,
+			t.JobId,
+		}, nil)
+	}
+
+	c.ui.Table(tbl)
+	return 0
+}
+
+func (c *TaskListCommand) Flags() component.CommandFlags {
+	return c.flagSet(0, func(set []*component.CommandFlag) []*component.CommandFlag {
+		return append(set,
+			&component.CommandFlag{
+				LongName:     "limit",
+				Description:  "Maximum number of tasks to list",
+				DefaultValue: "20",
+				Type:         component.FlagString,
+			},
+		)
+	})
+}
+
+func (c *TaskListCommand) Primary() bool {
+	return false
+}
+
+func (c *TaskListCommand) Synopsis() string {
+	return "List the tasks run in the project"
+}
+
+func (c *TaskListCommand) Help() string {
+	return formatHelp(`
+Usage: vagrant task list [options]
+  List the tasks run in the current project, most recent first.
+
+  This shows who requested each task and when it ran. With -target,
+  only the tasks run on that machine are listed.
+
+` + c.Flags().Display())
+}
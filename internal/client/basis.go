@@ -10,6 +10,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
 	"github.com/hashicorp/vagrant-plugin-sdk/config"
 	"github.com/hashicorp/vagrant-plugin-sdk/helper/path"
 	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
@@ -202,3 +203,13 @@ func (b *Basis) UI() terminal.UI {
 func (b *Basis) Path() path.Path {
 	return b.path
 }
+
+// Logs returns a log viewer for the logs of the basis. This includes
+// the logs of all projects and targets within the basis.
+func (b *Basis) Logs(ctx context.Context) (component.LogViewer, error) {
+	return b.client.Logs(ctx, &vagrant_server.GetLogStreamRequest{
+		Scope: &vagrant_server.GetLogStreamRequest_Basis{
+			Basis: b.Ref(),
+		},
+	})
+}
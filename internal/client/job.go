@@ -110,17 +110,44 @@ func (c *Client) queueAndStreamJob(
 	if err != nil {
 		return nil, err
 	}
-	log = log.With("job_id", queueResp.JobId)
 
 	// Call our callback if it was given
 	if jobIdCallback != nil {
 		jobIdCallback(queueResp.JobId)
 	}
 
-	// Get the stream
-	log.Debug("opening job stream")
+	return c.streamJob(ctx, queueResp.JobId, ui, false)
+}
+
+// JobOutput streams the output of the job to the UI. If the job is still
+// running, this will stream the output until the job completes. This can
+// be used to replay the output of a completed job.
+func (c *Client) JobOutput(
+	ctx context.Context,
+	jobId string,
+	ui terminal.UI,
+) (*vagrant_server.Job_Result, error) {
+	if ui == nil {
+		ui = c.ui
+	}
+
+	return c.streamJob(ctx, jobId, ui, true)
+}
+
+// jobStreamReconnects is the number of times streamJob will try to reopen
+// a job stream that was disconnected before the job completed.
+const jobStreamReconnects = 5
+
+// openJobStream opens the job stream and waits for the open confirmation.
+// Only output after the given offset is requested.
+func (c *Client) openJobStream(
+	ctx context.Context,
+	jobId string,
+	offset uint64,
+) (vagrant_server.Vagrant_GetJobStreamClient, error) {
 	stream, err := c.client.GetJobStream(ctx, &vagrant_server.GetJobStreamRequest{
-		JobId: queueResp.JobId,
+		JobId:  jobId,
+		Offset: offset,
 	})
 	if err != nil {
 		return nil, err
@@ -137,6 +164,28 @@ func (c *Client) queueAndStreamJob(
 			resp.Event)
 	}
 
+	return stream, nil
+}
+
+// streamJob streams the state and output of the job to the UI until the
+// job completes. If the stream is disconnected before the job completes,
+// it is reopened from the last output received. If replay is true, the
+// output is always shown and the job is never canceled on exit.
+func (c *Client) streamJob(
+	ctx context.Context,
+	jobId string,
+	ui terminal.UI,
+	replay bool,
+) (*vagrant_server.Job_Result, error) {
+	log := c.logger.With("job_id", jobId)
+
+	// Get the stream
+	log.Debug("opening job stream")
+	stream, err := c.openJobStream(ctx, jobId, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	type stepData struct {
 		terminal.Step
 
@@ -145,7 +194,9 @@ func (c *Client) queueAndStreamJob(
 
 	// Process events
 	var (
-		completed bool
+		completed  bool
+		offset     uint64
+		reconnects int
 
 		stateEventTimer *time.Timer
 		tstatus         terminal.Status
@@ -156,7 +207,7 @@ func (c *Client) queueAndStreamJob(
 		steps = map[int32]*stepData{}
 	)
 
-	if c.localRunner {
+	if c.localRunner && !replay {
 		defer func() {
 			// If we completed then do nothing, or if the context is still
 			// active since this means that we're not cancelled.
@@ -169,7 +220,7 @@ func (c *Client) queueAndStreamJob(
 
 			log.Warn("canceling job")
 			_, err := c.client.CancelJob(ctx, &vagrant_server.CancelJobRequest{
-				JobId: queueResp.JobId,
+				JobId: jobId,
 			})
 			if err != nil {
 				log.Warn("error canceling job", "err", err)
@@ -181,6 +232,16 @@ func (c *Client) queueAndStreamJob(
 
 	for {
 		resp, err := stream.Recv()
+		if status.Code(err) == codes.Unavailable && ctx.Err() == nil && reconnects < jobStreamReconnects {
+			// Reattach to the job from the last output we received
+			reconnects++
+			log.Warn("job stream disconnected, reconnecting", "error", err, "offset", offset)
+			time.Sleep(time.Duration(reconnects) * time.Second)
+			stream, err = c.openJobStream(ctx, jobId, offset)
+			if err == nil {
+				continue
+			}
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -210,14 +271,30 @@ func (c *Client) queueAndStreamJob(
 			log.Warn("job stream failure", "code", st.Code(), "message", st.Message())
 			return nil, st.Err()
 
+		case *vagrant_server.GetJobStreamResponse_Retry_:
+			st := status.FromProto(event.Retry.Error)
+			log.Warn("job attempt failed", "attempt", event.Retry.Attempt,
+				"code", st.Code(), "message", st.Message())
+
+			ui.Output("Attempt %d failed: %s", event.Retry.Attempt, st.Message(),
+				terminal.WithWarningStyle())
+			if event.Retry.RetryTime != nil {
+				ui.Output("Retrying at %s.",
+					event.Retry.RetryTime.AsTime().Local().Format(time.RFC1123),
+					terminal.WithInfoStyle())
+			}
+
 		case *vagrant_server.GetJobStreamResponse_Terminal_:
 			// Ignore this for local jobs since we're using our UI directly.
-			if c.localRunner {
+			if c.localRunner && !replay {
 				continue
 			}
 
 			for _, ev := range event.Terminal.Events {
 				log.Trace("job terminal output", "event", ev)
+				if ev.Offset > offset {
+					offset = ev.Offset
+				}
 
 				switch ev := ev.Event.(type) {
 				case *vagrant_server.GetJobStreamResponse_Terminal_Event_Line_:
@@ -334,6 +411,15 @@ func (c *Client) queueAndStreamJob(
 			// the wait time ends up being long.
 			switch event.State.Current {
 			case vagrant_server.Job_QUEUED:
+				// Blocked jobs are waiting on another job rather than a
+				// runner so let the user know right away.
+				if event.State.Blocked {
+					ui.Output("Operation is queued behind another operation.",
+						terminal.WithHeaderStyle())
+					ui.Output(event.State.BlockedMessage, terminal.WithWarningStyle())
+					break
+				}
+
 				stateEventTimer = time.AfterFunc(stateEventPause, func() {
 					ui.Output("Operation is queued. Waiting for runner assignment...",
 						terminal.WithHeaderStyle())
@@ -139,17 +139,16 @@ func (c *Client) Docs(
 	return result.Docs, nil
 }
 
-// TODO(spox): need to think about how to apply this
-func (c *Client) Logs(ctx context.Context) (component.LogViewer, error) {
+// Logs returns a log viewer for the log stream requested. The request
+// scope determines if logs are read for a basis, project, or target.
+func (c *Client) Logs(
+	ctx context.Context,
+	req *vagrant_server.GetLogStreamRequest,
+) (component.LogViewer, error) {
 	log := c.logger.Named("logs")
 
-	// First we attempt to query the server for logs for this deployment.
 	log.Info("requesting log stream")
-	client, err := c.client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
-		Scope: &vagrant_server.GetLogStreamRequest_Basis{
-			// Basis: b.Ref(),
-		},
-	})
+	client, err := c.client.GetLogStream(ctx, req)
 	if err != nil {
 		return nil, err
 	}
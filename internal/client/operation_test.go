@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess"
+)
+
+func TestClientLogs(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &Client{
+		client: singleprocess.TestServer(t),
+		logger: hclog.L(),
+	}
+
+	basisResp, err := c.client.UpsertBasis(ctx, &vagrant_server.UpsertBasisRequest{
+		Basis: &vagrant_server.Basis{Name: "mybasis"},
+	})
+	require.NoError(err)
+	projectResp, err := c.client.UpsertProject(ctx, &vagrant_server.UpsertProjectRequest{
+		Project: &vagrant_server.Project{
+			Name:  "myproject",
+			Basis: &vagrant_plugin_sdk.Ref_Basis{ResourceId: basisResp.Basis.ResourceId},
+		},
+	})
+	require.NoError(err)
+	project := &vagrant_plugin_sdk.Ref_Project{ResourceId: projectResp.Project.ResourceId}
+
+	// Start following the logs before anything is written
+	viewer, err := c.Logs(ctx, &vagrant_server.GetLogStreamRequest{
+		Scope: &vagrant_server.GetLogStreamRequest_Project{Project: project},
+	})
+	require.NoError(err)
+
+	// Push lines the way a runner does
+	send, err := c.client.SendLogStream(ctx)
+	require.NoError(err)
+	require.NoError(send.Send(&vagrant_server.SendLogStreamRequest{
+		Scope:      &vagrant_server.SendLogStreamRequest_Project{Project: project},
+		InstanceId: "runner",
+		Lines: []*vagrant_server.LogBatch_Entry{
+			{Line: "one"},
+			{Line: "two"},
+		},
+	}))
+	_, err = send.CloseAndRecv()
+	require.NoError(err)
+
+	events, err := viewer.NextLogBatch(ctx)
+	require.NoError(err)
+	require.Len(events, 2)
+	require.Equal("one", events[0].Message)
+	require.Equal("two", events[1].Message)
+	require.Equal("myproject/runner", events[0].Partition)
+}
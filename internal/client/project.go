@@ -7,6 +7,7 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
 	"github.com/hashicorp/vagrant-plugin-sdk/helper/path"
 	vagrant_plugin_sdk "github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
 	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
@@ -135,3 +136,13 @@ func (p *Project) Ref() *vagrant_plugin_sdk.Ref_Project {
 		Basis:      p.basis.Ref(),
 	}
 }
+
+// Logs returns a log viewer for the logs of the project. This includes
+// the logs of all targets within the project.
+func (p *Project) Logs(ctx context.Context) (component.LogViewer, error) {
+	return p.client.Logs(ctx, &vagrant_server.GetLogStreamRequest{
+		Scope: &vagrant_server.GetLogStreamRequest_Project{
+			Project: p.Ref(),
+		},
+	})
+}
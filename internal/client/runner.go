@@ -1,7 +1,10 @@
 package client
 
 import (
+	"context"
+
 	"github.com/hashicorp/vagrant/internal/runner"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 )
 
 // startRunner initializes and starts a local runner. If the returned
@@ -26,3 +29,21 @@ func (c *Client) startRunner() (*runner.Runner, error) {
 
 	return r, nil
 }
+
+// ListRunners returns the runners registered with the server.
+func (c *Client) ListRunners(ctx context.Context) ([]*vagrant_server.Runner, error) {
+	resp, err := c.client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Runners, nil
+}
+
+// DrainRunner stops the server from assigning new jobs to the runner.
+func (c *Client) DrainRunner(ctx context.Context, id string) error {
+	_, err := c.client.DrainRunner(ctx, &vagrant_server.DrainRunnerRequest{
+		RunnerId: id,
+	})
+	return err
+}
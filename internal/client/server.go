@@ -114,6 +114,8 @@ func (c *Client) initLocalServer(ctx context.Context) (_ *grpc.ClientConn, err e
 	impl, err := singleprocess.New(
 		singleprocess.WithDB(db),
 		singleprocess.WithLogger(log.Named("singleprocess")),
+		singleprocess.WithBoxCatalogDir(dataPath.Join("box-catalog").String()),
+		singleprocess.WithArchiveDir(dataPath.Join("archives").String()),
 	)
 	if err != nil {
 		log.Trace("failed singleprocess server setup", "error", err)
@@ -136,6 +138,14 @@ func (c *Client) initLocalServer(ctx context.Context) (_ *grpc.ClientConn, err e
 	}
 	cleanups = append(cleanups, func() error { return ln.Close() })
 
+	// The HTTP listener serves the endpoints of the server which aren't
+	// available over gRPC, such as the box catalog.
+	httpLn, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		return
+	}
+	cleanups = append(cleanups, func() error { return httpLn.Close() })
+
 	// Create a new cancellation context so we can cancel in the case of an error
 	ctx, cancel := context.WithCancel(ctx)
 	defer func() {
@@ -145,11 +155,13 @@ func (c *Client) initLocalServer(ctx context.Context) (_ *grpc.ClientConn, err e
 	}()
 
 	// Run the server
-	log.Info("starting built-in server for local operations", "addr", ln.Addr().String())
+	log.Info("starting built-in server for local operations",
+		"addr", ln.Addr().String(), "http_addr", httpLn.Addr().String())
 	go server.Run(
 		server.WithContext(ctx),
 		server.WithLogger(log),
 		server.WithGRPC(ln),
+		server.WithHTTP(httpLn),
 		server.WithImpl(impl),
 	)
 
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// ListServerKeys returns the keys the server signs tokens with.
+func (c *Client) ListServerKeys(ctx context.Context) ([]*vagrant_server.HMACKeyInfo, error) {
+	resp, err := c.client.ListHMACKeys(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Keys, nil
+}
+
+// RotateServerKey creates a new key the server signs tokens with. If
+// retireAfter is not empty, the previous key is retired after that
+// duration.
+func (c *Client) RotateServerKey(ctx context.Context, retireAfter string) (*vagrant_server.HMACKeyInfo, error) {
+	resp, err := c.client.RotateHMACKey(ctx, &vagrant_server.RotateHMACKeyRequest{
+		RetireAfter: retireAfter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Key, nil
+}
+
+// RetireServerKey retires the key with the given ID after retireAfter, or
+// immediately if retireAfter is empty.
+func (c *Client) RetireServerKey(ctx context.Context, id, retireAfter string) (*vagrant_server.HMACKeyInfo, error) {
+	resp, err := c.client.RetireHMACKey(ctx, &vagrant_server.RetireHMACKeyRequest{
+		Id:          id,
+		RetireAfter: retireAfter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Key, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 
+	"github.com/hashicorp/vagrant-plugin-sdk/component"
 	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
 	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
@@ -32,3 +33,12 @@ func (m *Target) Ref() *vagrant_plugin_sdk.Ref_Target {
 		Project:    m.project.Ref(),
 	}
 }
+
+// Logs returns a log viewer for the logs of the target.
+func (m *Target) Logs(ctx context.Context) (component.LogViewer, error) {
+	return m.client.Logs(ctx, &vagrant_server.GetLogStreamRequest{
+		Scope: &vagrant_server.GetLogStreamRequest_Target{
+			Target: m.Ref(),
+		},
+	})
+}
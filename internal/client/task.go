@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// ListTasks returns the tasks run within the scope of the request, most
+// recent first. At most limit tasks are returned if limit is not zero.
+func (c *Client) ListTasks(
+	ctx context.Context,
+	req *vagrant_server.ListTasksRequest,
+	limit uint32,
+) ([]*vagrant_server.Task, error) {
+	req.Order = &vagrant_server.OperationOrder{
+		Order: vagrant_server.OperationOrder_START_TIME,
+		Desc:  true,
+		Limit: limit,
+	}
+
+	resp, err := c.client.ListTasks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Tasks, nil
+}
@@ -95,7 +95,14 @@ func NewBox(opts ...BoxOption) (b *Box, err error) {
 	}
 	// The metadata should have provider info under the "provider" key
 	b.box.Provider = metadata["provider"].(string)
+	// Boxes built for a specific architecture may include it in the metadata
+	if arch, ok := metadata["architecture"].(string); ok && b.box.Architecture == "" {
+		b.box.Architecture = arch
+	}
 	b.box.Id = b.box.Name + "-" + b.box.Version + "-" + b.box.Provider
+	if b.box.Architecture != "" {
+		b.box.Id += "-" + b.box.Architecture
+	}
 	return
 }
 
@@ -211,6 +218,9 @@ func (b *Box) matches(box core.Box) (bool, error) {
 	if b.box.Name == name &&
 		b.box.Version == version &&
 		b.box.Provider == provider {
+		if other, ok := box.(*Box); ok && other.box.Architecture != b.box.Architecture {
+			return false, nil
+		}
 		return true, nil
 	}
 	return false, nil
@@ -256,6 +266,12 @@ func (b *Box) Destroy() (err error) {
 	return
 }
 
+// Returns the architecture the box is built for. Boxes without an
+// architecture can be used on any host.
+func (b *Box) Architecture() (string, error) {
+	return b.box.Architecture, nil
+}
+
 func (b *Box) Directory() (path.Path, error) {
 	return path.NewPath(b.box.Directory), nil
 }
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"runtime"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/core"
+)
+
+// Architecture names used in box metadata which differ from the
+// names used by Go
+var boxArchitectures = map[string]string{
+	"386": "i386",
+}
+
+// Box collections which can find boxes for an architecture. Collections
+// which don't implement this are searched without an architecture.
+type architectureBoxCollection interface {
+	Architecture() string
+	FindArchitecture(name, version, arch string, providers ...string) (core.Box, error)
+}
+
+var _ architectureBoxCollection = (*BoxCollection)(nil)
+
+// Returns the architecture of the host using the names found in
+// box metadata.
+func HostArchitecture() string {
+	if arch, ok := boxArchitectures[runtime.GOARCH]; ok {
+		return arch
+	}
+	return runtime.GOARCH
+}
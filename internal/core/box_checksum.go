@@ -0,0 +1,94 @@
+package core
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Supported checksum types for validating box files
+var BoxChecksumTypes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Calculates the checksum of the file at the given path using
+// the requested checksum type.
+func boxChecksum(path, checksumType string) (checksum string, err error) {
+	newHash, ok := BoxChecksumTypes[normalizeChecksumType(checksumType)]
+	if !ok {
+		return "", fmt.Errorf("unsupported checksum type %q", checksumType)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := newHash()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Validates the file at the given path matches the expected checksum.
+// If either the checksum or the checksum type are empty, no validation
+// is performed.
+func validateBoxChecksum(name, path, checksumType, checksum string) (err error) {
+	checksum = strings.TrimSpace(checksum)
+	if checksum == "" || strings.TrimSpace(checksumType) == "" {
+		return
+	}
+	if _, ok := BoxChecksumTypes[normalizeChecksumType(checksumType)]; !ok {
+		return localizeErr(
+			"box_checksum_invalid_type",
+			map[string]string{
+				"BoxName":       name,
+				"ChecksumType":  checksumType,
+				"ChecksumTypes": strings.Join(supportedChecksumTypes(), ", "),
+			},
+		)
+	}
+	actual, err := boxChecksum(path, checksumType)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, checksum) {
+		return localizeErr(
+			"box_checksum_mismatch",
+			map[string]string{
+				"BoxName":  name,
+				"Expected": checksum,
+				"Actual":   actual,
+			},
+		)
+	}
+	return
+}
+
+// Checksum types are matched without regard to case and
+// separators so values like "SHA-256" are accepted.
+func normalizeChecksumType(checksumType string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(checksumType)), "-", "")
+}
+
+func supportedChecksumTypes() []string {
+	types := make([]string, 0, len(BoxChecksumTypes))
+	for t := range BoxChecksumTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -29,47 +29,65 @@ const (
 )
 
 type BoxCollection struct {
-	basis     *Basis
-	directory string
-	logger    hclog.Logger
+	basis        *Basis
+	directory    string
+	logger       hclog.Logger
+	architecture string
 }
 
-func NewBoxCollection(basis *Basis, dir string, logger hclog.Logger) (bc *BoxCollection, err error) {
+func NewBoxCollection(basis *Basis, dir string, logger hclog.Logger, opts ...BoxCollectionOption) (bc *BoxCollection, err error) {
 	bc = &BoxCollection{
 		basis:     basis,
 		directory: dir,
 		logger:    logger,
 	}
+	for _, opt := range opts {
+		if err = opt(bc); err != nil {
+			return nil, err
+		}
+	}
 	err = bc.RecoverBoxes()
 	return
 }
 
+type BoxCollectionOption func(*BoxCollection) error
+
+// Sets the architecture used when finding boxes. Defaults to the
+// architecture of the host.
+func BoxCollectionWithArchitecture(arch string) BoxCollectionOption {
+	return func(b *BoxCollection) (err error) {
+		b.architecture = arch
+		return
+	}
+}
+
+// Returns the architecture used when finding boxes
+func (b *BoxCollection) Architecture() string {
+	if b.architecture != "" {
+		return b.architecture
+	}
+	return HostArchitecture()
+}
+
 // This adds a new box to the system.
 // There are some exceptional cases:
 // * BoxAlreadyExists - The box you're attempting to add already exists.
 // * BoxProviderDoesntMatch - If the given box provider doesn't match the
 // 	actual box provider in the untarred box.
 // * BoxUnpackageFailure - An invalid tar file.
+// * BoxChecksumMismatch - The box file does not match the checksum
+// 	provided by the box metadata.
+//
+// If the box has a metadata URL, the box file is validated against the
+// checksum for the box provider in the box metadata. A box that fails
+// validation is never registered. If the metadata can't be loaded the
+// box is added without being verified.
 func (b *BoxCollection) Add(p path.Path, name, version, metadataURL string, force bool, providers ...string) (box core.Box, err error) {
 	if _, err := os.Stat(p.String()); err != nil {
 		return nil, fmt.Errorf("Could not add box, unable to find path %s", p.String())
 	}
-	exists, err := b.Find(name, version, providers...)
-	if err != nil {
-		return nil, err
-	}
-
-	if exists != nil && !force {
-		return nil, fmt.Errorf("Box already exits, can't add %s v%s", name, version)
-	} else {
-		if exists != nil {
-			// If the box already exists but force is enabled, then delete the box
-			exists.Destroy()
-		}
-	}
 
-	tempDir := filepath.Join(b.basis.dir.TempDir().String(), "box-extractor")
-	err = os.MkdirAll(tempDir, 0755)
+	tempDir, err := ioutil.TempDir(b.basis.dir.TempDir().String(), "box-extractor")
 	if err != nil {
 		return nil, err
 	} // delete tempdir when finished
@@ -105,6 +123,7 @@ func (b *BoxCollection) Add(p path.Path, name, version, metadataURL string, forc
 		if err != nil {
 			return nil, err
 		}
+		defer os.RemoveAll(tempDir)
 	}
 
 	newBox, err := NewBox(
@@ -133,51 +152,54 @@ func (b *BoxCollection) Add(p path.Path, name, version, metadataURL string, forc
 		}
 	}
 
-	destDir := filepath.Join(b.directory, b.generateDirectoryName(name), version, provider)
-	b.logger.Debug("Box directory: %s", destDir)
-	os.MkdirAll(destDir, 0755)
-	// Copy the contents of the tempdir to the final dir
-	err = filepath.Walk(tempDir, func(path string, info os.FileInfo, erro error) (err error) {
-		destPath, err := validateNewPath(filepath.Join(destDir, info.Name()), destDir)
+	// The architecture is read from the box, falling back to the
+	// box metadata
+	architecture := newBox.box.Architecture
+	var metadataProvider *BoxVersionProvider
+	if metadataURL != "" {
+		metadataProvider, err = b.metadataProvider(metadataURL, version, provider)
 		if err != nil {
-			return err
+			b.logger.Warn("failed to load box metadata, box checksum will not be verified",
+				"box", name, "url", metadataURL, "error", err)
 		}
-		if info.IsDir() {
-			err = os.MkdirAll(destPath, info.Mode())
-			return err
-		} else {
-			data, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer data.Close()
-			dest, err := os.Create(destPath)
-			if err != nil {
-				return err
-			}
-			defer dest.Close()
-			if err != nil {
-				return err
-			}
-			if _, err := io.Copy(dest, data); err != nil {
-				return err
-			}
+	}
+	if metadataProvider != nil {
+		if architecture == "" {
+			architecture = metadataProvider.Architecture
 		}
-		return
-	})
 
-	newBox, err = NewBox(
-		BoxWithBasis(b.basis),
-		BoxWithBox(&vagrant_server.Box{
-			Name:        name,
-			Version:     version,
-			Directory:   destDir,
-			Provider:    provider,
-			MetadataUrl: metadataURL,
-		}),
-	)
-	newBox.Save()
-	return newBox, nil
+		if metadataProvider.Checksum != "" {
+			b.logger.Debug("validating box checksum from metadata",
+				"box", name, "type", metadataProvider.ChecksumType)
+		}
+		err = validateBoxChecksum(name, p.String(),
+			metadataProvider.ChecksumType, metadataProvider.Checksum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	exists, err := b.FindArchitecture(name, version, architecture, provider)
+	if err != nil {
+		return nil, err
+	}
+	// Boxes for other architectures are installed alongside this box
+	if exists != nil && exists.(*Box).box.Architecture != architecture {
+		exists = nil
+	}
+	if exists != nil && !force {
+		return nil, fmt.Errorf("Box already exits, can't add %s v%s", name, version)
+	}
+
+	destDir := b.boxDirectory(name, version, architecture, provider)
+	b.logger.Debug("installing box", "directory", destDir)
+	return b.installBox(tempDir, destDir, &boxInstallJournal{
+		Name:         name,
+		Version:      version,
+		Provider:     provider,
+		Architecture: architecture,
+		MetadataURL:  metadataURL,
+	}, exists)
 }
 
 // This returns an array of all the boxes on the system
@@ -201,8 +223,17 @@ func (b *BoxCollection) All() (boxes []core.Box, err error) {
 	return
 }
 
-// Find a box in the collection with the given name, version and provider.
+// Find a box in the collection with the given name, version and provider
+// for the collection architecture.
 func (b *BoxCollection) Find(name, version string, providers ...string) (box core.Box, err error) {
+	return b.FindArchitecture(name, version, b.Architecture(), providers...)
+}
+
+// Find a box in the collection with the given name, version and provider
+// which can be used on the given architecture. Boxes built for the
+// architecture are preferred over boxes without an architecture. If the
+// architecture is empty, boxes for any architecture are matched.
+func (b *BoxCollection) FindArchitecture(name, version, arch string, providers ...string) (box core.Box, err error) {
 	// If no providers are spcified then search for any provider
 	if len(providers) == 0 {
 		providers = append(providers, "")
@@ -214,6 +245,7 @@ func (b *BoxCollection) Find(name, version string, providers ...string) (box cor
 				Box: &vagrant_plugin_sdk.Ref_Box{
 					Name: name, Version: version, Provider: provider,
 				},
+				Architecture: arch,
 			},
 		)
 		if err != nil {
@@ -237,7 +269,13 @@ func (b *BoxCollection) Clean(name string) (err error) {
 	return os.RemoveAll(path)
 }
 
+// Recovers the state of the box collection. Interrupted box installs
+// are either finished or rolled back, and boxes which no longer exist
+// on disk are removed.
 func (b *BoxCollection) RecoverBoxes() (err error) {
+	if err = b.recoverInstalls(); err != nil {
+		return err
+	}
 	resp, err := b.basis.client.ListBoxes(
 		b.basis.ctx,
 		&emptypb.Empty{},
@@ -247,23 +285,52 @@ func (b *BoxCollection) RecoverBoxes() (err error) {
 	}
 	// Ensure that each box exists
 	for _, boxRef := range resp.Boxes {
-		box, erro := b.basis.client.GetBox(b.basis.ctx, &vagrant_server.GetBoxRequest{Box: boxRef})
+		box, err := b.basis.client.GetBox(b.basis.ctx, &vagrant_server.GetBoxRequest{Box: boxRef})
+		if err != nil {
+			return err
+		}
 		// If the box directory does not exist, then the box doesn't exist.
 		if _, err := os.Stat(box.Box.Directory); err != nil {
 			// Remove the box
-			_, erro := b.basis.client.DeleteBox(b.basis.ctx, &vagrant_server.DeleteBoxRequest{Box: boxRef})
-			if erro != nil {
-				return erro
+			_, err := b.basis.client.DeleteBox(b.basis.ctx, &vagrant_server.DeleteBoxRequest{Box: boxRef})
+			if err != nil {
+				return err
 			}
 		}
-		if erro != nil {
-			return erro
-		}
 	}
 
 	return
 }
 
+// Looks up the box provider for the collection architecture in the box
+// metadata. An error is returned if the metadata cannot be loaded or
+// does not include the provider.
+func (b *BoxCollection) metadataProvider(metadataURL, version, provider string) (p *BoxVersionProvider, err error) {
+	metadata := &BoxMetadata{}
+	if err = metadata.LoadMetadata(metadataURL); err != nil {
+		return nil, err
+	}
+	if p, err = metadata.ProviderArchitecture(version, provider, b.Architecture()); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("no %s provider for version %s and architecture %s",
+			provider, version, b.Architecture())
+	}
+	return p, nil
+}
+
+// Returns the directory a box is installed in. Boxes are stored as
+// <name>/<version>/<provider>, or <name>/<version>/<architecture>/<provider>
+// when the box is built for a specific architecture.
+func (b *BoxCollection) boxDirectory(name, version, arch, provider string) string {
+	dir := filepath.Join(b.directory, b.generateDirectoryName(name), version)
+	if arch != "" {
+		dir = filepath.Join(dir, arch)
+	}
+	return filepath.Join(dir, provider)
+}
+
 func (b *BoxCollection) generateDirectoryName(path string) (out string) {
 	out = strings.ReplaceAll(path, ":", VagrantColon)
 	return strings.ReplaceAll(out, "/", VagrantSlash)
@@ -3,8 +3,11 @@ package core
 import (
 	"archive/tar"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -69,6 +72,38 @@ func generateTestBox(t *testing.T, path string, basis *Basis) string {
 	return outputPath
 }
 
+func generateNestedTestBox(t *testing.T, path string) string {
+	outputPath := filepath.Join(path, "nested.box")
+	tarFile, err := os.Create(outputPath)
+	require.NoError(t, err)
+	defer tarFile.Close()
+	tw := tar.NewWriter(tarFile)
+	defer tw.Close()
+
+	files := map[string]string{
+		"metadata.json":    "{\"provider\":\"virtualbox\"}",
+		"disks/disk1.vmdk": "disk",
+	}
+	for name, content := range files {
+		err = tw.WriteHeader(&tar.Header{
+			Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg,
+		})
+		require.NoError(t, err)
+		_, err = tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	return outputPath
+}
+
+func stageTestBox(t *testing.T, bc *BoxCollection, journal *boxInstallJournal) string {
+	destDir := filepath.Join(bc.directory, bc.generateDirectoryName(journal.Name), journal.Version, journal.Provider)
+	stagingDir := destDir + BoxStagingSuffix
+	require.NoError(t, os.MkdirAll(stagingDir, 0755))
+	err := os.WriteFile(filepath.Join(stagingDir, "metadata.json"), []byte("{\"provider\":\"virtualbox\"}"), 0644)
+	require.NoError(t, err)
+	return destDir
+}
+
 func TestAddErrors(t *testing.T) {
 	bc := newBoxCollection(t)
 
@@ -121,6 +156,86 @@ func TestAddBadProviders(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestAddInvalidChecksumType(t *testing.T) {
+	bc := newBoxCollection(t)
+
+	td, err := ioutil.TempDir("/tmp", "box")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(td) })
+
+	testBoxPath := generateTestBox(t, td, bc.basis)
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "test/box", "versions": [{"version": "1.2.5", "providers": [
+			{"name": "virtualbox", "url": "http://doesnotexist", "checksum": "abc123", "checksum_type": "crc32"}
+		]}]}`))
+	}))
+	defer metadata.Close()
+
+	_, err = bc.Add(path.NewPath(testBoxPath), "test/box", "1.2.5", metadata.URL, false)
+	require.Error(t, err)
+}
+
+func TestAddChecksumFromMetadata(t *testing.T) {
+	bc := newBoxCollection(t)
+
+	td, err := ioutil.TempDir("/tmp", "box")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(td) })
+
+	testBoxPath := generateTestBox(t, td, bc.basis)
+	checksum, err := boxChecksum(testBoxPath, "sha512")
+	require.NoError(t, err)
+
+	metadata := func(checksum string) string {
+		return fmt.Sprintf(`{"name": "test/box", "versions": [{"version": "1.2.5", "providers": [
+			{"name": "virtualbox", "url": "http://doesnotexist", "checksum": "%s", "checksum_type": "sha512"}
+		]}]}`, checksum)
+	}
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(metadata(checksum)))
+	}))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(metadata("abc123")))
+	}))
+	defer bad.Close()
+
+	_, err = bc.Add(path.NewPath(testBoxPath), "test/box", "1.2.5", bad.URL, false)
+	require.Error(t, err)
+	box, err := bc.Find("test/box", "1.2.5")
+	require.NoError(t, err)
+	require.Nil(t, box)
+
+	box, err = bc.Add(path.NewPath(testBoxPath), "test/box", "1.2.5", good.URL, false)
+	require.NoError(t, err)
+	require.NotNil(t, box)
+}
+
+func TestAddChecksumMetadataUnavailable(t *testing.T) {
+	td, err := ioutil.TempDir("/tmp", "box")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(td) })
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	otherProvider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "test/box", "versions": [{"version": "1.2.5", "providers": [
+			{"name": "libvirt", "url": "http://doesnotexist", "checksum": "abc123", "checksum_type": "sha1"}
+		]}]}`))
+	}))
+	defer otherProvider.Close()
+
+	// The box is added without verification, such as when offline
+	for _, url := range []string{missing.URL, otherProvider.URL} {
+		bc := newBoxCollection(t)
+		testBoxPath := generateTestBox(t, td, bc.basis)
+
+		box, err := bc.Add(path.NewPath(testBoxPath), "test/box", "1.2.5", url, false)
+		require.NoError(t, err)
+		require.NotNil(t, box)
+	}
+}
+
 func TestAll(t *testing.T) {
 	bc := newBoxCollection(t)
 	boxes, err := bc.All()
@@ -156,6 +271,70 @@ func TestFind(t *testing.T) {
 	require.NotNil(t, boxes)
 }
 
+func generateArchitectureTestBox(t *testing.T, path, arch string) string {
+	outputPath := filepath.Join(path, arch+".box")
+	tarFile, err := os.Create(outputPath)
+	require.NoError(t, err)
+	defer tarFile.Close()
+	tw := tar.NewWriter(tarFile)
+	defer tw.Close()
+
+	content := "{\"provider\":\"virtualbox\",\"architecture\":\"" + arch + "\"}"
+	err = tw.WriteHeader(&tar.Header{
+		Name: "metadata.json", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg,
+	})
+	require.NoError(t, err)
+	_, err = tw.Write([]byte(content))
+	require.NoError(t, err)
+	return outputPath
+}
+
+func TestAddWithArchitecture(t *testing.T) {
+	bc := newBoxCollection(t)
+	bc.architecture = "arm64"
+	td := t.TempDir()
+
+	box, err := bc.Add(path.NewPath(generateArchitectureTestBox(t, td, "arm64")), "arch/box", "1.0.0", "", false)
+	require.NoError(t, err)
+	require.Equal(t, "arm64", box.(*Box).box.Architecture)
+	require.DirExists(t, filepath.Join(bc.directory, "arch-VAGRANTSLASH-box", "1.0.0", "arm64", "virtualbox"))
+
+	// Boxes for other architectures are added alongside
+	_, err = bc.Add(path.NewPath(generateArchitectureTestBox(t, td, "amd64")), "arch/box", "1.0.0", "", false)
+	require.NoError(t, err)
+	_, err = bc.Add(path.NewPath(generateNestedTestBox(t, td)), "arch/box", "1.0.0", "", false)
+	require.NoError(t, err)
+	require.DirExists(t, filepath.Join(bc.directory, "arch-VAGRANTSLASH-box", "1.0.0", "virtualbox"))
+
+	// Adding the same architecture again requires force
+	_, err = bc.Add(path.NewPath(generateArchitectureTestBox(t, td, "arm64")), "arch/box", "1.0.0", "", false)
+	require.Error(t, err)
+
+	// Find defaults to the collection architecture
+	found, err := bc.Find("arch/box", "1.0.0", "virtualbox")
+	require.NoError(t, err)
+	arch, err := found.(*Box).Architecture()
+	require.NoError(t, err)
+	require.Equal(t, "arm64", arch)
+
+	found, err = bc.FindArchitecture("arch/box", "1.0.0", "amd64", "virtualbox")
+	require.NoError(t, err)
+	require.Equal(t, "amd64", found.(*Box).box.Architecture)
+
+	// Boxes without an architecture are used for other architectures
+	found, err = bc.FindArchitecture("arch/box", "1.0.0", "s390x", "virtualbox")
+	require.NoError(t, err)
+	require.Empty(t, found.(*Box).box.Architecture)
+}
+
+func TestBoxCollectionArchitecture(t *testing.T) {
+	bc := newBoxCollection(t)
+	require.Equal(t, HostArchitecture(), bc.Architecture())
+
+	require.NoError(t, BoxCollectionWithArchitecture("arm64")(bc))
+	require.Equal(t, "arm64", bc.Architecture())
+}
+
 func TestRemoveMissingBox(t *testing.T) {
 	// Create initial box collection
 	bc := newBoxCollection(t)
@@ -186,3 +365,96 @@ func TestRemoveMissingBox(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, boxes)
 }
+
+func TestAddPreservesDirectories(t *testing.T) {
+	bc := newBoxCollection(t)
+
+	td, err := ioutil.TempDir("/tmp", "box")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(td) })
+
+	testBoxPath := generateNestedTestBox(t, td)
+	box, err := bc.Add(path.NewPath(testBoxPath), "test/box", "1.2.6", "", false)
+	require.NoError(t, err)
+	require.NotNil(t, box)
+
+	dir, err := box.Directory()
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir.String(), "disks", "disk1.vmdk"))
+	require.NoFileExists(t, filepath.Join(dir.String(), "disk1.vmdk"))
+	require.NoFileExists(t, filepath.Join(dir.String(), BoxInstallJournal))
+	require.NoDirExists(t, dir.String()+BoxStagingSuffix)
+}
+
+func TestRecoverIncompleteStagedBox(t *testing.T) {
+	bc := newBoxCollection(t)
+	destDir := stageTestBox(t, bc, &boxInstallJournal{
+		Name: "test/box", Version: "1.2.7", Provider: "virtualbox",
+	})
+
+	bc, err := NewBoxCollection(bc.basis, bc.directory, bc.logger)
+	require.NoError(t, err)
+	require.NoDirExists(t, destDir+BoxStagingSuffix)
+	require.NoDirExists(t, destDir)
+
+	box, err := bc.Find("test/box", "1.2.7")
+	require.NoError(t, err)
+	require.Nil(t, box)
+}
+
+func TestRecoverStagedBox(t *testing.T) {
+	bc := newBoxCollection(t)
+	journal := &boxInstallJournal{
+		Name: "test/box", Version: "1.2.7", Provider: "virtualbox",
+	}
+	destDir := stageTestBox(t, bc, journal)
+	require.NoError(t, writeBoxInstallJournal(destDir+BoxStagingSuffix, journal))
+
+	bc, err := NewBoxCollection(bc.basis, bc.directory, bc.logger)
+	require.NoError(t, err)
+	require.NoDirExists(t, destDir+BoxStagingSuffix)
+	require.FileExists(t, filepath.Join(destDir, "metadata.json"))
+	require.NoFileExists(t, filepath.Join(destDir, BoxInstallJournal))
+
+	box, err := bc.Find("test/box", "1.2.7")
+	require.NoError(t, err)
+	require.NotNil(t, box)
+}
+
+func TestRecoverStagedArchitectureBox(t *testing.T) {
+	bc := newBoxCollection(t)
+	journal := &boxInstallJournal{Name: "test/staged", Version: "1.0.0", Provider: "virtualbox", Architecture: "arm64"}
+	destDir := bc.boxDirectory(journal.Name, journal.Version, journal.Architecture, journal.Provider)
+	stagingDir := destDir + BoxStagingSuffix
+	require.NoError(t, os.MkdirAll(stagingDir, 0755))
+	err := os.WriteFile(filepath.Join(stagingDir, "metadata.json"), []byte("{\"provider\":\"virtualbox\"}"), 0644)
+	require.NoError(t, err)
+	require.NoError(t, writeBoxInstallJournal(stagingDir, journal))
+
+	require.NoError(t, bc.RecoverBoxes())
+	require.NoDirExists(t, stagingDir)
+	require.DirExists(t, destDir)
+
+	box, err := bc.FindArchitecture("test/staged", "1.0.0", "arm64", "virtualbox")
+	require.NoError(t, err)
+	require.NotNil(t, box)
+	require.Equal(t, "arm64", box.(*Box).box.Architecture)
+}
+
+func TestRecoverUnregisteredBox(t *testing.T) {
+	bc := newBoxCollection(t)
+	journal := &boxInstallJournal{
+		Name: "test/box", Version: "1.2.7", Provider: "virtualbox",
+	}
+	destDir := stageTestBox(t, bc, journal)
+	require.NoError(t, writeBoxInstallJournal(destDir+BoxStagingSuffix, journal))
+	require.NoError(t, os.Rename(destDir+BoxStagingSuffix, destDir))
+
+	bc, err := NewBoxCollection(bc.basis, bc.directory, bc.logger)
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(destDir, BoxInstallJournal))
+
+	box, err := bc.Find("test/box", "1.2.7")
+	require.NoError(t, err)
+	require.NotNil(t, box)
+}
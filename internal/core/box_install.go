@@ -0,0 +1,277 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/core"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+const (
+	// Suffix of the directory a box is staged in before it is moved
+	// into its final location
+	BoxStagingSuffix = ".vagrant-staging"
+
+	// Name of the file describing an install which has not yet
+	// been registered with the server
+	BoxInstallJournal = ".vagrant-install.json"
+)
+
+// Describes a box install which is in progress. The journal is written
+// into the staging directory once all files have been copied and is
+// removed after the box has been registered. If Vagrant is interrupted
+// the journal is used to finish the install.
+type boxInstallJournal struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Provider     string `json:"provider"`
+	Architecture string `json:"architecture,omitempty"`
+	MetadataURL  string `json:"metadata_url"`
+}
+
+// Installs the extracted box in srcDir into destDir. The box is staged
+// next to destDir, synced to disk and renamed into place before being
+// registered with the server. If replace is provided, it is destroyed
+// immediately before the staged box is moved into place.
+func (b *BoxCollection) installBox(srcDir, destDir string, journal *boxInstallJournal, replace core.Box) (box *Box, err error) {
+	stagingDir := destDir + BoxStagingSuffix
+	if err = os.RemoveAll(stagingDir); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(destDir), 0755); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(stagingDir)
+		}
+	}()
+
+	b.logger.Debug("staging box", "source", srcDir, "staging", stagingDir)
+	if err = copyBoxTree(srcDir, stagingDir); err != nil {
+		return nil, err
+	}
+	if err = writeBoxInstallJournal(stagingDir, journal); err != nil {
+		return nil, err
+	}
+
+	if replace != nil {
+		// If the box already exists but force is enabled, then delete the box
+		if err = replace.Destroy(); err != nil {
+			return nil, err
+		}
+	}
+	if err = os.RemoveAll(destDir); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(stagingDir, destDir); err != nil {
+		return nil, err
+	}
+	if err = syncDir(filepath.Dir(destDir)); err != nil {
+		return nil, err
+	}
+
+	return b.registerBox(destDir, journal)
+}
+
+// Registers the installed box with the server and removes the
+// install journal.
+func (b *BoxCollection) registerBox(dir string, journal *boxInstallJournal) (box *Box, err error) {
+	box, err = NewBox(
+		BoxWithBasis(b.basis),
+		BoxWithBox(&vagrant_server.Box{
+			Name:         journal.Name,
+			Version:      journal.Version,
+			Directory:    dir,
+			Provider:     journal.Provider,
+			Architecture: journal.Architecture,
+			MetadataUrl:  journal.MetadataURL,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err = box.Save(); err != nil {
+		return nil, err
+	}
+	if err = os.Remove(filepath.Join(dir, BoxInstallJournal)); err != nil {
+		return nil, err
+	}
+	return box, syncDir(dir)
+}
+
+// Finds box installs which were interrupted. Staged boxes which were
+// completely copied are moved into place and registered, partially
+// staged boxes are removed. Boxes which were moved into place but not
+// registered are registered.
+func (b *BoxCollection) recoverInstalls() (err error) {
+	// Boxes are stored as <name>/<version>/<provider> or
+	// <name>/<version>/<architecture>/<provider>
+	dirs, err := filepath.Glob(filepath.Join(b.directory, "*", "*", "*"))
+	if err != nil {
+		return err
+	}
+	archDirs, err := filepath.Glob(filepath.Join(b.directory, "*", "*", "*", "*"))
+	if err != nil {
+		return err
+	}
+	dirs = append(dirs, archDirs...)
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		journal, err := readBoxInstallJournal(dir)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(dir, BoxStagingSuffix) {
+			if journal == nil {
+				b.logger.Info("removing incomplete staged box", "path", dir)
+				if err = os.RemoveAll(dir); err != nil {
+					return err
+				}
+				continue
+			}
+			destDir := strings.TrimSuffix(dir, BoxStagingSuffix)
+			b.logger.Info("finishing interrupted box install",
+				"box", journal.Name, "version", journal.Version, "path", destDir)
+			if err = os.RemoveAll(destDir); err != nil {
+				return err
+			}
+			if err = os.Rename(dir, destDir); err != nil {
+				return err
+			}
+			if err = syncDir(filepath.Dir(destDir)); err != nil {
+				return err
+			}
+			dir = destDir
+		} else if journal == nil {
+			continue
+		}
+
+		b.logger.Info("registering interrupted box install",
+			"box", journal.Name, "version", journal.Version, "path", dir)
+		if _, err = b.registerBox(dir, journal); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+func writeBoxInstallJournal(dir string, journal *boxInstallJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, BoxInstallJournal))
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Reads the install journal from the given directory. If no journal
+// exists, nil is returned.
+func readBoxInstallJournal(dir string) (*boxInstallJournal, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, BoxInstallJournal))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	journal := &boxInstallJournal{}
+	if err = json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("invalid box install journal in %s: %w", dir, err)
+	}
+	return journal, nil
+}
+
+// Copies the contents of src into dst preserving the directory
+// structure. All files and directories are synced to disk.
+func copyBoxTree(src, dst string) error {
+	var dirs []string
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		destPath, err := validateNewPath(filepath.Join(dst, rel), dst)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, destPath)
+			return os.MkdirAll(destPath, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("could not add box, unsupported file type for %s", rel)
+		}
+		return copyBoxFile(path, destPath, info.Mode().Perm())
+	})
+	if err != nil {
+		return err
+	}
+	// Sync directories deepest first so entries are persisted
+	// before their parents
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = syncDir(dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyBoxFile(src, dst string, mode os.FileMode) (err error) {
+	data, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+	dest, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dest, data); err == nil {
+		err = dest.Sync()
+	}
+	if cerr := dest.Close(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+// Syncs the directory so that entries created within it are
+// persisted. Directories cannot be synced on Windows.
+func syncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
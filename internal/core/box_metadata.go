@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strings"
 
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/vagrant-plugin-sdk/core"
@@ -16,6 +17,11 @@ type BoxVersionProvider struct {
 	Url          string
 	Checksum     string
 	ChecksumType string
+	// Architecture the box is built for. Providers without an
+	// architecture can be used on any host.
+	Architecture string
+	// Provider is used when no architecture is requested
+	DefaultArchitecture bool
 }
 
 func (b *BoxVersionProvider) MatchesAny(p ...*BoxVersionProvider) (matches bool) {
@@ -35,7 +41,7 @@ func (b *BoxVersionProvider) Matches(p *BoxVersionProvider) (matches bool) {
 
 	fields := pVal.NumField()
 	for i := 0; i < fields; i++ {
-		if pVal.Field(i).Interface() != "" {
+		if !pVal.Field(i).IsZero() {
 			bField := bVal.FieldByName(typeOfMatcher.Field(i).Name).Interface()
 			pField := pVal.Field(i).Interface()
 			if pField != bField {
@@ -62,6 +68,30 @@ func (b *BoxVersion) Provider(name string) (p *BoxVersionProvider, err error) {
 	return
 }
 
+// Returns the provider with the given name built for the architecture.
+// Providers built for the architecture are preferred over providers
+// without an architecture. If the architecture is empty, the default
+// architecture provider is returned.
+func (b *BoxVersion) ProviderArchitecture(name, arch string) (p *BoxVersionProvider, err error) {
+	for _, provider := range b.Providers {
+		if provider.Name != name {
+			continue
+		}
+		switch {
+		case arch == "" && provider.DefaultArchitecture:
+			return provider, nil
+		case arch != "" && provider.Architecture == arch:
+			return provider, nil
+		case provider.Architecture == "" && p == nil:
+			p = provider
+		}
+	}
+	if p == nil && arch == "" {
+		return b.Provider(name)
+	}
+	return
+}
+
 func (b *BoxVersion) ListProviders() ([]string, error) {
 	p := []string{}
 	for _, provider := range b.Providers {
@@ -82,10 +112,36 @@ func LoadBoxMetadata(data []byte) (*BoxMetadata, error) {
 		return nil, err
 	}
 	var result BoxMetadata
-	err := mapstructure.Decode(metadata, &result)
+	err := decodeBoxMetadata(metadata, &result)
 	return &result, err
 }
 
+// Decodes raw box metadata into the result. Box metadata uses snake
+// cased keys (for example "checksum_type") which are mapped to the
+// matching struct fields.
+func decodeBoxMetadata(metadata map[string]interface{}, result interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: boxMetadataKeyHook,
+		Result:     result,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(metadata)
+}
+
+func boxMetadataKeyHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	raw, ok := data.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+	converted := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		converted[strings.ReplaceAll(k, "_", "")] = v
+	}
+	return converted, nil
+}
+
 func (b *BoxMetadata) matches(version string, name string, p *core.BoxProvider) (matches bool, err error) {
 	ver, err := b.version(version, &core.BoxProvider{Name: name})
 	if err != nil {
@@ -164,7 +220,7 @@ func (b *BoxMetadata) LoadMetadata(url string) (err error) {
 	if err := json.Unmarshal(raw, &metadata); err != nil {
 		return err
 	}
-	err = mapstructure.Decode(metadata, &b)
+	err = decodeBoxMetadata(metadata, b)
 	return
 }
 
@@ -210,6 +266,16 @@ func (b *BoxMetadata) ListVersions(providerOpts ...*core.BoxProvider) ([]string,
 	return v, nil
 }
 
+// Returns the provider with the given name for the version built for
+// the architecture. See BoxVersion.ProviderArchitecture.
+func (b *BoxMetadata) ProviderArchitecture(version, name, arch string) (p *BoxVersionProvider, err error) {
+	ver, err := b.version(version, &core.BoxProvider{Name: name})
+	if err != nil || ver == nil {
+		return nil, err
+	}
+	return ver.ProviderArchitecture(name, arch)
+}
+
 func (b *BoxMetadata) Provider(version string, name string) (p *core.BoxProvider, err error) {
 	ver, err := b.version(version, &core.BoxProvider{Name: name})
 	if err != nil {
@@ -16,7 +16,9 @@ var rawMetadata = `{
 			"description": "does things",
 			"providers": [{
 					"name": "virtualbox",
-					"url": "http://doesnotexist"
+					"url": "http://doesnotexist",
+					"checksum": "abc123",
+					"checksum_type": "sha256"
 				},
 				{
 					"name": "vmware",
@@ -158,6 +160,61 @@ func TestVersionGetProvider(t *testing.T) {
 	require.Nil(t, neProvider)
 }
 
+func TestVersionGetProviderChecksum(t *testing.T) {
+	_, provider := loadProvider(t, []byte(rawMetadata), "1.2.3", "virtualbox")
+	require.NotNil(t, provider)
+	require.Equal(t, "abc123", provider.Checksum)
+	require.Equal(t, "sha256", provider.ChecksumType)
+}
+
+var rawArchitectureMetadata = `{
+	"name": "test/box",
+	"versions": [{
+		"version": "1.2.3",
+		"providers": [{
+				"name": "virtualbox",
+				"url": "http://doesnotexist/amd64",
+				"architecture": "amd64",
+				"default_architecture": true
+			},
+			{
+				"name": "virtualbox",
+				"url": "http://doesnotexist/arm64",
+				"architecture": "arm64"
+			},
+			{
+				"name": "vmware",
+				"url": "http://doesnotexist/vmware"
+			}
+		]
+	}]
+}`
+
+func TestVersionGetProviderArchitecture(t *testing.T) {
+	metadata := loadMetadata(t, []byte(rawArchitectureMetadata))
+
+	provider, err := metadata.ProviderArchitecture("1.2.3", "virtualbox", "arm64")
+	require.NoError(t, err)
+	require.Equal(t, "http://doesnotexist/arm64", provider.Url)
+	require.Equal(t, "arm64", provider.Architecture)
+
+	// Default architecture is used when no architecture is requested
+	provider, err = metadata.ProviderArchitecture("1.2.3", "virtualbox", "")
+	require.NoError(t, err)
+	require.Equal(t, "amd64", provider.Architecture)
+	require.True(t, provider.DefaultArchitecture)
+
+	// No provider for an unknown architecture
+	provider, err = metadata.ProviderArchitecture("1.2.3", "virtualbox", "s390x")
+	require.NoError(t, err)
+	require.Nil(t, provider)
+
+	// Providers without an architecture match any architecture
+	provider, err = metadata.ProviderArchitecture("1.2.3", "vmware", "arm64")
+	require.NoError(t, err)
+	require.Equal(t, "http://doesnotexist/vmware", provider.Url)
+}
+
 func TestProviderMatches(t *testing.T) {
 	version := "1.2.3"
 	providerName := "virtualbox"
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/vagrant-plugin-sdk/core"
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// Options for pruning boxes from the box collection
+type BoxPruneOptions struct {
+	// Number of versions to keep for each box name, provider and
+	// architecture.
+	// Defaults to 1 when unset.
+	KeepVersions int
+	// Only prune boxes with this name
+	Name string
+	// Only prune boxes for this provider
+	Provider string
+	// Report the boxes that would be pruned without removing them
+	DryRun bool
+}
+
+// A box considered for pruning
+type BoxPruneEntry struct {
+	Name         string
+	Version      string
+	Provider     string
+	Architecture string
+	Directory    string
+	// Size of the box on disk in bytes
+	Size int64
+}
+
+// Result of pruning the box collection
+type BoxPruneReport struct {
+	// Boxes that were removed, or would be removed on a dry run
+	Pruned []*BoxPruneEntry
+	// Boxes that would have been pruned but are in use by a target
+	InUse []*BoxPruneEntry
+	// Total size in bytes of the pruned boxes
+	ReclaimedBytes int64
+	// Report was generated without removing any boxes
+	DryRun bool
+}
+
+// Prune removes old box versions from the collection. For each box name,
+// provider and architecture the newest versions are kept, and any older version which
+// is not in use by a target within the index is removed from disk and
+// from the server.
+func (b *BoxCollection) Prune(index core.TargetIndex, opts *BoxPruneOptions) (report *BoxPruneReport, err error) {
+	if opts == nil {
+		opts = &BoxPruneOptions{}
+	}
+	keep := opts.KeepVersions
+	if keep < 0 {
+		return nil, errors.New("number of box versions to keep cannot be negative")
+	}
+	if keep == 0 {
+		keep = 1
+	}
+
+	all, err := b.All()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the boxes by name, provider and architecture
+	groups := map[string][]*Box{}
+	for _, cb := range all {
+		box := cb.(*Box)
+		if opts.Name != "" && box.box.Name != opts.Name {
+			continue
+		}
+		if opts.Provider != "" && box.box.Provider != opts.Provider {
+			continue
+		}
+		key := box.box.Name + "/" + box.box.Provider + "/" + box.box.Architecture
+		groups[key] = append(groups[key], box)
+	}
+
+	report = &BoxPruneReport{
+		Pruned: []*BoxPruneEntry{},
+		InUse:  []*BoxPruneEntry{},
+		DryRun: opts.DryRun,
+	}
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		boxes := groups[key]
+		sort.SliceStable(boxes, func(i, j int) bool {
+			return compareBoxVersions(boxes[i].box.Version, boxes[j].box.Version) > 0
+		})
+		if len(boxes) <= keep {
+			continue
+		}
+		for _, box := range boxes[keep:] {
+			entry, err := newBoxPruneEntry(box)
+			if err != nil {
+				return nil, err
+			}
+			inUse, err := box.InUse(index)
+			if err != nil {
+				return nil, err
+			}
+			if inUse {
+				b.logger.Debug("skipping prune of box in use",
+					"box", entry.Name, "version", entry.Version, "provider", entry.Provider)
+				report.InUse = append(report.InUse, entry)
+				continue
+			}
+			if !opts.DryRun {
+				b.logger.Info("pruning box",
+					"box", entry.Name, "version", entry.Version, "provider", entry.Provider)
+				if err = b.removeBox(box); err != nil {
+					return nil, err
+				}
+			}
+			report.Pruned = append(report.Pruned, entry)
+			report.ReclaimedBytes += entry.Size
+		}
+	}
+
+	return
+}
+
+// Removes the box from the server and deletes the box files. Empty
+// parent directories left behind are cleaned up.
+func (b *BoxCollection) removeBox(box *Box) (err error) {
+	_, err = b.basis.client.DeleteBox(
+		b.basis.ctx,
+		&vagrant_server.DeleteBoxRequest{Box: &vagrant_plugin_sdk.Ref_Box{
+			ResourceId: box.box.Id,
+			Name:       box.box.Name,
+			Version:    box.box.Version,
+			Provider:   box.box.Provider,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if err = os.RemoveAll(box.box.Directory); err != nil {
+		return err
+	}
+
+	// Remove the version and name directories if they are now empty
+	root := b.directory + string(filepath.Separator)
+	for dir := filepath.Dir(box.box.Directory); strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func newBoxPruneEntry(box *Box) (*BoxPruneEntry, error) {
+	size, err := dirSize(box.box.Directory)
+	if err != nil {
+		return nil, err
+	}
+	return &BoxPruneEntry{
+		Name:         box.box.Name,
+		Version:      box.box.Version,
+		Provider:     box.box.Provider,
+		Architecture: box.box.Architecture,
+		Directory:    box.box.Directory,
+		Size:         size,
+	}, nil
+}
+
+// Compares two box versions. Versions which cannot be parsed are
+// sorted before valid versions.
+func compareBoxVersions(a, b string) int {
+	av, aerr := version.NewVersion(a)
+	bv, berr := version.NewVersion(b)
+	switch {
+	case aerr != nil && berr != nil:
+		return 0
+	case aerr != nil:
+		return -1
+	case berr != nil:
+		return 1
+	}
+	return av.Compare(bv)
+}
+
+// Returns the total size of the files within the directory. If the
+// directory does not exist, the size is zero.
+func dirSize(path string) (size int64, err error) {
+	err = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	return
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/core"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/stretchr/testify/require"
+)
+
+type pruneTestMachine struct {
+	core.Machine
+	box core.Box
+}
+
+func (m *pruneTestMachine) Box() (core.Box, error) {
+	return m.box, nil
+}
+
+type pruneTestTarget struct {
+	core.Target
+	machine core.Machine
+}
+
+func (t *pruneTestTarget) Specialize(interface{}) (interface{}, error) {
+	return t.machine, nil
+}
+
+type pruneTestIndex struct {
+	core.TargetIndex
+	targets []core.Target
+}
+
+func (i *pruneTestIndex) All() ([]core.Target, error) {
+	return i.targets, nil
+}
+
+func addPruneTestBox(t *testing.T, bc *BoxCollection, name, version, provider string) *Box {
+	dir := filepath.Join(bc.directory, bc.generateDirectoryName(name), version, provider)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	data := []byte("{\"provider\":\"" + provider + "\"}")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), data, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "box.img"), make([]byte, 100), 0644))
+	box, err := NewBox(
+		BoxWithBasis(bc.basis),
+		BoxWithBox(&vagrant_server.Box{
+			Name:      name,
+			Version:   version,
+			Directory: dir,
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, box.Save())
+	return box
+}
+
+func newPruneTestCollection(t *testing.T) *BoxCollection {
+	bc := newBoxCollection(t)
+	// Remove the seeded boxes so only the test boxes are considered
+	boxes, err := bc.All()
+	require.NoError(t, err)
+	for _, b := range boxes {
+		require.NoError(t, b.(*Box).Destroy())
+	}
+	return bc
+}
+
+func TestPruneKeepsNewestVersions(t *testing.T) {
+	bc := newPruneTestCollection(t)
+	addPruneTestBox(t, bc, "test/box", "1.0.0", "virtualbox")
+	addPruneTestBox(t, bc, "test/box", "1.10.0", "virtualbox")
+	addPruneTestBox(t, bc, "test/box", "1.2.0", "virtualbox")
+	addPruneTestBox(t, bc, "test/box", "1.0.0", "vmware")
+
+	report, err := bc.Prune(&pruneTestIndex{}, &BoxPruneOptions{KeepVersions: 2})
+	require.NoError(t, err)
+	require.Len(t, report.Pruned, 1)
+	require.Equal(t, "1.0.0", report.Pruned[0].Version)
+	require.Equal(t, "virtualbox", report.Pruned[0].Provider)
+	require.NoDirExists(t, report.Pruned[0].Directory)
+
+	box, err := bc.Find("test/box", "1.0.0", "virtualbox")
+	require.NoError(t, err)
+	require.Nil(t, box)
+	box, err = bc.Find("test/box", "1.0.0", "vmware")
+	require.NoError(t, err)
+	require.NotNil(t, box)
+	box, err = bc.Find("test/box", "1.2.0", "virtualbox")
+	require.NoError(t, err)
+	require.NotNil(t, box)
+}
+
+func TestPruneDryRun(t *testing.T) {
+	bc := newPruneTestCollection(t)
+	addPruneTestBox(t, bc, "test/box", "1.0.0", "virtualbox")
+	addPruneTestBox(t, bc, "test/box", "1.1.0", "virtualbox")
+
+	report, err := bc.Prune(&pruneTestIndex{}, &BoxPruneOptions{DryRun: true})
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Len(t, report.Pruned, 1)
+	require.Equal(t, "1.0.0", report.Pruned[0].Version)
+	require.Greater(t, report.ReclaimedBytes, int64(100))
+	require.DirExists(t, report.Pruned[0].Directory)
+
+	box, err := bc.Find("test/box", "1.0.0", "virtualbox")
+	require.NoError(t, err)
+	require.NotNil(t, box)
+}
+
+func TestPruneSkipsBoxesInUse(t *testing.T) {
+	bc := newPruneTestCollection(t)
+	inUse := addPruneTestBox(t, bc, "test/box", "1.0.0", "virtualbox")
+	addPruneTestBox(t, bc, "test/box", "1.1.0", "virtualbox")
+	addPruneTestBox(t, bc, "test/box", "1.2.0", "virtualbox")
+
+	index := &pruneTestIndex{
+		targets: []core.Target{
+			&pruneTestTarget{machine: &pruneTestMachine{box: inUse}},
+		},
+	}
+	report, err := bc.Prune(index, nil)
+	require.NoError(t, err)
+	require.Len(t, report.Pruned, 1)
+	require.Equal(t, "1.1.0", report.Pruned[0].Version)
+	require.Len(t, report.InUse, 1)
+	require.Equal(t, "1.0.0", report.InUse[0].Version)
+	require.DirExists(t, report.InUse[0].Directory)
+}
+
+func TestPruneInvalidOptions(t *testing.T) {
+	bc := newPruneTestCollection(t)
+	_, err := bc.Prune(&pruneTestIndex{}, &BoxPruneOptions{KeepVersions: -1})
+	require.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	_ "embed"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/localizer"
+	"golang.org/x/text/language"
+)
+
+// Locale data for messages which are specific to core and not
+// provided by the plugin SDK
+//
+//go:embed locales/en.json
+var localeDataEN []byte
+
+// Localizes the given message using the core locale data and
+// returns it as an error
+func localizeErr(msg string, templateData interface{}) error {
+	l, err := localizer.NewPluginLocalizer(
+		localizer.LocaleData{
+			LocaleData: localeDataEN,
+			LocalePath: "locales/en.json",
+			Languages:  []language.Tag{language.English, language.AmericanEnglish, language.BritishEnglish},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return l.LocalizeErr(msg, templateData)
+}
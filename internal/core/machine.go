@@ -68,7 +68,10 @@ func (m *Machine) SetID(value string) (err error) {
 
 func (m *Machine) Box() (b core.Box, err error) {
 	if m.box == nil {
-		boxes, _ := m.project.Boxes()
+		boxes, err := m.project.Boxes()
+		if err != nil {
+			return nil, err
+		}
 		boxName, err := m.vagrantfile.GetValue("vm", "box")
 		if err != nil {
 			m.logger.Error("failed to get machine box name from config",
@@ -85,7 +88,14 @@ func (m *Machine) Box() (b core.Box, err error) {
 		if err != nil {
 			return nil, err
 		}
-		b, err := boxes.Find(boxName.(string), "", provider)
+		var b core.Box
+		if collection, ok := boxes.(architectureBoxCollection); ok {
+			b, err = collection.FindArchitecture(
+				boxName.(string), "", m.boxArchitecture(collection), provider,
+			)
+		} else {
+			b, err = boxes.Find(boxName.(string), "", provider)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -98,14 +108,31 @@ func (m *Machine) Box() (b core.Box, err error) {
 				},
 			}, nil
 		}
-		m.machine.Box = b.(*Box).ToProto()
+		box, ok := b.(*Box)
+		if !ok {
+			return b, nil
+		}
+		m.machine.Box = box.ToProto()
 		m.SaveMachine()
-		m.box = b.(*Box)
+		m.box = box
 	}
 
 	return m.box, nil
 }
 
+// Returns the architecture of the box to use for the machine. The
+// architecture can be set using the "box_architecture" option in the
+// Vagrantfile, otherwise the box collection architecture is used.
+func (m *Machine) boxArchitecture(boxes architectureBoxCollection) string {
+	arch, err := m.vagrantfile.GetValue("vm", "box_architecture")
+	if err == nil {
+		if a, ok := arch.(string); ok && a != "" && a != "auto" {
+			return a
+		}
+	}
+	return boxes.Architecture()
+}
+
 // Guest implements core.Machine
 func (m *Machine) Guest() (g core.Guest, err error) {
 	comm, err := m.Communicate()
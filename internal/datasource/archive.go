@@ -0,0 +1,288 @@
+package datasource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+type ArchiveSource struct {
+	client vagrant_server.VagrantClient
+}
+
+func newArchiveSource() Sourcer { return &ArchiveSource{} }
+
+// SetClient implements ClientSourcer. The client is used to download
+// archives uploaded to the server.
+func (s *ArchiveSource) SetClient(client vagrant_server.VagrantClient) {
+	s.client = client
+}
+
+func (s *ArchiveSource) ProjectSource(body hcl.Body, ctx *hcl.EvalContext) (*vagrant_server.Job_DataSource, error) {
+	// Decode
+	var cfg archiveConfig
+	if diag := gohcl.DecodeBody(body, ctx, &cfg); len(diag) > 0 {
+		return nil, diag
+	}
+	if cfg.Url == "" && cfg.UploadId == "" {
+		return nil, fmt.Errorf("archive data source requires either url or upload_id")
+	}
+
+	// Return the data source
+	return &vagrant_server.Job_DataSource{
+		Source: &vagrant_server.Job_DataSource_Archive{
+			Archive: &vagrant_server.Job_Archive{
+				Url:      cfg.Url,
+				UploadId: cfg.UploadId,
+				Checksum: cfg.Checksum,
+				Path:     cfg.Path,
+			},
+		},
+	}, nil
+}
+
+func (s *ArchiveSource) Override(raw *vagrant_server.Job_DataSource, m map[string]string) error {
+	src := raw.Source.(*vagrant_server.Job_DataSource_Archive).Archive
+
+	var unused []string
+	for k, v := range m {
+		switch k {
+		case "url":
+			// The URL replaces any uploaded archive
+			src.Url = v
+			src.UploadId = ""
+
+		case "checksum":
+			src.Checksum = v
+
+		case "path":
+			src.Path = v
+
+		default:
+			unused = append(unused, k)
+		}
+	}
+
+	if len(unused) > 0 {
+		return fmt.Errorf("invalid override keys: %v", unused)
+	}
+
+	return nil
+}
+
+func (s *ArchiveSource) Get(
+	ctx context.Context,
+	log hclog.Logger,
+	ui terminal.UI,
+	raw *vagrant_server.Job_DataSource,
+	baseDir string,
+) (string, func() error, error) {
+	source := raw.Source.(*vagrant_server.Job_DataSource_Archive).Archive
+
+	// Some quick validation
+	if p := source.Path; p != "" {
+		if filepath.IsAbs(p) {
+			return "", nil, status.Errorf(codes.FailedPrecondition,
+				"archive path must be relative")
+		}
+
+		for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+			if part == ".." {
+				return "", nil, status.Errorf(codes.FailedPrecondition,
+					"archive path may not contain '..'")
+			}
+		}
+	}
+
+	// Download the archive to a temporary file
+	f, err := ioutil.TempFile(baseDir, "vagrant-archive-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	var expected string
+	if source.UploadId != "" {
+		expected, err = s.download(ctx, ui, source, io.MultiWriter(f, h))
+	} else {
+		err = s.fetch(ctx, ui, source, io.MultiWriter(f, h))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Verify the checksum. If the job doesn't set one, we still verify
+	// an uploaded archive against the checksum recorded by the server.
+	if source.Checksum != "" {
+		expected = source.Checksum
+	}
+	if err := verifyArchiveChecksum(h, expected); err != nil {
+		return "", nil, err
+	}
+
+	// Create a temporary directory where we will extract the archive.
+	td, err := ioutil.TempDir(baseDir, "vagrant")
+	if err != nil {
+		return "", nil, err
+	}
+	closer := func() error {
+		return os.RemoveAll(td)
+	}
+
+	log.Debug("extracting archive", "dir", td)
+	if err := extractArchive(f, td); err != nil {
+		closer()
+		return "", nil, err
+	}
+
+	// If we have a path, set it.
+	result := td
+	if p := source.Path; p != "" {
+		result = filepath.Join(result, p)
+	}
+
+	return result, closer, nil
+}
+
+// fetch downloads the archive from its URL and writes it to w.
+func (s *ArchiveSource) fetch(
+	ctx context.Context,
+	ui terminal.UI,
+	source *vagrant_server.Job_Archive,
+	w io.Writer,
+) error {
+	u, err := url.Parse(source.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return status.Errorf(codes.FailedPrecondition,
+			"archive url must be an http or https URL: %q", source.Url)
+	}
+
+	// Output
+	ui.Output("Downloading archive", terminal.WithHeaderStyle())
+	ui.Output("URL: %s", u.Redacted(), terminal.WithInfoStyle())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return status.Errorf(codes.Aborted,
+			"archive download failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return status.Errorf(codes.Aborted,
+			"archive download failed: %s", resp.Status)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return status.Errorf(codes.Aborted,
+			"archive download failed: %s", err)
+	}
+
+	return nil
+}
+
+// download downloads the archive uploaded to the server and writes it to
+// w. This returns the checksum of the archive recorded by the server.
+func (s *ArchiveSource) download(
+	ctx context.Context,
+	ui terminal.UI,
+	source *vagrant_server.Job_Archive,
+	w io.Writer,
+) (string, error) {
+	if s.client == nil {
+		return "", status.Errorf(codes.FailedPrecondition,
+			"uploaded archives require a connection to the server")
+	}
+
+	// Output
+	ui.Output("Downloading archive from the server", terminal.WithHeaderStyle())
+	ui.Output("Upload: %s", source.UploadId, terminal.WithInfoStyle())
+
+	stream, err := s.client.DownloadArchive(ctx, &vagrant_server.DownloadArchiveRequest{
+		UploadId: source.UploadId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The first message is always the open message
+	resp, err := stream.Recv()
+	if err != nil {
+		return "", err
+	}
+	open, ok := resp.Event.(*vagrant_server.DownloadArchiveResponse_Open)
+	if !ok {
+		return "", status.Errorf(codes.Aborted,
+			"expected open message, server sent %T", resp.Event)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return open.Open.Checksum, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		chunk, ok := resp.Event.(*vagrant_server.DownloadArchiveResponse_Chunk)
+		if !ok {
+			return "", status.Errorf(codes.Aborted,
+				"expected chunk message, server sent %T", resp.Event)
+		}
+		if _, err := w.Write(chunk.Chunk); err != nil {
+			return "", err
+		}
+	}
+}
+
+// verifyArchiveChecksum verifies the SHA256 checksum of the archive
+// written to h. If expected is empty, nothing is verified.
+func verifyArchiveChecksum(h hash.Hash, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(strings.TrimPrefix(expected, "sha256:"), actual) {
+		return status.Errorf(codes.FailedPrecondition,
+			"archive checksum mismatch, expected %s but got %s", expected, actual)
+	}
+
+	return nil
+}
+
+type archiveConfig struct {
+	Url      string `hcl:"url,optional"`
+	UploadId string `hcl:"upload_id,optional"`
+	Checksum string `hcl:"checksum,optional"`
+	Path     string `hcl:"path,optional"`
+}
+
+var (
+	_ Sourcer       = (*ArchiveSource)(nil)
+	_ ClientSourcer = (*ArchiveSource)(nil)
+)
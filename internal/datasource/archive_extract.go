@@ -0,0 +1,282 @@
+package datasource
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// extractArchive extracts the tar.gz or zip archive f into the directory
+// dst. The format is detected from the contents of the archive. Entries
+// are never written outside of dst: entries with paths outside of dst,
+// symlinks pointing outside of dst, and entries below a symlink are
+// rejected.
+func extractArchive(f *os.File, dst string) error {
+	e := &archiveExtractor{dst: filepath.Clean(dst)}
+	if err := e.extract(f); err != nil {
+		return err
+	}
+
+	return e.verifyLinks()
+}
+
+// archiveExtractor extracts the entries of an archive into dst.
+type archiveExtractor struct {
+	dst string
+
+	// links are the paths of the symlinks which were extracted.
+	links []string
+}
+
+func (e *archiveExtractor) extract(f *os.File) error {
+	head := make([]byte, 262)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	typ, _ := filetype.Match(head[:n])
+	switch typ.Extension {
+	case "gz":
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return archiveError(err)
+		}
+		defer gz.Close()
+
+		return e.extractTar(tar.NewReader(gz))
+
+	case "zip":
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return archiveError(err)
+		}
+
+		return e.extractZip(zr)
+
+	default:
+		return status.Errorf(codes.FailedPrecondition,
+			"archive must be a tar.gz or zip file")
+	}
+}
+
+func (e *archiveExtractor) extractTar(tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return archiveError(err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.dir(hdr.Name)
+
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.file(hdr.Name, hdr.FileInfo().Mode(), tr)
+
+		case tar.TypeSymlink:
+			err = e.symlink(hdr.Name, hdr.Linkname)
+
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			// Extended headers are not files
+			continue
+
+		default:
+			err = status.Errorf(codes.FailedPrecondition,
+				"archive entry %q has an unsupported type", hdr.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *archiveExtractor) extractZip(zr *zip.Reader) error {
+	for _, f := range zr.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := e.dir(f.Name); err != nil {
+				return err
+			}
+
+		case mode.IsRegular(), mode&os.ModeSymlink != 0:
+			r, err := f.Open()
+			if err != nil {
+				return archiveError(err)
+			}
+
+			if mode.IsRegular() {
+				err = e.file(f.Name, mode, r)
+			} else {
+				// The target of a symlink is the content of the entry
+				var target strings.Builder
+				if _, err = io.Copy(&target, io.LimitReader(r, 4096)); err == nil {
+					err = e.symlink(f.Name, target.String())
+				}
+			}
+			r.Close()
+			if err != nil {
+				return err
+			}
+
+		default:
+			return status.Errorf(codes.FailedPrecondition,
+				"archive entry %q has an unsupported type", f.Name)
+		}
+	}
+
+	return nil
+}
+
+func (e *archiveExtractor) dir(name string) error {
+	path, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(path, 0755)
+}
+
+func (e *archiveExtractor) file(name string, mode os.FileMode, r io.Reader) error {
+	path, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if path == e.dst {
+		return status.Errorf(codes.FailedPrecondition,
+			"archive entry %q is not a valid file name", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Remove any existing entry so we never write through a symlink
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Only keep the permission bits of the mode
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return archiveError(err)
+	}
+
+	return f.Close()
+}
+
+func (e *archiveExtractor) symlink(name, target string) error {
+	path, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if path == e.dst {
+		return status.Errorf(codes.FailedPrecondition,
+			"archive entry %q is not a valid file name", name)
+	}
+
+	// The target must be within the extraction directory. This is
+	// verified again once all entries are extracted since the target
+	// may go through other symlinks.
+	if filepath.IsAbs(target) || !archiveWithin(e.dst, filepath.Join(filepath.Dir(path), target)) {
+		return status.Errorf(codes.FailedPrecondition,
+			"archive entry %q links outside of the archive", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	e.links = append(e.links, path)
+	return os.Symlink(target, path)
+}
+
+// verifyLinks verifies that all the extracted symlinks resolve to a path
+// within the extraction directory.
+func (e *archiveExtractor) verifyLinks() error {
+	if len(e.links) == 0 {
+		return nil
+	}
+
+	root, err := filepath.EvalSymlinks(e.dst)
+	if err != nil {
+		return err
+	}
+	for _, link := range e.links {
+		resolved, err := filepath.EvalSymlinks(link)
+		if err == nil && !archiveWithin(root, resolved) {
+			err = status.Errorf(codes.FailedPrecondition, "links outside of the archive")
+		}
+		if err != nil {
+			rel, _ := filepath.Rel(e.dst, link)
+			return status.Errorf(codes.FailedPrecondition,
+				"archive entry %q is not a valid link: %s", filepath.ToSlash(rel), err)
+		}
+	}
+
+	return nil
+}
+
+// entryPath returns the path within dst to extract the archive entry
+// with the given name to. An error is returned if the path is outside of
+// dst or any of its parent directories within dst is a symlink.
+func (e *archiveExtractor) entryPath(name string) (string, error) {
+	dst := e.dst
+	rel := filepath.FromSlash(name)
+	path := filepath.Join(dst, rel)
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || !archiveWithin(dst, path) {
+		return "", status.Errorf(codes.FailedPrecondition,
+			"archive entry %q is outside of the archive", name)
+	}
+
+	// Entries below a symlink could be written outside of dst
+	for dir := filepath.Dir(path); archiveWithin(dst, dir) && dir != dst; dir = filepath.Dir(dir) {
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", status.Errorf(codes.FailedPrecondition,
+				"archive entry %q is below a symlink", name)
+		}
+	}
+
+	return path, nil
+}
+
+// archiveWithin returns true if path is dst or within dst. Both paths
+// must be clean.
+func archiveWithin(dst, path string) bool {
+	return path == dst || strings.HasPrefix(path, dst+string(filepath.Separator))
+}
+
+func archiveError(err error) error {
+	return status.Errorf(codes.FailedPrecondition, "invalid archive: %s", err)
+}
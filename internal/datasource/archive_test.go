@@ -0,0 +1,358 @@
+package datasource
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess"
+)
+
+func TestArchiveSourceOverride(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Input    *vagrant_server.Job_Archive
+		M        map[string]string
+		Expected *vagrant_server.Job_Archive
+		Error    string
+	}{
+		{
+			"nothing",
+			&vagrant_server.Job_Archive{Url: "foo"},
+			nil,
+			&vagrant_server.Job_Archive{Url: "foo"},
+			"",
+		},
+
+		{
+			"url and checksum",
+			&vagrant_server.Job_Archive{Url: "foo", Checksum: "abc"},
+			map[string]string{"url": "bar", "checksum": "def"},
+			&vagrant_server.Job_Archive{Url: "bar", Checksum: "def"},
+			"",
+		},
+
+		{
+			"url replaces upload",
+			&vagrant_server.Job_Archive{UploadId: "A"},
+			map[string]string{"url": "bar"},
+			&vagrant_server.Job_Archive{Url: "bar"},
+			"",
+		},
+
+		{
+			"invalid",
+			&vagrant_server.Job_Archive{Url: "foo"},
+			map[string]string{"other": "bar"},
+			nil,
+			"other",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			require := require.New(t)
+
+			raw := &vagrant_server.Job_DataSource{
+				Source: &vagrant_server.Job_DataSource_Archive{Archive: tt.Input},
+			}
+
+			var s ArchiveSource
+			err := s.Override(raw, tt.M)
+			if tt.Error != "" {
+				require.Error(err)
+				require.Contains(err.Error(), tt.Error)
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tt.Expected, tt.Input)
+		})
+	}
+}
+
+func TestArchiveSourceGet(t *testing.T) {
+	files := map[string]string{
+		"project/Vagrantfile": "Vagrant.configure",
+		"project/README":      "readme",
+	}
+	tgz := testArchiveTarGz(t, files, nil)
+	zipData := testArchiveZip(t, files)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/project.tar.gz":
+			w.Write(tgz)
+		case "/project.zip":
+			w.Write(zipData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("tar.gz from a URL", func(t *testing.T) {
+		require := require.New(t)
+
+		dir, closer, err := testArchiveGet(t, nil, &vagrant_server.Job_Archive{
+			Url:      srv.URL + "/project.tar.gz",
+			Checksum: testArchiveChecksum(tgz),
+			Path:     "project",
+		})
+		require.NoError(err)
+		defer closer()
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "Vagrantfile"))
+		require.NoError(err)
+		require.Equal("Vagrant.configure", string(data))
+	})
+
+	t.Run("zip from a URL", func(t *testing.T) {
+		require := require.New(t)
+
+		dir, closer, err := testArchiveGet(t, nil, &vagrant_server.Job_Archive{
+			Url: srv.URL + "/project.zip",
+		})
+		require.NoError(err)
+		defer closer()
+
+		require.FileExists(filepath.Join(dir, "project", "README"))
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		require := require.New(t)
+
+		_, _, err := testArchiveGet(t, nil, &vagrant_server.Job_Archive{
+			Url:      srv.URL + "/project.tar.gz",
+			Checksum: testArchiveChecksum(zipData),
+		})
+		require.Error(err)
+		require.Contains(err.Error(), "checksum mismatch")
+	})
+
+	t.Run("download error", func(t *testing.T) {
+		require := require.New(t)
+
+		_, _, err := testArchiveGet(t, nil, &vagrant_server.Job_Archive{
+			Url: srv.URL + "/nope.tar.gz",
+		})
+		require.Error(err)
+		require.Equal(codes.Aborted, status.Code(err))
+	})
+
+	t.Run("uploaded to the server", func(t *testing.T) {
+		require := require.New(t)
+
+		client := singleprocess.TestServer(t, singleprocess.WithArchiveDir(t.TempDir()))
+		stream, err := client.UploadArchive(context.Background())
+		require.NoError(err)
+		require.NoError(stream.Send(&vagrant_server.UploadArchiveRequest{
+			Event: &vagrant_server.UploadArchiveRequest_Open_{
+				Open: &vagrant_server.UploadArchiveRequest_Open{},
+			},
+		}))
+		require.NoError(stream.Send(&vagrant_server.UploadArchiveRequest{
+			Event: &vagrant_server.UploadArchiveRequest_Chunk{Chunk: tgz},
+		}))
+		resp, err := stream.CloseAndRecv()
+		require.NoError(err)
+
+		dir, closer, err := testArchiveGet(t, client, &vagrant_server.Job_Archive{
+			UploadId: resp.Upload.Id,
+		})
+		require.NoError(err)
+		defer closer()
+
+		require.FileExists(filepath.Join(dir, "project", "Vagrantfile"))
+	})
+}
+
+func TestExtractArchive(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Files map[string]string
+		Links []string
+		Error string
+	}{
+		{
+			"files and links",
+			map[string]string{"a/b": "b"},
+			[]string{"c", "a/b", "d", "./a"},
+			"",
+		},
+
+		{
+			"parent traversal",
+			map[string]string{"../evil": "evil"},
+			nil,
+			"outside of the archive",
+		},
+
+		{
+			"absolute path",
+			map[string]string{"/evil": "evil"},
+			nil,
+			"outside of the archive",
+		},
+
+		{
+			"link outside",
+			nil,
+			[]string{"evil", "../evil"},
+			"links outside of the archive",
+		},
+
+		{
+			"absolute link",
+			nil,
+			[]string{"evil", "/etc"},
+			"links outside of the archive",
+		},
+
+		{
+			"link through another link",
+			nil,
+			[]string{"d", ".", "evil", "d/.."},
+			"not a valid link",
+		},
+
+		{
+			"file below a link",
+			map[string]string{"d/evil": "evil"},
+			[]string{"d", "."},
+			"below a symlink",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			require := require.New(t)
+
+			root := t.TempDir()
+			dst := filepath.Join(root, "dst")
+			require.NoError(os.Mkdir(dst, 0755))
+
+			f := testArchiveFile(t, testArchiveTarGz(t, tt.Files, tt.Links))
+			defer f.Close()
+
+			err := extractArchive(f, dst)
+			if tt.Error != "" {
+				require.Error(err)
+				require.Contains(err.Error(), tt.Error)
+
+				// Nothing is written outside of the extraction directory
+				entries, err := ioutil.ReadDir(root)
+				require.NoError(err)
+				require.Len(entries, 1)
+				return
+			}
+
+			require.NoError(err)
+		})
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		f := testArchiveFile(t, []byte("not an archive"))
+		defer f.Close()
+
+		err := extractArchive(f, t.TempDir())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "tar.gz or zip")
+	})
+}
+
+func testArchiveGet(
+	t *testing.T,
+	client vagrant_server.VagrantClient,
+	archive *vagrant_server.Job_Archive,
+) (string, func() error, error) {
+	var s ArchiveSource
+	if client != nil {
+		s.SetClient(client)
+	}
+
+	return s.Get(
+		context.Background(),
+		hclog.L(),
+		terminal.ConsoleUI(context.Background()),
+		&vagrant_server.Job_DataSource{
+			Source: &vagrant_server.Job_DataSource_Archive{Archive: archive},
+		},
+		t.TempDir(),
+	)
+}
+
+// testArchiveTarGz returns a tar.gz archive with the given files and
+// links. Links are pairs of link names and targets and are added in order
+// before the files.
+func testArchiveTarGz(t *testing.T, files map[string]string, links []string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for i := 0; i < len(links); i += 2 {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     links[i],
+			Typeflag: tar.TypeSymlink,
+			Linkname: links[i+1],
+		}))
+	}
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func testArchiveZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func testArchiveFile(t *testing.T, data []byte) *os.File {
+	path := filepath.Join(t.TempDir(), "archive")
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	return f
+}
+
+func testArchiveChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
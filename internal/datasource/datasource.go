@@ -37,16 +37,38 @@ type Sourcer interface {
 	) (string, func() error, error)
 }
 
+// ClientSourcer is implemented by sourcers which get data from the
+// Vagrant server. The client is set before Get is called.
+type ClientSourcer interface {
+	SetClient(vagrant_server.VagrantClient)
+}
+
+// ConfigSourcer is implemented by sourcers which use config variables,
+// such as credentials. The variables available to the job are set before
+// Get is called.
+type ConfigSourcer interface {
+	SetConfig(vars map[string]string)
+}
+
+// CacheSourcer is implemented by sourcers which cache data between jobs.
+// The directory is set before Get is called. If it isn't set, nothing
+// is cached.
+type CacheSourcer interface {
+	SetCacheDir(dir string)
+}
+
 var (
 	// FromString maps a string key to a source implementation.
 	FromString = map[string]func() Sourcer{
-		"git":   newGitSource,
-		"local": newLocalSource,
+		"archive": newArchiveSource,
+		"git":     newGitSource,
+		"local":   newLocalSource,
 	}
 
 	// FromType maps a server DataSource type to a source implementation.
 	FromType = map[reflect.Type]func() Sourcer{
-		reflect.TypeOf((*vagrant_server.Job_DataSource_Git)(nil)):   newGitSource,
-		reflect.TypeOf((*vagrant_server.Job_DataSource_Local)(nil)): newLocalSource,
+		reflect.TypeOf((*vagrant_server.Job_DataSource_Archive)(nil)): newArchiveSource,
+		reflect.TypeOf((*vagrant_server.Job_DataSource_Git)(nil)):     newGitSource,
+		reflect.TypeOf((*vagrant_server.Job_DataSource_Local)(nil)):   newLocalSource,
 	}
 )
@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl/v2"
@@ -20,10 +22,29 @@ import (
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 )
 
-type GitSource struct{}
+// gitCommitSHA matches full commit SHAs. Abbreviated SHAs can't be told
+// apart from branch names and can't be fetched.
+var gitCommitSHA = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+type GitSource struct {
+	cacheDir string
+	config   map[string]string
+}
 
 func newGitSource() Sourcer { return &GitSource{} }
 
+// SetConfig implements ConfigSourcer. The config variables are used to
+// look up the credentials named by the data source.
+func (s *GitSource) SetConfig(vars map[string]string) {
+	s.config = vars
+}
+
+// SetCacheDir implements CacheSourcer. Repositories are mirrored into
+// the cache directory and updated with a fetch on each Get.
+func (s *GitSource) SetCacheDir(dir string) {
+	s.cacheDir = dir
+}
+
 func (s *GitSource) ProjectSource(body hcl.Body, ctx *hcl.EvalContext) (*vagrant_server.Job_DataSource, error) {
 	// Decode
 	var cfg gitConfig
@@ -35,8 +56,14 @@ func (s *GitSource) ProjectSource(body hcl.Body, ctx *hcl.EvalContext) (*vagrant
 	return &vagrant_server.Job_DataSource{
 		Source: &vagrant_server.Job_DataSource_Git{
 			Git: &vagrant_server.Job_Git{
-				Url:  cfg.Url,
-				Path: cfg.Path,
+				Url:        cfg.Url,
+				Ref:        cfg.Ref,
+				Path:       cfg.Path,
+				Depth:      cfg.Depth,
+				Submodules: cfg.Submodules,
+				SshKeyVar:  cfg.SshKeyVar,
+				TokenVar:   cfg.TokenVar,
+				Username:   cfg.Username,
 			},
 		},
 	}, nil
@@ -45,8 +72,19 @@ func (s *GitSource) ProjectSource(body hcl.Body, ctx *hcl.EvalContext) (*vagrant
 func (s *GitSource) Override(raw *vagrant_server.Job_DataSource, m map[string]string) error {
 	src := raw.Source.(*vagrant_server.Job_DataSource_Git).Git
 
+	// Keys match the field names used in the API, such as "ssh_key_var",
+	// and values are converted from strings as needed.
 	var md mapstructure.Metadata
-	if err := mapstructure.DecodeMetadata(m, src, &md); err != nil {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata:         &md,
+		Result:           src,
+		TagName:          "json",
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(m); err != nil {
 		return err
 	}
 
@@ -81,6 +119,13 @@ func (s *GitSource) Get(
 		}
 	}
 
+	// Setup the environment for authentication
+	env, cleanup, err := s.authEnv(source.Git, baseDir)
+	if err != nil {
+		return "", nil, err
+	}
+	defer cleanup()
+
 	// Create a temporary directory where we will store the cloned data.
 	td, err := ioutil.TempDir(baseDir, "vagrant")
 	if err != nil {
@@ -96,32 +141,35 @@ func (s *GitSource) Get(
 	if source.Git.Ref != "" {
 		ui.Output("Ref: %s", source.Git.Ref, terminal.WithInfoStyle())
 	}
+	if source.Git.Depth > 0 {
+		ui.Output("Depth: %d", source.Git.Depth, terminal.WithInfoStyle())
+	}
 
-	// Clone
-	var output bytes.Buffer
-	cmd := exec.CommandContext(ctx, "git", "clone", source.Git.Url, td)
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-	cmd.Stdin = nil
-	if err := cmd.Run(); err != nil {
+	if err := s.clone(ctx, log, env, source.Git, td); err != nil {
 		closer()
-		return "", nil, status.Errorf(codes.Aborted,
-			"Git clone failed: %s", output.String())
+		return "", nil, err
 	}
 
 	// Checkout if we have a ref. If we don't have a ref we use the
-	// default of whatever we got.
-	if ref := source.Git.Ref; ref != "" {
-		output.Reset()
-		cmd := exec.CommandContext(ctx, "git", "checkout", ref)
-		cmd.Dir = td
-		cmd.Stdout = &output
-		cmd.Stderr = &output
-		cmd.Stdin = nil
-		if err := cmd.Run(); err != nil {
+	// default of whatever we got. Shallow clones already checked out
+	// the ref when cloning.
+	if ref := source.Git.Ref; ref != "" && source.Git.Depth == 0 {
+		if output, err := runGit(ctx, td, env, "checkout", ref); err != nil {
+			closer()
+			return "", nil, status.Errorf(codes.Aborted,
+				"Git checkout failed: %s", output)
+		}
+	}
+
+	if source.Git.Submodules {
+		args := []string{"submodule", "update", "--init", "--recursive"}
+		if d := source.Git.Depth; d > 0 {
+			args = append(args, "--depth", strconv.FormatUint(uint64(d), 10))
+		}
+		if output, err := runGit(ctx, td, env, args...); err != nil {
 			closer()
 			return "", nil, status.Errorf(codes.Aborted,
-				"Git checkout failed: %s", output.String())
+				"Git submodule update failed: %s", output)
 		}
 	}
 
@@ -134,9 +182,114 @@ func (s *GitSource) Get(
 	return result, closer, nil
 }
 
+// clone clones the repository into dir. If a cache directory is set, the
+// repository is cloned from the cached mirror which is updated first.
+func (s *GitSource) clone(
+	ctx context.Context,
+	log hclog.Logger,
+	env []string,
+	source *vagrant_server.Job_Git,
+	dir string,
+) error {
+	// Shallow clones can only clone branches and tags, so commits are
+	// fetched after cloning.
+	args := []string{"clone"}
+	commit := ""
+	if d := source.Depth; d > 0 {
+		args = append(args, "--depth", strconv.FormatUint(uint64(d), 10))
+		switch {
+		case gitCommitSHA.MatchString(source.Ref):
+			args = append(args, "--no-checkout")
+			commit = source.Ref
+		case source.Ref != "":
+			args = append(args, "--branch", source.Ref)
+		}
+	}
+
+	if s.cacheDir == "" {
+		if output, err := runGit(ctx, "", env, append(args, source.Url, dir)...); err != nil {
+			return status.Errorf(codes.Aborted,
+				"Git clone failed: %s", output)
+		}
+
+		return checkoutCommit(ctx, env, dir, commit, source.Depth)
+	}
+
+	mirror, unlock, err := s.updateCache(ctx, log, env, source.Url)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Local clones ignore the depth unless cloned with a file URL
+	if source.Depth > 0 {
+		mirror = "file://" + filepath.ToSlash(mirror)
+	}
+	if output, err := runGit(ctx, "", env, append(args, mirror, dir)...); err != nil {
+		return status.Errorf(codes.Aborted,
+			"Git clone failed: %s", output)
+	}
+	if err := checkoutCommit(ctx, env, dir, commit, source.Depth); err != nil {
+		return err
+	}
+
+	// Point origin at the real remote so that relative submodule URLs
+	// are resolved against it.
+	if output, err := runGit(ctx, dir, env, "remote", "set-url", "origin", source.Url); err != nil {
+		return status.Errorf(codes.Aborted,
+			"Git remote update failed: %s", output)
+	}
+
+	return nil
+}
+
+// checkoutCommit fetches the commit into the shallow clone in dir and
+// checks it out. Nothing is done if commit is empty.
+func checkoutCommit(ctx context.Context, env []string, dir, commit string, depth uint32) error {
+	if commit == "" {
+		return nil
+	}
+
+	if output, err := runGit(ctx, dir, env,
+		"fetch", "--depth", strconv.FormatUint(uint64(depth), 10), "origin", commit); err != nil {
+		return status.Errorf(codes.Aborted,
+			"Git fetch of commit %s failed: %s", commit, output)
+	}
+	if output, err := runGit(ctx, dir, env, "checkout", commit); err != nil {
+		return status.Errorf(codes.Aborted,
+			"Git checkout failed: %s", output)
+	}
+
+	return nil
+}
+
+// runGit runs git with the given arguments and environment in dir and
+// returns the combined output.
+func runGit(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Stdin = nil
+	err := cmd.Run()
+	return output.String(), err
+}
+
 type gitConfig struct {
-	Url  string `hcl:"url,attr"`
-	Path string `hcl:"path,optional"`
+	Url        string `hcl:"url,attr"`
+	Ref        string `hcl:"ref,optional"`
+	Path       string `hcl:"path,optional"`
+	Depth      uint32 `hcl:"depth,optional"`
+	Submodules bool   `hcl:"submodules,optional"`
+	SshKeyVar  string `hcl:"ssh_key_var,optional"`
+	TokenVar   string `hcl:"token_var,optional"`
+	Username   string `hcl:"username,optional"`
 }
 
-var _ Sourcer = (*GitSource)(nil)
+var (
+	_ Sourcer       = (*GitSource)(nil)
+	_ ConfigSourcer = (*GitSource)(nil)
+	_ CacheSourcer  = (*GitSource)(nil)
+)
@@ -0,0 +1,125 @@
+package datasource
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// authEnv returns the environment to run git with for the given source.
+// Credentials are read from the config variables named by the source. An
+// SSH key is written to a temporary file in tempDir, so the returned
+// cleanup function must always be called once git is done.
+func (s *GitSource) authEnv(
+	source *vagrant_server.Job_Git,
+	tempDir string,
+) ([]string, func(), error) {
+	// Git must never prompt for credentials since nobody can answer
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cleanup := func() {}
+
+	if source.SshKeyVar != "" && source.TokenVar != "" {
+		return nil, nil, status.Errorf(codes.FailedPrecondition,
+			"git data source may only set one of ssh_key_var or token_var")
+	}
+
+	if name := source.SshKeyVar; name != "" {
+		key, err := s.configVar(name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// ssh refuses keys which are readable by others, TempFile creates
+		// files with 0600 permissions.
+		f, err := ioutil.TempFile(tempDir, "vagrant-git-key-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.Remove(f.Name()) }
+		if !strings.HasSuffix(key, "\n") {
+			key += "\n"
+		}
+		_, err = f.WriteString(key)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+
+		env = append(env, "GIT_SSH_COMMAND="+fmt.Sprintf(
+			"ssh -i %s -o IdentitiesOnly=yes", shellQuote(f.Name())))
+	}
+
+	if name := source.TokenVar; name != "" {
+		token, err := s.configVar(name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		u, err := url.Parse(source.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, nil, status.Errorf(codes.FailedPrecondition,
+				"git token authentication requires an http or https URL")
+		}
+
+		username := source.Username
+		if username == "" {
+			username = "git"
+		}
+
+		// The header is scoped to the host so that submodules on the
+		// same host are authenticated as well.
+		header := "Authorization: Basic " + base64.StdEncoding.EncodeToString(
+			[]byte(username+":"+token))
+		env = gitConfigEnv(env, fmt.Sprintf("http.%s://%s/.extraHeader", u.Scheme, u.Host), header)
+	}
+
+	return env, cleanup, nil
+}
+
+// configVar returns the value of the config variable with the given name.
+func (s *GitSource) configVar(name string) (string, error) {
+	v, ok := s.config[name]
+	if !ok || v == "" {
+		return "", status.Errorf(codes.FailedPrecondition,
+			"config variable %q for git authentication is not set", name)
+	}
+
+	return v, nil
+}
+
+// gitConfigEnv adds the git config key and value to env using the
+// GIT_CONFIG_COUNT variables. Any config already set this way in env is
+// preserved.
+func gitConfigEnv(env []string, key, value string) []string {
+	count := 0
+	for _, kv := range env {
+		if v := strings.TrimPrefix(kv, "GIT_CONFIG_COUNT="); v != kv {
+			count, _ = strconv.Atoi(v)
+		}
+	}
+
+	idx := strconv.Itoa(count)
+	return append(env,
+		"GIT_CONFIG_COUNT="+strconv.Itoa(count+1),
+		"GIT_CONFIG_KEY_"+idx+"="+key,
+		"GIT_CONFIG_VALUE_"+idx+"="+value,
+	)
+}
+
+// shellQuote quotes s for use as a single argument in the shell command
+// run by git.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
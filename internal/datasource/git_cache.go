@@ -0,0 +1,83 @@
+package datasource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// updateCache updates the cached mirror of the repository at url,
+// creating it if it doesn't exist yet, and returns its path. The mirror
+// is locked until the returned unlock function is called so that other
+// jobs don't update it while it is being cloned from.
+func (s *GitSource) updateCache(
+	ctx context.Context,
+	log hclog.Logger,
+	env []string,
+	url string,
+) (string, func() error, error) {
+	dir := filepath.Join(s.cacheDir, "git")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, err
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:])[:16]+".git")
+
+	lock := flock.New(path + ".lock")
+	locked, err := lock.TryLockContext(ctx, 250*time.Millisecond)
+	if err != nil {
+		return "", nil, err
+	}
+	if !locked {
+		return "", nil, status.Errorf(codes.Aborted,
+			"failed to lock git cache at %s", path)
+	}
+
+	// If the mirror exists, fetch any changes. Otherwise clone it.
+	if _, err := os.Stat(filepath.Join(path, "HEAD")); err == nil {
+		log.Debug("updating cached git repository", "path", path)
+		if output, err := runGit(ctx, path, env, "fetch", "--prune", "origin"); err != nil {
+			lock.Unlock()
+			return "", nil, status.Errorf(codes.Aborted,
+				"Git fetch failed: %s", output)
+		}
+
+		return path, lock.Unlock, nil
+	}
+
+	// Clone next to the final location so a failed clone never leaves
+	// a partial mirror behind.
+	log.Debug("caching git repository", "path", path)
+	td, err := ioutil.TempDir(dir, ".clone-")
+	if err != nil {
+		lock.Unlock()
+		return "", nil, err
+	}
+	defer os.RemoveAll(td)
+
+	if output, err := runGit(ctx, "", env, "clone", "--mirror", url, td); err != nil {
+		lock.Unlock()
+		return "", nil, status.Errorf(codes.Aborted,
+			"Git clone failed: %s", output)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		lock.Unlock()
+		return "", nil, err
+	}
+	if err := os.Rename(td, path); err != nil {
+		lock.Unlock()
+		return "", nil, err
+	}
+
+	return path, lock.Unlock, nil
+}
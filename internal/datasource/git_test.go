@@ -2,13 +2,18 @@ package datasource
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
@@ -71,6 +76,47 @@ func TestGitSourceOverride(t *testing.T) {
 			"",
 		},
 
+		{
+			"auth and shallow clone",
+			&vagrant_server.Job_DataSource{
+				Source: &vagrant_server.Job_DataSource_Git{
+					Git: &vagrant_server.Job_Git{
+						Url: "foo",
+					},
+				},
+			},
+			map[string]string{
+				"depth":       "1",
+				"submodules":  "true",
+				"ssh_key_var": "KEY",
+			},
+			&vagrant_server.Job_DataSource{
+				Source: &vagrant_server.Job_DataSource_Git{
+					Git: &vagrant_server.Job_Git{
+						Url:        "foo",
+						Depth:      1,
+						Submodules: true,
+						SshKeyVar:  "KEY",
+					},
+				},
+			},
+			"",
+		},
+
+		{
+			"invalid depth",
+			&vagrant_server.Job_DataSource{
+				Source: &vagrant_server.Job_DataSource_Git{
+					Git: &vagrant_server.Job_Git{
+						Url: "foo",
+					},
+				},
+			},
+			map[string]string{"depth": "deep"},
+			nil,
+			"depth",
+		},
+
 		{
 			"invalid",
 			&vagrant_server.Job_DataSource{
@@ -136,6 +182,252 @@ func TestGitSourceGet(t *testing.T) {
 	require.NoError(err)
 }
 
+func TestGitSourceGet_cache(t *testing.T) {
+	if !testHasGit {
+		t.Skip("git not installed")
+		return
+	}
+
+	require := require.New(t)
+
+	repo := testGitRepo(t)
+	testGitCommit(t, repo, "a.txt", "a")
+
+	s := &GitSource{}
+	s.SetCacheDir(t.TempDir())
+	source := &vagrant_server.Job_Git{Url: repo}
+
+	dir, closer, err := testGitGet(t, s, source)
+	require.NoError(err)
+	defer closer()
+	require.FileExists(filepath.Join(dir, "a.txt"))
+
+	// New commits are fetched into the cache
+	testGitCommit(t, repo, "b.txt", "b")
+	dir, closer, err = testGitGet(t, s, source)
+	require.NoError(err)
+	defer closer()
+	require.FileExists(filepath.Join(dir, "b.txt"))
+
+	// Origin is the real remote rather than the cache
+	output, err := runGit(context.Background(), dir, nil, "remote", "get-url", "origin")
+	require.NoError(err)
+	require.Equal(repo, strings.TrimSpace(output))
+
+	mirrors, err := filepath.Glob(filepath.Join(s.cacheDir, "git", "*.git"))
+	require.NoError(err)
+	require.Len(mirrors, 1)
+}
+
+func TestGitSourceGet_depth(t *testing.T) {
+	if !testHasGit {
+		t.Skip("git not installed")
+		return
+	}
+
+	repo := testGitRepo(t)
+	testGitCommit(t, repo, "a.txt", "a")
+	testGitCommit(t, repo, "b.txt", "b")
+
+	for _, cache := range []bool{false, true} {
+		t.Run(fmt.Sprintf("cache %v", cache), func(t *testing.T) {
+			require := require.New(t)
+
+			s := &GitSource{}
+			if cache {
+				s.SetCacheDir(t.TempDir())
+			}
+			dir, closer, err := testGitGet(t, s, &vagrant_server.Job_Git{
+				Url:   "file://" + filepath.ToSlash(repo),
+				Ref:   "main",
+				Depth: 1,
+			})
+			require.NoError(err)
+			defer closer()
+
+			require.FileExists(filepath.Join(dir, "b.txt"))
+			output, err := runGit(context.Background(), dir, nil, "rev-list", "--count", "HEAD")
+			require.NoError(err)
+			require.Equal("1", strings.TrimSpace(output))
+		})
+
+		t.Run(fmt.Sprintf("commit cache %v", cache), func(t *testing.T) {
+			require := require.New(t)
+
+			commit, err := runGit(context.Background(), repo, nil, "rev-parse", "HEAD~1")
+			require.NoError(err)
+			commit = strings.TrimSpace(commit)
+
+			s := &GitSource{}
+			if cache {
+				s.SetCacheDir(t.TempDir())
+			}
+			dir, closer, err := testGitGet(t, s, &vagrant_server.Job_Git{
+				Url:   "file://" + filepath.ToSlash(repo),
+				Ref:   commit,
+				Depth: 1,
+			})
+			require.NoError(err)
+			defer closer()
+
+			require.FileExists(filepath.Join(dir, "a.txt"))
+			require.NoFileExists(filepath.Join(dir, "b.txt"))
+			output, err := runGit(context.Background(), dir, nil, "rev-parse", "HEAD")
+			require.NoError(err)
+			require.Equal(commit, strings.TrimSpace(output))
+		})
+	}
+}
+
+func TestGitSourceGet_submodules(t *testing.T) {
+	if !testHasGit {
+		t.Skip("git not installed")
+		return
+	}
+
+	require := require.New(t)
+
+	// Newer versions of git don't allow local submodules by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	sub := testGitRepo(t)
+	testGitCommit(t, sub, "sub.txt", "sub")
+
+	repo := testGitRepo(t)
+	testGitRun(t, repo, "submodule", "add", sub, "sub")
+	testGitCommit(t, repo, "a.txt", "a")
+
+	dir, closer, err := testGitGet(t, &GitSource{}, &vagrant_server.Job_Git{
+		Url:        repo,
+		Submodules: true,
+	})
+	require.NoError(err)
+	defer closer()
+	require.FileExists(filepath.Join(dir, "sub", "sub.txt"))
+}
+
+func TestGitSourceAuthEnv(t *testing.T) {
+	t.Run("ssh key", func(t *testing.T) {
+		require := require.New(t)
+
+		s := &GitSource{}
+		s.SetConfig(map[string]string{"KEY": "private key"})
+		env, cleanup, err := s.authEnv(&vagrant_server.Job_Git{
+			Url:       "git@example.com:foo/bar.git",
+			SshKeyVar: "KEY",
+		}, t.TempDir())
+		require.NoError(err)
+
+		path := testGitKeyPath(t, env)
+		data, err := ioutil.ReadFile(path)
+		require.NoError(err)
+		require.Equal("private key\n", string(data))
+		info, err := os.Stat(path)
+		require.NoError(err)
+		require.Equal(os.FileMode(0600), info.Mode().Perm())
+		require.Contains(env, "GIT_TERMINAL_PROMPT=0")
+
+		cleanup()
+		require.NoFileExists(path)
+	})
+
+	t.Run("token", func(t *testing.T) {
+		require := require.New(t)
+		t.Setenv("GIT_CONFIG_COUNT", "1")
+
+		s := &GitSource{}
+		s.SetConfig(map[string]string{"TOKEN": "secret"})
+		env, cleanup, err := s.authEnv(&vagrant_server.Job_Git{
+			Url:      "https://example.com/foo/bar.git",
+			TokenVar: "TOKEN",
+			Username: "user",
+		}, t.TempDir())
+		require.NoError(err)
+		defer cleanup()
+
+		require.Equal("GIT_CONFIG_COUNT=2", env[len(env)-3])
+		require.Equal("GIT_CONFIG_KEY_1=http.https://example.com/.extraHeader", env[len(env)-2])
+		require.Equal("GIT_CONFIG_VALUE_1=Authorization: Basic dXNlcjpzZWNyZXQ=", env[len(env)-1])
+	})
+
+	t.Run("token requires http", func(t *testing.T) {
+		s := &GitSource{}
+		s.SetConfig(map[string]string{"TOKEN": "secret"})
+		_, _, err := s.authEnv(&vagrant_server.Job_Git{
+			Url:      "git@example.com:foo/bar.git",
+			TokenVar: "TOKEN",
+		}, t.TempDir())
+		require.Error(t, err)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("missing variable", func(t *testing.T) {
+		s := &GitSource{}
+		_, _, err := s.authEnv(&vagrant_server.Job_Git{
+			Url:       "git@example.com:foo/bar.git",
+			SshKeyVar: "KEY",
+		}, t.TempDir())
+		require.Error(t, err)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.Contains(t, err.Error(), "KEY")
+	})
+}
+
+func testGitGet(t *testing.T, s *GitSource, source *vagrant_server.Job_Git) (string, func() error, error) {
+	return s.Get(
+		context.Background(),
+		hclog.L(),
+		terminal.ConsoleUI(context.Background()),
+		&vagrant_server.Job_DataSource{
+			Source: &vagrant_server.Job_DataSource_Git{Git: source},
+		},
+		t.TempDir(),
+	)
+}
+
+// testGitRepo creates an empty git repository with a "main" branch and
+// returns its path.
+func testGitRepo(t *testing.T) string {
+	dir := t.TempDir()
+	testGitRun(t, dir, "init", "-b", "main")
+	return dir
+}
+
+// testGitCommit writes the file to the repository and commits it.
+func testGitCommit(t *testing.T, dir, name, content string) {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	testGitRun(t, dir, "add", "-A")
+	testGitRun(t, dir, "commit", "-m", name)
+}
+
+func testGitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	args = append([]string{
+		"-c", "user.name=test",
+		"-c", "user.email=test@example.com",
+		"-c", "commit.gpgsign=false",
+	}, args...)
+	output, err := runGit(context.Background(), dir, os.Environ(), args...)
+	require.NoError(t, err, output)
+}
+
+func testGitKeyPath(t *testing.T, env []string) string {
+	for _, kv := range env {
+		if v := strings.TrimPrefix(kv, "GIT_SSH_COMMAND="); v != kv {
+			start := strings.Index(v, "'")
+			end := strings.LastIndex(v, "'")
+			require.True(t, start >= 0 && end > start, v)
+			return v[start+1 : end]
+		}
+	}
+
+	t.Fatal("GIT_SSH_COMMAND not set")
+	return ""
+}
+
 // testGitFixture MUST be called before TestRunner since TestRunner
 // changes our working directory.
 func testGitFixture(t *testing.T, n string) string {
@@ -0,0 +1,207 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keySetRefreshInterval is how often a key set is fetched again at most
+// when a token is signed with an unknown key.
+var keySetRefreshInterval = time.Minute
+
+// KeySet is the set of public keys a provider signs tokens with. Keys are
+// fetched from the JWKS URI of the provider and fetched again when a token
+// is signed with an unknown key, such as after the provider rotated its
+// keys.
+type KeySet struct {
+	uri    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*signingKey
+	fetched time.Time
+}
+
+// signingKey is a key of a key set and the algorithm it is used with, if
+// the provider restricts it to one.
+type signingKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// NewKeySet returns the key set at the JWKS URI. If client is nil,
+// http.DefaultClient is used.
+func NewKeySet(client *http.Client, uri string) *KeySet {
+	return &KeySet{uri: uri, client: client}
+}
+
+// Key returns the key with the given ID. If kid is empty, the key set must
+// contain a single key.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, err := s.signingKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.key, nil
+}
+
+func (s *KeySet) signingKey(ctx context.Context, kid string) (*signingKey, error) {
+	s.mu.Lock()
+	key, ok := s.find(kid)
+	refresh := time.Since(s.fetched) >= keySetRefreshInterval
+	s.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	// The keys are fetched without holding the lock so that a slow
+	// provider doesn't block the verification of tokens with known keys.
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.fetched = time.Now()
+
+	if key, ok := s.find(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *KeySet) find(kid string) (*signingKey, bool) {
+	if kid == "" {
+		if len(s.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *KeySet) fetch(ctx context.Context) (map[string]*signingKey, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, s.client, s.uri, &doc); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := map[string]*signingKey{}
+	for _, jwk := range doc.Keys {
+		// Keys for encryption are of no use to verify signatures
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		// Keys we can't parse are skipped like unsupported key types so
+		// that one bad key doesn't prevent the use of the others.
+		key, err := jwk.publicKey()
+		if err != nil || key == nil {
+			continue
+		}
+		keys[jwk.Kid] = &signingKey{key: key, alg: jwk.Alg}
+	}
+
+	return keys, nil
+}
+
+// jsonWebKey is a public key as described by RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// publicKey returns the key, or nil if the key type is not supported.
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, nil
+}
+
+func decodeBigInt(v string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
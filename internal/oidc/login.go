@@ -0,0 +1,356 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config configures logging in with a provider.
+type Config struct {
+	// Issuer is the issuer URL of the provider.
+	Issuer string
+
+	// ClientID and ClientSecret identify Vagrant to the provider. Public
+	// clients have no secret.
+	ClientID     string
+	ClientSecret string
+
+	// Scopes are requested in addition to the "openid" scope.
+	Scopes []string
+
+	// HTTPClient is used for requests to the provider. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Token is the result of a successful login.
+type Token struct {
+	// IDToken is the verified ID token of the user. This is the token
+	// presented to the Vagrant server.
+	IDToken string
+
+	AccessToken  string
+	RefreshToken string
+
+	// Expiry is when the ID token expires.
+	Expiry time.Time
+
+	// Claims are the claims of the ID token.
+	Claims Claims
+}
+
+// defaultDeviceInterval is how often the token endpoint is polled during a
+// device login if the provider doesn't say.
+const defaultDeviceInterval = 5 * time.Second
+
+// AuthCodeLogin logs in with the authorization code flow using PKCE. The
+// provider redirects back to a listener on the loopback interface once the
+// user has logged in. open is called with the URL the user must visit,
+// usually to open it in a browser.
+func AuthCodeLogin(ctx context.Context, cfg *Config, open func(string) error) (*Token, error) {
+	p, err := Discover(ctx, cfg.HTTPClient, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	if p.AuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider %s does not support the authorization code flow", cfg.Issuer)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	redirectURI := fmt.Sprintf("http://%s/callback", ln.Addr())
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	// Wait for the provider to redirect back to us
+	type result struct {
+		code string
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("state") != state:
+			res.err = fmt.Errorf("login callback has an invalid state")
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("login failed: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			res.err = fmt.Errorf("login callback has no code")
+		default:
+			res.code = q.Get("code")
+		}
+
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			io.WriteString(w, "Login complete, you can close this window.\n")
+		}
+
+		select {
+		case resultCh <- res:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	authURL, err := url.Parse(p.AuthorizationEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", cfg.scope())
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	if err := open(authURL.String()); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-resultCh:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	token, err := cfg.exchange(ctx, p, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.verify(ctx, p, token, nonce)
+}
+
+// DeviceLogin logs in with the device authorization flow. This doesn't
+// require a browser on this host. prompt is called with the URL the user
+// must visit and the code to enter there.
+func DeviceLogin(ctx context.Context, cfg *Config, prompt func(uri, code string)) (*Token, error) {
+	p, err := Discover(ctx, cfg.HTTPClient, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	if p.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider %s does not support the device flow", cfg.Issuer)
+	}
+
+	var auth struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	err = cfg.post(ctx, p.DeviceAuthorizationEndpoint, url.Values{
+		"scope": {cfg.scope()},
+	}, &auth)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := auth.VerificationURIComplete
+	if uri == "" {
+		uri = auth.VerificationURI
+	}
+	prompt(uri, auth.UserCode)
+
+	interval := defaultDeviceInterval
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("device login was not completed: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		token, err := cfg.exchange(ctx, p, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {auth.DeviceCode},
+		})
+		if err, ok := err.(*tokenError); ok {
+			switch err.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return cfg.verify(ctx, p, token, "")
+	}
+}
+
+func (c *Config) scope() string {
+	return strings.Join(append([]string{"openid"}, c.Scopes...), " ")
+}
+
+// tokenResponse is the response of the token endpoint.
+type tokenResponse struct {
+	IDToken      string `json:"id_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenError is an error returned by the token endpoint as described by
+// RFC 6749.
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("login failed: %s: %s", e.Code, e.Description)
+	}
+
+	return fmt.Sprintf("login failed: %s", e.Code)
+}
+
+// exchange requests a token from the token endpoint with the given grant.
+func (c *Config) exchange(ctx context.Context, p *Provider, form url.Values) (*tokenResponse, error) {
+	var token tokenResponse
+	if err := c.post(ctx, p.TokenEndpoint, form, &token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("OIDC provider did not return an ID token")
+	}
+
+	return &token, nil
+}
+
+// post posts the form to an endpoint of the provider and decodes the JSON
+// response into v. Errors described by the provider are returned as a
+// *tokenError.
+func (c *Config) post(ctx context.Context, endpoint string, form url.Values, v interface{}) error {
+	form.Set("client_id", c.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var terr tokenError
+		if json.Unmarshal(body, &terr) == nil && terr.Code != "" {
+			return &terr
+		}
+
+		return fmt.Errorf("OIDC provider returned %s", resp.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// verify verifies the ID token returned by the provider. If nonce is not
+// empty, the token must have been issued for that nonce.
+func (c *Config) verify(ctx context.Context, p *Provider, token *tokenResponse, nonce string) (*Token, error) {
+	v := NewVerifier(p, c.ClientID, NewKeySet(c.HTTPClient, p.JWKSURI))
+	claims, err := v.Verify(ctx, token.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if nonce != "" && claims.String("nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	exp, _ := claims.Time("exp")
+	return &Token{
+		IDToken:      token.IDToken,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		Expiry:       exp,
+		Claims:       claims,
+	}, nil
+}
+
+// randomString returns a random URL safe string suitable for the state,
+// nonce and PKCE verifier.
+func randomString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,54 @@
+package oidc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthCodeLogin(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p := NewTestProvider(t)
+	p.Claims["email"] = "alice@example.com"
+
+	cfg := &Config{Issuer: p.Issuer(), ClientID: p.ClientID, Scopes: []string{"email"}}
+
+	// Visiting the URL follows the redirect back to the login listener
+	token, err := AuthCodeLogin(ctx, cfg, func(u string) error {
+		resp, err := http.Get(u)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	require.NoError(err)
+	require.NotEmpty(token.IDToken)
+	require.Equal("alice@example.com", token.Claims.String("email"))
+	require.True(token.Expiry.After(time.Now()))
+}
+
+func TestDeviceLogin(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p := NewTestProvider(t)
+
+	var code string
+	token, err := DeviceLogin(ctx, &Config{Issuer: p.Issuer(), ClientID: p.ClientID},
+		func(uri, userCode string) { code = userCode })
+	require.NoError(err)
+	require.Equal("ABCD-EFGH", code)
+	require.Equal("test-user", token.Claims.String("sub"))
+}
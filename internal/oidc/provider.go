@@ -0,0 +1,72 @@
+// Package oidc implements the parts of OpenID Connect used by Vagrant: logging
+// in with the authorization code or device flow, and verifying the ID
+// tokens issued by a provider against its published keys.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Provider is the discovery document of an OpenID Connect provider.
+type Provider struct {
+	Issuer                      string   `json:"issuer"`
+	AuthorizationEndpoint       string   `json:"authorization_endpoint"`
+	TokenEndpoint               string   `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string   `json:"device_authorization_endpoint"`
+	JWKSURI                     string   `json:"jwks_uri"`
+	SigningAlgs                 []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Discover fetches the discovery document of the provider with the given
+// issuer URL. If client is nil, http.DefaultClient is used.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	var p Provider
+	if err := getJSON(ctx, client, u, &p); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider %s: %w", issuer, err)
+	}
+
+	// The issuer must match exactly so that tokens can't be issued by one
+	// provider for another.
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("OIDC provider issuer %q does not match %q", p.Issuer, issuer)
+	}
+	if p.JWKSURI == "" || p.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider %s is missing jwks_uri or token_endpoint", issuer)
+	}
+
+	return &p, nil
+}
+
+// getJSON decodes the JSON document at the URL into v.
+func getJSON(ctx context.Context, client *http.Client, u string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
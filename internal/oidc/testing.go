@@ -0,0 +1,294 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-testing-interface"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProvider is a local stand-in for an OpenID Connect provider. It
+// serves the discovery and JWKS documents and supports the authorization
+// code and device flows, approving every login right away.
+type TestProvider struct {
+	// ClientID is the client the provider issues tokens to.
+	ClientID string
+
+	// Claims are added to the ID tokens issued by logins.
+	Claims map[string]interface{}
+
+	server *httptest.Server
+
+	mu      sync.Mutex
+	key     *rsa.PrivateKey
+	kid     string
+	codes   map[string]testAuthCode
+	devices map[string]int
+}
+
+type testAuthCode struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// NewTestProvider starts a test provider. It is stopped with t.Cleanup.
+func NewTestProvider(t testing.T) *TestProvider {
+	p := &TestProvider{
+		ClientID: "vagrant",
+		Claims:   map[string]interface{}{"sub": "test-user"},
+		codes:    map[string]testAuthCode{},
+		devices:  map[string]int{},
+	}
+	p.RotateKey(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/device", p.handleDevice)
+	mux.HandleFunc("/token", p.handleToken)
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// Issuer returns the issuer URL of the provider.
+func (p *TestProvider) Issuer() string {
+	return p.server.URL
+}
+
+// RotateKey replaces the key the provider signs tokens with.
+func (p *TestProvider) RotateKey(t testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key = key
+	p.kid = fmt.Sprintf("key-%d", time.Now().UnixNano())
+}
+
+// SignToken returns an ID token with the given claims signed by the
+// provider. The issuer, audience and expiry are set unless the claims
+// include them.
+func (p *TestProvider) SignToken(t testing.T, claims map[string]interface{}) string {
+	token, err := p.signToken(claims)
+	require.NoError(t, err)
+	return token
+}
+
+func (p *TestProvider) signToken(claims map[string]interface{}) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	all := map[string]interface{}{
+		"iss": p.server.URL,
+		"aud": p.ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": p.kid, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(all)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// loginToken returns the token response for a completed login.
+func (p *TestProvider) loginToken(nonce string) (map[string]string, error) {
+	claims := map[string]interface{}{}
+	for k, v := range p.Claims {
+		claims[k] = v
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	token, err := p.signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"id_token":     token,
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+	}, nil
+}
+
+func (p *TestProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, &Provider{
+		Issuer:                      p.server.URL,
+		AuthorizationEndpoint:       p.server.URL + "/authorize",
+		TokenEndpoint:               p.server.URL + "/token",
+		DeviceAuthorizationEndpoint: p.server.URL + "/device",
+		JWKSURI:                     p.server.URL + "/jwks",
+		SigningAlgs:                 []string{"RS256"},
+	})
+}
+
+func (p *TestProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []jsonWebKey{{
+			Kty: "RSA",
+			Kid: p.kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// handleAuthorize approves the login and redirects back with a code.
+func (p *TestProvider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = testAuthCode{
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+	}
+	p.mu.Unlock()
+
+	u, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	uq := u.Query()
+	uq.Set("code", code)
+	uq.Set("state", q.Get("state"))
+	u.RawQuery = uq.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (p *TestProvider) handleDevice(w http.ResponseWriter, r *http.Request) {
+	code, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.devices[code] = 0
+	p.mu.Unlock()
+
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"device_code":      code,
+		"user_code":        "ABCD-EFGH",
+		"verification_uri": p.server.URL + "/activate",
+		"expires_in":       60,
+		"interval":         1,
+	})
+}
+
+func (p *TestProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != p.ClientID {
+		writeTestJSON(w, http.StatusBadRequest, &tokenError{Code: "invalid_client"})
+		return
+	}
+
+	nonce, terr := p.redeemGrant(r.PostForm)
+	if terr != nil {
+		writeTestJSON(w, http.StatusBadRequest, terr)
+		return
+	}
+
+	resp, err := p.loginToken(nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeTestJSON(w, http.StatusOK, resp)
+}
+
+// redeemGrant checks the grant of a token request and returns the nonce
+// of the login, if any.
+func (p *TestProvider) redeemGrant(form url.Values) (string, *tokenError) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch form.Get("grant_type") {
+	case "authorization_code":
+		code, ok := p.codes[form.Get("code")]
+		delete(p.codes, form.Get("code"))
+
+		challenge := sha256.Sum256([]byte(form.Get("code_verifier")))
+		if !ok || code.redirectURI != form.Get("redirect_uri") ||
+			code.challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+			return "", &tokenError{Code: "invalid_grant"}
+		}
+
+		return code.nonce, nil
+
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		// The first poll is pending, as if the user hasn't logged in yet
+		polls, ok := p.devices[form.Get("device_code")]
+		if !ok {
+			return "", &tokenError{Code: "invalid_grant"}
+		}
+		p.devices[form.Get("device_code")] = polls + 1
+		if polls == 0 {
+			return "", &tokenError{Code: "authorization_pending"}
+		}
+
+		return "", nil
+	}
+
+	return "", &tokenError{Code: "unsupported_grant_type"}
+}
+
+func writeTestJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
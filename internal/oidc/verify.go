@@ -0,0 +1,276 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// Register the hashes used by the supported algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// ErrInvalidToken is returned for tokens which fail verification.
+var ErrInvalidToken = errors.New("invalid OIDC token")
+
+// defaultLeeway is the clock skew allowed when checking the times in a
+// token.
+const defaultLeeway = time.Minute
+
+// Verifier verifies the ID tokens issued by a provider.
+type Verifier struct {
+	// Issuer is the issuer URL tokens must be issued by.
+	Issuer string
+
+	// ClientID is the audience tokens must be issued for.
+	ClientID string
+
+	// Keys are the keys tokens may be signed with.
+	Keys *KeySet
+
+	// Leeway is the clock skew allowed when checking the expiry of a
+	// token. If zero, a minute is allowed.
+	Leeway time.Duration
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewVerifier returns a verifier for the ID tokens the provider issues to
+// the client.
+func NewVerifier(p *Provider, clientID string, keys *KeySet) *Verifier {
+	return &Verifier{
+		Issuer:   p.Issuer,
+		ClientID: clientID,
+		Keys:     keys,
+	}
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]interface{}
+
+// String returns the claim with the given name if it is a string.
+func (c Claims) String(name string) string {
+	v, _ := c[name].(string)
+	return v
+}
+
+// Strings returns the claim with the given name as a list. Claims with a
+// single string value are returned as a list with one item.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	return nil
+}
+
+// Time returns the claim with the given name as a time. These are stored
+// as seconds since the epoch.
+func (c Claims) Time(name string) (time.Time, bool) {
+	v, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	f, err := v.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+// IsJWT returns true if the token has the form of a JWT. This doesn't
+// verify the token.
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && strings.HasPrefix(token, "eyJ")
+}
+
+// Verify verifies the signature, issuer, audience and expiry of the token
+// and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	key, err := v.Keys.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	// Keys restricted to an algorithm may only be used with it
+	if key.alg != "" && key.alg != header.Alg {
+		return nil, fmt.Errorf("%w: algorithm %q doesn't match the %q signing key",
+			ErrInvalidToken, header.Alg, key.alg)
+	}
+
+	signed := parts[0] + "." + parts[1]
+	if err := verifySignature(header.Alg, key.key, []byte(signed), signature); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	if err := v.verifyClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) verifyClaims(claims Claims) error {
+	if iss := claims.String("iss"); iss != v.Issuer {
+		return fmt.Errorf("issued by %q, expected %q", iss, v.Issuer)
+	}
+
+	audience := false
+	for _, aud := range claims.Strings("aud") {
+		if aud == v.ClientID {
+			audience = true
+		}
+	}
+	if !audience {
+		return fmt.Errorf("not issued for %q", v.ClientID)
+	}
+
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	leeway := v.Leeway
+	if leeway == 0 {
+		leeway = defaultLeeway
+	}
+
+	exp, ok := claims.Time("exp")
+	if !ok {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(exp.Add(leeway)) {
+		return fmt.Errorf("token expired at %s", exp)
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(leeway).Before(nbf) {
+		return fmt.Errorf("token is not valid before %s", nbf)
+	}
+
+	return nil
+}
+
+// ecdsaCurves are the curves of the ECDSA algorithms.
+var ecdsaCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// verifySignature verifies the signature of data with the algorithm of
+// the token. Only asymmetric algorithms are supported, tokens using "none"
+// or a shared secret are rejected.
+func verifySignature(alg string, key crypto.PublicKey, data, signature []byte) error {
+	// All the supported algorithms are named like "RS256"
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %q requires an RSA key", alg)
+		}
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(pub, hash, digest, signature, nil)
+		}
+
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %q requires an EC key", alg)
+		}
+
+		// Each algorithm is defined for a single curve
+		if curve := ecdsaCurves[alg]; pub.Curve.Params().Name != curve {
+			return fmt.Errorf("algorithm %q requires a %s key", alg, curve)
+		}
+
+		// The signature is the concatenation of r and s
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
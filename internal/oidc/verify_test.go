@@ -0,0 +1,223 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewTestProvider(t)
+	provider, err := Discover(ctx, nil, p.Issuer())
+	require.NoError(t, err)
+
+	newVerifier := func() *Verifier {
+		return NewVerifier(provider, p.ClientID, NewKeySet(nil, provider.JWKSURI))
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		require := require.New(t)
+
+		token := p.SignToken(t, map[string]interface{}{
+			"sub":    "alice",
+			"groups": []string{"admins", "ops"},
+		})
+		require.True(IsJWT(token))
+
+		claims, err := newVerifier().Verify(ctx, token)
+		require.NoError(err)
+		require.Equal("alice", claims.String("sub"))
+		require.Equal([]string{"admins", "ops"}, claims.Strings("groups"))
+	})
+
+	t.Run("invalid claims", func(t *testing.T) {
+		cases := map[string]map[string]interface{}{
+			"issuer":     {"iss": "https://other.example.com"},
+			"audience":   {"aud": []string{"other"}},
+			"expired":    {"exp": time.Now().Add(-time.Hour).Unix()},
+			"no expiry":  {"exp": nil},
+			"not before": {"nbf": time.Now().Add(time.Hour).Unix()},
+		}
+
+		for name, claims := range cases {
+			t.Run(name, func(t *testing.T) {
+				_, err := newVerifier().Verify(ctx, p.SignToken(t, claims))
+				require.Error(t, err)
+				require.True(t, errors.Is(err, ErrInvalidToken))
+			})
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		require := require.New(t)
+
+		token := p.SignToken(t, nil)
+		parts := strings.Split(token, ".")
+		claims, err := json.Marshal(map[string]interface{}{
+			"iss": p.Issuer(),
+			"aud": p.ClientID,
+			"sub": "mallory",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(err)
+		parts[1] = base64.RawURLEncoding.EncodeToString(claims)
+
+		_, err = newVerifier().Verify(ctx, strings.Join(parts, "."))
+		require.Error(err)
+	})
+
+	t.Run("unsigned tokens", func(t *testing.T) {
+		require := require.New(t)
+
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+		parts := strings.Split(p.SignToken(t, nil), ".")
+
+		_, err := newVerifier().Verify(ctx, header+"."+parts[1]+".")
+		require.Error(err)
+	})
+
+	t.Run("rotated keys", func(t *testing.T) {
+		require := require.New(t)
+
+		defer func(v time.Duration) { keySetRefreshInterval = v }(keySetRefreshInterval)
+		keySetRefreshInterval = 0
+
+		v := newVerifier()
+		_, err := v.Verify(ctx, p.SignToken(t, nil))
+		require.NoError(err)
+
+		// The new key is fetched once a token is signed with it
+		p.RotateKey(t)
+		_, err = v.Verify(ctx, p.SignToken(t, nil))
+		require.NoError(err)
+	})
+
+	t.Run("EC keys", func(t *testing.T) {
+		require := require.New(t)
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(err)
+
+		v := testECVerifier(t, testECKey(key, ""))
+		_, err = v.Verify(ctx, testECToken(t, key, "ES256"))
+		require.NoError(err)
+	})
+
+	t.Run("algorithm doesn't match the curve", func(t *testing.T) {
+		require := require.New(t)
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(err)
+
+		v := testECVerifier(t, testECKey(key, ""))
+		_, err = v.Verify(ctx, testECToken(t, key, "ES512"))
+		require.Error(err)
+		require.True(errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("algorithm doesn't match the key", func(t *testing.T) {
+		require := require.New(t)
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(err)
+
+		v := testECVerifier(t, testECKey(key, "ES384"))
+		_, err = v.Verify(ctx, testECToken(t, key, "ES256"))
+		require.Error(err)
+		require.True(errors.Is(err, ErrInvalidToken))
+	})
+
+	t.Run("invalid keys are skipped", func(t *testing.T) {
+		require := require.New(t)
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(err)
+
+		invalid := testECKey(key, "")
+		invalid.Kid = "invalid"
+		invalid.Crv = "P-1"
+
+		v := testECVerifier(t, invalid, testECKey(key, ""))
+		_, err = v.Verify(ctx, testECToken(t, key, "ES256"))
+		require.NoError(err)
+	})
+}
+
+// testECKey returns the JWK of the key with the ID "ec".
+func testECKey(key *ecdsa.PrivateKey, alg string) jsonWebKey {
+	return jsonWebKey{
+		Kty: "EC",
+		Kid: "ec",
+		Alg: alg,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// testECVerifier returns a verifier for tokens of "issuer" for "client"
+// signed with the given keys.
+func testECVerifier(t *testing.T, keys ...jsonWebKey) *Verifier {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(srv.Close)
+
+	return &Verifier{Issuer: "issuer", ClientID: "client", Keys: NewKeySet(nil, srv.URL)}
+}
+
+// testECToken returns a token signed with the P-256 key using the hash of
+// the given algorithm.
+func testECToken(t *testing.T, key *ecdsa.PrivateKey, alg string) string {
+	header := base64.RawURLEncoding.EncodeToString(
+		[]byte(fmt.Sprintf(`{"alg":%q,"kid":"ec"}`, alg)))
+	payload, err := json.Marshal(map[string]interface{}{
+		"iss": "issuer",
+		"aud": "client",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var digest []byte
+	switch alg {
+	case "ES384":
+		sum := sha512.Sum384([]byte(signed))
+		digest = sum[:]
+	case "ES512":
+		sum := sha512.Sum512([]byte(signed))
+		digest = sum[:]
+	default:
+		sum := sha256.Sum256([]byte(signed))
+		digest = sum[:]
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	require.NoError(t, err)
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestDiscover_issuerMismatch(t *testing.T) {
+	p := NewTestProvider(t)
+
+	_, err := Discover(context.Background(), nil, p.Issuer()+"/")
+	require.Error(t, err)
+}
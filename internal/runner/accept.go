@@ -38,17 +38,27 @@ func (r *Runner) AcceptExact(ctx context.Context, id string) error {
 }
 
 func (r *Runner) accept(ctx context.Context, id string) error {
+	// Track this call so that Close waits for it. This is done with the
+	// lock held so that Close can't start waiting in between.
+	r.acceptMu.Lock()
 	if r.closed() {
+		r.acceptMu.Unlock()
 		return ErrClosed
 	}
+	r.acceptWg.Add(1)
+	r.acceptMu.Unlock()
+	defer r.acceptWg.Done()
 
 	log := r.logger
 
 	// Open a new job stream. NOTE: we purposely do NOT use ctx above
 	// since if the context is cancelled we want to continue reporting
-	// errors.
+	// errors. The stream is only cancelled if ctx is cancelled or the
+	// runner is closed while waiting for an assignment.
 	log.Debug("opening job stream")
-	client, err := r.client.RunnerJobStream(context.Background())
+	streamCtx, streamCancel := context.WithCancel(context.Background())
+	defer streamCancel()
+	client, err := r.client.RunnerJobStream(streamCtx)
 	if err != nil {
 		return err
 	}
@@ -66,10 +76,32 @@ func (r *Runner) accept(ctx context.Context, id string) error {
 		return err
 	}
 
-	// Wait for an assignment
+	// Wait for an assignment. If ctx is cancelled or the runner is closed
+	// in the meantime, we cancel the stream so that we stop waiting.
 	log.Info("waiting for job assignment")
+	waitCh := make(chan struct{})
+	waitDoneCh := make(chan struct{})
+	go func() {
+		defer close(waitDoneCh)
+		select {
+		case <-r.closeCh:
+			streamCancel()
+		case <-ctx.Done():
+			streamCancel()
+		case <-waitCh:
+		}
+	}()
 	resp, err := client.Recv()
+	close(waitCh)
+	<-waitDoneCh
 	if err != nil {
+		if r.closed() {
+			return ErrClosed
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		return err
 	}
 
@@ -83,12 +115,23 @@ func (r *Runner) accept(ctx context.Context, id string) error {
 	log = log.With("job_id", assignment.Assignment.Job.Id)
 	log.Info("job assignment received")
 
-	// We increment the waitgroup at this point since prior to this if we're
-	// forcefully quit, we shouldn't have acked. This is somewhat brittle so
-	// a todo here is to build a better notification mechanism that we've quit
-	// and exit here.
-	r.acceptWg.Add(1)
-	defer r.acceptWg.Done()
+	// If the runner was closed or ctx was cancelled before we acked, we
+	// hand the job back to the server so it can be assigned to another
+	// runner. If the stream was already cancelled, the server does this
+	// when the stream closes.
+	if r.closed() || ctx.Err() != nil {
+		log.Info("runner is closing, nacking job assignment")
+		client.Send(&vagrant_server.RunnerJobStreamRequest{
+			Event: &vagrant_server.RunnerJobStreamRequest_Error_{
+				Error: &vagrant_server.RunnerJobStreamRequest_Error{},
+			},
+		})
+
+		if r.closed() {
+			return ErrClosed
+		}
+		return ctx.Err()
+	}
 
 	// If this isn't the job we expected then we nack and error.
 	if id != "" {
@@ -125,12 +168,24 @@ func (r *Runner) accept(ctx context.Context, id string) error {
 	// We need a mutex to protect against simultaneous sends to the client.
 	var sendMutex sync.Mutex
 
+	// The output of the job is also written to the log stream of the
+	// job's scope so that it can be followed with the scope's logs.
+	var evc vagrant_server.Vagrant_RunnerJobStreamClient = client
+	logs, err := r.openLogStream(streamCtx, log, client, assignment.Assignment.Job)
+	if err != nil {
+		log.Warn("error opening log stream for job output", "error", err)
+	}
+	if logs != nil {
+		defer logs.Close()
+		evc = logs
+	}
+
 	// For our UI, we always send output to the server. If we have a local UI
 	// set, we mirror to that as well.
 	var ui terminal.UI = &runnerUI{
 		ctx:    ctx,
 		cancel: cancel,
-		evc:    client,
+		evc:    evc,
 		mu:     &sendMutex,
 	}
 	if r.ui != nil {
@@ -195,8 +250,7 @@ func (r *Runner) accept(ctx context.Context, id string) error {
 		ctx,
 		log,
 		ui,
-		assignment.Assignment.Job.DataSource,
-		assignment.Assignment.Job.DataSourceOverrides,
+		assignment.Assignment.Job,
 	)
 	if err == nil {
 		log.Debug("job data downloaded (or local)", "pwd", wd)
@@ -262,8 +316,12 @@ func (r *Runner) accept(ctx context.Context, id string) error {
 			},
 		}); rpcerr != nil {
 			log.Warn("error sending error event, job may be dangling", "err", rpcerr)
+			return nil
 		}
 
+		// Wait for the server to close the stream so that the error is
+		// recorded before the stream is cancelled.
+		<-errCh
 		return nil
 	}
 
@@ -8,6 +8,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/hashicorp/vagrant-plugin-sdk/helper/paths"
 	"github.com/hashicorp/vagrant-plugin-sdk/terminal"
 	"github.com/hashicorp/vagrant/internal/datasource"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
@@ -24,9 +25,10 @@ func (r *Runner) downloadJobData(
 	ctx context.Context,
 	log hclog.Logger,
 	ui terminal.UI,
-	source *vagrant_server.Job_DataSource,
-	overrides map[string]string,
+	job *vagrant_server.Job,
 ) (string, func() error, error) {
+	source := job.DataSource
+	overrides := job.DataSourceOverrides
 	if source == nil {
 		return "", nil, status.Errorf(codes.Internal,
 			"data source not set for job")
@@ -40,6 +42,21 @@ func (r *Runner) downloadJobData(
 			"invalid data source type: %s", typ.String())
 	}
 	sourcer := factory()
+	if cs, ok := sourcer.(datasource.ClientSourcer); ok && r.client != nil {
+		cs.SetClient(r.client)
+	}
+	if cs, ok := sourcer.(datasource.ConfigSourcer); ok {
+		vars, err := r.jobConfigVars(ctx, job)
+		if err != nil {
+			return "", nil, err
+		}
+		cs.SetConfig(vars)
+	}
+	if cs, ok := sourcer.(datasource.CacheSourcer); ok {
+		if dir := r.dataCacheDir(log); dir != "" {
+			cs.SetCacheDir(dir)
+		}
+	}
 
 	// Apply any overrides
 	if len(overrides) > 0 {
@@ -52,3 +69,51 @@ func (r *Runner) downloadJobData(
 	// Get data
 	return sourcer.Get(ctx, log, ui, source, r.tempDir)
 }
+
+// jobConfigVars returns the config variables available to the data source
+// of the job. Variables scoped to the project of the job take precedence
+// over the variables of the runner.
+func (r *Runner) jobConfigVars(
+	ctx context.Context,
+	job *vagrant_server.Job,
+) (map[string]string, error) {
+	vars := map[string]string{}
+	if r.config != nil {
+		for _, v := range r.config.ConfigVars {
+			vars[v.Name] = v.Value
+		}
+	}
+
+	if job.Project != nil && r.client != nil {
+		resp, err := r.client.GetConfig(ctx, &vagrant_server.ConfigGetRequest{
+			Scope: &vagrant_server.ConfigGetRequest_Project{
+				Project: job.Project,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range resp.Variables {
+			vars[v.Name] = v.Value
+		}
+	}
+
+	return vars, nil
+}
+
+// dataCacheDir returns the directory data sources may cache data in. If
+// no directory was configured, the Vagrant cache directory is used. An
+// empty string is returned if there is no cache directory.
+func (r *Runner) dataCacheDir(log hclog.Logger) string {
+	if r.dataCache != "" {
+		return r.dataCache
+	}
+
+	dir, err := paths.VagrantCache()
+	if err != nil {
+		log.Warn("no cache directory for job data", "err", err)
+		return ""
+	}
+
+	return dir.Join("runner-data").String()
+}
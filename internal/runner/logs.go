@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// logStreamClient forwards the terminal output of a job to the log stream
+// of the basis, project, or target the job runs in. It wraps the job
+// stream client so that all output the job sends to the server is also
+// available from GetLogStream.
+type logStreamClient struct {
+	vagrant_server.Vagrant_RunnerJobStreamClient
+
+	log    hclog.Logger
+	mu     sync.Mutex
+	stream vagrant_server.Vagrant_SendLogStreamClient
+}
+
+// openLogStream opens the log stream for the scope of the job. The
+// output sent on client is written to the log stream until it is closed.
+// If the job has no scope, nil is returned.
+func (r *Runner) openLogStream(
+	ctx context.Context,
+	log hclog.Logger,
+	client vagrant_server.Vagrant_RunnerJobStreamClient,
+	job *vagrant_server.Job,
+) (*logStreamClient, error) {
+	req := &vagrant_server.SendLogStreamRequest{InstanceId: r.id}
+	switch {
+	case job.Target != nil:
+		req.Scope = &vagrant_server.SendLogStreamRequest_Target{Target: job.Target}
+	case job.Project != nil:
+		req.Scope = &vagrant_server.SendLogStreamRequest_Project{Project: job.Project}
+	case job.Basis != nil:
+		req.Scope = &vagrant_server.SendLogStreamRequest_Basis{Basis: job.Basis}
+	default:
+		return nil, nil
+	}
+
+	stream, err := r.client.SendLogStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first message sets the scope so the log stream is opened
+	// before the job writes any output.
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+
+	return &logStreamClient{
+		Vagrant_RunnerJobStreamClient: client,
+		log:                           log,
+		stream:                        stream,
+	}, nil
+}
+
+// Send implements vagrant_server.Vagrant_RunnerJobStreamClient
+func (c *logStreamClient) Send(req *vagrant_server.RunnerJobStreamRequest) error {
+	if ev, ok := req.Event.(*vagrant_server.RunnerJobStreamRequest_Terminal); ok {
+		c.sendLines(terminalLines(ev.Terminal.Events))
+	}
+
+	return c.Vagrant_RunnerJobStreamClient.Send(req)
+}
+
+// Close closes the log stream. The job stream is not closed.
+func (c *logStreamClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stream == nil {
+		return nil
+	}
+
+	_, err := c.stream.CloseAndRecv()
+	c.stream = nil
+	return err
+}
+
+func (c *logStreamClient) sendLines(lines []*vagrant_server.LogBatch_Entry) {
+	if len(lines) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stream == nil {
+		return
+	}
+
+	// Failing to write the log stream doesn't fail the job, we only
+	// stop writing to it.
+	err := c.stream.Send(&vagrant_server.SendLogStreamRequest{Lines: lines})
+	if err != nil {
+		c.log.Warn("error sending job output to log stream", "error", err)
+		c.stream.CloseSend()
+		c.stream = nil
+	}
+}
+
+// terminalLines returns the log lines for the output in the terminal
+// events. Events without text output, such as tables, are skipped.
+func terminalLines(events []*vagrant_server.GetJobStreamResponse_Terminal_Event) []*vagrant_server.LogBatch_Entry {
+	var result []*vagrant_server.LogBatch_Entry
+	add := func(ts *timestamppb.Timestamp, text string) {
+		text = strings.TrimRight(text, "\r\n")
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(text, "\n") {
+			result = append(result, &vagrant_server.LogBatch_Entry{
+				Timestamp: ts,
+				Line:      strings.TrimRight(line, "\r"),
+			})
+		}
+	}
+
+	for _, ev := range events {
+		ts := ev.Timestamp
+		if ts == nil {
+			ts = timestamppb.Now()
+		}
+
+		switch e := ev.Event.(type) {
+		case *vagrant_server.GetJobStreamResponse_Terminal_Event_Line_:
+			add(ts, e.Line.Msg)
+		case *vagrant_server.GetJobStreamResponse_Terminal_Event_Raw_:
+			add(ts, string(e.Raw.Data))
+		case *vagrant_server.GetJobStreamResponse_Terminal_Event_Status_:
+			add(ts, e.Status.Msg)
+		case *vagrant_server.GetJobStreamResponse_Terminal_Event_Step_:
+			add(ts, e.Step.Msg)
+			add(ts, string(e.Step.Output))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess"
+)
+
+func TestRunnerLogStream(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := singleprocess.TestServer(t)
+	basisResp, err := client.UpsertBasis(ctx, &vagrant_server.UpsertBasisRequest{
+		Basis: &vagrant_server.Basis{Name: "mybasis"},
+	})
+	require.NoError(err)
+	basis := &vagrant_plugin_sdk.Ref_Basis{ResourceId: basisResp.Basis.ResourceId}
+
+	r := &Runner{id: "R_A", client: client}
+	jobStream := &testJobStreamClient{}
+	logs, err := r.openLogStream(ctx, hclog.L(), jobStream, &vagrant_server.Job{Basis: basis})
+	require.NoError(err)
+	require.NotNil(logs)
+
+	// Write output like a job does
+	ui := &runnerUI{
+		ctx:    ctx,
+		cancel: cancel,
+		evc:    logs,
+		mu:     &sync.Mutex{},
+	}
+	ui.Output("hello")
+	ui.Output("one\ntwo\n")
+	require.NoError(logs.Close())
+
+	// The output is still sent to the job stream
+	require.Len(jobStream.reqs, 2)
+
+	logRecvClient, err := client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
+		Scope: &vagrant_server.GetLogStreamRequest_Basis{Basis: basis},
+	})
+	require.NoError(err)
+
+	batch, err := logRecvClient.Recv()
+	require.NoError(err)
+	require.Equal("R_A", batch.InstanceId)
+	var lines []string
+	for _, l := range batch.Lines {
+		lines = append(lines, l.Line)
+	}
+	require.Equal([]string{"hello", "one", "two"}, lines)
+
+	// Jobs without a scope don't have a log stream
+	logs, err = r.openLogStream(ctx, hclog.L(), jobStream, &vagrant_server.Job{})
+	require.NoError(err)
+	require.Nil(logs)
+}
+
+// testJobStreamClient records the requests sent on a job stream.
+type testJobStreamClient struct {
+	vagrant_server.Vagrant_RunnerJobStreamClient
+
+	reqs []*vagrant_server.RunnerJobStreamRequest
+}
+
+func (c *testJobStreamClient) Send(req *vagrant_server.RunnerJobStreamRequest) error {
+	c.reqs = append(c.reqs, req)
+	return nil
+}
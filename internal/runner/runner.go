@@ -3,6 +3,7 @@ package runner
 import (
 	"context"
 	"errors"
+	"os"
 	"sync"
 	"sync/atomic"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/hashicorp/vagrant/internal/server"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 	"github.com/hashicorp/vagrant/internal/serverclient"
+	"github.com/hashicorp/vagrant/internal/version"
 )
 
 var ErrClosed = errors.New("runner is closed")
@@ -40,9 +42,12 @@ var ErrClosed = errors.New("runner is closed")
 //      network listener "accepting" a connection. This will request a single
 //      job from the Vagrant server, block until one is available, and execute
 //      it. Repeat this call for however many jobs you want to execute.
+//      Alternatively, call "Run" to execute jobs with a pool of workers
+//      until the runner is closed.
 //
 //   4. Clean up with "Close". This will gracefully exit the runner, waiting
-//      for any running jobs to finish.
+//      for any running jobs to finish. Job assignments which were not yet
+//      acked are handed back to the server.
 //
 type Runner struct {
 	id                 string
@@ -58,8 +63,11 @@ type Runner struct {
 	ui                 terminal.UI
 	local              bool
 	tempDir            string
+	dataCache          string
 
 	closedVal int32
+	closeCh   chan struct{}
+	acceptMu  sync.Mutex
 	acceptWg  sync.WaitGroup
 
 	plugins *plugin.Manager
@@ -95,14 +103,21 @@ func New(opts ...Option) (*Runner, error) {
 
 	// Our default runner
 	runner := &Runner{
-		id:       id,
-		logger:   hclog.L(),
-		cleanup:  cleanup.New(),
-		ctx:      context.Background(),
-		runner:   &vagrant_server.Runner{Id: id},
+		id:      id,
+		logger:  hclog.L(),
+		cleanup: cleanup.New(),
+		ctx:     context.Background(),
+		runner: &vagrant_server.Runner{
+			Id:      id,
+			Version: version.GetVersion().FullVersionNumber(false),
+		},
 		opConfig: &intcfg.Config{},
+		closeCh:  make(chan struct{}),
 	}
 
+	// The hostname is only informational so we ignore any error
+	runner.runner.Hostname, _ = os.Hostname()
+
 	// Build our config
 	var cfg config
 	for _, o := range opts {
@@ -218,11 +233,15 @@ func (r *Runner) Start() error {
 // return errors immediately.
 func (r *Runner) Close() error {
 	// If we can't swap, we're already closed.
-	if !atomic.CompareAndSwapInt32(&r.closedVal, 0, 1) {
+	r.acceptMu.Lock()
+	swapped := atomic.CompareAndSwapInt32(&r.closedVal, 0, 1)
+	r.acceptMu.Unlock()
+	if !swapped {
 		return nil
 	}
 
-	// Wait for our jobs to complete
+	// Stop waiting for new jobs and wait for our running jobs to complete
+	close(r.closeCh)
 	r.acceptWg.Wait()
 
 	r.logger.Info("closing down the runner")
@@ -292,6 +311,51 @@ func WithLocal(ui terminal.UI) Option {
 	}
 }
 
+// WithConcurrency sets the maximum number of jobs the runner executes at
+// the same time. This is the number of workers started by Run and is
+// advertised to the server so that no more jobs are assigned. If this
+// isn't set, one job is executed at a time.
+func WithConcurrency(n uint32) Option {
+	return func(r *Runner, cfg *config) error {
+		if n == 0 {
+			return status.Errorf(codes.InvalidArgument,
+				"runner concurrency must be greater than zero")
+		}
+
+		r.runner.Concurrency = n
+		return nil
+	}
+}
+
+// WithResourceSlot adds a resource slot to the runner. At most capacity
+// jobs running any of the given commands are assigned to the runner at the
+// same time. The command "*" matches any command not used by another slot.
+func WithResourceSlot(name string, capacity uint32, commands ...string) Option {
+	return func(r *Runner, cfg *config) error {
+		if len(commands) == 0 {
+			return status.Errorf(codes.InvalidArgument,
+				"resource slot %q must have at least one command", name)
+		}
+
+		r.runner.Slots = append(r.runner.Slots, &vagrant_server.Runner_ResourceSlot{
+			Name:     name,
+			Capacity: capacity,
+			Commands: commands,
+		})
+		return nil
+	}
+}
+
+// WithDataCacheDir sets the directory where data sources cache data
+// between jobs, such as mirrors of Git repositories. If this isn't set,
+// the Vagrant cache directory is used.
+func WithDataCacheDir(dir string) Option {
+	return func(r *Runner, cfg *config) error {
+		r.dataCache = dir
+		return nil
+	}
+}
+
 // ByIdOnly sets it so that only jobs that target this runner by specific
 // ID may be assigned.
 func ByIdOnly() Option {
@@ -301,6 +365,20 @@ func ByIdOnly() Option {
 	}
 }
 
+// WithLabels sets the labels the runner registers with. Jobs with label
+// selectors are only assigned to runners with matching labels.
+func WithLabels(labels map[string]string) Option {
+	return func(r *Runner, cfg *config) error {
+		if r.runner.Labels == nil {
+			r.runner.Labels = map[string]string{}
+		}
+		for k, v := range labels {
+			r.runner.Labels[k] = v
+		}
+		return nil
+	}
+}
+
 func WithContext(ctx context.Context) Option {
 	return func(r *Runner, cfg *config) error {
 		r.ctx = ctx
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+var (
+	// workerBackoff is how long a worker waits before accepting another
+	// job after Accept returned an error. This doubles on each consecutive
+	// error up to workerMaxBackoff.
+	workerBackoff    = 1 * time.Second
+	workerMaxBackoff = 1 * time.Minute
+)
+
+// Run executes jobs with a pool of workers until ctx is cancelled or the
+// runner is closed. The number of workers is the concurrency set with
+// WithConcurrency, or one if it isn't set. Each worker repeatedly calls
+// Accept. This returns once all the workers have exited, which means any
+// job being executed has completed.
+//
+// To stop the runner gracefully, call Close. Jobs which are assigned but
+// not yet acked are handed back to the server so they can be assigned
+// to another runner.
+//
+// The local runner of the CLI doesn't use Run since it only executes the
+// jobs it queues itself with AcceptExact. Run is for runners which take
+// any jobs from the server and are built with this package directly.
+func (r *Runner) Run(ctx context.Context) error {
+	if r.closed() {
+		return ErrClosed
+	}
+
+	n := int(r.runner.Concurrency)
+	if n == 0 {
+		n = 1
+	}
+
+	r.logger.Info("starting workers", "count", n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(log hclog.Logger) {
+			defer wg.Done()
+			r.runWorker(ctx, log)
+		}(r.logger.Named("worker").With("worker", i))
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// runWorker accepts and executes jobs until ctx is cancelled or the
+// runner is closed.
+func (r *Runner) runWorker(ctx context.Context, log hclog.Logger) {
+	backoff := workerBackoff
+	for {
+		err := r.Accept(ctx)
+		if err == ErrClosed || ctx.Err() != nil {
+			log.Debug("worker exiting")
+			return
+		}
+		if err == nil {
+			backoff = workerBackoff
+			continue
+		}
+
+		log.Warn("error accepting job, retrying", "error", err, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		case <-r.closeCh:
+			return
+		}
+
+		backoff *= 2
+		if backoff > workerMaxBackoff {
+			backoff = workerMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/internal-shared/cleanup"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess"
+	"github.com/hashicorp/vagrant/internal/serverclient"
+)
+
+func TestRunnerRun(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	client := singleprocess.TestServer(t)
+
+	r := testWorkerRunner(t, client,
+		WithConcurrency(2),
+		WithResourceSlot("vms", 1, "up"),
+	)
+	require.NoError(r.Start())
+
+	// The runner registers with its concurrency and slots
+	resp, err := client.GetRunner(ctx, &vagrant_server.GetRunnerRequest{RunnerId: r.Id()})
+	require.NoError(err)
+	require.Equal(uint32(2), resp.Concurrency)
+	require.Len(resp.Slots, 1)
+	require.Equal("vms", resp.Slots[0].Name)
+
+	// Queue jobs that fail to get their data so they complete without
+	// needing plugins
+	var ids []string
+	for i := 0; i < 3; i++ {
+		queueResp, err := client.QueueJob(ctx, &vagrant_server.QueueJobRequest{
+			Job: serverptypes.TestJobNew(t, &vagrant_server.Job{
+				DataSource: &vagrant_server.Job_DataSource{
+					Source: &vagrant_server.Job_DataSource_Git{
+						Git: &vagrant_server.Job_Git{Url: "file:///nonexistent/repo"},
+					},
+				},
+			}),
+		})
+		require.NoError(err)
+		ids = append(ids, queueResp.JobId)
+	}
+
+	runCh := make(chan error, 1)
+	go func() { runCh <- r.Run(ctx) }()
+
+	// All the jobs are executed
+	for _, id := range ids {
+		require.Eventually(func() bool {
+			job, err := client.GetJob(ctx, &vagrant_server.GetJobRequest{JobId: id})
+			require.NoError(err)
+			return job.State == vagrant_server.Job_ERROR
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+
+	// Closing the runner stops the workers
+	require.NoError(r.Close())
+	select {
+	case err := <-runCh:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after Close")
+	}
+
+	require.Equal(ErrClosed, r.Run(ctx))
+}
+
+func TestRunnerRun_closeWhileWaiting(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	client := singleprocess.TestServer(t)
+
+	r := testWorkerRunner(t, client, WithConcurrency(3))
+	require.NoError(r.Start())
+
+	runCh := make(chan error, 1)
+	go func() { runCh <- r.Run(ctx) }()
+
+	// Wait for the workers to wait for an assignment
+	time.Sleep(100 * time.Millisecond)
+
+	closeCh := make(chan error, 1)
+	go func() { closeCh <- r.Close() }()
+	select {
+	case err := <-closeCh:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't return while waiting for jobs")
+	}
+
+	select {
+	case err := <-runCh:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after Close")
+	}
+
+	// Jobs queued afterwards are not assigned to the closed runner
+	queueResp, err := client.QueueJob(ctx, &vagrant_server.QueueJobRequest{
+		Job: serverptypes.TestJobNew(t, nil),
+	})
+	require.NoError(err)
+	time.Sleep(100 * time.Millisecond)
+	job, err := client.GetJob(ctx, &vagrant_server.GetJobRequest{JobId: queueResp.JobId})
+	require.NoError(err)
+	require.Equal(vagrant_server.Job_QUEUED, job.State)
+
+	require.Equal(ErrClosed, r.Accept(ctx))
+}
+
+// testWorkerRunner returns a runner without plugins for tests which don't
+// execute job operations.
+func testWorkerRunner(t *testing.T, client *serverclient.VagrantClient, opts ...Option) *Runner {
+	r := &Runner{
+		id:        "R_A",
+		client:    client,
+		logger:    hclog.L(),
+		cleanup:   cleanup.New(),
+		ctx:       context.Background(),
+		runner:    &vagrant_server.Runner{Id: "R_A"},
+		closeCh:   make(chan struct{}),
+		dataCache: t.TempDir(),
+	}
+	for _, o := range opts {
+		require.NoError(t, o(r, &config{}))
+	}
+	t.Cleanup(func() { r.Close() })
+
+	return r
+}
@@ -2,12 +2,16 @@ package server
 
 import (
 	"context"
+	"net/http"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -18,12 +22,37 @@ type AuthChecker interface {
 	Authenticate(ctx context.Context, token, endpoint string, effects []string) error
 }
 
+// TokenWatcher is implemented by an AuthChecker which can revoke tokens.
+// Streaming RPCs are ended as soon as the token they were authenticated
+// with is revoked.
+type TokenWatcher interface {
+	// WatchToken returns a channel which is closed once the token is
+	// revoked. The token is watched until ctx is done.
+	WatchToken(ctx context.Context, token string) (<-chan struct{}, error)
+}
+
+// Authorizer is implemented by an AuthChecker which checks the permissions
+// of authenticated requests.
+type Authorizer interface {
+	// Authorize checks if the token may call the endpoint with the given
+	// request. This is called after the request has been authenticated.
+	//
+	// Streams are first authorized with a nil request, which checks if the
+	// token may call the endpoint with any request. The first message
+	// received on the stream is then authorized as the request.
+	Authorize(ctx context.Context, token, endpoint string, req interface{}) error
+}
+
 var readonly = []string{"readonly"}
 
 // Information about the effects of endpoints that are authenticated. If a endpoint
 // is not listed, the DefaultEffect value is used.
 var Effects = map[string][]string{
-	"ListBuilds": readonly,
+	"ListBuilds":   readonly,
+	"ListTokens":   readonly,
+	"ListHMACKeys": readonly,
+	"ListUsers":    readonly,
+	"ListRoles":    readonly,
 }
 
 var DefaultEffects = []string{"mutable"}
@@ -64,6 +93,13 @@ func authUnaryInterceptor(checker AuthChecker) grpc.UnaryServerInterceptor {
 		if err != nil {
 			return nil, err
 		}
+
+		if authorizer, ok := checker.(Authorizer); ok {
+			if err := authorizer.Authorize(ctx, token, name, req); err != nil {
+				return nil, err
+			}
+		}
+
 		return handler(ctx, req)
 	}
 }
@@ -95,19 +131,192 @@ func authStreamInterceptor(checker AuthChecker) grpc.StreamServerInterceptor {
 			return status.Errorf(codes.InvalidArgument, "Retrieving metadata is failed")
 		}
 
-		authHeader, ok := md["authorization"]
-		if !ok {
-			return status.Errorf(codes.Unauthenticated, "Authorization token is not supplied")
+		// Without a token the checker may still authenticate the stream
+		// by the client certificate of the connection.
+		var token string
+		if authHeader, ok := md["authorization"]; ok {
+			token = authHeader[0]
 		}
 
-		token := authHeader[0]
-
 		err := checker.Authenticate(ss.Context(), token, name, effects)
 		if err != nil {
 			return err
 		}
 
-		// Invoke the handler.
-		return handler(srv, ss)
+		if authorizer, ok := checker.(Authorizer); ok {
+			if err := authorizer.Authorize(ss.Context(), token, name, nil); err != nil {
+				return err
+			}
+
+			ss = &authorizedStream{
+				ServerStream: ss,
+				authorizer:   authorizer,
+				token:        token,
+				endpoint:     name,
+			}
+		}
+
+		watcher, ok := checker.(TokenWatcher)
+		if !ok {
+			// Invoke the handler.
+			return handler(srv, ss)
+		}
+
+		return watchStream(watcher, token, srv, ss, handler)
 	}
 }
+
+// watchStream invokes the handler for a stream authenticated with token
+// and ends the stream as soon as the token is revoked. The context of the
+// handler is cancelled once the token is revoked and any message it sends
+// or receives after that fails. The stream ends once the handler returns.
+func watchStream(
+	watcher TokenWatcher,
+	token string,
+	srv interface{},
+	ss grpc.ServerStream,
+	handler grpc.StreamHandler,
+) error {
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+
+	revokedCh, err := watcher.WatchToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	ws := &revocableStream{ServerStream: ss, ctx: ctx}
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- handler(srv, ws)
+	}()
+
+	select {
+	case err := <-doneCh:
+		return err
+
+	case <-revokedCh:
+	}
+
+	// Fail any further messages and end the handler through its context.
+	// gRPC doesn't allow using the stream once we return, so we wait for
+	// the handler to return first.
+	atomic.StoreInt32(&ws.revoked, 1)
+	cancel()
+	<-doneCh
+
+	return errTokenRevoked
+}
+
+var errTokenRevoked = status.Errorf(codes.Unauthenticated, "Authorization token has been revoked")
+
+// revocableStream is a server stream that fails once the token it was
+// authenticated with is revoked.
+type revocableStream struct {
+	grpc.ServerStream
+
+	ctx     context.Context
+	revoked int32
+}
+
+func (s *revocableStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *revocableStream) SendMsg(m interface{}) error {
+	if atomic.LoadInt32(&s.revoked) > 0 {
+		return errTokenRevoked
+	}
+
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *revocableStream) RecvMsg(m interface{}) error {
+	if atomic.LoadInt32(&s.revoked) > 0 {
+		return errTokenRevoked
+	}
+
+	return s.ServerStream.RecvMsg(m)
+}
+
+// authorizedStream is a server stream that authorizes the first message it
+// receives as the request of the stream.
+type authorizedStream struct {
+	grpc.ServerStream
+
+	authorizer Authorizer
+	token      string
+	endpoint   string
+	received   bool
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.received {
+		return nil
+	}
+
+	s.received = true
+	return s.authorizer.Authorize(s.Context(), s.token, s.endpoint, m)
+}
+
+// authHTTPHandler returns a handler that authenticates requests using the
+// token in the Authorization header before calling the given handler. Read
+// only requests (GET and HEAD) are only allowed without authentication if
+// anonymousReads is set.
+//
+// If the service implements HTTPEndpointService, requests are authorized as
+// the RPC they correspond to.
+func authHTTPHandler(checker AuthChecker, service interface{}, anonymousReads bool, h http.Handler) http.Handler {
+	if checker == nil {
+		return h
+	}
+	endpoints, _ := service.(HTTPEndpointService)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		effects := DefaultEffects
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if anonymousReads {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			effects = readonly
+		}
+
+		// Expose the client certificate like gRPC does so that checkers
+		// handle both the same way.
+		ctx := r.Context()
+		if r.TLS != nil {
+			ctx = peer.NewContext(ctx, &peer.Peer{
+				AuthInfo: credentials.TLSInfo{State: *r.TLS},
+			})
+		}
+
+		endpoint, req := r.URL.Path, interface{}(nil)
+		if endpoints != nil {
+			if name, msg := endpoints.HTTPEndpoint(r); name != "" {
+				endpoint, req = name, msg
+			}
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		err := checker.Authenticate(ctx, token, endpoint, effects)
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusUnauthorized)
+			return
+		}
+
+		// The request body isn't read until it is authorized
+		if authorizer, ok := checker.(Authorizer); ok {
+			if err := authorizer.Authorize(ctx, token, endpoint, req); err != nil {
+				http.Error(w, status.Convert(err).Message(), http.StatusForbidden)
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
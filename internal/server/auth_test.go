@@ -2,11 +2,17 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type trivialAuth struct {
@@ -55,3 +61,278 @@ func TestAuthUnaryInterceptor(t *testing.T) {
 	require.Equal("bar", chk.method)
 	require.Equal(DefaultEffects, chk.effects)
 }
+
+func TestAuthHTTPHandler(t *testing.T) {
+	require := require.New(t)
+
+	var chk trivialAuth
+	called := false
+	h := authHTTPHandler(&chk, nil, false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPut, "/foo/bar", nil)
+	req.Header.Set("Authorization", "Bearer this-is-a-token")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.True(called)
+	require.Equal("this-is-a-token", chk.token)
+	require.Equal("/foo/bar", chk.method)
+	require.Equal(DefaultEffects, chk.effects)
+
+	// Read only requests are authenticated too
+	called = false
+	req = httptest.NewRequest(http.MethodGet, "/foo/baz", nil)
+	req.Header.Set("Authorization", "Bearer other-token")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.True(called)
+	require.Equal("other-token", chk.token)
+	require.Equal("/foo/baz", chk.method)
+	require.Equal(readonly, chk.effects)
+}
+
+func TestAuthHTTPHandler_anonymousReads(t *testing.T) {
+	require := require.New(t)
+
+	var chk trivialAuth
+	called := false
+	h := authHTTPHandler(&chk, nil, true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/foo/bar", nil))
+	require.True(called)
+	require.Empty(chk.method)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/foo/bar", nil))
+	require.Equal("/foo/bar", chk.method)
+}
+
+type testHTTPEndpoints struct{}
+
+func (testHTTPEndpoints) HTTPEndpoint(r *http.Request) (string, interface{}) {
+	msg := r.URL.Query().Get("msg")
+	return "Publish", &msg
+}
+
+func TestAuthHTTPHandler_authorize(t *testing.T) {
+	require := require.New(t)
+
+	var chk authorizingAuth
+	called := false
+	h := authHTTPHandler(&chk, testHTTPEndpoints{}, false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	// Requests are authorized as the endpoint of the service
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/foo/bar?msg=no", nil))
+	require.False(called)
+	require.Equal(http.StatusForbidden, rec.Code)
+	require.Equal("Publish", chk.method)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/foo/bar?msg=ok", nil))
+	require.True(called)
+	require.Equal(http.StatusOK, rec.Code)
+}
+
+type revokingAuth struct {
+	trivialAuth
+	revokeCh chan struct{}
+}
+
+func (t *revokingAuth) WatchToken(ctx context.Context, token string) (<-chan struct{}, error) {
+	return t.revokeCh, nil
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
+
+func TestAuthStreamInterceptor_noToken(t *testing.T) {
+	require := require.New(t)
+
+	// Streams without a token are left to the checker, which may
+	// authenticate them by the client certificate.
+	chk := trivialAuth{token: "unset"}
+	f := authStreamInterceptor(&chk)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+	called := false
+	err := f(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/foo/bar"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			called = true
+			return nil
+		},
+	)
+	require.NoError(err)
+	require.True(called)
+	require.Equal("", chk.token)
+	require.Equal("bar", chk.method)
+}
+
+func TestAuthStreamInterceptor_revoke(t *testing.T) {
+	require := require.New(t)
+
+	chk := &revokingAuth{revokeCh: make(chan struct{})}
+	f := authStreamInterceptor(chk)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
+		"authorization": []string{"this-is-a-token"},
+	})
+
+	startedCh := make(chan struct{})
+	handlerCtxCh := make(chan context.Context, 1)
+	errCh := make(chan error, 1)
+	var stream grpc.ServerStream
+	go func() {
+		errCh <- f(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/foo/bar"},
+			func(srv interface{}, ss grpc.ServerStream) error {
+				stream = ss
+				handlerCtxCh <- ss.Context()
+				close(startedCh)
+
+				// Block until the stream is done
+				<-ss.Context().Done()
+				return nil
+			},
+		)
+	}()
+
+	<-startedCh
+	close(chk.revokeCh)
+
+	select {
+	case err := <-errCh:
+		require.Error(err)
+		require.Equal(codes.Unauthenticated, status.Code(err))
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream should end once the token is revoked")
+	}
+
+	// The handler context is canceled and messages fail
+	require.Error((<-handlerCtxCh).Err())
+	require.Error(stream.SendMsg(nil))
+}
+
+func TestAuthStreamInterceptor_revokeWaitsForHandler(t *testing.T) {
+	require := require.New(t)
+
+	chk := &revokingAuth{revokeCh: make(chan struct{})}
+	f := authStreamInterceptor(chk)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
+		"authorization": []string{"this-is-a-token"},
+	})
+
+	// The handler takes a while to notice that its context is done
+	var returned int32
+	startedCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/foo/bar"},
+			func(srv interface{}, ss grpc.ServerStream) error {
+				close(startedCh)
+				<-ss.Context().Done()
+				time.Sleep(50 * time.Millisecond)
+				atomic.StoreInt32(&returned, 1)
+				return nil
+			},
+		)
+	}()
+
+	<-startedCh
+	close(chk.revokeCh)
+
+	err := <-errCh
+	require.Equal(codes.Unauthenticated, status.Code(err))
+	require.Equal(int32(1), atomic.LoadInt32(&returned))
+}
+
+// authorizingAuth authorizes requests for the endpoint "allowed" and any
+// request which is the string "ok".
+type authorizingAuth struct {
+	trivialAuth
+
+	requests []interface{}
+}
+
+func (a *authorizingAuth) Authorize(ctx context.Context, token, endpoint string, req interface{}) error {
+	a.requests = append(a.requests, req)
+	if endpoint == "allowed" {
+		return nil
+	}
+	if s, ok := req.(*string); (ok && *s == "ok") || req == nil {
+		return nil
+	}
+
+	return status.Errorf(codes.PermissionDenied, "denied")
+}
+
+type recvServerStream struct {
+	testServerStream
+	msg string
+}
+
+func (s *recvServerStream) RecvMsg(m interface{}) error {
+	*(m.(*string)) = s.msg
+	return nil
+}
+
+func TestAuthInterceptor_authorize(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
+		"authorization": []string{"this-is-a-token"},
+	})
+	unary := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "hello", nil
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		require := require.New(t)
+
+		chk := &authorizingAuth{}
+		f := authUnaryInterceptor(chk)
+
+		ok := "ok"
+		_, err := f(ctx, &ok, &grpc.UnaryServerInfo{FullMethod: "/foo/bar"}, unary)
+		require.NoError(err)
+
+		denied := "denied"
+		_, err = f(ctx, &denied, &grpc.UnaryServerInfo{FullMethod: "/foo/bar"}, unary)
+		require.Error(err)
+		require.Equal(codes.PermissionDenied, status.Code(err))
+
+		_, err = f(ctx, &denied, &grpc.UnaryServerInfo{FullMethod: "/foo/allowed"}, unary)
+		require.NoError(err)
+	})
+
+	t.Run("stream authorizes the first message", func(t *testing.T) {
+		require := require.New(t)
+
+		chk := &authorizingAuth{}
+		f := authStreamInterceptor(chk)
+
+		handler := func(srv interface{}, ss grpc.ServerStream) error {
+			var msg string
+			if err := ss.RecvMsg(&msg); err != nil {
+				return err
+			}
+			return ss.RecvMsg(&msg)
+		}
+
+		ss := &recvServerStream{testServerStream: testServerStream{ctx: ctx}, msg: "ok"}
+		require.NoError(f(nil, ss, &grpc.StreamServerInfo{FullMethod: "/foo/bar"}, handler))
+
+		// The stream is authorized without a request and then only once
+		require.Len(chk.requests, 2)
+		require.Nil(chk.requests[0])
+
+		ss.msg = "denied"
+		err := f(nil, ss, &grpc.StreamServerInfo{FullMethod: "/foo/bar"}, handler)
+		require.Error(err)
+		require.Equal(codes.PermissionDenied, status.Code(err))
+	})
+}
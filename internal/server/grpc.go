@@ -5,6 +5,7 @@ import (
 
 	"github.com/oklog/run"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/types/known/emptypb"
 
@@ -39,6 +40,10 @@ func grpcInit(group *run.Group, opts *options) error {
 		),
 	)
 
+	if opts.TLSConfig != nil {
+		so = append(so, grpc.Creds(credentials.NewTLS(opts.TLSConfig)))
+	}
+
 	if opts.AuthChecker != nil {
 		so = append(so,
 			grpc.ChainUnaryInterceptor(authUnaryInterceptor(opts.AuthChecker)),
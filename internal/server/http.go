@@ -13,6 +13,23 @@ import (
 	"github.com/oklog/run"
 )
 
+// HTTPHandlerService is implemented by services which serve endpoints on
+// the HTTP listener in addition to the gRPC API.
+type HTTPHandlerService interface {
+	// HTTPHandlers returns the handlers to mount keyed by path prefix.
+	HTTPHandlers() map[string]http.Handler
+}
+
+// HTTPEndpointService is implemented by services whose HTTP endpoints do
+// the same as an RPC. Requests to those endpoints are authenticated and
+// authorized as the RPC, so that they are subject to the same permissions.
+type HTTPEndpointService interface {
+	// HTTPEndpoint returns the name of the RPC and the request message the
+	// HTTP request is equivalent to. If the name is empty, the request is
+	// authenticated using its path and authorized without a request.
+	HTTPEndpoint(r *http.Request) (string, interface{})
+}
+
 // httpInit initializes the HTTP server and adds it to the run group.
 func httpInit(group *run.Group, opts *options) error {
 	log := opts.Logger.Named("http")
@@ -39,7 +56,7 @@ func httpInit(group *run.Group, opts *options) error {
 
 	// If the path has a grpc prefix we assume it's a GRPC gateway request,
 	// otherwise fall back to serving the UI from the filesystem
-	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	grpcHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// if strings.HasPrefix(r.URL.Path, "/grpc") {
 		grpcWrapped.ServeHTTP(w, r)
 		// } else if opts.BrowserUIEnabled {
@@ -47,6 +64,17 @@ func httpInit(group *run.Group, opts *options) error {
 		// }
 	})
 
+	// Mount any endpoints provided by the service, everything else
+	// is handled by the gRPC gateway
+	rootHandler := http.NewServeMux()
+	rootHandler.Handle("/", grpcHandler)
+	if hs, ok := opts.Service.(HTTPHandlerService); ok {
+		for prefix, h := range hs.HTTPHandlers() {
+			log.Debug("mounting service HTTP handler", "path", prefix)
+			rootHandler.Handle(prefix, authHTTPHandler(opts.AuthChecker, opts.Service, opts.AnonymousHTTPReads, h))
+		}
+	}
+
 	// Create our http server
 	httpSrv := &http.Server{
 		ReadHeaderTimeout: 5 * time.Second,
@@ -55,6 +83,7 @@ func httpInit(group *run.Group, opts *options) error {
 		BaseContext: func(net.Listener) context.Context {
 			return opts.Context
 		},
+		TLSConfig: opts.TLSConfig,
 	}
 
 	// Add our gRPC server to the run group
@@ -62,6 +91,9 @@ func httpInit(group *run.Group, opts *options) error {
 		// Serve traffic
 		ln := opts.HTTPListener
 		log.Info("starting HTTP server", "addr", ln.Addr().String())
+		if httpSrv.TLSConfig != nil {
+			return httpSrv.ServeTLS(ln, "", "")
+		}
 		return httpSrv.Serve(ln)
 	}, func(err error) {
 		ctx, cancelFunc := context.WithCancel(context.Background())
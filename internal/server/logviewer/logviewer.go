@@ -26,12 +26,22 @@ func (v *Viewer) NextLogBatch(ctx context.Context) ([]component.LogEvent, error)
 		return nil, err
 	}
 
+	// Prefix the partition with the name of the scope since a stream
+	// may include the logs of multiple projects or targets.
+	partition := batch.InstanceId
+	switch scope := batch.Scope.(type) {
+	case *vagrant_server.LogBatch_Project:
+		partition = scope.Project.Name + "/" + partition
+	case *vagrant_server.LogBatch_Target:
+		partition = scope.Target.Name + "/" + partition
+	}
+
 	events := make([]component.LogEvent, len(batch.Lines))
 	for i, entry := range batch.Lines {
 		ts := entry.Timestamp.AsTime()
 
 		events[i] = component.LogEvent{
-			Partition: batch.InstanceId,
+			Partition: partition,
 			Timestamp: ts,
 			Message:   entry.Line,
 		}
@@ -58,11 +58,33 @@ type VagrantClient interface {
 	GetBox(ctx context.Context, in *GetBoxRequest, opts ...grpc.CallOption) (*GetBoxResponse, error)
 	ListBoxes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListBoxesResponse, error)
 	FindBox(ctx context.Context, in *FindBoxRequest, opts ...grpc.CallOption) (*FindBoxResponse, error)
-	// GetLogStream reads the log stream for a deployment. This will immediately
-	// send a single LogEntry with the lines we have so far. If there are no
-	// available lines this will NOT block and instead will return an error.
-	// The client can choose to retry or not.
+	// PublishCatalogBox adds a box provider to the box catalog hosted by
+	// the server. The box file is streamed in chunks after the Open message.
+	PublishCatalogBox(ctx context.Context, opts ...grpc.CallOption) (Vagrant_PublishCatalogBoxClient, error)
+	// GetCatalogBox returns the catalog entry for a box.
+	GetCatalogBox(ctx context.Context, in *GetCatalogBoxRequest, opts ...grpc.CallOption) (*GetCatalogBoxResponse, error)
+	// ListCatalogBoxes returns all boxes in the box catalog.
+	ListCatalogBoxes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListCatalogBoxesResponse, error)
+	// DeleteCatalogBox removes a box, box version, or box provider from
+	// the box catalog along with the box files.
+	DeleteCatalogBox(ctx context.Context, in *DeleteCatalogBoxRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// DownloadCatalogBox streams the box file of a box provider in the
+	// box catalog.
+	DownloadCatalogBox(ctx context.Context, in *DownloadCatalogBoxRequest, opts ...grpc.CallOption) (Vagrant_DownloadCatalogBoxClient, error)
+	// UploadArchive stores an archive of project data on the server so that
+	// jobs can use it with an archive data source. The archive is streamed
+	// in chunks after the Open message.
+	UploadArchive(ctx context.Context, opts ...grpc.CallOption) (Vagrant_UploadArchiveClient, error)
+	// DownloadArchive streams an archive previously stored with UploadArchive.
+	DownloadArchive(ctx context.Context, in *DownloadArchiveRequest, opts ...grpc.CallOption) (Vagrant_DownloadArchiveClient, error)
+	// GetLogStream reads the log stream for a basis, project, or target. The
+	// stream for a basis or project includes the logs of all projects and
+	// targets within it. This will immediately send the backlog of lines
+	// we have so far and then stream new lines as they are received.
 	GetLogStream(ctx context.Context, in *GetLogStreamRequest, opts ...grpc.CallOption) (Vagrant_GetLogStreamClient, error)
+	// SendLogStream sends log lines for a basis, project, or target. The
+	// lines are buffered by the server and can be read with GetLogStream.
+	SendLogStream(ctx context.Context, opts ...grpc.CallOption) (Vagrant_SendLogStreamClient, error)
 	// Set a single configuration item for the application.
 	SetConfig(ctx context.Context, in *ConfigSetRequest, opts ...grpc.CallOption) (*ConfigSetResponse, error)
 	// Retrieve merged configuration values for a specific scope. You can determine
@@ -95,14 +117,30 @@ type VagrantClient interface {
 	GetJobStream(ctx context.Context, in *GetJobStreamRequest, opts ...grpc.CallOption) (Vagrant_GetJobStreamClient, error)
 	// Clean out old jobs from the job database
 	PruneOldJobs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// UpsertSchedule creates or updates a schedule which queues a job
+	// periodically.
+	UpsertSchedule(ctx context.Context, in *UpsertScheduleRequest, opts ...grpc.CallOption) (*UpsertScheduleResponse, error)
+	// GetSchedule gets a single schedule by ID.
+	GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*GetScheduleResponse, error)
+	// ListSchedules lists all the schedules.
+	ListSchedules(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListSchedulesResponse, error)
+	// DeleteSchedule deletes a schedule. Jobs already queued by the schedule
+	// are not affected.
+	DeleteSchedule(ctx context.Context, in *DeleteScheduleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// GetRunner gets information about a single runner.
 	GetRunner(ctx context.Context, in *GetRunnerRequest, opts ...grpc.CallOption) (*Runner, error)
+	// ListRunners lists the registered runners, including runners which
+	// recently disconnected and are within the reconnect grace period.
+	ListRunners(ctx context.Context, in *ListRunnersRequest, opts ...grpc.CallOption) (*ListRunnersResponse, error)
+	// DrainRunner stops assigning new jobs to a runner. Any job currently
+	// running on the runner is allowed to complete.
+	DrainRunner(ctx context.Context, in *DrainRunnerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// GetServerConfig sets configuration for the Vagrant server.
 	GetServerConfig(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetServerConfigResponse, error)
 	// SetServerConfig sets configuration for the Vagrant server.
 	SetServerConfig(ctx context.Context, in *SetServerConfigRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// CreateSnapshot creates a new database snapshot.
-	CreateSnapshot(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Vagrant_CreateSnapshotClient, error)
+	CreateSnapshot(ctx context.Context, in *CreateSnapshotRequest, opts ...grpc.CallOption) (Vagrant_CreateSnapshotClient, error)
 	// RestoreSnapshot performs a database restore with the given snapshot.
 	// This API doesn't do a full online restore, it only stages the restore
 	// for the next server start to finalize the restore. See the arguments for
@@ -118,6 +156,34 @@ type VagrantClient interface {
 	GenerateLoginToken(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NewTokenResponse, error)
 	// Exchange a invite token for a login token.
 	ConvertInviteToken(ctx context.Context, in *ConvertInviteTokenRequest, opts ...grpc.CallOption) (*NewTokenResponse, error)
+	// ListTokens returns the tokens issued by the server.
+	ListTokens(ctx context.Context, in *ListTokensRequest, opts ...grpc.CallOption) (*ListTokensResponse, error)
+	// RevokeToken revokes a token issued by the server. The token is rejected
+	// from then on and streams authenticated with it are closed. Tokens issued
+	// before tokens were recorded have no ID and can't be revoked this way,
+	// these are rejected once the key that signed them is retired.
+	RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RevokeTokenResponse, error)
+	// ListHMACKeys returns the keys used to sign tokens. The key material
+	// itself is never returned.
+	ListHMACKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListHMACKeysResponse, error)
+	// RotateHMACKey creates a new key that is used to sign new tokens. Tokens
+	// signed with the previous keys remain valid until those keys are retired.
+	RotateHMACKey(ctx context.Context, in *RotateHMACKeyRequest, opts ...grpc.CallOption) (*RotateHMACKeyResponse, error)
+	// RetireHMACKey sets when a key is retired. Tokens signed with a retired
+	// key are rejected.
+	RetireHMACKey(ctx context.Context, in *RetireHMACKeyRequest, opts ...grpc.CallOption) (*RetireHMACKeyResponse, error)
+	// UpsertUser creates or updates a user and the roles bound to it.
+	UpsertUser(ctx context.Context, in *UpsertUserRequest, opts ...grpc.CallOption) (*UpsertUserResponse, error)
+	// DeleteUser deletes a user. Tokens of the user are rejected from then on.
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListUsers returns all the users.
+	ListUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListUsersResponse, error)
+	// UpsertRole creates or updates a role. Built-in roles can't be changed.
+	UpsertRole(ctx context.Context, in *UpsertRoleRequest, opts ...grpc.CallOption) (*UpsertRoleResponse, error)
+	// DeleteRole deletes a role which isn't bound to any user.
+	DeleteRole(ctx context.Context, in *DeleteRoleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListRoles returns all the roles, including the built-in roles.
+	ListRoles(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListRolesResponse, error)
 	// RunnerConfig is called to register a runner and receive the configuration
 	// for the runner. The response is a stream so that the configuration can
 	// be updated later.
@@ -342,8 +408,167 @@ func (c *vagrantClient) FindBox(ctx context.Context, in *FindBoxRequest, opts ..
 	return out, nil
 }
 
+func (c *vagrantClient) PublishCatalogBox(ctx context.Context, opts ...grpc.CallOption) (Vagrant_PublishCatalogBoxClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[0], "/hashicorp.vagrant.Vagrant/PublishCatalogBox", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vagrantPublishCatalogBoxClient{stream}
+	return x, nil
+}
+
+type Vagrant_PublishCatalogBoxClient interface {
+	Send(*PublishCatalogBoxRequest) error
+	CloseAndRecv() (*PublishCatalogBoxResponse, error)
+	grpc.ClientStream
+}
+
+type vagrantPublishCatalogBoxClient struct {
+	grpc.ClientStream
+}
+
+func (x *vagrantPublishCatalogBoxClient) Send(m *PublishCatalogBoxRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *vagrantPublishCatalogBoxClient) CloseAndRecv() (*PublishCatalogBoxResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PublishCatalogBoxResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vagrantClient) GetCatalogBox(ctx context.Context, in *GetCatalogBoxRequest, opts ...grpc.CallOption) (*GetCatalogBoxResponse, error) {
+	out := new(GetCatalogBoxResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/GetCatalogBox", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) ListCatalogBoxes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListCatalogBoxesResponse, error) {
+	out := new(ListCatalogBoxesResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListCatalogBoxes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) DeleteCatalogBox(ctx context.Context, in *DeleteCatalogBoxRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/DeleteCatalogBox", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) DownloadCatalogBox(ctx context.Context, in *DownloadCatalogBoxRequest, opts ...grpc.CallOption) (Vagrant_DownloadCatalogBoxClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[1], "/hashicorp.vagrant.Vagrant/DownloadCatalogBox", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vagrantDownloadCatalogBoxClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Vagrant_DownloadCatalogBoxClient interface {
+	Recv() (*DownloadCatalogBoxResponse, error)
+	grpc.ClientStream
+}
+
+type vagrantDownloadCatalogBoxClient struct {
+	grpc.ClientStream
+}
+
+func (x *vagrantDownloadCatalogBoxClient) Recv() (*DownloadCatalogBoxResponse, error) {
+	m := new(DownloadCatalogBoxResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vagrantClient) UploadArchive(ctx context.Context, opts ...grpc.CallOption) (Vagrant_UploadArchiveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[2], "/hashicorp.vagrant.Vagrant/UploadArchive", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vagrantUploadArchiveClient{stream}
+	return x, nil
+}
+
+type Vagrant_UploadArchiveClient interface {
+	Send(*UploadArchiveRequest) error
+	CloseAndRecv() (*UploadArchiveResponse, error)
+	grpc.ClientStream
+}
+
+type vagrantUploadArchiveClient struct {
+	grpc.ClientStream
+}
+
+func (x *vagrantUploadArchiveClient) Send(m *UploadArchiveRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *vagrantUploadArchiveClient) CloseAndRecv() (*UploadArchiveResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadArchiveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vagrantClient) DownloadArchive(ctx context.Context, in *DownloadArchiveRequest, opts ...grpc.CallOption) (Vagrant_DownloadArchiveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[3], "/hashicorp.vagrant.Vagrant/DownloadArchive", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vagrantDownloadArchiveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Vagrant_DownloadArchiveClient interface {
+	Recv() (*DownloadArchiveResponse, error)
+	grpc.ClientStream
+}
+
+type vagrantDownloadArchiveClient struct {
+	grpc.ClientStream
+}
+
+func (x *vagrantDownloadArchiveClient) Recv() (*DownloadArchiveResponse, error) {
+	m := new(DownloadArchiveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *vagrantClient) GetLogStream(ctx context.Context, in *GetLogStreamRequest, opts ...grpc.CallOption) (Vagrant_GetLogStreamClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[0], "/hashicorp.vagrant.Vagrant/GetLogStream", opts...)
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[4], "/hashicorp.vagrant.Vagrant/GetLogStream", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -374,6 +599,40 @@ func (x *vagrantGetLogStreamClient) Recv() (*LogBatch, error) {
 	return m, nil
 }
 
+func (c *vagrantClient) SendLogStream(ctx context.Context, opts ...grpc.CallOption) (Vagrant_SendLogStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[5], "/hashicorp.vagrant.Vagrant/SendLogStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vagrantSendLogStreamClient{stream}
+	return x, nil
+}
+
+type Vagrant_SendLogStreamClient interface {
+	Send(*SendLogStreamRequest) error
+	CloseAndRecv() (*emptypb.Empty, error)
+	grpc.ClientStream
+}
+
+type vagrantSendLogStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *vagrantSendLogStreamClient) Send(m *SendLogStreamRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *vagrantSendLogStreamClient) CloseAndRecv() (*emptypb.Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(emptypb.Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *vagrantClient) SetConfig(ctx context.Context, in *ConfigSetRequest, opts ...grpc.CallOption) (*ConfigSetResponse, error) {
 	out := new(ConfigSetResponse)
 	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/SetConfig", in, out, opts...)
@@ -438,7 +697,7 @@ func (c *vagrantClient) ValidateJob(ctx context.Context, in *ValidateJobRequest,
 }
 
 func (c *vagrantClient) GetJobStream(ctx context.Context, in *GetJobStreamRequest, opts ...grpc.CallOption) (Vagrant_GetJobStreamClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[1], "/hashicorp.vagrant.Vagrant/GetJobStream", opts...)
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[6], "/hashicorp.vagrant.Vagrant/GetJobStream", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -478,6 +737,42 @@ func (c *vagrantClient) PruneOldJobs(ctx context.Context, in *emptypb.Empty, opt
 	return out, nil
 }
 
+func (c *vagrantClient) UpsertSchedule(ctx context.Context, in *UpsertScheduleRequest, opts ...grpc.CallOption) (*UpsertScheduleResponse, error) {
+	out := new(UpsertScheduleResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/UpsertSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*GetScheduleResponse, error) {
+	out := new(GetScheduleResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/GetSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) ListSchedules(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListSchedulesResponse, error) {
+	out := new(ListSchedulesResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListSchedules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) DeleteSchedule(ctx context.Context, in *DeleteScheduleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/DeleteSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *vagrantClient) GetRunner(ctx context.Context, in *GetRunnerRequest, opts ...grpc.CallOption) (*Runner, error) {
 	out := new(Runner)
 	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/GetRunner", in, out, opts...)
@@ -487,6 +782,24 @@ func (c *vagrantClient) GetRunner(ctx context.Context, in *GetRunnerRequest, opt
 	return out, nil
 }
 
+func (c *vagrantClient) ListRunners(ctx context.Context, in *ListRunnersRequest, opts ...grpc.CallOption) (*ListRunnersResponse, error) {
+	out := new(ListRunnersResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListRunners", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) DrainRunner(ctx context.Context, in *DrainRunnerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/DrainRunner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *vagrantClient) GetServerConfig(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetServerConfigResponse, error) {
 	out := new(GetServerConfigResponse)
 	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/GetServerConfig", in, out, opts...)
@@ -505,8 +818,8 @@ func (c *vagrantClient) SetServerConfig(ctx context.Context, in *SetServerConfig
 	return out, nil
 }
 
-func (c *vagrantClient) CreateSnapshot(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Vagrant_CreateSnapshotClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[2], "/hashicorp.vagrant.Vagrant/CreateSnapshot", opts...)
+func (c *vagrantClient) CreateSnapshot(ctx context.Context, in *CreateSnapshotRequest, opts ...grpc.CallOption) (Vagrant_CreateSnapshotClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[7], "/hashicorp.vagrant.Vagrant/CreateSnapshot", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -538,7 +851,7 @@ func (x *vagrantCreateSnapshotClient) Recv() (*CreateSnapshotResponse, error) {
 }
 
 func (c *vagrantClient) RestoreSnapshot(ctx context.Context, opts ...grpc.CallOption) (Vagrant_RestoreSnapshotClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[3], "/hashicorp.vagrant.Vagrant/RestoreSnapshot", opts...)
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[8], "/hashicorp.vagrant.Vagrant/RestoreSnapshot", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -607,8 +920,107 @@ func (c *vagrantClient) ConvertInviteToken(ctx context.Context, in *ConvertInvit
 	return out, nil
 }
 
+func (c *vagrantClient) ListTokens(ctx context.Context, in *ListTokensRequest, opts ...grpc.CallOption) (*ListTokensResponse, error) {
+	out := new(ListTokensResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListTokens", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RevokeTokenResponse, error) {
+	out := new(RevokeTokenResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/RevokeToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) ListHMACKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListHMACKeysResponse, error) {
+	out := new(ListHMACKeysResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListHMACKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) RotateHMACKey(ctx context.Context, in *RotateHMACKeyRequest, opts ...grpc.CallOption) (*RotateHMACKeyResponse, error) {
+	out := new(RotateHMACKeyResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/RotateHMACKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) RetireHMACKey(ctx context.Context, in *RetireHMACKeyRequest, opts ...grpc.CallOption) (*RetireHMACKeyResponse, error) {
+	out := new(RetireHMACKeyResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/RetireHMACKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) UpsertUser(ctx context.Context, in *UpsertUserRequest, opts ...grpc.CallOption) (*UpsertUserResponse, error) {
+	out := new(UpsertUserResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/UpsertUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/DeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) ListUsers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListUsersResponse, error) {
+	out := new(ListUsersResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) UpsertRole(ctx context.Context, in *UpsertRoleRequest, opts ...grpc.CallOption) (*UpsertRoleResponse, error) {
+	out := new(UpsertRoleResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/UpsertRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) DeleteRole(ctx context.Context, in *DeleteRoleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/DeleteRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vagrantClient) ListRoles(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListRolesResponse, error) {
+	out := new(ListRolesResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.vagrant.Vagrant/ListRoles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *vagrantClient) RunnerConfig(ctx context.Context, opts ...grpc.CallOption) (Vagrant_RunnerConfigClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[4], "/hashicorp.vagrant.Vagrant/RunnerConfig", opts...)
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[9], "/hashicorp.vagrant.Vagrant/RunnerConfig", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -639,7 +1051,7 @@ func (x *vagrantRunnerConfigClient) Recv() (*RunnerConfigResponse, error) {
 }
 
 func (c *vagrantClient) RunnerJobStream(ctx context.Context, opts ...grpc.CallOption) (Vagrant_RunnerJobStreamClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[5], "/hashicorp.vagrant.Vagrant/RunnerJobStream", opts...)
+	stream, err := c.cc.NewStream(ctx, &Vagrant_ServiceDesc.Streams[10], "/hashicorp.vagrant.Vagrant/RunnerJobStream", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -712,11 +1124,33 @@ type VagrantServer interface {
 	GetBox(context.Context, *GetBoxRequest) (*GetBoxResponse, error)
 	ListBoxes(context.Context, *emptypb.Empty) (*ListBoxesResponse, error)
 	FindBox(context.Context, *FindBoxRequest) (*FindBoxResponse, error)
-	// GetLogStream reads the log stream for a deployment. This will immediately
-	// send a single LogEntry with the lines we have so far. If there are no
-	// available lines this will NOT block and instead will return an error.
-	// The client can choose to retry or not.
+	// PublishCatalogBox adds a box provider to the box catalog hosted by
+	// the server. The box file is streamed in chunks after the Open message.
+	PublishCatalogBox(Vagrant_PublishCatalogBoxServer) error
+	// GetCatalogBox returns the catalog entry for a box.
+	GetCatalogBox(context.Context, *GetCatalogBoxRequest) (*GetCatalogBoxResponse, error)
+	// ListCatalogBoxes returns all boxes in the box catalog.
+	ListCatalogBoxes(context.Context, *emptypb.Empty) (*ListCatalogBoxesResponse, error)
+	// DeleteCatalogBox removes a box, box version, or box provider from
+	// the box catalog along with the box files.
+	DeleteCatalogBox(context.Context, *DeleteCatalogBoxRequest) (*emptypb.Empty, error)
+	// DownloadCatalogBox streams the box file of a box provider in the
+	// box catalog.
+	DownloadCatalogBox(*DownloadCatalogBoxRequest, Vagrant_DownloadCatalogBoxServer) error
+	// UploadArchive stores an archive of project data on the server so that
+	// jobs can use it with an archive data source. The archive is streamed
+	// in chunks after the Open message.
+	UploadArchive(Vagrant_UploadArchiveServer) error
+	// DownloadArchive streams an archive previously stored with UploadArchive.
+	DownloadArchive(*DownloadArchiveRequest, Vagrant_DownloadArchiveServer) error
+	// GetLogStream reads the log stream for a basis, project, or target. The
+	// stream for a basis or project includes the logs of all projects and
+	// targets within it. This will immediately send the backlog of lines
+	// we have so far and then stream new lines as they are received.
 	GetLogStream(*GetLogStreamRequest, Vagrant_GetLogStreamServer) error
+	// SendLogStream sends log lines for a basis, project, or target. The
+	// lines are buffered by the server and can be read with GetLogStream.
+	SendLogStream(Vagrant_SendLogStreamServer) error
 	// Set a single configuration item for the application.
 	SetConfig(context.Context, *ConfigSetRequest) (*ConfigSetResponse, error)
 	// Retrieve merged configuration values for a specific scope. You can determine
@@ -749,14 +1183,30 @@ type VagrantServer interface {
 	GetJobStream(*GetJobStreamRequest, Vagrant_GetJobStreamServer) error
 	// Clean out old jobs from the job database
 	PruneOldJobs(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// UpsertSchedule creates or updates a schedule which queues a job
+	// periodically.
+	UpsertSchedule(context.Context, *UpsertScheduleRequest) (*UpsertScheduleResponse, error)
+	// GetSchedule gets a single schedule by ID.
+	GetSchedule(context.Context, *GetScheduleRequest) (*GetScheduleResponse, error)
+	// ListSchedules lists all the schedules.
+	ListSchedules(context.Context, *emptypb.Empty) (*ListSchedulesResponse, error)
+	// DeleteSchedule deletes a schedule. Jobs already queued by the schedule
+	// are not affected.
+	DeleteSchedule(context.Context, *DeleteScheduleRequest) (*emptypb.Empty, error)
 	// GetRunner gets information about a single runner.
 	GetRunner(context.Context, *GetRunnerRequest) (*Runner, error)
+	// ListRunners lists the registered runners, including runners which
+	// recently disconnected and are within the reconnect grace period.
+	ListRunners(context.Context, *ListRunnersRequest) (*ListRunnersResponse, error)
+	// DrainRunner stops assigning new jobs to a runner. Any job currently
+	// running on the runner is allowed to complete.
+	DrainRunner(context.Context, *DrainRunnerRequest) (*emptypb.Empty, error)
 	// GetServerConfig sets configuration for the Vagrant server.
 	GetServerConfig(context.Context, *emptypb.Empty) (*GetServerConfigResponse, error)
 	// SetServerConfig sets configuration for the Vagrant server.
 	SetServerConfig(context.Context, *SetServerConfigRequest) (*emptypb.Empty, error)
 	// CreateSnapshot creates a new database snapshot.
-	CreateSnapshot(*emptypb.Empty, Vagrant_CreateSnapshotServer) error
+	CreateSnapshot(*CreateSnapshotRequest, Vagrant_CreateSnapshotServer) error
 	// RestoreSnapshot performs a database restore with the given snapshot.
 	// This API doesn't do a full online restore, it only stages the restore
 	// for the next server start to finalize the restore. See the arguments for
@@ -772,6 +1222,34 @@ type VagrantServer interface {
 	GenerateLoginToken(context.Context, *emptypb.Empty) (*NewTokenResponse, error)
 	// Exchange a invite token for a login token.
 	ConvertInviteToken(context.Context, *ConvertInviteTokenRequest) (*NewTokenResponse, error)
+	// ListTokens returns the tokens issued by the server.
+	ListTokens(context.Context, *ListTokensRequest) (*ListTokensResponse, error)
+	// RevokeToken revokes a token issued by the server. The token is rejected
+	// from then on and streams authenticated with it are closed. Tokens issued
+	// before tokens were recorded have no ID and can't be revoked this way,
+	// these are rejected once the key that signed them is retired.
+	RevokeToken(context.Context, *RevokeTokenRequest) (*RevokeTokenResponse, error)
+	// ListHMACKeys returns the keys used to sign tokens. The key material
+	// itself is never returned.
+	ListHMACKeys(context.Context, *emptypb.Empty) (*ListHMACKeysResponse, error)
+	// RotateHMACKey creates a new key that is used to sign new tokens. Tokens
+	// signed with the previous keys remain valid until those keys are retired.
+	RotateHMACKey(context.Context, *RotateHMACKeyRequest) (*RotateHMACKeyResponse, error)
+	// RetireHMACKey sets when a key is retired. Tokens signed with a retired
+	// key are rejected.
+	RetireHMACKey(context.Context, *RetireHMACKeyRequest) (*RetireHMACKeyResponse, error)
+	// UpsertUser creates or updates a user and the roles bound to it.
+	UpsertUser(context.Context, *UpsertUserRequest) (*UpsertUserResponse, error)
+	// DeleteUser deletes a user. Tokens of the user are rejected from then on.
+	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
+	// ListUsers returns all the users.
+	ListUsers(context.Context, *emptypb.Empty) (*ListUsersResponse, error)
+	// UpsertRole creates or updates a role. Built-in roles can't be changed.
+	UpsertRole(context.Context, *UpsertRoleRequest) (*UpsertRoleResponse, error)
+	// DeleteRole deletes a role which isn't bound to any user.
+	DeleteRole(context.Context, *DeleteRoleRequest) (*emptypb.Empty, error)
+	// ListRoles returns all the roles, including the built-in roles.
+	ListRoles(context.Context, *emptypb.Empty) (*ListRolesResponse, error)
 	// RunnerConfig is called to register a runner and receive the configuration
 	// for the runner. The response is a stream so that the configuration can
 	// be updated later.
@@ -854,9 +1332,33 @@ func (UnimplementedVagrantServer) ListBoxes(context.Context, *emptypb.Empty) (*L
 func (UnimplementedVagrantServer) FindBox(context.Context, *FindBoxRequest) (*FindBoxResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FindBox not implemented")
 }
+func (UnimplementedVagrantServer) PublishCatalogBox(Vagrant_PublishCatalogBoxServer) error {
+	return status.Errorf(codes.Unimplemented, "method PublishCatalogBox not implemented")
+}
+func (UnimplementedVagrantServer) GetCatalogBox(context.Context, *GetCatalogBoxRequest) (*GetCatalogBoxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCatalogBox not implemented")
+}
+func (UnimplementedVagrantServer) ListCatalogBoxes(context.Context, *emptypb.Empty) (*ListCatalogBoxesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCatalogBoxes not implemented")
+}
+func (UnimplementedVagrantServer) DeleteCatalogBox(context.Context, *DeleteCatalogBoxRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCatalogBox not implemented")
+}
+func (UnimplementedVagrantServer) DownloadCatalogBox(*DownloadCatalogBoxRequest, Vagrant_DownloadCatalogBoxServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadCatalogBox not implemented")
+}
+func (UnimplementedVagrantServer) UploadArchive(Vagrant_UploadArchiveServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadArchive not implemented")
+}
+func (UnimplementedVagrantServer) DownloadArchive(*DownloadArchiveRequest, Vagrant_DownloadArchiveServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadArchive not implemented")
+}
 func (UnimplementedVagrantServer) GetLogStream(*GetLogStreamRequest, Vagrant_GetLogStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetLogStream not implemented")
 }
+func (UnimplementedVagrantServer) SendLogStream(Vagrant_SendLogStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SendLogStream not implemented")
+}
 func (UnimplementedVagrantServer) SetConfig(context.Context, *ConfigSetRequest) (*ConfigSetResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetConfig not implemented")
 }
@@ -884,16 +1386,34 @@ func (UnimplementedVagrantServer) GetJobStream(*GetJobStreamRequest, Vagrant_Get
 func (UnimplementedVagrantServer) PruneOldJobs(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PruneOldJobs not implemented")
 }
-func (UnimplementedVagrantServer) GetRunner(context.Context, *GetRunnerRequest) (*Runner, error) {
+func (UnimplementedVagrantServer) UpsertSchedule(context.Context, *UpsertScheduleRequest) (*UpsertScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpsertSchedule not implemented")
+}
+func (UnimplementedVagrantServer) GetSchedule(context.Context, *GetScheduleRequest) (*GetScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSchedule not implemented")
+}
+func (UnimplementedVagrantServer) ListSchedules(context.Context, *emptypb.Empty) (*ListSchedulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSchedules not implemented")
+}
+func (UnimplementedVagrantServer) DeleteSchedule(context.Context, *DeleteScheduleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSchedule not implemented")
+}
+func (UnimplementedVagrantServer) GetRunner(context.Context, *GetRunnerRequest) (*Runner, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRunner not implemented")
 }
+func (UnimplementedVagrantServer) ListRunners(context.Context, *ListRunnersRequest) (*ListRunnersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRunners not implemented")
+}
+func (UnimplementedVagrantServer) DrainRunner(context.Context, *DrainRunnerRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainRunner not implemented")
+}
 func (UnimplementedVagrantServer) GetServerConfig(context.Context, *emptypb.Empty) (*GetServerConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetServerConfig not implemented")
 }
 func (UnimplementedVagrantServer) SetServerConfig(context.Context, *SetServerConfigRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetServerConfig not implemented")
 }
-func (UnimplementedVagrantServer) CreateSnapshot(*emptypb.Empty, Vagrant_CreateSnapshotServer) error {
+func (UnimplementedVagrantServer) CreateSnapshot(*CreateSnapshotRequest, Vagrant_CreateSnapshotServer) error {
 	return status.Errorf(codes.Unimplemented, "method CreateSnapshot not implemented")
 }
 func (UnimplementedVagrantServer) RestoreSnapshot(Vagrant_RestoreSnapshotServer) error {
@@ -911,6 +1431,39 @@ func (UnimplementedVagrantServer) GenerateLoginToken(context.Context, *emptypb.E
 func (UnimplementedVagrantServer) ConvertInviteToken(context.Context, *ConvertInviteTokenRequest) (*NewTokenResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ConvertInviteToken not implemented")
 }
+func (UnimplementedVagrantServer) ListTokens(context.Context, *ListTokensRequest) (*ListTokensResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTokens not implemented")
+}
+func (UnimplementedVagrantServer) RevokeToken(context.Context, *RevokeTokenRequest) (*RevokeTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeToken not implemented")
+}
+func (UnimplementedVagrantServer) ListHMACKeys(context.Context, *emptypb.Empty) (*ListHMACKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHMACKeys not implemented")
+}
+func (UnimplementedVagrantServer) RotateHMACKey(context.Context, *RotateHMACKeyRequest) (*RotateHMACKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateHMACKey not implemented")
+}
+func (UnimplementedVagrantServer) RetireHMACKey(context.Context, *RetireHMACKeyRequest) (*RetireHMACKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetireHMACKey not implemented")
+}
+func (UnimplementedVagrantServer) UpsertUser(context.Context, *UpsertUserRequest) (*UpsertUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpsertUser not implemented")
+}
+func (UnimplementedVagrantServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedVagrantServer) ListUsers(context.Context, *emptypb.Empty) (*ListUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedVagrantServer) UpsertRole(context.Context, *UpsertRoleRequest) (*UpsertRoleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpsertRole not implemented")
+}
+func (UnimplementedVagrantServer) DeleteRole(context.Context, *DeleteRoleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRole not implemented")
+}
+func (UnimplementedVagrantServer) ListRoles(context.Context, *emptypb.Empty) (*ListRolesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoles not implemented")
+}
 func (UnimplementedVagrantServer) RunnerConfig(Vagrant_RunnerConfigServer) error {
 	return status.Errorf(codes.Unimplemented, "method RunnerConfig not implemented")
 }
@@ -1343,6 +1896,154 @@ func _Vagrant_FindBox_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Vagrant_PublishCatalogBox_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VagrantServer).PublishCatalogBox(&vagrantPublishCatalogBoxServer{stream})
+}
+
+type Vagrant_PublishCatalogBoxServer interface {
+	SendAndClose(*PublishCatalogBoxResponse) error
+	Recv() (*PublishCatalogBoxRequest, error)
+	grpc.ServerStream
+}
+
+type vagrantPublishCatalogBoxServer struct {
+	grpc.ServerStream
+}
+
+func (x *vagrantPublishCatalogBoxServer) SendAndClose(m *PublishCatalogBoxResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *vagrantPublishCatalogBoxServer) Recv() (*PublishCatalogBoxRequest, error) {
+	m := new(PublishCatalogBoxRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Vagrant_GetCatalogBox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCatalogBoxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).GetCatalogBox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/GetCatalogBox",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).GetCatalogBox(ctx, req.(*GetCatalogBoxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_ListCatalogBoxes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListCatalogBoxes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListCatalogBoxes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListCatalogBoxes(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_DeleteCatalogBox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCatalogBoxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).DeleteCatalogBox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/DeleteCatalogBox",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).DeleteCatalogBox(ctx, req.(*DeleteCatalogBoxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_DownloadCatalogBox_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadCatalogBoxRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VagrantServer).DownloadCatalogBox(m, &vagrantDownloadCatalogBoxServer{stream})
+}
+
+type Vagrant_DownloadCatalogBoxServer interface {
+	Send(*DownloadCatalogBoxResponse) error
+	grpc.ServerStream
+}
+
+type vagrantDownloadCatalogBoxServer struct {
+	grpc.ServerStream
+}
+
+func (x *vagrantDownloadCatalogBoxServer) Send(m *DownloadCatalogBoxResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Vagrant_UploadArchive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VagrantServer).UploadArchive(&vagrantUploadArchiveServer{stream})
+}
+
+type Vagrant_UploadArchiveServer interface {
+	SendAndClose(*UploadArchiveResponse) error
+	Recv() (*UploadArchiveRequest, error)
+	grpc.ServerStream
+}
+
+type vagrantUploadArchiveServer struct {
+	grpc.ServerStream
+}
+
+func (x *vagrantUploadArchiveServer) SendAndClose(m *UploadArchiveResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *vagrantUploadArchiveServer) Recv() (*UploadArchiveRequest, error) {
+	m := new(UploadArchiveRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Vagrant_DownloadArchive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadArchiveRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VagrantServer).DownloadArchive(m, &vagrantDownloadArchiveServer{stream})
+}
+
+type Vagrant_DownloadArchiveServer interface {
+	Send(*DownloadArchiveResponse) error
+	grpc.ServerStream
+}
+
+type vagrantDownloadArchiveServer struct {
+	grpc.ServerStream
+}
+
+func (x *vagrantDownloadArchiveServer) Send(m *DownloadArchiveResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Vagrant_GetLogStream_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(GetLogStreamRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -1364,6 +2065,32 @@ func (x *vagrantGetLogStreamServer) Send(m *LogBatch) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Vagrant_SendLogStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VagrantServer).SendLogStream(&vagrantSendLogStreamServer{stream})
+}
+
+type Vagrant_SendLogStreamServer interface {
+	SendAndClose(*emptypb.Empty) error
+	Recv() (*SendLogStreamRequest, error)
+	grpc.ServerStream
+}
+
+type vagrantSendLogStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *vagrantSendLogStreamServer) SendAndClose(m *emptypb.Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *vagrantSendLogStreamServer) Recv() (*SendLogStreamRequest, error) {
+	m := new(SendLogStreamRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _Vagrant_SetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ConfigSetRequest)
 	if err := dec(in); err != nil {
@@ -1529,6 +2256,78 @@ func _Vagrant_PruneOldJobs_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Vagrant_UpsertSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).UpsertSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/UpsertSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).UpsertSchedule(ctx, req.(*UpsertScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_GetSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).GetSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/GetSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).GetSchedule(ctx, req.(*GetScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_ListSchedules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListSchedules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListSchedules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListSchedules(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_DeleteSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).DeleteSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/DeleteSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).DeleteSchedule(ctx, req.(*DeleteScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Vagrant_GetRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetRunnerRequest)
 	if err := dec(in); err != nil {
@@ -1547,6 +2346,42 @@ func _Vagrant_GetRunner_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Vagrant_ListRunners_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunnersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListRunners(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListRunners",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListRunners(ctx, req.(*ListRunnersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_DrainRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRunnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).DrainRunner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/DrainRunner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).DrainRunner(ctx, req.(*DrainRunnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Vagrant_GetServerConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -1584,7 +2419,7 @@ func _Vagrant_SetServerConfig_Handler(srv interface{}, ctx context.Context, dec
 }
 
 func _Vagrant_CreateSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(emptypb.Empty)
+	m := new(CreateSnapshotRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -1702,6 +2537,204 @@ func _Vagrant_ConvertInviteToken_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Vagrant_ListTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTokensRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListTokens(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListTokens",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListTokens(ctx, req.(*ListTokensRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_RevokeToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).RevokeToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/RevokeToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).RevokeToken(ctx, req.(*RevokeTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_ListHMACKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListHMACKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListHMACKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListHMACKeys(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_RotateHMACKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateHMACKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).RotateHMACKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/RotateHMACKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).RotateHMACKey(ctx, req.(*RotateHMACKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_RetireHMACKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetireHMACKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).RetireHMACKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/RetireHMACKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).RetireHMACKey(ctx, req.(*RetireHMACKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_UpsertUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).UpsertUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/UpsertUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).UpsertUser(ctx, req.(*UpsertUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/DeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListUsers(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_UpsertRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).UpsertRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/UpsertRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).UpsertRole(ctx, req.(*UpsertRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_DeleteRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).DeleteRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/DeleteRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).DeleteRole(ctx, req.(*DeleteRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vagrant_ListRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VagrantServer).ListRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.vagrant.Vagrant/ListRoles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VagrantServer).ListRoles(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Vagrant_RunnerConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(VagrantServer).RunnerConfig(&vagrantRunnerConfigServer{stream})
 }
@@ -1853,6 +2886,18 @@ var Vagrant_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "FindBox",
 			Handler:    _Vagrant_FindBox_Handler,
 		},
+		{
+			MethodName: "GetCatalogBox",
+			Handler:    _Vagrant_GetCatalogBox_Handler,
+		},
+		{
+			MethodName: "ListCatalogBoxes",
+			Handler:    _Vagrant_ListCatalogBoxes_Handler,
+		},
+		{
+			MethodName: "DeleteCatalogBox",
+			Handler:    _Vagrant_DeleteCatalogBox_Handler,
+		},
 		{
 			MethodName: "SetConfig",
 			Handler:    _Vagrant_SetConfig_Handler,
@@ -1885,10 +2930,34 @@ var Vagrant_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PruneOldJobs",
 			Handler:    _Vagrant_PruneOldJobs_Handler,
 		},
+		{
+			MethodName: "UpsertSchedule",
+			Handler:    _Vagrant_UpsertSchedule_Handler,
+		},
+		{
+			MethodName: "GetSchedule",
+			Handler:    _Vagrant_GetSchedule_Handler,
+		},
+		{
+			MethodName: "ListSchedules",
+			Handler:    _Vagrant_ListSchedules_Handler,
+		},
+		{
+			MethodName: "DeleteSchedule",
+			Handler:    _Vagrant_DeleteSchedule_Handler,
+		},
 		{
 			MethodName: "GetRunner",
 			Handler:    _Vagrant_GetRunner_Handler,
 		},
+		{
+			MethodName: "ListRunners",
+			Handler:    _Vagrant_ListRunners_Handler,
+		},
+		{
+			MethodName: "DrainRunner",
+			Handler:    _Vagrant_DrainRunner_Handler,
+		},
 		{
 			MethodName: "GetServerConfig",
 			Handler:    _Vagrant_GetServerConfig_Handler,
@@ -1913,13 +2982,82 @@ var Vagrant_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ConvertInviteToken",
 			Handler:    _Vagrant_ConvertInviteToken_Handler,
 		},
+		{
+			MethodName: "ListTokens",
+			Handler:    _Vagrant_ListTokens_Handler,
+		},
+		{
+			MethodName: "RevokeToken",
+			Handler:    _Vagrant_RevokeToken_Handler,
+		},
+		{
+			MethodName: "ListHMACKeys",
+			Handler:    _Vagrant_ListHMACKeys_Handler,
+		},
+		{
+			MethodName: "RotateHMACKey",
+			Handler:    _Vagrant_RotateHMACKey_Handler,
+		},
+		{
+			MethodName: "RetireHMACKey",
+			Handler:    _Vagrant_RetireHMACKey_Handler,
+		},
+		{
+			MethodName: "UpsertUser",
+			Handler:    _Vagrant_UpsertUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _Vagrant_DeleteUser_Handler,
+		},
+		{
+			MethodName: "ListUsers",
+			Handler:    _Vagrant_ListUsers_Handler,
+		},
+		{
+			MethodName: "UpsertRole",
+			Handler:    _Vagrant_UpsertRole_Handler,
+		},
+		{
+			MethodName: "DeleteRole",
+			Handler:    _Vagrant_DeleteRole_Handler,
+		},
+		{
+			MethodName: "ListRoles",
+			Handler:    _Vagrant_ListRoles_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PublishCatalogBox",
+			Handler:       _Vagrant_PublishCatalogBox_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadCatalogBox",
+			Handler:       _Vagrant_DownloadCatalogBox_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadArchive",
+			Handler:       _Vagrant_UploadArchive_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadArchive",
+			Handler:       _Vagrant_DownloadArchive_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "GetLogStream",
 			Handler:       _Vagrant_GetLogStream_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "SendLogStream",
+			Handler:       _Vagrant_SendLogStream_Handler,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "GetJobStream",
 			Handler:       _Vagrant_GetJobStream_Handler,
@@ -2,7 +2,9 @@ package ptypes
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/imdario/mergo"
@@ -52,11 +54,84 @@ func TestJobNew(t testing.T, src *vagrant_server.Job) *vagrant_server.Job {
 func ValidateJob(job *vagrant_server.Job) error {
 	return validation.ValidateStruct(job,
 		validation.Field(&job.Id, validation.By(isEmpty)),
-		validation.Field(&job.TargetRunner, validation.Required),
+		validation.Field(&job.TargetRunner, validation.Required, validation.By(validateRunnerSelectors)),
 		validation.Field(&job.Operation, validation.Required),
+		validation.Field(&job.RetryPolicy, validation.By(validateRetryPolicy)),
+		validation.Field(&job.Timeout, validation.By(validateDuration)),
 	)
 }
 
+// validateDuration validates that the value is a valid duration string.
+func validateDuration(v interface{}) error {
+	d, ok := v.(string)
+	if !ok || d == "" {
+		return nil
+	}
+
+	if _, err := time.ParseDuration(d); err != nil {
+		return fmt.Errorf("invalid duration %q: %s", d, err)
+	}
+
+	return nil
+}
+
+// validateRetryPolicy validates the retry policy of a job.
+func validateRetryPolicy(v interface{}) error {
+	policy, ok := v.(*vagrant_server.Job_RetryPolicy)
+	if !ok || policy == nil {
+		return nil
+	}
+
+	if policy.MaxAttempts == 0 {
+		return errors.New("max attempts must be set")
+	}
+
+	for _, d := range []string{policy.Backoff, policy.MaxBackoff} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("invalid backoff duration %q: %s", d, err)
+		}
+	}
+
+	return nil
+}
+
+// validateRunnerSelectors validates the label selectors of a job that
+// targets any runner.
+func validateRunnerSelectors(v interface{}) error {
+	ref, ok := v.(*vagrant_server.Ref_Runner)
+	if !ok || ref == nil {
+		return nil
+	}
+
+	for _, sel := range ref.GetAny().GetSelectors() {
+		if sel.Key == "" {
+			return errors.New("selector key must be set")
+		}
+
+		switch sel.Operator {
+		case vagrant_server.Ref_RunnerLabelSelector_EQUALS,
+			vagrant_server.Ref_RunnerLabelSelector_NOT_EQUALS:
+			if len(sel.Values) != 1 {
+				return fmt.Errorf("selector %q must have exactly one value", sel.Key)
+			}
+
+		case vagrant_server.Ref_RunnerLabelSelector_IN,
+			vagrant_server.Ref_RunnerLabelSelector_NOT_IN:
+			if len(sel.Values) == 0 {
+				return fmt.Errorf("selector %q must have at least one value", sel.Key)
+			}
+
+		default:
+			return fmt.Errorf("selector %q has unknown operator %s", sel.Key, sel.Operator)
+		}
+	}
+
+	return nil
+}
+
 func isEmpty(v interface{}) error {
 	if reflect.ValueOf(v).IsZero() {
 		return nil
@@ -25,6 +25,94 @@ func TestValidateJob(t *testing.T) {
 			func(j *vagrant_server.Job) { j.Id = "nope" },
 			"id: must be empty",
 		},
+
+		{
+			"valid selectors",
+			func(j *vagrant_server.Job) {
+				j.TargetRunner.GetAny().Selectors = []*vagrant_server.Ref_RunnerLabelSelector{
+					{Key: "provider", Values: []string{"virtualbox"}},
+					{
+						Key:      "arch",
+						Operator: vagrant_server.Ref_RunnerLabelSelector_IN,
+						Values:   []string{"amd64", "arm64"},
+					},
+				}
+			},
+			"",
+		},
+
+		{
+			"selector without key",
+			func(j *vagrant_server.Job) {
+				j.TargetRunner.GetAny().Selectors = []*vagrant_server.Ref_RunnerLabelSelector{
+					{Values: []string{"virtualbox"}},
+				}
+			},
+			"selector key must be set",
+		},
+
+		{
+			"equals selector with multiple values",
+			func(j *vagrant_server.Job) {
+				j.TargetRunner.GetAny().Selectors = []*vagrant_server.Ref_RunnerLabelSelector{
+					{Key: "provider", Values: []string{"virtualbox", "libvirt"}},
+				}
+			},
+			"must have exactly one value",
+		},
+
+		{
+			"in selector without values",
+			func(j *vagrant_server.Job) {
+				j.TargetRunner.GetAny().Selectors = []*vagrant_server.Ref_RunnerLabelSelector{
+					{Key: "provider", Operator: vagrant_server.Ref_RunnerLabelSelector_NOT_IN},
+				}
+			},
+			"must have at least one value",
+		},
+
+		{
+			"valid retry policy",
+			func(j *vagrant_server.Job) {
+				j.RetryPolicy = &vagrant_server.Job_RetryPolicy{
+					MaxAttempts: 3,
+					Backoff:     "5s",
+					MaxBackoff:  "1m",
+				}
+			},
+			"",
+		},
+
+		{
+			"retry policy without max attempts",
+			func(j *vagrant_server.Job) {
+				j.RetryPolicy = &vagrant_server.Job_RetryPolicy{Backoff: "5s"}
+			},
+			"max attempts must be set",
+		},
+
+		{
+			"retry policy with invalid backoff",
+			func(j *vagrant_server.Job) {
+				j.RetryPolicy = &vagrant_server.Job_RetryPolicy{
+					MaxAttempts: 3,
+					Backoff:     "soon",
+				}
+			},
+			"invalid backoff duration",
+		},
+
+		{
+			"valid timeout",
+			func(j *vagrant_server.Job) { j.Timeout = "30m" },
+			"",
+		},
+
+		{
+			"invalid timeout",
+			func(j *vagrant_server.Job) { j.Timeout = "forever" },
+			"timeout: invalid duration",
+		},
 	}
 
 	for _, tt := range cases {
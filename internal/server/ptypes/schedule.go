@@ -0,0 +1,65 @@
+package ptypes
+
+import (
+	"errors"
+
+	"github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/hashicorp/cronexpr"
+	"github.com/imdario/mergo"
+	"github.com/mitchellh/go-testing-interface"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestSchedule(t testing.T, src *vagrant_server.Schedule) *vagrant_server.Schedule {
+	t.Helper()
+
+	if src == nil {
+		src = &vagrant_server.Schedule{}
+	}
+
+	require.NoError(t, mergo.Merge(src, &vagrant_server.Schedule{
+		Name: "test",
+		Cron: "0 * * * *",
+		Job:  TestJobNew(t, nil),
+	}))
+
+	return src
+}
+
+// ValidateSchedule validates the schedule structure.
+func ValidateSchedule(sched *vagrant_server.Schedule) error {
+	return validation.ValidateStruct(sched,
+		validation.Field(&sched.Cron, validation.Required, validation.By(validateCron)),
+		validation.Field(&sched.Job, validation.Required, validation.By(validateScheduleJob)),
+	)
+}
+
+// validateCron validates that the value is a valid cron expression.
+func validateCron(v interface{}) error {
+	expr, ok := v.(string)
+	if !ok || expr == "" {
+		return nil
+	}
+
+	if _, err := cronexpr.Parse(expr); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateScheduleJob validates the job template of a schedule.
+func validateScheduleJob(v interface{}) error {
+	job, ok := v.(*vagrant_server.Job)
+	if !ok || job == nil {
+		return nil
+	}
+
+	if len(job.DependsOn) > 0 {
+		return errors.New("scheduled jobs can't depend on other jobs")
+	}
+
+	return ValidateJob(job)
+}
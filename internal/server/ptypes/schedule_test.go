@@ -0,0 +1,73 @@
+package ptypes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Modify func(*vagrant_server.Schedule)
+		Error  string
+	}{
+		{
+			"valid",
+			nil,
+			"",
+		},
+
+		{
+			"cron is required",
+			func(s *vagrant_server.Schedule) { s.Cron = "" },
+			"cron: cannot be blank",
+		},
+
+		{
+			"invalid cron",
+			func(s *vagrant_server.Schedule) { s.Cron = "every day" },
+			"cron:",
+		},
+
+		{
+			"job is required",
+			func(s *vagrant_server.Schedule) { s.Job = nil },
+			"job: cannot be blank",
+		},
+
+		{
+			"job id is set",
+			func(s *vagrant_server.Schedule) { s.Job.Id = "nope" },
+			"id: must be empty",
+		},
+
+		{
+			"job with dependencies",
+			func(s *vagrant_server.Schedule) { s.Job.DependsOn = []string{"A"} },
+			"can't depend on other jobs",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			require := require.New(t)
+
+			sched := TestSchedule(t, nil)
+			if f := tt.Modify; f != nil {
+				f(sched)
+			}
+
+			err := ValidateSchedule(sched)
+			if tt.Error == "" {
+				require.NoError(err)
+				return
+			}
+
+			require.Error(err)
+			require.Contains(err.Error(), tt.Error)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 
 	"github.com/hashicorp/go-hclog"
@@ -91,12 +92,19 @@ type options struct {
 	// the HTTP-based API will be disabled.
 	HTTPListener net.Listener
 
+	// TLSConfig, if set, serves the gRPC and HTTP listeners with TLS.
+	TLSConfig *tls.Config
+
 	// AuthChecker, if set, activates authentication checking on the server.
 	AuthChecker AuthChecker
 
 	// BrowserUIEnabled determines if the browser UI should be mounted
 	BrowserUIEnabled bool
 
+	// AnonymousHTTPReads allows read only HTTP requests without
+	// authentication, even if an AuthChecker is set.
+	AnonymousHTTPReads bool
+
 	grpcServer *grpc.Server
 }
 
@@ -130,11 +138,28 @@ func WithImpl(impl vagrant_server.VagrantServer) Option {
 	return func(opts *options) { opts.Service = impl }
 }
 
+// WithTLS serves the listeners with TLS. Clients presenting a certificate
+// that the config verifies are available to the AuthChecker through the
+// peer of the request context. The local server started by the CLI only
+// listens on loopback and doesn't set this, so it's only used by programs
+// that run the server themselves, such as with Listener.TLSConfig from
+// the serverconfig package.
+func WithTLS(cfg *tls.Config) Option {
+	return func(opts *options) { opts.TLSConfig = cfg }
+}
+
 // WithAuthentication configures the server to require authentication.
 func WithAuthentication(ac AuthChecker) Option {
 	return func(opts *options) { opts.AuthChecker = ac }
 }
 
+// WithAnonymousHTTPReads allows read only (GET and HEAD) requests to the
+// HTTP endpoints of the service without authentication. This lets tools
+// without access to a token download content such as box files.
+func WithAnonymousHTTPReads(enabled bool) Option {
+	return func(opts *options) { opts.AnonymousHTTPReads = enabled }
+}
+
 // WithBrowserUI configures the server to enable the browser UI.
 func WithBrowserUI(enabled bool) Option {
 	return func(opts *options) { opts.BrowserUIEnabled = enabled }
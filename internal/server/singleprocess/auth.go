@@ -5,25 +5,32 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/subtle"
+	"encoding/hex"
 	"io"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-memdb"
 	"github.com/mr-tron/base58"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/blake2b"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/hashicorp/vagrant/internal/oidc"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
 )
 
 const (
-	// The user that all tokens are encoded with. The server has a single server model
-	// so all actions are mapped to this user.
+	// The user that tokens created while bootstrapping the server are for.
+	// This user is created with the admin role when the server starts
+	// without any users.
 	DefaultUser = "vagrant"
 
 	// The identifier for the default key to use to generating tokens.
@@ -68,6 +75,13 @@ func (s *service) DecodeToken(token string) (*vagrant_server.TokenTransport, *va
 	if err != nil || key == nil {
 		return nil, nil, errors.Wrapf(ErrInvalidToken, "unknown key")
 	}
+	if len(key.Key) == 0 {
+		// The key was restored from a snapshot without its material
+		return nil, nil, errors.Wrapf(ErrInvalidToken, "key has no key material")
+	}
+	if state.HMACKeyRetired(key, time.Now()) {
+		return nil, nil, errors.Wrapf(ErrInvalidToken, "key has been retired")
+	}
 
 	h, err := blake2b.New256(key.Key)
 	if err != nil {
@@ -98,21 +112,82 @@ func (s *service) DecodeToken(token string) (*vagrant_server.TokenTransport, *va
 		}
 	}
 
+	// Tokens issued before tokens were recorded have no ID, so they can't
+	// be revoked individually. They are rejected once the key that signed
+	// them is retired.
+	revoked, err := s.state.TokenRevoked(hex.EncodeToString(body.TokenId), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, errors.Wrapf(ErrInvalidToken, "token has been revoked")
+	}
+
 	return &tt, &body, nil
 }
 
+// WatchToken implements server.TokenWatcher. The returned channel is closed
+// once the token is revoked.
+func (s *service) WatchToken(ctx context.Context, token string) (<-chan struct{}, error) {
+	// Streams authenticated by a client certificate have no token to
+	// revoke, and ID tokens can't be revoked by the server.
+	if (token == "" && s.certUser(ctx) != "") || (s.oidcAuth != nil && oidc.IsJWT(token)) {
+		return make(chan struct{}), nil
+	}
+
+	_, body, err := s.DecodeToken(token)
+	if err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(body.TokenId)
+
+	ch := make(chan struct{})
+	go func() {
+		for {
+			ws := memdb.NewWatchSet()
+			revoked, err := s.state.TokenRevoked(id, ws)
+			if err != nil {
+				hclog.FromContext(ctx).Warn("error watching token", "err", err)
+				return
+			}
+			if revoked {
+				close(ch)
+				return
+			}
+
+			if err := ws.WatchCtx(ctx); err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
 // Authenticate checks if the given endpoint should be allowed. This is called during a
 // gRPC request. Effects is some information about the endpoint, at present these are
 // either ["readonly"] or ["mutable"] to indicate if the endpoint will be only reading
 // data or also mutating it.
 func (s *service) Authenticate(ctx context.Context, token, endpoint string, effects []string) error {
-	// We always allow ConvertInviteToken so that folks can actually get authentication data
-	if endpoint == "ConvertInviteToken" || endpoint == "BootstrapToken" || endpoint == "GetVersionInfo" {
+	if authExempt(endpoint) {
 		return nil
 	}
 
+	if s.oidcAuth != nil && oidc.IsJWT(token) {
+		_, _, err := s.oidcAuth.identity(ctx, token)
+		return err
+	}
+
 	if token == "" {
-		return status.Errorf(codes.Unauthenticated, "Authorization token is not supplied")
+		user := s.certUser(ctx)
+		if user == "" {
+			return status.Errorf(codes.Unauthenticated, "Authorization token is not supplied")
+		}
+		if _, err := s.state.UserGet(user); err != nil {
+			return status.Errorf(codes.Unauthenticated, "Unknown user %q", user)
+		}
+
+		return nil
 	}
 
 	_, body, err := s.DecodeToken(token)
@@ -129,8 +204,8 @@ func (s *service) Authenticate(ctx context.Context, token, endpoint string, effe
 		return status.Errorf(codes.Unauthenticated, "Unauthorized endpoint")
 	}
 
-	// TODO When we have a user model, this is where you'll check for the user.
-	if body.User != DefaultUser {
+	// Tokens of deleted users are rejected
+	if _, err := s.state.UserGet(body.User); err != nil {
 		return ErrInvalidToken
 	}
 
@@ -145,6 +220,10 @@ func (s *service) GenerateToken(keyId string, metadata map[string]string, body *
 	if err != nil {
 		return "", err
 	}
+	if state.HMACKeyRetired(key, time.Now()) || len(key.Key) == 0 {
+		return "", status.Errorf(codes.FailedPrecondition,
+			"key %s has been retired and can't sign new tokens", keyId)
+	}
 
 	bodyData, err := proto.Marshal(body)
 	if err != nil {
@@ -176,31 +255,158 @@ func (s *service) GenerateToken(keyId string, metadata map[string]string, body *
 	return base58.Encode(buf.Bytes()), nil
 }
 
-// Create a new login token.
+// Returns the ID of the key new tokens are signed with. This is the most
+// recently created key which isn't retired, or DefaultKeyId if no key
+// exists yet.
+func (s *service) activeKeyId() (string, error) {
+	key, err := s.state.HMACKeyActive()
+	if err != nil {
+		return "", err
+	}
+	if key == nil {
+		return DefaultKeyId, nil
+	}
+
+	return key.Id, nil
+}
+
+// Generate a new token by signing the data in body and record it as issued
+// so that it can be listed and revoked. creatorId is the ID of the token
+// used to request the new token, if any.
+func (s *service) issueToken(
+	keyId string,
+	creatorId string,
+	metadata map[string]string,
+	body *vagrant_server.Token,
+) (string, error) {
+	token, err := s.GenerateToken(keyId, metadata, body)
+	if err != nil {
+		return "", err
+	}
+
+	purpose := vagrant_server.IssuedToken_LOGIN
+	if body.Invite {
+		purpose = vagrant_server.IssuedToken_INVITE
+	}
+
+	err = s.state.TokenPut(&vagrant_server.IssuedToken{
+		Id:         hex.EncodeToString(body.TokenId),
+		CreatorId:  creatorId,
+		Purpose:    purpose,
+		KeyId:      keyId,
+		IssueTime:  timestamppb.Now(),
+		ValidUntil: body.ValidUntil,
+		Entrypoint: body.Entrypoint != nil,
+		User:       body.User,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Returns the ID of the token the request was authenticated with. This is
+// empty if the request has no valid token, such as when the server
+// doesn't require authentication.
+func (s *service) requestTokenId(ctx context.Context) string {
+	body := s.requestToken(ctx)
+	if body == nil {
+		return ""
+	}
+
+	return hex.EncodeToString(body.TokenId)
+}
+
+// Returns the user the request was authenticated as. This is empty if the
+// request has no valid token, such as when the server doesn't require
+// authentication.
+func (s *service) requestUser(ctx context.Context) string {
+	return s.requestToken(ctx).GetUser()
+}
+
+// Returns the token the request was authenticated with. This is nil if the
+// request has no valid token.
+func (s *service) requestToken(ctx context.Context) *vagrant_server.Token {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md["authorization"]) == 0 {
+		// Client certificates authenticate as their user with all its roles
+		if user := s.certUser(ctx); user != "" {
+			return &vagrant_server.Token{User: user}
+		}
+
+		return nil
+	}
+	token := md["authorization"][0]
+
+	// ID tokens have no ID and aren't restricted to roles
+	if s.oidcAuth != nil && oidc.IsJWT(token) {
+		username, _, err := s.oidcAuth.identity(ctx, token)
+		if err != nil {
+			return nil
+		}
+
+		return &vagrant_server.Token{User: username}
+	}
+
+	_, body, err := s.DecodeToken(token)
+	if err != nil {
+		return nil
+	}
+
+	return body
+}
+
+// Create a new login token for the default user.
 // keyId controls which key is used to sign the key (key values are generated lazily).
+// creatorId is the ID of the token used to request the new token, if any.
 // metadata is attached to the token transport as configuration style information
 func (s *service) NewLoginToken(
 	keyId string,
+	creatorId string,
 	metadata map[string]string,
 	entrypoint *vagrant_server.Token_Entrypoint,
 ) (string, error) {
-	var body vagrant_server.Token
+	return s.newLoginToken(keyId, creatorId, metadata, &vagrant_server.Token{
+		User:       DefaultUser,
+		Entrypoint: entrypoint,
+	})
+}
+
+// Create a new login token for the user, roles and entrypoint set in body.
+func (s *service) newLoginToken(
+	keyId string,
+	creatorId string,
+	metadata map[string]string,
+	body *vagrant_server.Token,
+) (string, error) {
 	body.Login = true
-	body.User = DefaultUser
 	body.TokenId = make([]byte, 16)
-	body.Entrypoint = entrypoint
 
 	_, err := io.ReadFull(rand.Reader, body.TokenId)
 	if err != nil {
 		return "", err
 	}
 
-	return s.GenerateToken(keyId, metadata, &body)
+	return s.issueToken(keyId, creatorId, metadata, body)
 }
 
 // Create a new login token. This is just a gRPC wrapper around NewLoginToken.
 func (s *service) GenerateLoginToken(ctx context.Context, _ *emptypb.Empty) (*vagrant_server.NewTokenResponse, error) {
-	token, err := s.NewLoginToken(DefaultKeyId, nil, nil)
+	keyId, err := s.activeKeyId()
+	if err != nil {
+		return nil, err
+	}
+
+	// The new token is for the same user and roles as the token used to
+	// request it.
+	body := &vagrant_server.Token{User: DefaultUser}
+	if caller := s.requestToken(ctx); caller != nil {
+		body.User = caller.User
+		body.Roles = caller.Roles
+	}
+
+	token, err := s.newLoginToken(keyId, s.requestTokenId(ctx), nil, body)
 	if err != nil {
 		return nil, err
 	}
@@ -208,19 +414,33 @@ func (s *service) GenerateLoginToken(ctx context.Context, _ *emptypb.Empty) (*va
 	return &vagrant_server.NewTokenResponse{Token: token}, nil
 }
 
-// Create a new invite token. The duration controls for how long the invite token is valid.
+// Create a new invite token for the default user. The duration controls for how long the invite token is valid.
 // keyId controls which key is used to sign the key (key values are generated lazily).
+// creatorId is the ID of the token used to request the new token, if any.
 // metadata is attached to the token transport as configuration style information
 func (s *service) NewInviteToken(
 	duration time.Duration,
 	keyId string,
+	creatorId string,
 	metadata map[string]string,
 	entrypoint *vagrant_server.Token_Entrypoint,
 ) (string, error) {
-	var body vagrant_server.Token
+	return s.newInviteToken(duration, keyId, creatorId, metadata, &vagrant_server.Token{
+		User:       DefaultUser,
+		Entrypoint: entrypoint,
+	})
+}
+
+// Create a new invite token for the user, roles and entrypoint set in body.
+func (s *service) newInviteToken(
+	duration time.Duration,
+	keyId string,
+	creatorId string,
+	metadata map[string]string,
+	body *vagrant_server.Token,
+) (string, error) {
 	body.Invite = true
 	body.TokenId = make([]byte, 16)
-	body.Entrypoint = entrypoint
 
 	now := time.Now().UTC().Add(duration)
 	body.ValidUntil = &timestamppb.Timestamp{
@@ -233,7 +453,7 @@ func (s *service) NewInviteToken(
 		return "", err
 	}
 
-	return s.GenerateToken(keyId, metadata, &body)
+	return s.issueToken(keyId, creatorId, metadata, body)
 }
 
 // Create a new invite token. This is just a gRPC wrapper around NewInviteToken.
@@ -243,7 +463,17 @@ func (s *service) GenerateInviteToken(ctx context.Context, req *vagrant_server.I
 		return nil, err
 	}
 
-	token, err := s.NewInviteToken(dur, DefaultKeyId, nil, req.Entrypoint)
+	keyId, err := s.activeKeyId()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.inviteTokenBody(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.newInviteToken(dur, keyId, s.requestTokenId(ctx), nil, body)
 	if err != nil {
 		return nil, err
 	}
@@ -262,12 +492,27 @@ func (s *service) ExchangeInvite(keyId, invite string) (string, error) {
 		return "", errors.Wrapf(ErrInvalidToken, "not an invite token")
 	}
 
-	return s.NewLoginToken(keyId, tt.Metadata, body.Entrypoint)
+	// Invites created before users existed have no user
+	user := body.User
+	if user == "" {
+		user = DefaultUser
+	}
+
+	return s.newLoginToken(keyId, hex.EncodeToString(body.TokenId), tt.Metadata, &vagrant_server.Token{
+		User:       user,
+		Roles:      body.Roles,
+		Entrypoint: body.Entrypoint,
+	})
 }
 
 // Given an invite token, validate it and return a login token. This is a gRPC wrapper around ExchangeInvite.
 func (s *service) ConvertInviteToken(ctx context.Context, req *vagrant_server.ConvertInviteTokenRequest) (*vagrant_server.NewTokenResponse, error) {
-	token, err := s.ExchangeInvite(DefaultKeyId, req.Token)
+	keyId, err := s.activeKeyId()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.ExchangeInvite(keyId, req.Token)
 	if err != nil {
 		return nil, err
 	}
@@ -280,7 +525,7 @@ func (s *service) BootstrapToken(ctx context.Context, req *emptypb.Empty) (*vagr
 		return nil, status.Errorf(codes.PermissionDenied, "server is already bootstrapped")
 	}
 
-	token, err := s.NewLoginToken(DefaultKeyId, nil, nil)
+	token, err := s.NewLoginToken(DefaultKeyId, "", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -291,3 +536,29 @@ func (s *service) BootstrapToken(ctx context.Context, req *emptypb.Empty) (*vagr
 func (s *service) Bootstrapped() bool {
 	return !s.state.HMACKeyEmpty()
 }
+
+// List the tokens issued by the server.
+func (s *service) ListTokens(ctx context.Context, req *vagrant_server.ListTokensRequest) (*vagrant_server.ListTokensResponse, error) {
+	tokens, err := s.state.TokenList(req.IncludeRevoked)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.ListTokensResponse{Tokens: tokens}, nil
+}
+
+// Revoke a token issued by the server. Requests using the token are rejected
+// from now on and streams authenticated with it are closed.
+func (s *service) RevokeToken(ctx context.Context, req *vagrant_server.RevokeTokenRequest) (*vagrant_server.RevokeTokenResponse, error) {
+	if req.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "token id must be set")
+	}
+
+	token, err := s.state.TokenRevoke(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("revoked token", "id", token.Id)
+	return &vagrant_server.RevokeTokenResponse{Token: token}, nil
+}
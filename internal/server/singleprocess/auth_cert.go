@@ -0,0 +1,37 @@
+package singleprocess
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// certUser returns the user that the client certificate of the request is
+// mapped to. This is empty if the request has no verified certificate or
+// its subject isn't mapped to a user. Only certificates verified against
+// the client CA of the listener are considered.
+func (s *service) certUser(ctx context.Context) string {
+	if len(s.certUsers) == 0 {
+		return ""
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	subject := info.State.VerifiedChains[0][0].Subject
+	if user, ok := s.certUsers[subject.String()]; ok {
+		return user
+	}
+	if subject.CommonName != "" {
+		return s.certUsers[subject.CommonName]
+	}
+
+	return ""
+}
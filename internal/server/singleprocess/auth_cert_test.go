@@ -0,0 +1,244 @@
+package singleprocess
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/clicontext"
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
+	"github.com/hashicorp/vagrant/internal/serverclient"
+	"github.com/hashicorp/vagrant/internal/serverconfig"
+)
+
+func TestServiceAuthenticate_clientCert(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	ca := testCertificate(t, dir, "ca", nil)
+	other := testCertificate(t, dir, "other-ca", nil)
+	srv := testCertificate(t, dir, "server", ca)
+	runner := testCertificate(t, dir, "runner-1", ca)
+	unmapped := testCertificate(t, dir, "unmapped", ca)
+	untrusted := testCertificate(t, dir, "runner-2", other)
+
+	impl, err := New(
+		WithDB(testDB(t)),
+		WithClientCertUsers(map[string]string{
+			"runner-1":    "runner",
+			"CN=runner-2": "runner",
+		}),
+	)
+	require.NoError(t, err)
+	s := impl.(*service)
+	require.NoError(t, s.state.UserPut(&vagrant_server.User{
+		Username: "runner",
+		Roles:    []*vagrant_server.RoleBinding{{Role: state.RoleOperator}},
+	}))
+
+	listener := &serverconfig.Listener{
+		Addr:            "127.0.0.1:0",
+		TLSCertFile:     srv.certFile,
+		TLSKeyFile:      srv.keyFile,
+		TLSClientCAFile: ca.certFile,
+	}
+
+	// Starts a server with the listener config and returns its address
+	start := func(t *testing.T, listener *serverconfig.Listener) string {
+		tlsConfig, err := listener.TLSConfig()
+		require.NoError(t, err)
+
+		ln, err := net.Listen("tcp", listener.Addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { ln.Close() })
+
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+		go server.Run(
+			server.WithContext(ctx),
+			server.WithGRPC(ln),
+			server.WithImpl(impl),
+			server.WithTLS(tlsConfig),
+			server.WithAuthentication(s),
+		)
+
+		return ln.Addr().String()
+	}
+
+	// Calls ListRunners on the server with the given certificate and token.
+	// The TLS handshake may only fail once the first request is sent.
+	listRunners := func(t *testing.T, addr string, cert *testCert, token string) error {
+		cfg := &clicontext.Config{Server: serverconfig.Client{
+			Address:       addr,
+			Tls:           true,
+			TlsSkipVerify: true,
+			RequireAuth:   true,
+			AuthToken:     token,
+		}}
+		if cert != nil {
+			cfg.Server.TlsCertFile = cert.certFile
+			cfg.Server.TlsKeyFile = cert.keyFile
+		}
+
+		conn, err := serverclient.Connect(ctx,
+			serverclient.Timeout(time.Second),
+			serverclient.FromContextConfig(cfg),
+		)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		_, err = vagrant_server.NewVagrantClient(conn).ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+		return err
+	}
+
+	// Returns a client which connects with the given certificate and token
+	connect := func(t *testing.T, addr string, cert *testCert, token string) vagrant_server.VagrantClient {
+		cfg := &clicontext.Config{Server: serverconfig.Client{
+			Address:       addr,
+			Tls:           true,
+			TlsSkipVerify: true,
+			RequireAuth:   true,
+			AuthToken:     token,
+			TlsCertFile:   cert.certFile,
+			TlsKeyFile:    cert.keyFile,
+		}}
+
+		conn, err := serverclient.Connect(ctx, serverclient.FromContextConfig(cfg))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		return vagrant_server.NewVagrantClient(conn)
+	}
+
+	addr := start(t, listener)
+
+	t.Run("authenticates as the mapped user", func(t *testing.T) {
+		require := require.New(t)
+		client := connect(t, addr, runner, "")
+
+		_, err := client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+		require.NoError(err)
+
+		_, err = client.DeleteUser(ctx, &vagrant_server.DeleteUserRequest{Username: "runner"})
+		require.Error(err)
+		require.Equal(codes.PermissionDenied, status.Code(err))
+
+		// Login tokens generated with the certificate are for its user
+		resp, err := client.GenerateLoginToken(ctx, &emptypb.Empty{})
+		require.NoError(err)
+		_, body, err := s.DecodeToken(resp.Token)
+		require.NoError(err)
+		require.Equal("runner", body.User)
+	})
+
+	t.Run("rejects unmapped certificates", func(t *testing.T) {
+		err := listRunners(t, addr, unmapped, "")
+		require.Error(t, err)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("rejects certificates of other CAs", func(t *testing.T) {
+		require.Error(t, listRunners(t, addr, untrusted, ""))
+	})
+
+	t.Run("tokens take precedence", func(t *testing.T) {
+		require := require.New(t)
+
+		token, err := s.NewLoginToken(DefaultKeyId, "", nil, nil)
+		require.NoError(err)
+		client := connect(t, addr, runner, token)
+
+		_, err = client.UpsertRole(ctx, &vagrant_server.UpsertRoleRequest{
+			Role: &vagrant_server.Role{Name: "custom", Permissions: []string{"ListRunners"}},
+		})
+		require.NoError(err)
+	})
+
+	t.Run("requires certificates", func(t *testing.T) {
+		require := require.New(t)
+
+		required := *listener
+		required.TLSRequireClientCert = true
+		addr := start(t, &required)
+
+		require.NoError(listRunners(t, addr, runner, ""))
+		require.Error(listRunners(t, addr, nil, ""))
+	})
+}
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// testCertificate creates a certificate with the given common name signed
+// by the parent, or a self signed CA if parent is nil, and writes it to dir.
+func testCertificate(t *testing.T, dir, name string, parent *testCert) *testCert {
+	require := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(err)
+
+	result := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	require.NoError(ioutil.WriteFile(result.certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(ioutil.WriteFile(result.keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return result
+}
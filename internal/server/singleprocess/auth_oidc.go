@@ -0,0 +1,150 @@
+package singleprocess
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/oidc"
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/serverconfig"
+)
+
+// OIDCAuthChecker authenticates requests with the ID tokens issued by an
+// OpenID Connect provider. Tokens issued by the server itself are still
+// accepted so that runners and invites keep working.
+//
+// The identity of an ID token is namespaced by the issuer, so that the
+// user of subject "alice" is named "oidc:<issuer>:alice" on the server.
+// It gets the roles bound to that user plus the roles mapped from its
+// groups. Identities without any roles are rejected, so subjects must be
+// linked to the server by creating their user or mapping their groups.
+type OIDCAuthChecker struct {
+	s        *service
+	cfg      *serverconfig.OIDC
+	verifier *oidc.Verifier
+}
+
+var (
+	_ server.AuthChecker  = (*OIDCAuthChecker)(nil)
+	_ server.Authorizer   = (*OIDCAuthChecker)(nil)
+	_ server.TokenWatcher = (*OIDCAuthChecker)(nil)
+)
+
+// NewOIDCAuthChecker returns an AuthChecker for the service which accepts
+// the ID tokens of the provider in cfg. The discovery document of the
+// provider is fetched with client, or http.DefaultClient if nil.
+//
+// Servers created with an OIDC block in their config accept ID tokens
+// already, this is only needed to use a specific HTTP client.
+func NewOIDCAuthChecker(
+	ctx context.Context,
+	impl vagrant_server.VagrantServer,
+	cfg *serverconfig.OIDC,
+	client *http.Client,
+) (*OIDCAuthChecker, error) {
+	s, ok := impl.(*service)
+	if !ok {
+		return nil, fmt.Errorf("OIDC authentication requires the built-in server")
+	}
+
+	c, err := newOIDCAuthChecker(ctx, s, cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	s.oidcAuth = c
+
+	return c, nil
+}
+
+func newOIDCAuthChecker(
+	ctx context.Context,
+	s *service,
+	cfg *serverconfig.OIDC,
+	client *http.Client,
+) (*OIDCAuthChecker, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, fmt.Errorf("OIDC authentication requires an issuer and client_id")
+	}
+
+	p, err := oidc.Discover(ctx, client, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthChecker{
+		s:        s,
+		cfg:      cfg,
+		verifier: oidc.NewVerifier(p, cfg.ClientID, oidc.NewKeySet(client, p.JWKSURI)),
+	}, nil
+}
+
+// Authenticate implements server.AuthChecker.
+func (c *OIDCAuthChecker) Authenticate(ctx context.Context, token, endpoint string, effects []string) error {
+	return c.s.Authenticate(ctx, token, endpoint, effects)
+}
+
+// Authorize implements server.Authorizer.
+func (c *OIDCAuthChecker) Authorize(ctx context.Context, token, endpoint string, req interface{}) error {
+	return c.s.Authorize(ctx, token, endpoint, req)
+}
+
+// WatchToken implements server.TokenWatcher.
+func (c *OIDCAuthChecker) WatchToken(ctx context.Context, token string) (<-chan struct{}, error) {
+	return c.s.WatchToken(ctx, token)
+}
+
+// identity verifies the ID token and returns the name of its user and the
+// roles bound to it. Tokens which map to no roles at all are rejected.
+func (c *OIDCAuthChecker) identity(
+	ctx context.Context,
+	token string,
+) (string, []*vagrant_server.RoleBinding, error) {
+	claims, err := c.verifier.Verify(ctx, token)
+	if err != nil {
+		return "", nil, status.Errorf(codes.Unauthenticated, "%s", err)
+	}
+
+	claim := c.cfg.UsernameClaim
+	if claim == "" {
+		claim = "sub"
+	}
+	subject := claims.String(claim)
+	if subject == "" {
+		return "", nil, status.Errorf(codes.Unauthenticated,
+			"ID token has no %q claim", claim)
+	}
+
+	// Users of the provider never share the roles of local users
+	username := oidcUsername(c.verifier.Issuer, subject)
+
+	var bindings []*vagrant_server.RoleBinding
+	if user, err := c.s.state.UserGet(username); err == nil {
+		bindings = append(bindings, user.Roles...)
+	}
+
+	if c.cfg.RolesClaim != "" {
+		for _, group := range claims.Strings(c.cfg.RolesClaim) {
+			if role, ok := c.cfg.RoleMappings[group]; ok {
+				bindings = append(bindings, &vagrant_server.RoleBinding{Role: role})
+			}
+		}
+	}
+
+	if len(bindings) == 0 {
+		return "", nil, status.Errorf(codes.Unauthenticated,
+			"user %q has no roles on this server", username)
+	}
+
+	return username, bindings, nil
+}
+
+// oidcUsername returns the name of the server user for a subject of the
+// provider.
+func oidcUsername(issuer, subject string) string {
+	return "oidc:" + issuer + ":" + subject
+}
@@ -0,0 +1,232 @@
+package singleprocess
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/clicontext"
+	"github.com/hashicorp/vagrant/internal/oidc"
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
+	"github.com/hashicorp/vagrant/internal/serverclient"
+	"github.com/hashicorp/vagrant/internal/serverconfig"
+)
+
+func TestOIDCAuthChecker(t *testing.T) {
+	ctx := context.Background()
+	provider := oidc.NewTestProvider(t)
+
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(t, err)
+	s := impl.(*service)
+
+	c, err := NewOIDCAuthChecker(ctx, impl, &serverconfig.OIDC{
+		Issuer:        provider.Issuer(),
+		ClientID:      provider.ClientID,
+		UsernameClaim: "email",
+		RolesClaim:    "groups",
+		RoleMappings:  map[string]string{"vagrant-ops": state.RoleOperator},
+	}, nil)
+	require.NoError(t, err)
+
+	alice := oidcUsername(provider.Issuer(), "alice@example.com")
+	require.NoError(t, s.state.UserPut(&vagrant_server.User{
+		Username: alice,
+		Roles:    []*vagrant_server.RoleBinding{{Role: state.RoleReadOnly}},
+	}))
+
+	t.Run("maps the user to its roles", func(t *testing.T) {
+		require := require.New(t)
+
+		token := provider.SignToken(t, map[string]interface{}{"email": "alice@example.com"})
+		require.NoError(c.Authenticate(ctx, token, "GetProject", nil))
+		require.NoError(c.Authorize(ctx, token, "GetProject", &emptypb.Empty{}))
+
+		err := c.Authorize(ctx, token, "DeleteTarget", &emptypb.Empty{})
+		require.Error(err)
+		require.Equal(codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("maps groups to roles", func(t *testing.T) {
+		require := require.New(t)
+
+		// Users don't have to exist if their groups map to roles
+		token := provider.SignToken(t, map[string]interface{}{
+			"email":  "bob@example.com",
+			"groups": []string{"vagrant-ops", "other"},
+		})
+		require.NoError(c.Authenticate(ctx, token, "DeleteTarget", nil))
+		require.NoError(c.Authorize(ctx, token, "DeleteTarget", &emptypb.Empty{}))
+		require.Error(c.Authorize(ctx, token, "SetServerConfig", &emptypb.Empty{}))
+	})
+
+	t.Run("rejects users without roles", func(t *testing.T) {
+		require := require.New(t)
+
+		token := provider.SignToken(t, map[string]interface{}{
+			"email":  "mallory@example.com",
+			"groups": []string{"other"},
+		})
+		err := c.Authenticate(ctx, token, "GetProject", nil)
+		require.Error(err)
+		require.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("subjects never get the roles of local users", func(t *testing.T) {
+		require := require.New(t)
+
+		// The user of the bootstrap token is an admin
+		token := provider.SignToken(t, map[string]interface{}{"email": DefaultUser})
+		err := c.Authenticate(ctx, token, "GetProject", nil)
+		require.Error(err)
+		require.Equal(codes.Unauthenticated, status.Code(err))
+		require.Error(c.Authorize(ctx, token, "SetServerConfig", &emptypb.Empty{}))
+	})
+
+	t.Run("rejects invalid tokens", func(t *testing.T) {
+		require := require.New(t)
+
+		expired := provider.SignToken(t, map[string]interface{}{
+			"email": "alice@example.com",
+			"exp":   time.Now().Add(-time.Hour).Unix(),
+		})
+		require.Error(c.Authenticate(ctx, expired, "GetProject", nil))
+
+		other := provider.SignToken(t, map[string]interface{}{
+			"email": "alice@example.com",
+			"aud":   "other-client",
+		})
+		require.Error(c.Authenticate(ctx, other, "GetProject", nil))
+
+		// Exempt endpoints are not affected
+		require.NoError(c.Authenticate(ctx, expired, "GetVersionInfo", nil))
+	})
+
+	t.Run("accepts tokens issued by the server", func(t *testing.T) {
+		require := require.New(t)
+
+		token, err := s.NewLoginToken(DefaultKeyId, "", nil, nil)
+		require.NoError(err)
+		require.NoError(c.Authenticate(ctx, token, "SetServerConfig", nil))
+		require.NoError(c.Authorize(ctx, token, "SetServerConfig", &emptypb.Empty{}))
+	})
+
+	t.Run("login tokens are for the user of the ID token", func(t *testing.T) {
+		require := require.New(t)
+
+		token := provider.SignToken(t, map[string]interface{}{"email": "alice@example.com"})
+		resp, err := s.GenerateLoginToken(tokenContext(token), &emptypb.Empty{})
+		require.NoError(err)
+
+		_, body, err := s.DecodeToken(resp.Token)
+		require.NoError(err)
+		require.Equal(alice, body.User)
+		require.Error(c.Authorize(ctx, resp.Token, "DeleteTarget", &emptypb.Empty{}))
+	})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+	provider := oidc.NewTestProvider(t)
+	provider.Claims = map[string]interface{}{"sub": "alice"}
+
+	// The provider is configured with the server config, like a server
+	// started from its config file would be.
+	impl, err := New(
+		WithDB(testDB(t)),
+		WithConfig(&serverconfig.Config{
+			OIDC: &serverconfig.OIDC{
+				Issuer:   provider.Issuer(),
+				ClientID: provider.ClientID,
+			},
+		}),
+	)
+	require.NoError(t, err)
+	s := impl.(*service)
+	require.NoError(t, s.state.UserPut(&vagrant_server.User{
+		Username: oidcUsername(provider.Issuer(), "alice"),
+		Roles:    []*vagrant_server.RoleBinding{{Role: state.RoleOperator}},
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go server.Run(
+		server.WithContext(ctx),
+		server.WithGRPC(ln),
+		server.WithImpl(impl),
+		server.WithAuthentication(s),
+	)
+
+	// connect returns a client presenting the token to the server
+	connect := func(t *testing.T, token string) vagrant_server.VagrantClient {
+		conn, err := serverclient.Connect(ctx, serverclient.FromContextConfig(&clicontext.Config{
+			Server: serverconfig.Client{
+				Address:     ln.Addr().String(),
+				RequireAuth: true,
+				AuthToken:   token,
+			},
+		}))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		return vagrant_server.NewVagrantClient(conn)
+	}
+
+	cfg := &oidc.Config{Issuer: provider.Issuer(), ClientID: provider.ClientID}
+
+	t.Run("authorization code", func(t *testing.T) {
+		require := require.New(t)
+
+		// The test provider approves the login right away and redirects
+		// back to the login, as a browser would.
+		token, err := oidc.AuthCodeLogin(ctx, cfg, func(u string) error {
+			resp, err := http.Get(u)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		})
+		require.NoError(err)
+
+		client := connect(t, token.IDToken)
+		_, err = client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+		require.NoError(err)
+
+		_, err = client.SetServerConfig(ctx, &vagrant_server.SetServerConfigRequest{
+			Config: &vagrant_server.ServerConfig{},
+		})
+		require.Equal(codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("device", func(t *testing.T) {
+		require := require.New(t)
+
+		token, err := oidc.DeviceLogin(ctx, cfg, func(uri, code string) {})
+		require.NoError(err)
+
+		client := connect(t, token.IDToken)
+		_, err = client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+		require.NoError(err)
+	})
+
+	t.Run("unlinked subjects are rejected", func(t *testing.T) {
+		require := require.New(t)
+
+		token := provider.SignToken(t, map[string]interface{}{"sub": DefaultUser})
+		client := connect(t, token)
+		_, err := client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+		require.Equal(codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -3,6 +3,7 @@ package singleprocess
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"testing"
 	"time"
 
@@ -30,7 +31,7 @@ func TestServiceAuth(t *testing.T) {
 			"addr": "test",
 		}
 
-		token, err := s.NewLoginToken(DefaultKeyId, md, nil)
+		token, err := s.NewLoginToken(DefaultKeyId, "", md, nil)
 		require.NoError(t, err)
 
 		require.True(t, len(token) > 5)
@@ -60,7 +61,7 @@ func TestServiceAuth(t *testing.T) {
 	t.Run("entrypoint token can only access entrypoint APIs", func(t *testing.T) {
 		s := impl.(*service)
 
-		token, err := s.NewLoginToken(DefaultKeyId, nil, &vagrant_server.Token_Entrypoint{})
+		token, err := s.NewLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token_Entrypoint{})
 		require.NoError(t, err)
 
 		{
@@ -81,7 +82,7 @@ func TestServiceAuth(t *testing.T) {
 			"addr": "test",
 		}
 
-		token, err := s.NewLoginToken(DefaultKeyId, md, nil)
+		token, err := s.NewLoginToken(DefaultKeyId, "", md, nil)
 		require.NoError(t, err)
 
 		require.True(t, len(token) > 5)
@@ -115,7 +116,7 @@ func TestServiceAuth(t *testing.T) {
 	t.Run("exchange an invite token", func(t *testing.T) {
 		s := impl.(*service)
 
-		invite, err := s.NewInviteToken(2*time.Second, DefaultKeyId, nil, nil)
+		invite, err := s.NewInviteToken(2*time.Second, DefaultKeyId, "", nil, nil)
 		require.NoError(t, err)
 
 		lt, err := s.ExchangeInvite(DefaultKeyId, invite)
@@ -137,7 +138,7 @@ func TestServiceAuth(t *testing.T) {
 
 		entry := &vagrant_server.Token_Entrypoint{DeploymentId: "foo"}
 
-		invite, err := s.NewInviteToken(2*time.Second, DefaultKeyId, nil, entry)
+		invite, err := s.NewInviteToken(2*time.Second, DefaultKeyId, "", nil, entry)
 		require.NoError(t, err)
 
 		lt, err := s.ExchangeInvite(DefaultKeyId, invite)
@@ -180,3 +181,107 @@ func TestServiceBootstrapToken(t *testing.T) {
 		require.Nil(resp)
 	}
 }
+
+func TestServiceAuth_revoke(t *testing.T) {
+	ctx := context.Background()
+
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(t, err)
+	s := impl.(*service)
+
+	t.Run("revoked tokens are rejected", func(t *testing.T) {
+		require := require.New(t)
+
+		token, err := s.NewLoginToken(DefaultKeyId, "", nil, nil)
+		require.NoError(err)
+		require.NoError(s.Authenticate(ctx, token, "test", nil))
+
+		_, body, err := s.DecodeToken(token)
+		require.NoError(err)
+		id := hex.EncodeToString(body.TokenId)
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		revokedCh, err := s.WatchToken(watchCtx, token)
+		require.NoError(err)
+
+		resp, err := s.RevokeToken(ctx, &vagrant_server.RevokeTokenRequest{Id: id})
+		require.NoError(err)
+		require.True(resp.Token.Revoked)
+
+		select {
+		case <-revokedCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("token watch should be notified of the revocation")
+		}
+
+		require.Error(s.Authenticate(ctx, token, "test", nil))
+	})
+
+	t.Run("lists issued tokens with their creator", func(t *testing.T) {
+		require := require.New(t)
+
+		invite, err := s.NewInviteToken(time.Minute, DefaultKeyId, "", nil, nil)
+		require.NoError(err)
+		_, inviteBody, err := s.DecodeToken(invite)
+		require.NoError(err)
+		inviteId := hex.EncodeToString(inviteBody.TokenId)
+
+		login, err := s.ExchangeInvite(DefaultKeyId, invite)
+		require.NoError(err)
+		_, loginBody, err := s.DecodeToken(login)
+		require.NoError(err)
+
+		resp, err := s.ListTokens(ctx, &vagrant_server.ListTokensRequest{})
+		require.NoError(err)
+
+		tokens := map[string]*vagrant_server.IssuedToken{}
+		for _, token := range resp.Tokens {
+			require.False(token.Revoked)
+			tokens[token.Id] = token
+		}
+
+		require.Contains(tokens, inviteId)
+		require.Equal(vagrant_server.IssuedToken_INVITE, tokens[inviteId].Purpose)
+		require.NotNil(tokens[inviteId].ValidUntil)
+
+		loginToken := tokens[hex.EncodeToString(loginBody.TokenId)]
+		require.NotNil(loginToken)
+		require.Equal(vagrant_server.IssuedToken_LOGIN, loginToken.Purpose)
+		require.Equal(inviteId, loginToken.CreatorId)
+
+		// A revoked invite can't be exchanged
+		_, err = s.RevokeToken(ctx, &vagrant_server.RevokeTokenRequest{Id: inviteId})
+		require.NoError(err)
+		_, err = s.ExchangeInvite(DefaultKeyId, invite)
+		require.Error(err)
+	})
+
+	t.Run("tokens without an ID are rejected once their key is retired", func(t *testing.T) {
+		require := require.New(t)
+
+		impl, err := New(WithDB(testDB(t)))
+		require.NoError(err)
+		s := impl.(*service)
+
+		// Tokens issued before tokens were recorded have no ID
+		token, err := s.GenerateToken(DefaultKeyId, nil, &vagrant_server.Token{
+			Login: true,
+			User:  DefaultUser,
+		})
+		require.NoError(err)
+		require.NoError(s.Authenticate(ctx, token, "test", nil))
+
+		_, err = s.RotateHMACKey(ctx, &vagrant_server.RotateHMACKeyRequest{})
+		require.NoError(err)
+		_, err = s.RetireHMACKey(ctx, &vagrant_server.RetireHMACKeyRequest{Id: DefaultKeyId})
+		require.NoError(err)
+		require.Error(s.Authenticate(ctx, token, "test", nil))
+	})
+
+	t.Run("revoke unknown token", func(t *testing.T) {
+		_, err := s.RevokeToken(ctx, &vagrant_server.RevokeTokenRequest{Id: "nope"})
+		require.Error(t, err)
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
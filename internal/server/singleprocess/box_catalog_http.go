@@ -0,0 +1,278 @@
+package singleprocess
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// BoxCatalogPath is the HTTP path prefix the box catalog is served at.
+// The box metadata is available at <prefix><box name> which can be used
+// as the metadata URL when adding a box. Box files are available at
+// <prefix><box name>/versions/<version>/providers/<provider>.box and
+// can be published by uploading to the same path with a PUT request.
+// If the server requires authentication, these requests need the same
+// permissions as the matching RPCs. The local server the CLI starts
+// serves these on a random port of the loopback interface.
+const BoxCatalogPath = "/box-catalog/"
+
+// HTTPHandlers implements server.HTTPHandlerService
+func (s *service) HTTPHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		BoxCatalogPath: http.HandlerFunc(s.serveBoxCatalog),
+	}
+}
+
+// Box metadata in the format used by box metadata URLs
+type catalogMetadata struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Versions    []catalogMetadataVersion `json:"versions"`
+}
+
+type catalogMetadataVersion struct {
+	Version     string                    `json:"version"`
+	Status      string                    `json:"status"`
+	Description string                    `json:"description"`
+	Providers   []catalogMetadataProvider `json:"providers"`
+}
+
+type catalogMetadataProvider struct {
+	Name         string `json:"name"`
+	Url          string `json:"url"`
+	Checksum     string `json:"checksum"`
+	ChecksumType string `json:"checksum_type"`
+}
+
+// HTTPEndpoint implements server.HTTPEndpointService. Reading box metadata
+// and box files over HTTP requires the same permissions as GetCatalogBox
+// and DownloadCatalogBox, publishing a box the same as PublishCatalogBox.
+func (s *service) HTTPEndpoint(r *http.Request) (string, interface{}) {
+	if !strings.HasPrefix(r.URL.Path, BoxCatalogPath) {
+		return "", nil
+	}
+
+	name, ver, provider, ok := parseBoxCatalogPath(r.URL.Path)
+	if !ok {
+		return "", nil
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if ver == "" {
+			return "GetCatalogBox", &vagrant_server.GetCatalogBoxRequest{Name: name}
+		}
+
+		return "DownloadCatalogBox", &vagrant_server.DownloadCatalogBoxRequest{
+			Name:     name,
+			Version:  ver,
+			Provider: provider,
+		}
+
+	case http.MethodPut:
+		if ver == "" {
+			return "", nil
+		}
+
+		return "PublishCatalogBox", &vagrant_server.PublishCatalogBoxRequest{
+			Event: &vagrant_server.PublishCatalogBoxRequest_Open_{
+				Open: &vagrant_server.PublishCatalogBoxRequest_Open{
+					Name:     name,
+					Version:  ver,
+					Provider: provider,
+				},
+			},
+		}
+	}
+
+	return "", nil
+}
+
+func (s *service) serveBoxCatalog(w http.ResponseWriter, r *http.Request) {
+	name, ver, provider, ok := parseBoxCatalogPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if ver == "" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.serveBoxCatalogMetadata(w, r, name)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.serveBoxCatalogFile(w, r, name, ver, provider)
+	case http.MethodPut:
+		s.serveBoxCatalogPublish(w, r, name, ver, provider)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *service) serveBoxCatalogMetadata(w http.ResponseWriter, r *http.Request, name string) {
+	box, err := s.state.CatalogBoxGet(name)
+	if err != nil {
+		writeBoxCatalogError(w, err)
+		return
+	}
+
+	base := s.boxCatalogBaseURL(r)
+	result := catalogMetadata{
+		Name:        box.Name,
+		Description: box.Description,
+		Versions:    []catalogMetadataVersion{},
+	}
+	for _, v := range box.Versions {
+		ver := catalogMetadataVersion{
+			Version:     v.Version,
+			Status:      v.Status,
+			Description: v.Description,
+			Providers:   []catalogMetadataProvider{},
+		}
+		for _, p := range v.Providers {
+			ver.Providers = append(ver.Providers, catalogMetadataProvider{
+				Name:         p.Name,
+				Url:          base + boxCatalogFilePath(box.Name, v.Version, p.Name),
+				Checksum:     p.Checksum,
+				ChecksumType: p.ChecksumType,
+			})
+		}
+		result.Versions = append(result.Versions, ver)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&result); err != nil {
+		hclog.FromContext(r.Context()).Warn("failed to write box catalog metadata", "error", err)
+	}
+}
+
+func (s *service) serveBoxCatalogFile(w http.ResponseWriter, r *http.Request, name, ver, provider string) {
+	_, f, err := s.openCatalogBox(name, ver, provider)
+	if err != nil {
+		writeBoxCatalogError(w, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeBoxCatalogError(w, err)
+		return
+	}
+
+	// ServeContent handles range requests so interrupted downloads
+	// can be resumed
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, provider+".box", info.ModTime(), f)
+}
+
+func (s *service) serveBoxCatalogPublish(w http.ResponseWriter, r *http.Request, name, ver, provider string) {
+	query := r.URL.Query()
+	force, _ := strconv.ParseBool(query.Get("force"))
+	box, err := s.publishCatalogBox(r.Context(), hclog.FromContext(r.Context()),
+		&vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:               name,
+			Description:        query.Get("description"),
+			Version:            ver,
+			VersionDescription: query.Get("version_description"),
+			Provider:           provider,
+			Checksum:           query.Get("checksum"),
+			Force:              force,
+		},
+		r.Body,
+	)
+	if err != nil {
+		writeBoxCatalogError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"name":     box.Name,
+		"version":  ver,
+		"provider": provider,
+	})
+}
+
+// Returns the box name of a box catalog path, and the version and provider
+// if the path is of a box file.
+func parseBoxCatalogPath(path string) (name, ver, provider string, ok bool) {
+	path = strings.TrimPrefix(path, BoxCatalogPath)
+	idx := strings.Index(path, "/versions/")
+	if idx < 0 {
+		return path, "", "", true
+	}
+	name, file := path[:idx], path[idx+len("/versions/"):]
+
+	// Box files are located at <version>/providers/<provider>.box
+	parts := strings.Split(file, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] != "providers" || !strings.HasSuffix(parts[2], ".box") {
+		return "", "", "", false
+	}
+
+	return name, parts[0], strings.TrimSuffix(parts[2], ".box"), true
+}
+
+// Returns the path of the box file relative to the server address
+func boxCatalogFilePath(name, ver, provider string) string {
+	return BoxCatalogPath + name + "/versions/" + url.PathEscape(ver) +
+		"/providers/" + url.PathEscape(provider) + ".box"
+}
+
+// Returns the URL box files are linked with. This is the configured box
+// catalog URL if set. Otherwise it is the scheme and host the request was
+// made to, which are only taken from the X-Forwarded headers if these are
+// trusted.
+func (s *service) boxCatalogBaseURL(r *http.Request) string {
+	if s.boxCatalogURL != "" {
+		return strings.TrimSuffix(s.boxCatalogURL, "/")
+	}
+
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s.trustForwardedHeaders {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fwdHost := r.Header.Get("X-Forwarded-Host"); fwdHost != "" {
+			host = fwdHost
+		}
+	}
+
+	return scheme + "://" + host
+}
+
+// Writes the error using the HTTP status matching the gRPC status code
+func writeBoxCatalogError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.AlreadyExists:
+		code = http.StatusConflict
+	case codes.FailedPrecondition:
+		code = http.StatusPreconditionFailed
+	}
+	http.Error(w, st.Message(), code)
+}
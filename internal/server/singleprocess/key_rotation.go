@@ -0,0 +1,62 @@
+package singleprocess
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// keyRotationIdleInterval is how long the key rotation waits before
+// checking again when the server has no signing key yet.
+var keyRotationIdleInterval = 1 * time.Hour
+
+// runKeyRotation rotates the token signing key once it has been used for
+// the rotation interval. This runs until the context is cancelled.
+func (s *service) runKeyRotation(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	funclog hclog.Logger,
+) {
+	defer wg.Done()
+
+	funclog.Info("starting", "interval", s.keyRotateInterval, "retire-after", s.keyRetireAfter)
+	defer funclog.Info("exiting")
+
+	for {
+		next := time.Now().Add(keyRotationIdleInterval)
+
+		key, err := s.state.HMACKeyActive()
+		if err != nil {
+			funclog.Error("error getting the active key", "error", err)
+		}
+
+		// We never create the first key since that would prevent the
+		// server from being bootstrapped.
+		if key != nil {
+			due := key.CreateTime.AsTime().Add(s.keyRotateInterval)
+			if !due.After(time.Now()) {
+				key, err = s.state.HMACKeyRotate(hmacKeySize, s.keyRetireAfter)
+				if err != nil {
+					funclog.Error("error rotating the key", "error", err)
+				} else {
+					funclog.Info("rotated the token signing key", "id", key.Id)
+					due = key.CreateTime.AsTime().Add(s.keyRotateInterval)
+				}
+			}
+
+			if due.Before(next) {
+				next = due
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package singleprocess
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/oidc"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// Authorize implements server.Authorizer. The token may call the endpoint
+// if a role bound to its user permits the endpoint. If the role binding is
+// scoped to a basis or project, the request must only refer to that basis
+// or project. Requests without a token have the roles of the user their
+// client certificate is mapped to.
+func (s *service) Authorize(ctx context.Context, token, endpoint string, req interface{}) error {
+	if authExempt(endpoint) {
+		return nil
+	}
+
+	// The roles of an ID token are the roles bound to its user plus the
+	// roles mapped from its groups.
+	if s.oidcAuth != nil && oidc.IsJWT(token) {
+		username, bindings, err := s.oidcAuth.identity(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		return s.authorizeBindings(username, bindings, endpoint, req)
+	}
+
+	if token == "" {
+		if user := s.certUser(ctx); user != "" {
+			return s.authorize(&vagrant_server.Token{User: user}, endpoint, req)
+		}
+	}
+
+	_, body, err := s.DecodeToken(token)
+	if err != nil {
+		return err
+	}
+
+	return s.authorize(body, endpoint, req)
+}
+
+// authorize checks if the token body permits calling the endpoint with the
+// request. A nil request is permitted if any of the roles of the token
+// permit the endpoint, whatever their scope.
+func (s *service) authorize(body *vagrant_server.Token, endpoint string, req interface{}) error {
+	user, err := s.state.UserGet(body.User)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied, "unknown user %q", body.User)
+	}
+
+	// Tokens restricted to some roles only get the permissions of those
+	bindings := user.Roles
+	if len(body.Roles) > 0 {
+		bindings = nil
+		for _, binding := range user.Roles {
+			if containsFold(body.Roles, binding.Role) {
+				bindings = append(bindings, binding)
+			}
+		}
+	}
+
+	return s.authorizeBindings(user.Username, bindings, endpoint, req)
+}
+
+// authorizeBindings checks if any of the role bindings permit calling the
+// endpoint with the request.
+func (s *service) authorizeBindings(
+	username string,
+	bindings []*vagrant_server.RoleBinding,
+	endpoint string,
+	req interface{},
+) error {
+	var scope *requestScope
+	for _, binding := range bindings {
+		role, err := s.state.RoleGet(binding.Role)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !rolePermits(role, endpoint) {
+			continue
+		}
+
+		if binding.Scope == nil || req == nil {
+			return nil
+		}
+
+		// Only find the scope of the request once it is needed since this
+		// requires looking up the refs it contains.
+		if scope == nil {
+			scope = s.requestScope(req)
+		}
+		if scope.matches(binding) {
+			return nil
+		}
+	}
+
+	return status.Errorf(codes.PermissionDenied,
+		"user %q is not permitted to call %s", username, endpoint)
+}
+
+// rolePermits returns true if the permissions of the role match the
+// endpoint and none of its exclusions do.
+func rolePermits(role *vagrant_server.Role, endpoint string) bool {
+	permitted := false
+	for _, p := range role.Permissions {
+		exclude := strings.HasPrefix(p, "!")
+		if !permissionMatches(strings.TrimPrefix(p, "!"), endpoint) {
+			continue
+		}
+		if exclude {
+			return false
+		}
+
+		permitted = true
+	}
+
+	return permitted
+}
+
+// permissionMatches returns true if the endpoint matches the permission. A
+// trailing "*" matches any endpoint with the preceding prefix.
+func permissionMatches(permission, endpoint string) bool {
+	if prefix := strings.TrimSuffix(permission, "*"); prefix != permission {
+		return strings.HasPrefix(endpoint, prefix)
+	}
+
+	return permission == endpoint
+}
+
+// Returns the body of the invite token to create for the request. Inviting
+// a user other than the one of the requesting token requires permission to
+// manage users. Otherwise a token restricted to some roles can only invite
+// with those roles.
+func (s *service) inviteTokenBody(
+	ctx context.Context,
+	req *vagrant_server.InviteTokenRequest,
+) (*vagrant_server.Token, error) {
+	body := &vagrant_server.Token{
+		User:       req.User,
+		Roles:      req.Roles,
+		Entrypoint: req.Entrypoint,
+	}
+
+	caller := s.requestToken(ctx)
+	if body.User == "" {
+		body.User = DefaultUser
+		if caller != nil {
+			body.User = caller.User
+		}
+	}
+	if _, err := s.state.UserGet(body.User); err != nil {
+		return nil, err
+	}
+
+	// Without authentication there is nothing to restrict
+	if caller == nil {
+		return body, nil
+	}
+
+	if !strings.EqualFold(body.User, caller.User) {
+		// An empty request has no scope, so this requires the permission
+		// for all bases and projects.
+		if err := s.authorize(caller, "UpsertUser", &emptypb.Empty{}); err != nil {
+			return nil, status.Errorf(codes.PermissionDenied,
+				"inviting another user requires permission to manage users")
+		}
+
+		return body, nil
+	}
+
+	if len(caller.Roles) > 0 {
+		if len(body.Roles) == 0 {
+			body.Roles = caller.Roles
+		}
+		for _, role := range body.Roles {
+			if !containsFold(caller.Roles, role) {
+				return nil, status.Errorf(codes.PermissionDenied,
+					"token is not permitted to invite with role %q", role)
+			}
+		}
+	}
+
+	return body, nil
+}
+
+// Returns true if the endpoint can be called without authentication.
+func authExempt(endpoint string) bool {
+	// We always allow ConvertInviteToken so that folks can actually get authentication data
+	return endpoint == "ConvertInviteToken" || endpoint == "BootstrapToken" || endpoint == "GetVersionInfo"
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maxScopeDepth limits how deeply requests are searched for refs.
+const maxScopeDepth = 4
+
+// requestScope is the bases and projects that a request refers to.
+type requestScope struct {
+	bases    []*vagrant_plugin_sdk.Ref_Basis
+	projects []*vagrant_plugin_sdk.Ref_Project
+
+	// noBasis is set if the basis of a project is unknown and unresolved
+	// is set if the project of a target is unknown. Scoped role bindings
+	// never match such requests.
+	noBasis    bool
+	unresolved bool
+}
+
+// requestScope finds the bases and projects the request refers to. Refs
+// are resolved using the state so that a ref to a target or project also
+// counts as a ref to the project or basis it belongs to.
+func (s *service) requestScope(req interface{}) *requestScope {
+	var result requestScope
+	if m, ok := req.(proto.Message); ok {
+		s.collectScope(&result, m.ProtoReflect(), 0)
+	}
+
+	return &result
+}
+
+func (s *service) collectScope(r *requestScope, m protoreflect.Message, depth int) {
+	switch v := m.Interface().(type) {
+	case *vagrant_plugin_sdk.Ref_Basis:
+		r.bases = append(r.bases, s.resolveBasis(v))
+		return
+
+	case *vagrant_plugin_sdk.Ref_Project:
+		s.addProject(r, v)
+		return
+
+	case *vagrant_plugin_sdk.Ref_Target:
+		s.addTarget(r, v)
+		return
+
+	case *vagrant_plugin_sdk.Ref_Machine:
+		s.addTarget(r, &vagrant_plugin_sdk.Ref_Target{
+			ResourceId: v.ResourceId,
+			Project:    v.Project,
+		})
+		return
+
+	// Updates of existing records also refer to the scope the record is
+	// in now, so that records can't be moved out of another scope.
+	case *vagrant_server.Basis:
+		if v.ResourceId != "" {
+			r.bases = append(r.bases, s.resolveBasis(
+				&vagrant_plugin_sdk.Ref_Basis{ResourceId: v.ResourceId}))
+		}
+	case *vagrant_server.Project:
+		if v.ResourceId != "" {
+			if _, err := s.state.ProjectGet(&vagrant_plugin_sdk.Ref_Project{ResourceId: v.ResourceId}); err == nil {
+				s.addProject(r, &vagrant_plugin_sdk.Ref_Project{ResourceId: v.ResourceId})
+			}
+		}
+	case *vagrant_server.Target:
+		if v.ResourceId != "" {
+			if _, err := s.state.TargetGet(&vagrant_plugin_sdk.Ref_Target{ResourceId: v.ResourceId}); err == nil {
+				s.addTarget(r, &vagrant_plugin_sdk.Ref_Target{ResourceId: v.ResourceId})
+			}
+		}
+	}
+
+	if depth >= maxScopeDepth {
+		return
+	}
+
+	// Lists are not searched since they contain the children of a record,
+	// such as the projects of a basis, rather than what the request is for.
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() {
+			s.collectScope(r, v.Message(), depth+1)
+		}
+
+		return true
+	})
+}
+
+func (s *service) addProject(r *requestScope, ref *vagrant_plugin_sdk.Ref_Project) {
+	project := s.resolveProject(ref)
+	r.projects = append(r.projects, project)
+	if project.Basis == nil {
+		r.noBasis = true
+		return
+	}
+
+	r.bases = append(r.bases, s.resolveBasis(project.Basis))
+}
+
+func (s *service) addTarget(r *requestScope, ref *vagrant_plugin_sdk.Ref_Target) {
+	project := ref.Project
+	if ref.ResourceId != "" {
+		if target, err := s.state.TargetGet(ref); err == nil && target.Project != nil {
+			project = target.Project
+		}
+	}
+	if project == nil {
+		r.unresolved = true
+		return
+	}
+
+	s.addProject(r, project)
+}
+
+// resolveBasis returns a ref with the resource ID and name of the basis if
+// it exists. Otherwise the ref is returned as is.
+func (s *service) resolveBasis(ref *vagrant_plugin_sdk.Ref_Basis) *vagrant_plugin_sdk.Ref_Basis {
+	if ref.ResourceId == "" {
+		return ref
+	}
+
+	basis, err := s.state.BasisGet(ref)
+	if err != nil {
+		return ref
+	}
+
+	return &vagrant_plugin_sdk.Ref_Basis{
+		ResourceId: basis.ResourceId,
+		Name:       basis.Name,
+	}
+}
+
+// resolveProject returns a ref with the resource ID, name and basis of the
+// project if it exists. Otherwise the ref is returned as is.
+func (s *service) resolveProject(ref *vagrant_plugin_sdk.Ref_Project) *vagrant_plugin_sdk.Ref_Project {
+	if ref.ResourceId == "" {
+		return ref
+	}
+
+	project, err := s.state.ProjectGet(ref)
+	if err != nil {
+		return ref
+	}
+
+	basis := project.Basis
+	if basis == nil {
+		basis = ref.Basis
+	}
+
+	return &vagrant_plugin_sdk.Ref_Project{
+		ResourceId: project.ResourceId,
+		Name:       project.Name,
+		Basis:      basis,
+	}
+}
+
+// matches returns true if the request is only for the basis or project the
+// role binding is scoped to.
+func (r *requestScope) matches(binding *vagrant_server.RoleBinding) bool {
+	if r.unresolved {
+		return false
+	}
+
+	switch scope := binding.Scope.(type) {
+	case *vagrant_server.RoleBinding_Basis:
+		if r.noBasis || len(r.bases) == 0 {
+			return false
+		}
+		for _, basis := range r.bases {
+			if !refMatches(scope.Basis.ResourceId, scope.Basis.Name, basis.ResourceId, basis.Name) {
+				return false
+			}
+		}
+
+		return true
+
+	case *vagrant_server.RoleBinding_Project:
+		if len(r.projects) == 0 {
+			return false
+		}
+		for _, project := range r.projects {
+			if !refMatches(scope.Project.ResourceId, scope.Project.Name, project.ResourceId, project.Name) {
+				return false
+			}
+		}
+
+		// Any basis the request refers to must be the basis of the project
+		for _, basis := range r.bases {
+			pb := r.projects[0].Basis
+			if pb == nil || !refMatches(pb.ResourceId, pb.Name, basis.ResourceId, basis.Name) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// refMatches compares two refs by resource ID if both have one, or by name
+// otherwise.
+func refMatches(wantId, wantName, id, name string) bool {
+	if wantId != "" && id != "" {
+		return wantId == id
+	}
+
+	return wantName != "" && wantName == name
+}
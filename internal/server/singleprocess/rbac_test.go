@@ -0,0 +1,279 @@
+package singleprocess
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
+)
+
+func TestServiceAuthorize(t *testing.T) {
+	ctx := context.Background()
+
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(t, err)
+	s := impl.(*service)
+
+	// Two projects in two bases
+	var projects []*vagrant_plugin_sdk.Ref_Project
+	for _, name := range []string{"one", "two"} {
+		basisResp, err := s.UpsertBasis(ctx, &vagrant_server.UpsertBasisRequest{
+			Basis: &vagrant_server.Basis{Name: name},
+		})
+		require.NoError(t, err)
+
+		projectResp, err := s.UpsertProject(ctx, &vagrant_server.UpsertProjectRequest{
+			Project: &vagrant_server.Project{
+				Name:  name,
+				Basis: &vagrant_plugin_sdk.Ref_Basis{ResourceId: basisResp.Basis.ResourceId},
+			},
+		})
+		require.NoError(t, err)
+
+		projects = append(projects, &vagrant_plugin_sdk.Ref_Project{
+			ResourceId: projectResp.Project.ResourceId,
+		})
+	}
+
+	// Returns a login token for a user with the given role bindings
+	userToken := func(t *testing.T, username string, roles ...*vagrant_server.RoleBinding) string {
+		require.NoError(t, s.state.UserPut(&vagrant_server.User{
+			Username: username,
+			Roles:    roles,
+		}))
+
+		token, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{User: username})
+		require.NoError(t, err)
+		return token
+	}
+
+	deleteTarget := func(project *vagrant_plugin_sdk.Ref_Project) *vagrant_server.DeleteTargetRequest {
+		return &vagrant_server.DeleteTargetRequest{
+			Project: project,
+			Target:  &vagrant_plugin_sdk.Ref_Target{Project: project, Name: "web"},
+		}
+	}
+
+	t.Run("the default user is an admin", func(t *testing.T) {
+		require := require.New(t)
+
+		token, err := s.NewLoginToken(DefaultKeyId, "", nil, nil)
+		require.NoError(err)
+		require.NoError(s.Authorize(ctx, token, "SetServerConfig", &emptypb.Empty{}))
+	})
+
+	t.Run("built-in roles", func(t *testing.T) {
+		require := require.New(t)
+
+		readonly := userToken(t, "reader", &vagrant_server.RoleBinding{Role: state.RoleReadOnly})
+		require.NoError(s.Authorize(ctx, readonly, "GetProject",
+			&vagrant_server.GetProjectRequest{Project: projects[0]}))
+		for _, endpoint := range []string{"DeleteTarget", "GetConfig", "SetServerConfig", "ListTokens"} {
+			err := s.Authorize(ctx, readonly, endpoint, deleteTarget(projects[0]))
+			require.Error(err, endpoint)
+			require.Equal(codes.PermissionDenied, status.Code(err))
+		}
+
+		operator := userToken(t, "operator", &vagrant_server.RoleBinding{Role: state.RoleOperator})
+		require.NoError(s.Authorize(ctx, operator, "DeleteTarget", deleteTarget(projects[0])))
+		require.Error(s.Authorize(ctx, operator, "RestoreSnapshot", nil))
+		require.Error(s.Authorize(ctx, operator, "UpsertUser", &emptypb.Empty{}))
+		require.Error(s.Authorize(ctx, operator, "PruneOldJobs", &emptypb.Empty{}))
+
+		// Endpoints the operator role doesn't list aren't permitted
+		require.Error(s.Authorize(ctx, operator, "UnknownEndpoint", &emptypb.Empty{}))
+	})
+
+	t.Run("operator permissions are endpoints", func(t *testing.T) {
+		endpoints := map[string]bool{"_ListJobs": true}
+		for _, m := range vagrant_server.Vagrant_ServiceDesc.Methods {
+			endpoints[m.MethodName] = true
+		}
+		for _, m := range vagrant_server.Vagrant_ServiceDesc.Streams {
+			endpoints[m.StreamName] = true
+		}
+
+		role, err := s.state.RoleGet(state.RoleOperator)
+		require.NoError(t, err)
+		for _, p := range role.Permissions {
+			p = strings.TrimPrefix(p, "!")
+			if strings.HasSuffix(p, "*") {
+				continue
+			}
+			require.True(t, endpoints[p], p)
+		}
+	})
+
+	t.Run("scoped to a project", func(t *testing.T) {
+		require := require.New(t)
+
+		token := userToken(t, "scoped", &vagrant_server.RoleBinding{
+			Role:  state.RoleOperator,
+			Scope: &vagrant_server.RoleBinding_Project{Project: projects[0]},
+		})
+
+		require.NoError(s.Authorize(ctx, token, "DeleteTarget", deleteTarget(projects[0])))
+		require.Error(s.Authorize(ctx, token, "DeleteTarget", deleteTarget(projects[1])))
+
+		// A request mixing both projects is denied
+		req := deleteTarget(projects[0])
+		req.Target.Project = projects[1]
+		require.Error(s.Authorize(ctx, token, "DeleteTarget", req))
+
+		// Requests without a scope are denied
+		require.Error(s.Authorize(ctx, token, "ListProjects", &emptypb.Empty{}))
+
+		// Streams are permitted until their first message is authorized
+		require.NoError(s.Authorize(ctx, token, "GetLogStream", nil))
+	})
+
+	t.Run("scoped to a basis", func(t *testing.T) {
+		require := require.New(t)
+
+		basis := &vagrant_plugin_sdk.Ref_Basis{Name: "two"}
+		token := userToken(t, "basis", &vagrant_server.RoleBinding{
+			Role:  state.RoleReadOnly,
+			Scope: &vagrant_server.RoleBinding_Basis{Basis: basis},
+		})
+
+		// The basis of the project is found from its resource ID
+		require.NoError(s.Authorize(ctx, token, "GetProject",
+			&vagrant_server.GetProjectRequest{Project: projects[1]}))
+		require.Error(s.Authorize(ctx, token, "GetProject",
+			&vagrant_server.GetProjectRequest{Project: projects[0]}))
+	})
+
+	t.Run("tokens restricted to roles", func(t *testing.T) {
+		require := require.New(t)
+
+		require.NoError(s.state.UserPut(&vagrant_server.User{
+			Username: "restricted",
+			Roles: []*vagrant_server.RoleBinding{
+				{Role: state.RoleReadOnly},
+				{Role: state.RoleAdmin},
+			},
+		}))
+
+		token, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{
+			User:  "restricted",
+			Roles: []string{state.RoleReadOnly},
+		})
+		require.NoError(err)
+
+		require.NoError(s.Authorize(ctx, token, "GetProject",
+			&vagrant_server.GetProjectRequest{Project: projects[0]}))
+		require.Error(s.Authorize(ctx, token, "SetServerConfig", &emptypb.Empty{}))
+
+		// Login tokens generated with the token keep the restriction
+		resp, err := s.GenerateLoginToken(tokenContext(token), &emptypb.Empty{})
+		require.NoError(err)
+		_, body, err := s.DecodeToken(resp.Token)
+		require.NoError(err)
+		require.Equal("restricted", body.User)
+		require.Equal([]string{state.RoleReadOnly}, body.Roles)
+
+		// And it can't invite with other roles
+		_, err = s.GenerateInviteToken(tokenContext(token), &vagrant_server.InviteTokenRequest{
+			Duration: "1m",
+			Roles:    []string{state.RoleAdmin},
+		})
+		require.Error(err)
+		require.Equal(codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("invites for other users", func(t *testing.T) {
+		require := require.New(t)
+
+		operator := userToken(t, "inviter", &vagrant_server.RoleBinding{Role: state.RoleOperator})
+		_, err := s.GenerateInviteToken(tokenContext(operator), &vagrant_server.InviteTokenRequest{
+			Duration: "1m",
+			User:     "reader",
+		})
+		require.Error(err)
+		require.Equal(codes.PermissionDenied, status.Code(err))
+
+		admin, err := s.NewLoginToken(DefaultKeyId, "", nil, nil)
+		require.NoError(err)
+		resp, err := s.GenerateInviteToken(tokenContext(admin), &vagrant_server.InviteTokenRequest{
+			Duration: "1m",
+			User:     "reader",
+		})
+		require.NoError(err)
+
+		login, err := s.ExchangeInvite(DefaultKeyId, resp.Token)
+		require.NoError(err)
+		_, body, err := s.DecodeToken(login)
+		require.NoError(err)
+		require.Equal("reader", body.User)
+		require.Error(s.Authorize(ctx, login, "DeleteTarget", deleteTarget(projects[0])))
+	})
+
+	t.Run("tokens of deleted users are rejected", func(t *testing.T) {
+		require := require.New(t)
+
+		token := userToken(t, "deleted", &vagrant_server.RoleBinding{Role: state.RoleAdmin})
+		require.NoError(s.Authenticate(ctx, token, "GetProject", nil))
+
+		_, err := s.DeleteUser(ctx, &vagrant_server.DeleteUserRequest{Username: "deleted"})
+		require.NoError(err)
+		require.Error(s.Authenticate(ctx, token, "GetProject", nil))
+		require.Error(s.Authorize(ctx, token, "GetProject", &emptypb.Empty{}))
+	})
+
+	t.Run("custom roles", func(t *testing.T) {
+		require := require.New(t)
+
+		_, err := s.UpsertRole(ctx, &vagrant_server.UpsertRoleRequest{
+			Role: &vagrant_server.Role{
+				Name:        "jobs",
+				Permissions: []string{"GetJob"},
+			},
+		})
+		require.NoError(err)
+
+		token := userToken(t, "jobs", &vagrant_server.RoleBinding{Role: "jobs"})
+		require.NoError(s.Authorize(ctx, token, "GetJob", &emptypb.Empty{}))
+		require.Error(s.Authorize(ctx, token, "QueueJob", &emptypb.Empty{}))
+
+		// Changes to the role apply to existing tokens
+		_, err = s.UpsertRole(ctx, &vagrant_server.UpsertRoleRequest{
+			Role: &vagrant_server.Role{Name: "jobs", Permissions: []string{"QueueJob"}},
+		})
+		require.NoError(err)
+		require.NoError(s.Authorize(ctx, token, "QueueJob", &emptypb.Empty{}))
+		require.Error(s.Authorize(ctx, token, "GetJob", &emptypb.Empty{}))
+
+		_, err = s.DeleteRole(ctx, &vagrant_server.DeleteRoleRequest{Name: "jobs"})
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestRolePermits(t *testing.T) {
+	role := &vagrant_server.Role{
+		Permissions: []string{"Get*", "ListTargets", "!GetConfig"},
+	}
+
+	require.True(t, rolePermits(role, "GetProject"))
+	require.True(t, rolePermits(role, "ListTargets"))
+	require.False(t, rolePermits(role, "ListTargetsAll"))
+	require.False(t, rolePermits(role, "GetConfig"))
+	require.False(t, rolePermits(role, "DeleteTarget"))
+	require.False(t, rolePermits(&vagrant_server.Role{}, "GetProject"))
+}
+
+// Returns a context for a request authenticated with token.
+func tokenContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.MD{
+		"authorization": []string{token},
+	})
+}
@@ -0,0 +1,119 @@
+package singleprocess
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-memdb"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// scheduleIdleInterval is how long the scheduler waits before checking
+// the schedules again when no schedule is due.
+var scheduleIdleInterval = 1 * time.Hour
+
+// runScheduler queues the jobs of schedules when they are due. This runs
+// until the context is cancelled.
+func (s *service) runScheduler(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	funclog hclog.Logger,
+) {
+	defer wg.Done()
+
+	funclog.Info("starting")
+	defer funclog.Info("exiting")
+
+	for {
+		ws := memdb.NewWatchSet()
+		schedules, err := s.state.ScheduleList(ws)
+		if err != nil {
+			funclog.Error("error listing schedules", "error", err)
+		}
+
+		// Run every schedule that is due and find when the next one is
+		next := time.Now().Add(scheduleIdleInterval)
+		for _, sched := range schedules {
+			// Schedules without a next run will never run again
+			if sched.Disabled || sched.NextRunTime == nil {
+				continue
+			}
+
+			runTime := sched.NextRunTime.AsTime()
+			if !runTime.After(time.Now()) {
+				s.scheduleRun(ctx, sched, funclog)
+				continue
+			}
+
+			if runTime.Before(next) {
+				next = runTime
+			}
+		}
+
+		// Wait until the next schedule is due or the schedules change.
+		// Running a schedule changes it so we loop right away after that.
+		watchCtx, cancel := context.WithDeadline(ctx, next)
+		ws.WatchCtx(watchCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// scheduleRun queues the job of the schedule according to its overlap
+// policy and records the run.
+func (s *service) scheduleRun(
+	ctx context.Context,
+	sched *vagrant_server.Schedule,
+	funclog hclog.Logger,
+) {
+	log := funclog.With("schedule", sched.Id, "name", sched.Name)
+
+	// Check if the previous job is still running
+	if sched.LastJobId != "" && sched.Overlap != vagrant_server.Schedule_ALLOW {
+		job, err := s.state.JobById(sched.LastJobId, nil)
+		if err != nil {
+			log.Warn("error looking up previous job", "job", sched.LastJobId, "error", err)
+		}
+
+		if job != nil && job.State != vagrant_server.Job_SUCCESS && job.State != vagrant_server.Job_ERROR {
+			switch sched.Overlap {
+			case vagrant_server.Schedule_SKIP:
+				log.Info("skipping schedule, previous job is still running", "job", job.Id)
+				if err := s.state.ScheduleRecordRun(sched.Id, "", nil); err != nil {
+					log.Error("error recording schedule run", "error", err)
+				}
+				return
+
+			case vagrant_server.Schedule_CANCEL_PREVIOUS:
+				log.Info("canceling previous job of schedule", "job", job.Id)
+				if err := s.state.JobCancel(job.Id, false); err != nil {
+					log.Warn("error canceling previous job", "job", job.Id, "error", err)
+				}
+			}
+		}
+	}
+
+	// Queue the job through the normal path so it is validated and
+	// treated like any other job.
+	job := proto.Clone(sched.Job).(*vagrant_server.Job)
+	resp, err := s.QueueJob(ctx, &vagrant_server.QueueJobRequest{Job: job})
+
+	var jobId string
+	if err != nil {
+		log.Error("error queueing scheduled job", "error", err)
+	} else {
+		jobId = resp.JobId
+		log.Info("queued scheduled job", "job", jobId)
+	}
+
+	if err := s.state.ScheduleRecordRun(sched.Id, jobId, err); err != nil {
+		log.Error("error recording schedule run", "error", err)
+	}
+}
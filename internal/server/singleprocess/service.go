@@ -2,7 +2,9 @@ package singleprocess
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 
@@ -33,6 +35,36 @@ type service struct {
 	// that we fully shut down before returning.
 	bgWg sync.WaitGroup
 
+	// boxCatalogDir is the directory box files published to the box
+	// catalog are stored in. If empty, publishing boxes is disabled.
+	boxCatalogDir string
+
+	// boxCatalogURL is the external URL of the server that box files in
+	// the box catalog are linked with. If empty, the URL the metadata was
+	// requested with is used, taking X-Forwarded headers into account
+	// only if trustForwardedHeaders is set.
+	boxCatalogURL         string
+	trustForwardedHeaders bool
+
+	// archiveDir is the directory archives uploaded for archive data
+	// sources are stored in. If empty, uploading archives is disabled.
+	archiveDir string
+
+	// keyRotateInterval is how often the token signing key is rotated. If
+	// zero, keys are only rotated manually. Once rotated, the previous
+	// key is retired after keyRetireAfter, unless that is zero.
+	keyRotateInterval time.Duration
+	keyRetireAfter    time.Duration
+
+	// oidcAuth is set if the service accepts the ID tokens of an OpenID
+	// Connect provider, either configured with the OIDC block of the
+	// server config or by creating an OIDCAuthChecker.
+	oidcAuth *OIDCAuthChecker
+
+	// certUsers maps the subjects of client certificates to the users
+	// that requests without a token are authenticated as.
+	certUsers map[string]string
+
 	vagrant_server.UnimplementedVagrantServer
 }
 
@@ -60,6 +92,42 @@ func New(opts ...Option) (vagrant_server.VagrantServer, error) {
 	}
 	s.state = st
 
+	// Set the commands which conflict with each other
+	exclusive := cfg.exclusiveCommands
+	if exclusive == nil && cfg.serverConfig != nil {
+		exclusive = cfg.serverConfig.ExclusiveCommands
+	}
+	st.SetExclusiveCommands(exclusive)
+
+	// Set how long disconnected runners are kept
+	if cfg.runnerGracePeriod != nil {
+		st.SetRunnerGracePeriod(*cfg.runnerGracePeriod)
+	} else if scfg := cfg.serverConfig; scfg != nil && scfg.RunnerGracePeriod != "" {
+		grace, err := time.ParseDuration(scfg.RunnerGracePeriod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid runner_grace_period: %s", err)
+		}
+
+		st.SetRunnerGracePeriod(grace)
+	}
+
+	// Set the default timeouts of jobs
+	timeouts := cfg.jobTimeouts
+	if timeouts == nil && cfg.serverConfig != nil {
+		for cmd, v := range cfg.serverConfig.JobTimeouts {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid job_timeouts value for %q: %s", cmd, err)
+			}
+
+			if timeouts == nil {
+				timeouts = map[string]time.Duration{}
+			}
+			timeouts[cmd] = d
+		}
+	}
+	st.SetJobTimeouts(timeouts)
+
 	// If we don't have a server ID, set that.
 	id, err := st.ServerIdGet()
 	if err != nil {
@@ -77,6 +145,69 @@ func New(opts ...Option) (vagrant_server.VagrantServer, error) {
 	}
 	s.id = id
 
+	// Tokens created while bootstrapping are for the default user, so it
+	// must exist and be able to administer the server.
+	err = st.UserBootstrap(&vagrant_server.User{
+		Username: DefaultUser,
+		Roles:    []*vagrant_server.RoleBinding{{Role: state.RoleAdmin}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A snapshot restored without HMAC key material leaves keys which
+	// can't sign or validate tokens, so they are replaced.
+	rotated, err := st.HMACKeyRotateStripped(hmacKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if rotated {
+		log.Warn("HMAC keys were restored without key material and have been rotated, " +
+			"tokens issued before the snapshot must be reissued")
+	}
+
+	s.boxCatalogDir = cfg.boxCatalogDir
+	s.boxCatalogURL = cfg.boxCatalogURL
+	s.trustForwardedHeaders = cfg.trustForwardedHeaders
+	if scfg := cfg.serverConfig; scfg != nil {
+		if s.boxCatalogURL == "" {
+			s.boxCatalogURL = scfg.BoxCatalogURL
+		}
+		s.trustForwardedHeaders = s.trustForwardedHeaders || scfg.TrustForwardedHeaders
+	}
+	s.certUsers = cfg.clientCertUsers
+	if s.certUsers == nil && cfg.serverConfig != nil {
+		s.certUsers = cfg.serverConfig.ClientCertUsers
+	}
+	s.archiveDir = cfg.archiveDir
+
+	// Set how often the token signing key is rotated
+	if cfg.keyRotateInterval != nil {
+		s.keyRotateInterval = *cfg.keyRotateInterval
+		s.keyRetireAfter = cfg.keyRetireAfter
+	} else if scfg := cfg.serverConfig; scfg != nil && scfg.KeyRotation != nil {
+		s.keyRotateInterval, err = time.ParseDuration(scfg.KeyRotation.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key_rotation interval: %s", err)
+		}
+		if v := scfg.KeyRotation.RetireAfter; v != "" {
+			s.keyRetireAfter, err = time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key_rotation retire_after: %s", err)
+			}
+		}
+	}
+
+	// Accept the ID tokens of the configured OIDC provider
+	if scfg := cfg.serverConfig; scfg != nil && scfg.OIDC != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), oidcDiscoveryTimeout)
+		s.oidcAuth, err = newOIDCAuthChecker(ctx, &s, scfg.OIDC, nil)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("invalid oidc config: %s", err)
+		}
+	}
+
 	// Set specific server config for the deployment entrypoint binaries
 	if scfg := cfg.serverConfig; scfg != nil && scfg.CEBConfig != nil && scfg.CEBConfig.Addr != "" {
 		// only one advertise address can be configured
@@ -103,15 +234,40 @@ func New(opts ...Option) (vagrant_server.VagrantServer, error) {
 	s.bgWg.Add(1)
 	go s.runPrune(s.bgCtx, &s.bgWg, log.Named("prune"))
 
+	// Start the scheduler which queues the jobs of schedules when they
+	// are due.
+	s.bgWg.Add(1)
+	go s.runScheduler(s.bgCtx, &s.bgWg, log.Named("scheduler"))
+
+	// Rotate the token signing key on a schedule if configured
+	if s.keyRotateInterval > 0 {
+		s.bgWg.Add(1)
+		go s.runKeyRotation(s.bgCtx, &s.bgWg, log.Named("key-rotation"))
+	}
+
 	return &s, nil
 }
 
+// oidcDiscoveryTimeout limits how long fetching the discovery document of
+// the configured OIDC provider may take when the server starts.
+const oidcDiscoveryTimeout = 30 * time.Second
+
 type config struct {
 	db           *bolt.DB
 	serverConfig *serverconfig.Config
 	log          hclog.Logger
 
-	acceptUrlTerms bool
+	boxCatalogDir         string
+	boxCatalogURL         string
+	trustForwardedHeaders bool
+	archiveDir            string
+	acceptUrlTerms        bool
+	exclusiveCommands     []string
+	runnerGracePeriod     *time.Duration
+	jobTimeouts           map[string]time.Duration
+	keyRotateInterval     *time.Duration
+	keyRetireAfter        time.Duration
+	clientCertUsers       map[string]string
 }
 
 type Option func(*service, *config) error
@@ -140,6 +296,96 @@ func WithLogger(log hclog.Logger) Option {
 	}
 }
 
+// WithBoxCatalogDir sets the directory used to store box files published
+// to the box catalog.
+func WithBoxCatalogDir(dir string) Option {
+	return func(s *service, cfg *config) error {
+		cfg.boxCatalogDir = dir
+		return nil
+	}
+}
+
+// WithBoxCatalogURL sets the external URL of the server, such as
+// "https://vagrant.example.com", that box files in the box catalog are
+// linked with in the box metadata.
+func WithBoxCatalogURL(url string) Option {
+	return func(s *service, cfg *config) error {
+		cfg.boxCatalogURL = url
+		return nil
+	}
+}
+
+// WithTrustForwardedHeaders uses the X-Forwarded-Proto and X-Forwarded-Host
+// headers to link box files if no box catalog URL is set. This must only be
+// enabled behind a proxy which sets these headers.
+func WithTrustForwardedHeaders(trust bool) Option {
+	return func(s *service, cfg *config) error {
+		cfg.trustForwardedHeaders = trust
+		return nil
+	}
+}
+
+// WithArchiveDir sets the directory used to store archives uploaded for
+// use with archive data sources.
+func WithArchiveDir(dir string) Option {
+	return func(s *service, cfg *config) error {
+		cfg.archiveDir = dir
+		return nil
+	}
+}
+
+// WithExclusiveCommands sets the commands that can not be run in parallel
+// on the same target. Jobs running these commands are queued until any
+// other job running one of these commands for the target completes.
+func WithExclusiveCommands(cmds ...string) Option {
+	return func(s *service, cfg *config) error {
+		cfg.exclusiveCommands = cmds
+		return nil
+	}
+}
+
+// WithRunnerGracePeriod sets how long a runner which disconnected is
+// kept before it is removed and the jobs assigned to it are requeued.
+// A zero duration removes runners as soon as they disconnect.
+func WithRunnerGracePeriod(d time.Duration) Option {
+	return func(s *service, cfg *config) error {
+		cfg.runnerGracePeriod = &d
+		return nil
+	}
+}
+
+// WithJobTimeouts sets the default execution timeouts of jobs by the
+// name of the command they run. A job exceeding its timeout is cancelled
+// and then force cancelled if it doesn't complete within a grace period.
+func WithJobTimeouts(timeouts map[string]time.Duration) Option {
+	return func(s *service, cfg *config) error {
+		cfg.jobTimeouts = timeouts
+		return nil
+	}
+}
+
+// WithHMACKeyRotation rotates the key used to sign tokens once it has
+// been used for the given interval. The previous key is retired after
+// retireAfter, or remains valid until retired manually if that is zero.
+func WithHMACKeyRotation(interval, retireAfter time.Duration) Option {
+	return func(s *service, cfg *config) error {
+		cfg.keyRotateInterval = &interval
+		cfg.keyRetireAfter = retireAfter
+		return nil
+	}
+}
+
+// WithClientCertUsers maps the subjects of client certificates to users.
+// Requests without a token are authenticated as the user the verified
+// client certificate of the connection is mapped to. Subjects are either
+// the full subject or only its common name.
+func WithClientCertUsers(users map[string]string) Option {
+	return func(s *service, cfg *config) error {
+		cfg.clientCertUsers = users
+		return nil
+	}
+}
+
 func WithAcceptURLTerms(accept bool) Option {
 	return func(s *service, cfg *config) error {
 		cfg.acceptUrlTerms = true
@@ -0,0 +1,199 @@
+package singleprocess
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func (s *service) UploadArchive(
+	srv vagrant_server.Vagrant_UploadArchiveServer,
+) error {
+	log := hclog.FromContext(srv.Context())
+
+	// Read our first event which must be an Open event.
+	log.Trace("waiting for Open message")
+	req, err := srv.Recv()
+	if err != nil {
+		return err
+	}
+	open, ok := req.Event.(*vagrant_server.UploadArchiveRequest_Open_)
+	if !ok {
+		return status.Errorf(codes.FailedPrecondition,
+			"first message must be Open type")
+	}
+
+	upload, err := s.uploadArchive(log, open.Open, &archiveReader{srv: srv})
+	if err != nil {
+		return err
+	}
+	if err := srv.Context().Err(); err != nil {
+		return err
+	}
+
+	return srv.SendAndClose(&vagrant_server.UploadArchiveResponse{Upload: upload})
+}
+
+func (s *service) DownloadArchive(
+	req *vagrant_server.DownloadArchiveRequest,
+	srv vagrant_server.Vagrant_DownloadArchiveServer,
+) error {
+	if s.archiveDir == "" {
+		return status.Errorf(codes.FailedPrecondition,
+			"archive uploads are not enabled on this server")
+	}
+	upload, err := s.state.ArchiveUploadGet(req.UploadId)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(s.archivePath(upload.Id))
+	if os.IsNotExist(err) {
+		return status.Errorf(codes.NotFound,
+			"archive file not found for upload %s", upload.Id)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Always send the open message first with the upload information
+	if err := srv.Send(&vagrant_server.DownloadArchiveResponse{
+		Event: &vagrant_server.DownloadArchiveResponse_Open{
+			Open: upload,
+		},
+	}); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(&archiveWriter{srv: srv})
+	if _, err := io.Copy(bw, f); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Stores the archive read from r and records the upload.
+func (s *service) uploadArchive(
+	log hclog.Logger,
+	req *vagrant_server.UploadArchiveRequest_Open,
+	r io.Reader,
+) (*vagrant_server.ArchiveUpload, error) {
+	if s.archiveDir == "" {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"archive uploads are not enabled on this server")
+	}
+	id, err := server.Id()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "uuid generation failed: %s", err)
+	}
+
+	// Write the archive next to its final location so it can be moved
+	// into place once it is complete
+	if err := os.MkdirAll(s.archiveDir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(s.archiveDir, ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	log.Debug("receiving archive", "id", id, "path", f.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+	if req.Checksum != "" && !strings.EqualFold(req.Checksum, checksum) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"archive checksum mismatch, expected %s but received %s", req.Checksum, checksum)
+	}
+
+	upload := &vagrant_server.ArchiveUpload{
+		Id:         id,
+		Checksum:   checksum,
+		Size:       size,
+		UploadTime: timestamppb.Now(),
+	}
+	if err := os.Rename(f.Name(), s.archivePath(id)); err != nil {
+		return nil, err
+	}
+	if err := s.state.ArchiveUploadPut(upload); err != nil {
+		os.Remove(s.archivePath(id))
+		return nil, err
+	}
+
+	log.Info("stored archive", "id", id, "size", size)
+	return upload, nil
+}
+
+// Returns the path of the archive with the given upload ID. The ID is
+// always generated by the server.
+func (s *service) archivePath(id string) string {
+	return filepath.Join(s.archiveDir, filepath.Base(id)+".archive")
+}
+
+// archiveReader reads the archive chunks sent to UploadArchive
+type archiveReader struct {
+	srv vagrant_server.Vagrant_UploadArchiveServer
+	buf []byte
+}
+
+func (r *archiveReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.srv.Recv()
+		if err != nil {
+			return 0, err
+		}
+		chunk, ok := req.Event.(*vagrant_server.UploadArchiveRequest_Chunk)
+		if !ok {
+			return 0, status.Errorf(codes.FailedPrecondition,
+				"expected Chunk message after Open")
+		}
+		r.buf = chunk.Chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// archiveWriter sends the archive in chunks to DownloadArchive
+type archiveWriter struct {
+	srv vagrant_server.Vagrant_DownloadArchiveServer
+}
+
+func (w *archiveWriter) Write(p []byte) (int, error) {
+	// Ignore empty data.
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	return len(p), w.srv.Send(&vagrant_server.DownloadArchiveResponse{
+		Event: &vagrant_server.DownloadArchiveResponse_Chunk{
+			Chunk: p,
+		},
+	})
+}
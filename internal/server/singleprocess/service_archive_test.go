@@ -0,0 +1,124 @@
+package singleprocess
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/serverclient"
+)
+
+func testUploadArchive(
+	t *testing.T,
+	client *serverclient.VagrantClient,
+	checksum string,
+	data []byte,
+) (*vagrant_server.UploadArchiveResponse, error) {
+	stream, err := client.UploadArchive(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&vagrant_server.UploadArchiveRequest{
+		Event: &vagrant_server.UploadArchiveRequest_Open_{
+			Open: &vagrant_server.UploadArchiveRequest_Open{Checksum: checksum},
+		},
+	}))
+	// Send the data in multiple chunks
+	for len(data) > 0 {
+		n := 3
+		if n > len(data) {
+			n = len(data)
+		}
+		err = stream.Send(&vagrant_server.UploadArchiveRequest{
+			Event: &vagrant_server.UploadArchiveRequest_Chunk{Chunk: data[:n]},
+		})
+		if err != nil {
+			break
+		}
+		data = data[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestServiceArchive(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("test archive data")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("upload and download", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)), WithArchiveDir(t.TempDir()))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		resp, err := testUploadArchive(t, client, checksum, data)
+		require.NoError(err)
+		require.NotEmpty(resp.Upload.Id)
+		require.Equal(checksum, resp.Upload.Checksum)
+		require.Equal(int64(len(data)), resp.Upload.Size)
+
+		stream, err := client.DownloadArchive(ctx, &vagrant_server.DownloadArchiveRequest{
+			UploadId: resp.Upload.Id,
+		})
+		require.NoError(err)
+		msg, err := stream.Recv()
+		require.NoError(err)
+		open, ok := msg.Event.(*vagrant_server.DownloadArchiveResponse_Open)
+		require.True(ok)
+		require.Equal(checksum, open.Open.Checksum)
+
+		var buf bytes.Buffer
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(err)
+			buf.Write(msg.Event.(*vagrant_server.DownloadArchiveResponse_Chunk).Chunk)
+		}
+		require.Equal(data, buf.Bytes())
+	})
+
+	t.Run("rejects a checksum mismatch", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)), WithArchiveDir(t.TempDir()))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		_, err = testUploadArchive(t, client, "abc", data)
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("download of unknown upload", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)), WithArchiveDir(t.TempDir()))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		stream, err := client.DownloadArchive(ctx, &vagrant_server.DownloadArchiveRequest{
+			UploadId: "nope",
+		})
+		require.NoError(err)
+		_, err = stream.Recv()
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("disabled without a directory", func(t *testing.T) {
+		require := require.New(t)
+		client := TestServer(t)
+
+		_, err := testUploadArchive(t, client, "", data)
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+}
@@ -57,7 +57,7 @@ func (s *service) FindBox(
 	ctx context.Context,
 	req *vagrant_server.FindBoxRequest,
 ) (*vagrant_server.FindBoxResponse, error) {
-	result, err := s.state.BoxFind(req.Box)
+	result, err := s.state.BoxFindArchitecture(req.Box, req.Architecture)
 	if err != nil {
 		return nil, err
 	}
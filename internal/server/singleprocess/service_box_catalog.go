@@ -0,0 +1,399 @@
+package singleprocess
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-version"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+const (
+	// Checksum type used for box files published to the catalog
+	catalogBoxChecksumType = "sha256"
+
+	// Used in place of the slash in box names when storing box files.
+	// This matches the directory names used by the local box collection.
+	catalogBoxSlash = "-VAGRANTSLASH-"
+)
+
+// Valid characters for each part of a box name and for provider names
+var catalogBoxNamePart = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Valid characters for box versions, which may include build metadata
+// such as 1.0.0+20240101
+var catalogBoxVersion = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+func (s *service) PublishCatalogBox(
+	srv vagrant_server.Vagrant_PublishCatalogBoxServer,
+) error {
+	log := hclog.FromContext(srv.Context())
+
+	// Read our first event which must be an Open event.
+	log.Trace("waiting for Open message")
+	req, err := srv.Recv()
+	if err != nil {
+		return err
+	}
+	open, ok := req.Event.(*vagrant_server.PublishCatalogBoxRequest_Open_)
+	if !ok {
+		return status.Errorf(codes.FailedPrecondition,
+			"first message must be Open type")
+	}
+
+	box, err := s.publishCatalogBox(srv.Context(), log, open.Open, &catalogBoxReader{srv: srv})
+	if err != nil {
+		return err
+	}
+
+	return srv.SendAndClose(&vagrant_server.PublishCatalogBoxResponse{Box: box})
+}
+
+func (s *service) GetCatalogBox(
+	ctx context.Context,
+	req *vagrant_server.GetCatalogBoxRequest,
+) (*vagrant_server.GetCatalogBoxResponse, error) {
+	result, err := s.state.CatalogBoxGet(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.GetCatalogBoxResponse{Box: result}, nil
+}
+
+func (s *service) ListCatalogBoxes(
+	ctx context.Context,
+	req *emptypb.Empty,
+) (*vagrant_server.ListCatalogBoxesResponse, error) {
+	result, err := s.state.CatalogBoxList()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.ListCatalogBoxesResponse{Boxes: result}, nil
+}
+
+func (s *service) DeleteCatalogBox(
+	ctx context.Context,
+	req *vagrant_server.DeleteCatalogBoxRequest,
+) (*emptypb.Empty, error) {
+	if req.Version == "" && req.Provider != "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"version is required when deleting a box provider")
+	}
+	if err := s.state.CatalogBoxDelete(req.Name, req.Version, req.Provider); err != nil {
+		return nil, err
+	}
+	if s.boxCatalogDir == "" {
+		return &emptypb.Empty{}, nil
+	}
+
+	// Remove the box files
+	path, err := s.catalogBoxPath(req.Name, req.Version, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if req.Provider == "" {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	s.removeEmptyCatalogDirs(filepath.Dir(path))
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *service) DownloadCatalogBox(
+	req *vagrant_server.DownloadCatalogBoxRequest,
+	srv vagrant_server.Vagrant_DownloadCatalogBoxServer,
+) error {
+	provider, f, err := s.openCatalogBox(req.Name, req.Version, req.Provider)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Always send the open message first with the provider information
+	if err := srv.Send(&vagrant_server.DownloadCatalogBoxResponse{
+		Event: &vagrant_server.DownloadCatalogBoxResponse_Open{
+			Open: provider,
+		},
+	}); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(&catalogBoxWriter{srv: srv})
+	if _, err := io.Copy(bw, f); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Stores the box file read from r and adds the box provider to the catalog.
+func (s *service) publishCatalogBox(
+	ctx context.Context,
+	log hclog.Logger,
+	req *vagrant_server.PublishCatalogBoxRequest_Open,
+	r io.Reader,
+) (*vagrant_server.CatalogBox, error) {
+	if s.boxCatalogDir == "" {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"box catalog is not enabled on this server")
+	}
+	if req.Version == "" || req.Provider == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"box version and provider are required")
+	}
+	if _, err := version.NewVersion(req.Version); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid box version %q: %s", req.Version, err)
+	}
+	path, err := s.catalogBoxPath(req.Name, req.Version, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check for an existing provider before receiving the box file
+	if !req.Force {
+		if _, err := s.catalogBoxProvider(req.Name, req.Version, req.Provider); err == nil {
+			return nil, status.Errorf(codes.AlreadyExists,
+				"box %s version %s already has provider %s", req.Name, req.Version, req.Provider)
+		}
+	}
+
+	// Write the box file next to its final location so it can be
+	// moved into place once it is complete
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	log.Debug("receiving box file", "box", req.Name, "version", req.Version,
+		"provider", req.Provider, "path", f.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+	if req.Checksum != "" && !strings.EqualFold(req.Checksum, checksum) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"box file checksum mismatch, expected %s but received %s", req.Checksum, checksum)
+	}
+
+	// Move the box file into place before the catalog lists it, so that
+	// listed providers always have a box file. A replaced box file is kept
+	// until the catalog is updated so that it can be restored on failure.
+	previous := ""
+	if _, err := os.Stat(path); err == nil {
+		previous = f.Name() + ".previous"
+		if err := os.Link(path, previous); err != nil {
+			return nil, err
+		}
+		defer os.Remove(previous)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+	syncDir(filepath.Dir(path))
+
+	box, err := s.state.CatalogBoxPublish(req, &vagrant_server.CatalogBox_Provider{
+		Name:         req.Provider,
+		Checksum:     checksum,
+		ChecksumType: catalogBoxChecksumType,
+		Size:         size,
+	})
+	if err != nil {
+		if previous != "" {
+			os.Rename(previous, path)
+		} else {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+
+	log.Info("published box to catalog", "box", req.Name, "version", req.Version,
+		"provider", req.Provider)
+	return box, nil
+}
+
+// Returns the catalog entry for the box provider.
+func (s *service) catalogBoxProvider(name, ver, provider string) (*vagrant_server.CatalogBox_Provider, error) {
+	box, err := s.state.CatalogBoxGet(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range box.Versions {
+		if v.Version != ver {
+			continue
+		}
+		for _, p := range v.Providers {
+			if p.Name == provider {
+				return p, nil
+			}
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound,
+		"box %s version %s does not have provider %s", name, ver, provider)
+}
+
+// Opens the box file of the box provider for reading.
+func (s *service) openCatalogBox(name, ver, provider string) (*vagrant_server.CatalogBox_Provider, *os.File, error) {
+	if s.boxCatalogDir == "" {
+		return nil, nil, status.Errorf(codes.FailedPrecondition,
+			"box catalog is not enabled on this server")
+	}
+	p, err := s.catalogBoxProvider(name, ver, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	path, err := s.catalogBoxPath(name, ver, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, status.Errorf(codes.NotFound,
+			"box file not found for box %s version %s provider %s", name, ver, provider)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, f, nil
+}
+
+// Returns the path of the box files within the catalog directory. Box
+// files are stored as <name>/<version>/<provider>.box. If the version
+// or provider are empty the path of the parent directory is returned.
+func (s *service) catalogBoxPath(name, ver, provider string) (string, error) {
+	// Box names are matched without regard to case
+	parts := strings.Split(strings.ToLower(name), "/")
+	if name == "" || len(parts) > 2 {
+		return "", status.Errorf(codes.InvalidArgument, "invalid box name %q", name)
+	}
+	for _, part := range parts {
+		if !validCatalogPathPart(part) {
+			return "", status.Errorf(codes.InvalidArgument, "invalid box name %q", name)
+		}
+	}
+
+	path := filepath.Join(s.boxCatalogDir, strings.Join(parts, catalogBoxSlash))
+	if ver == "" {
+		return path, nil
+	}
+	if ver == "." || ver == ".." || !catalogBoxVersion.MatchString(ver) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid box version %q", ver)
+	}
+	path = filepath.Join(path, ver)
+	if provider == "" {
+		return path, nil
+	}
+	if !validCatalogPathPart(provider) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid box provider %q", provider)
+	}
+
+	return filepath.Join(path, provider+".box"), nil
+}
+
+// Removes the directory and its parents if they are empty, stopping
+// at the catalog directory.
+func (s *service) removeEmptyCatalogDirs(dir string) {
+	root := s.boxCatalogDir + string(filepath.Separator)
+	for ; strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+func validCatalogPathPart(part string) bool {
+	return part != "." && part != ".." && catalogBoxNamePart.MatchString(part)
+}
+
+// catalogBoxReader reads the box file chunks sent to PublishCatalogBox
+type catalogBoxReader struct {
+	srv vagrant_server.Vagrant_PublishCatalogBoxServer
+	buf []byte
+}
+
+func (r *catalogBoxReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.srv.Recv()
+		if err != nil {
+			return 0, err
+		}
+		chunk, ok := req.Event.(*vagrant_server.PublishCatalogBoxRequest_Chunk)
+		if !ok {
+			return 0, status.Errorf(codes.FailedPrecondition,
+				"expected Chunk message after Open")
+		}
+		r.buf = chunk.Chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// catalogBoxWriter sends the box file in chunks to DownloadCatalogBox
+type catalogBoxWriter struct {
+	srv vagrant_server.Vagrant_DownloadCatalogBoxServer
+}
+
+func (w *catalogBoxWriter) Write(p []byte) (int, error) {
+	// Ignore empty data.
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	return len(p), w.srv.Send(&vagrant_server.DownloadCatalogBoxResponse{
+		Event: &vagrant_server.DownloadCatalogBoxResponse_Chunk{
+			Chunk: p,
+		},
+	})
+}
+
+// syncDir flushes the entries of the directory to disk so that a file
+// moved into it is still there after a crash. This is best effort since
+// directories can't be synced on every platform.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
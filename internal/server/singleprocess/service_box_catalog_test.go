@@ -0,0 +1,485 @@
+package singleprocess
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
+	"github.com/hashicorp/vagrant/internal/serverclient"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testPublishCatalogBox(
+	t *testing.T,
+	client *serverclient.VagrantClient,
+	open *vagrant_server.PublishCatalogBoxRequest_Open,
+	data []byte,
+) (*vagrant_server.PublishCatalogBoxResponse, error) {
+	stream, err := client.PublishCatalogBox(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&vagrant_server.PublishCatalogBoxRequest{
+		Event: &vagrant_server.PublishCatalogBoxRequest_Open_{Open: open},
+	}))
+	// Send the data in multiple chunks
+	for len(data) > 0 {
+		n := 3
+		if n > len(data) {
+			n = len(data)
+		}
+		err = stream.Send(&vagrant_server.PublishCatalogBoxRequest{
+			Event: &vagrant_server.PublishCatalogBoxRequest_Chunk{Chunk: data[:n]},
+		})
+		if err != nil {
+			break
+		}
+		data = data[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestServiceBoxCatalog(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("test box data")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("publish and download", func(t *testing.T) {
+		require := require.New(t)
+		dir := t.TempDir()
+		impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(dir))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		resp, err := testPublishCatalogBox(t, client, &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:        "hashicorp/bionic",
+			Description: "Ubuntu",
+			Version:     "1.0.0",
+			Provider:    "virtualbox",
+			Checksum:    checksum,
+		}, data)
+		require.NoError(err)
+		require.Equal("hashicorp/bionic", resp.Box.Name)
+		require.Len(resp.Box.Versions, 1)
+		provider := resp.Box.Versions[0].Providers[0]
+		require.Equal(checksum, provider.Checksum)
+		require.Equal("sha256", provider.ChecksumType)
+		require.Equal(int64(len(data)), provider.Size)
+		require.FileExists(filepath.Join(dir, "hashicorp-VAGRANTSLASH-bionic", "1.0.0", "virtualbox.box"))
+
+		getResp, err := client.GetCatalogBox(ctx, &vagrant_server.GetCatalogBoxRequest{
+			Name: "hashicorp/bionic",
+		})
+		require.NoError(err)
+		require.Equal("Ubuntu", getResp.Box.Description)
+
+		stream, err := client.DownloadCatalogBox(ctx, &vagrant_server.DownloadCatalogBoxRequest{
+			Name:     "hashicorp/bionic",
+			Version:  "1.0.0",
+			Provider: "virtualbox",
+		})
+		require.NoError(err)
+		msg, err := stream.Recv()
+		require.NoError(err)
+		require.IsType((*vagrant_server.DownloadCatalogBoxResponse_Open)(nil), msg.Event)
+		var buf bytes.Buffer
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(err)
+			buf.Write(msg.Event.(*vagrant_server.DownloadCatalogBoxResponse_Chunk).Chunk)
+		}
+		require.Equal(data, buf.Bytes())
+	})
+
+	t.Run("publish existing provider requires force", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(t.TempDir()))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		open := &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "bionic",
+			Version:  "1.0.0",
+			Provider: "virtualbox",
+		}
+		_, err = testPublishCatalogBox(t, client, open, data)
+		require.NoError(err)
+		_, err = testPublishCatalogBox(t, client, open, data)
+		require.Error(err)
+		require.Equal(codes.AlreadyExists, status.Code(err))
+
+		open.Force = true
+		_, err = testPublishCatalogBox(t, client, open, []byte("updated"))
+		require.NoError(err)
+	})
+
+	t.Run("publish with invalid checksum", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(t.TempDir()))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		_, err = testPublishCatalogBox(t, client, &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "bionic",
+			Version:  "1.0.0",
+			Provider: "virtualbox",
+			Checksum: "abcd",
+		}, data)
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+
+		_, err = client.GetCatalogBox(ctx, &vagrant_server.GetCatalogBoxRequest{Name: "bionic"})
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("publish with invalid name", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(t.TempDir()))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		_, err = testPublishCatalogBox(t, client, &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "../bionic",
+			Version:  "1.0.0",
+			Provider: "virtualbox",
+		}, data)
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("publish with build metadata", func(t *testing.T) {
+		require := require.New(t)
+		dir := t.TempDir()
+		impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(dir))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		_, err = testPublishCatalogBox(t, client, &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "bionic",
+			Version:  "1.0.0+20240101",
+			Provider: "virtualbox",
+		}, data)
+		require.NoError(err)
+		require.FileExists(filepath.Join(dir, "bionic", "1.0.0+20240101", "virtualbox.box"))
+
+		srv := httptest.NewServer(impl.(server.HTTPHandlerService).HTTPHandlers()[BoxCatalogPath])
+		defer srv.Close()
+		resp, err := http.Get(srv.URL + BoxCatalogPath + "bionic/versions/1.0.0+20240101/providers/virtualbox.box")
+		require.NoError(err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(err)
+		require.Equal(data, body)
+	})
+
+	t.Run("publish without catalog directory", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		_, err = testPublishCatalogBox(t, client, &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "bionic",
+			Version:  "1.0.0",
+			Provider: "virtualbox",
+		}, data)
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require := require.New(t)
+		dir := t.TempDir()
+		impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(dir))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		for _, p := range []string{"virtualbox", "libvirt"} {
+			_, err = testPublishCatalogBox(t, client, &vagrant_server.PublishCatalogBoxRequest_Open{
+				Name:     "bionic",
+				Version:  "1.0.0",
+				Provider: p,
+			}, data)
+			require.NoError(err)
+		}
+
+		_, err = client.DeleteCatalogBox(ctx, &vagrant_server.DeleteCatalogBoxRequest{
+			Name:     "bionic",
+			Version:  "1.0.0",
+			Provider: "libvirt",
+		})
+		require.NoError(err)
+		require.NoFileExists(filepath.Join(dir, "bionic", "1.0.0", "libvirt.box"))
+		require.FileExists(filepath.Join(dir, "bionic", "1.0.0", "virtualbox.box"))
+
+		_, err = client.DeleteCatalogBox(ctx, &vagrant_server.DeleteCatalogBoxRequest{
+			Name: "bionic",
+		})
+		require.NoError(err)
+		require.NoDirExists(filepath.Join(dir, "bionic"))
+
+		_, err = client.GetCatalogBox(ctx, &vagrant_server.GetCatalogBoxRequest{Name: "bionic"})
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+}
+
+func TestServiceBoxCatalogHTTP(t *testing.T) {
+	require := require.New(t)
+	data := []byte("test box data")
+
+	impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(t.TempDir()))
+	require.NoError(err)
+	handlers := impl.(server.HTTPHandlerService).HTTPHandlers()
+	srv := httptest.NewServer(handlers[BoxCatalogPath])
+	defer srv.Close()
+
+	// Publish a box
+	boxURL := srv.URL + BoxCatalogPath + "hashicorp/bionic/versions/1.0.0/providers/virtualbox.box"
+	req, err := http.NewRequest(http.MethodPut, boxURL+"?description=Ubuntu", bytes.NewReader(data))
+	require.NoError(err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(err)
+	resp.Body.Close()
+	require.Equal(http.StatusCreated, resp.StatusCode)
+
+	// Read the metadata
+	resp, err = http.Get(srv.URL + BoxCatalogPath + "hashicorp/bionic")
+	require.NoError(err)
+	defer resp.Body.Close()
+	require.Equal(http.StatusOK, resp.StatusCode)
+	var metadata map[string]interface{}
+	require.NoError(json.NewDecoder(resp.Body).Decode(&metadata))
+	require.Equal("hashicorp/bionic", metadata["name"])
+	require.Equal("Ubuntu", metadata["description"])
+	versions := metadata["versions"].([]interface{})
+	require.Len(versions, 1)
+	providers := versions[0].(map[string]interface{})["providers"].([]interface{})
+	require.Len(providers, 1)
+	provider := providers[0].(map[string]interface{})
+	require.Equal("virtualbox", provider["name"])
+	require.Equal(boxURL, provider["url"])
+	require.Equal("sha256", provider["checksum_type"])
+
+	// Download the box file
+	resp, err = http.Get(boxURL)
+	require.NoError(err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(err)
+	require.Equal(data, body)
+
+	// Resume a download
+	req, err = http.NewRequest(http.MethodGet, boxURL, nil)
+	require.NoError(err)
+	req.Header.Set("Range", "bytes=5-")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(err)
+	defer resp.Body.Close()
+	require.Equal(http.StatusPartialContent, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	require.NoError(err)
+	require.Equal(data[5:], body)
+
+	// Unknown boxes are not found
+	resp, err = http.Get(srv.URL + BoxCatalogPath + "hashicorp/unknown")
+	require.NoError(err)
+	resp.Body.Close()
+	require.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServiceBoxCatalogHTTP_authorize(t *testing.T) {
+	require := require.New(t)
+
+	impl, err := New(WithDB(testDB(t)), WithBoxCatalogDir(t.TempDir()))
+	require.NoError(err)
+	s := impl.(*service)
+	baseURL := testHTTPServer(t, impl)
+
+	for _, user := range []string{state.RoleReadOnly, state.RoleOperator} {
+		require.NoError(s.state.UserPut(&vagrant_server.User{
+			Username: user,
+			Roles:    []*vagrant_server.RoleBinding{{Role: user}},
+		}))
+	}
+
+	publish := func(user string) int {
+		token, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{User: user})
+		require.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPut,
+			baseURL+BoxCatalogPath+"hashicorp/bionic/versions/1.0.0/providers/virtualbox.box",
+			bytes.NewReader([]byte("test box data")))
+		require.NoError(err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	// Read only users may not publish boxes
+	require.Equal(http.StatusForbidden, publish(state.RoleReadOnly))
+	_, err = s.state.CatalogBoxGet("hashicorp/bionic")
+	require.Equal(codes.NotFound, status.Code(err))
+
+	require.Equal(http.StatusCreated, publish(state.RoleOperator))
+
+	// Reads require authentication too
+	get := func(token string) int {
+		req, err := http.NewRequest(http.MethodGet, baseURL+BoxCatalogPath+"hashicorp/bionic", nil)
+		require.NoError(err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+	require.Equal(http.StatusUnauthorized, get(""))
+
+	token, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{User: state.RoleReadOnly})
+	require.NoError(err)
+	require.Equal(http.StatusOK, get(token))
+}
+
+func TestServiceBoxCatalogHTTP_baseURL(t *testing.T) {
+	metadataURL := func(t *testing.T, opts ...Option) string {
+		require := require.New(t)
+
+		impl, err := New(append([]Option{WithDB(testDB(t)), WithBoxCatalogDir(t.TempDir())}, opts...)...)
+		require.NoError(err)
+		s := impl.(*service)
+		_, err = s.publishCatalogBox(context.Background(), hclog.L(),
+			&vagrant_server.PublishCatalogBoxRequest_Open{Name: "bionic", Version: "1.0.0", Provider: "virtualbox"},
+			bytes.NewReader([]byte("data")))
+		require.NoError(err)
+
+		req := httptest.NewRequest(http.MethodGet, "http://vagrant.internal"+BoxCatalogPath+"bionic", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "attacker.example.com")
+		rec := httptest.NewRecorder()
+		s.serveBoxCatalog(rec, req)
+		require.Equal(http.StatusOK, rec.Code)
+
+		var metadata catalogMetadata
+		require.NoError(json.NewDecoder(rec.Body).Decode(&metadata))
+		return metadata.Versions[0].Providers[0].Url
+	}
+
+	path := BoxCatalogPath + "bionic/versions/1.0.0/providers/virtualbox.box"
+
+	t.Run("forwarded headers are ignored by default", func(t *testing.T) {
+		require.Equal(t, "http://vagrant.internal"+path, metadataURL(t))
+	})
+
+	t.Run("forwarded headers can be trusted", func(t *testing.T) {
+		require.Equal(t, "https://attacker.example.com"+path,
+			metadataURL(t, WithTrustForwardedHeaders(true)))
+	})
+
+	t.Run("configured URL", func(t *testing.T) {
+		require.Equal(t, "https://vagrant.example.com"+path,
+			metadataURL(t, WithBoxCatalogURL("https://vagrant.example.com/"), WithTrustForwardedHeaders(true)))
+	})
+}
+
+// testHTTPServer runs the service with authentication and an HTTP listener
+// and returns the URL of the HTTP listener.
+func testHTTPServer(t *testing.T, impl vagrant_server.VagrantServer) string {
+	grpcLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { grpcLn.Close() })
+	httpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { httpLn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go server.Run(
+		server.WithContext(ctx),
+		server.WithGRPC(grpcLn),
+		server.WithHTTP(httpLn),
+		server.WithImpl(impl),
+		server.WithAuthentication(impl.(server.AuthChecker)),
+	)
+
+	return "http://" + httpLn.Addr().String()
+}
+
+func TestServiceBoxCatalog_publishFailure(t *testing.T) {
+	ctx := context.Background()
+	log := hclog.L()
+	dir := t.TempDir()
+
+	db := testDB(t)
+	impl, err := New(WithDB(db), WithBoxCatalogDir(dir))
+	require.NoError(t, err)
+	s := impl.(*service)
+
+	open := func(provider string) *vagrant_server.PublishCatalogBoxRequest_Open {
+		return &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "bionic",
+			Version:  "1.0.0",
+			Provider: provider,
+			Force:    true,
+		}
+	}
+
+	t.Run("box files that can't be moved into place are not listed", func(t *testing.T) {
+		require := require.New(t)
+
+		// A directory in the way of the box file makes the rename fail
+		path := filepath.Join(dir, "bionic", "1.0.0", "libvirt.box")
+		require.NoError(os.MkdirAll(filepath.Join(path, "blocked"), 0755))
+		defer os.RemoveAll(path)
+
+		_, err := s.publishCatalogBox(ctx, log, open("libvirt"), bytes.NewReader([]byte("data")))
+		require.Error(err)
+		_, err = s.catalogBoxProvider("bionic", "1.0.0", "libvirt")
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("box files are restored if the catalog can't be updated", func(t *testing.T) {
+		require := require.New(t)
+
+		_, err := s.publishCatalogBox(ctx, log, open("virtualbox"), bytes.NewReader([]byte("original")))
+		require.NoError(err)
+
+		require.NoError(db.Close())
+		_, err = s.publishCatalogBox(ctx, log, open("virtualbox"), bytes.NewReader([]byte("replaced")))
+		require.Error(err)
+		_, err = s.publishCatalogBox(ctx, log, open("libvirt"), bytes.NewReader([]byte("new")))
+		require.Error(err)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "bionic", "1.0.0", "virtualbox.box"))
+		require.NoError(err)
+		require.Equal("original", string(data))
+		require.NoFileExists(filepath.Join(dir, "bionic", "1.0.0", "libvirt.box"))
+
+		// Nothing is left behind from the uploads
+		entries, err := ioutil.ReadDir(filepath.Join(dir, "bionic", "1.0.0"))
+		require.NoError(err)
+		require.Len(entries, 1)
+	})
+}
@@ -0,0 +1,105 @@
+package singleprocess
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
+)
+
+func (s *service) ListHMACKeys(
+	ctx context.Context,
+	req *emptypb.Empty,
+) (*vagrant_server.ListHMACKeysResponse, error) {
+	keys, err := s.state.HMACKeyList()
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.state.HMACKeyActive()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*vagrant_server.HMACKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, hmacKeyInfo(key, active))
+	}
+
+	return &vagrant_server.ListHMACKeysResponse{Keys: result}, nil
+}
+
+func (s *service) RotateHMACKey(
+	ctx context.Context,
+	req *vagrant_server.RotateHMACKeyRequest,
+) (*vagrant_server.RotateHMACKeyResponse, error) {
+	retireAfter, err := parseRetireAfter(req.RetireAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotating before bootstrapping would make bootstrapping impossible
+	if s.state.HMACKeyEmpty() {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"server must be bootstrapped before rotating keys")
+	}
+
+	key, err := s.state.HMACKeyRotate(hmacKeySize, retireAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("rotated the token signing key", "id", key.Id)
+	return &vagrant_server.RotateHMACKeyResponse{Key: hmacKeyInfo(key, key)}, nil
+}
+
+func (s *service) RetireHMACKey(
+	ctx context.Context,
+	req *vagrant_server.RetireHMACKeyRequest,
+) (*vagrant_server.RetireHMACKeyResponse, error) {
+	if req.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "key id must be set")
+	}
+	retireAfter, err := parseRetireAfter(req.RetireAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.state.HMACKeyRetire(req.Id, time.Now().Add(retireAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("retiring key", "id", key.Id, "time", key.RetireTime.AsTime())
+	return &vagrant_server.RetireHMACKeyResponse{Key: hmacKeyInfo(key, nil)}, nil
+}
+
+// Returns the information about the key without the key material.
+func hmacKeyInfo(key, active *vagrant_server.HMACKey) *vagrant_server.HMACKeyInfo {
+	return &vagrant_server.HMACKeyInfo{
+		Id:         key.Id,
+		CreateTime: key.CreateTime,
+		RetireTime: key.RetireTime,
+		Active:     active != nil && active.Id == key.Id,
+		Retired:    state.HMACKeyRetired(key, time.Now()),
+	}
+}
+
+func parseRetireAfter(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, status.Errorf(codes.InvalidArgument,
+			"invalid retire_after duration: %q", v)
+	}
+
+	return d, nil
+}
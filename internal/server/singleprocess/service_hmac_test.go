@@ -0,0 +1,103 @@
+package singleprocess
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestServiceHMACKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(err)
+	s := impl.(*service)
+
+	// Rotating requires a bootstrapped server
+	_, err = s.RotateHMACKey(ctx, &vagrant_server.RotateHMACKeyRequest{})
+	require.Error(err)
+	require.Equal(codes.FailedPrecondition, status.Code(err))
+
+	resp, err := s.BootstrapToken(ctx, &emptypb.Empty{})
+	require.NoError(err)
+	oldToken := resp.Token
+
+	rotated, err := s.RotateHMACKey(ctx, &vagrant_server.RotateHMACKeyRequest{})
+	require.NoError(err)
+	require.Equal("k2", rotated.Key.Id)
+	require.True(rotated.Key.Active)
+
+	// New tokens are signed with the new key and old tokens still work
+	resp, err = s.GenerateLoginToken(ctx, &emptypb.Empty{})
+	require.NoError(err)
+	newToken := resp.Token
+	tt, _, err := s.DecodeToken(newToken)
+	require.NoError(err)
+	require.Equal("k2", tt.KeyId)
+	require.NoError(s.Authenticate(ctx, oldToken, "test", nil))
+
+	list, err := s.ListHMACKeys(ctx, &emptypb.Empty{})
+	require.NoError(err)
+	require.Len(list.Keys, 2)
+
+	// The active key can't be retired
+	_, err = s.RetireHMACKey(ctx, &vagrant_server.RetireHMACKeyRequest{Id: "k2"})
+	require.Error(err)
+	require.Equal(codes.FailedPrecondition, status.Code(err))
+
+	// Retiring the old key rejects its tokens
+	retired, err := s.RetireHMACKey(ctx, &vagrant_server.RetireHMACKeyRequest{Id: "k1"})
+	require.NoError(err)
+	require.True(retired.Key.Retired)
+	require.Error(s.Authenticate(ctx, oldToken, "test", nil))
+	require.NoError(s.Authenticate(ctx, newToken, "test", nil))
+
+	_, err = s.RetireHMACKey(ctx, &vagrant_server.RetireHMACKeyRequest{
+		Id:          "k1",
+		RetireAfter: "soon",
+	})
+	require.Error(err)
+	require.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func TestServiceHMACKeyRotation_scheduled(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// The server starts without a key so check for one quickly
+	defer func(v time.Duration) { keyRotationIdleInterval = v }(keyRotationIdleInterval)
+	keyRotationIdleInterval = 50 * time.Millisecond
+
+	impl, err := New(
+		WithDB(testDB(t)),
+		WithHMACKeyRotation(500*time.Millisecond, time.Hour),
+	)
+	require.NoError(err)
+	s := impl.(*service)
+	defer func() {
+		s.bgCtxCancel()
+		s.bgWg.Wait()
+	}()
+
+	_, err = s.BootstrapToken(ctx, &emptypb.Empty{})
+	require.NoError(err)
+
+	require.Eventually(func() bool {
+		key, err := s.state.HMACKeyActive()
+		return err == nil && key != nil && key.Id != DefaultKeyId
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// The previous key retires later
+	old, err := s.state.HMACKeyGet(DefaultKeyId)
+	require.NoError(err)
+	require.NotNil(old.RetireTime)
+	require.True(old.RetireTime.AsTime().After(time.Now()))
+}
@@ -20,6 +20,10 @@ import (
 
 const (
 	maximumJobsIndexed = 50
+
+	// jobStreamBatchSize is the maximum number of stored terminal events
+	// sent in a single message.
+	jobStreamBatchSize = 64
 )
 
 func (s *service) PruneOldJobs(
@@ -82,6 +86,11 @@ func (s *service) QueueJob(
 	if job == nil {
 		return nil, status.Errorf(codes.FailedPrecondition, "job must be set")
 	}
+
+	// The retry policy on the request takes precedence over the job
+	if req.RetryPolicy != nil {
+		job.RetryPolicy = req.RetryPolicy
+	}
 	if err := serverptypes.ValidateJob(job); err != nil {
 		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
 	}
@@ -129,6 +138,9 @@ func (s *service) QueueJob(
 	}
 	job.Id = id
 
+	// Record who queued the job so its tasks can be attributed
+	job.RequestedBy = s.requestUser(ctx)
+
 	// Validate expiry if we have one
 	job.ExpireTime = nil
 	if req.ExpiresIn != "" {
@@ -168,6 +180,9 @@ func (s *service) ValidateJob(
 	if err != nil {
 		return nil, err
 	}
+	if !result.Assignable {
+		result.AssignableError = status.New(codes.FailedPrecondition, "no matching runner").Proto()
+	}
 
 	return result, nil
 }
@@ -237,7 +252,13 @@ func (s *service) GetJobStream(
 	// Enter the event loop
 	var lastState vagrant_server.Job_State
 	var cancelSent bool
+	var blockedSent string
+	var lastAttempt uint32
 	var eventsCh <-chan []*vagrant_server.GetJobStreamResponse_Terminal_Event
+	output := &jobStreamOutput{offset: req.Offset}
+	if req.Since != nil {
+		output.since = req.Since.AsTime()
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -249,17 +270,38 @@ func (s *service) GetJobStream(
 		case job := <-jobCh:
 			log.Debug("job state change", "state", job.State)
 
+			// If the job was retried since the last update, send down the
+			// failed attempt before the state change.
+			if lastAttempt > 0 && job.Attempt > lastAttempt && len(job.Attempts) > 0 {
+				attempt := job.Attempts[len(job.Attempts)-1]
+				if err := server.Send(&vagrant_server.GetJobStreamResponse{
+					Event: &vagrant_server.GetJobStreamResponse_Retry_{
+						Retry: &vagrant_server.GetJobStreamResponse_Retry{
+							Attempt:   attempt.Number,
+							Error:     attempt.Error,
+							RetryTime: job.RetryTime,
+						},
+					},
+				}); err != nil {
+					return err
+				}
+			}
+			lastAttempt = job.Attempt
+
 			// If we have a state change, send that event down. We also send
-			// down a state change if we enter a "cancelled" scenario.
+			// down a state change if we enter a "cancelled" scenario or if
+			// the job is blocked on a different job.
 			canceling := job.CancelTime != nil
-			if lastState != job.State || cancelSent != canceling {
+			if lastState != job.State || cancelSent != canceling || blockedSent != job.BlockedMessage {
 				if err := server.Send(&vagrant_server.GetJobStreamResponse{
 					Event: &vagrant_server.GetJobStreamResponse_State_{
 						State: &vagrant_server.GetJobStreamResponse_State{
-							Previous:  lastState,
-							Current:   job.State,
-							Job:       job.Job,
-							Canceling: canceling,
+							Previous:       lastState,
+							Current:        job.State,
+							Job:            job.Job,
+							Canceling:      canceling,
+							Blocked:        job.Blocked,
+							BlockedMessage: job.BlockedMessage,
 						},
 					},
 				}); err != nil {
@@ -268,13 +310,14 @@ func (s *service) GetJobStream(
 
 				lastState = job.State
 				cancelSent = canceling
+				blockedSent = job.BlockedMessage
 			}
 
 			// If we haven't initialized output streaming and the output buffer
 			// is now non-nil, initialize that. This will send any buffered
 			// data down.
 			if eventsCh == nil && job.OutputBuffer != nil {
-				eventsCh, err = s.getJobStreamOutputInit(ctx, job, server)
+				eventsCh, err = s.getJobStreamOutputInit(ctx, job, server, output)
 				if err != nil {
 					return err
 				}
@@ -282,7 +325,12 @@ func (s *service) GetJobStream(
 
 			switch job.State {
 			case vagrant_server.Job_SUCCESS, vagrant_server.Job_ERROR:
-				// TODO(mitchellh): we should drain the output buffer
+				// Send any stored output that hasn't been sent yet. If we
+				// never streamed the output, this is all of the output of
+				// the job which may have completed long ago.
+				if err := s.sendJobStreamStoredOutput(job.Id, server, output, eventsCh == nil); err != nil {
+					return err
+				}
 
 				// Job is done. For success, error will be nil, so this
 				// populates the event with the proper values.
@@ -297,6 +345,11 @@ func (s *service) GetJobStream(
 			}
 
 		case events := <-eventsCh:
+			events = output.filter(events)
+			if len(events) == 0 {
+				continue
+			}
+
 			if err := server.Send(&vagrant_server.GetJobStreamResponse{
 				Event: &vagrant_server.GetJobStreamResponse_Terminal_{
 					Terminal: &vagrant_server.GetJobStreamResponse_Terminal{
@@ -328,15 +381,28 @@ func (s *service) getJobStreamOutputInit(
 	ctx context.Context,
 	job *state.Job,
 	server vagrant_server.Vagrant_GetJobStreamServer,
+	output *jobStreamOutput,
 ) (<-chan []*vagrant_server.GetJobStreamResponse_Terminal_Event, error) {
-	// Send down all our buffered lines.
+	// Start reading before sending the stored output. Events are stored
+	// before they are written to the buffer so no events will be missed.
 	outputR := job.OutputBuffer.Reader(-1)
 	go outputR.CloseContext(ctx)
+
+	// Send down all our stored lines.
+	if err := s.sendJobStreamStoredOutput(job.Id, server, output, true); err != nil {
+		return nil, err
+	}
+
+	// Send down all our buffered lines which were not stored.
 	for {
 		events := s.readJobLogBatch(outputR, false)
 		if events == nil {
 			break
 		}
+		events = output.filter(events)
+		if len(events) == 0 {
+			continue
+		}
 
 		if err := server.Send(&vagrant_server.GetJobStreamResponse{
 			Event: &vagrant_server.GetJobStreamResponse_Terminal_{
@@ -369,3 +435,73 @@ func (s *service) getJobStreamOutputInit(
 
 	return eventsCh, nil
 }
+
+// sendJobStreamStoredOutput sends the stored output of the job which has
+// not been sent on the stream yet.
+func (s *service) sendJobStreamStoredOutput(
+	jobId string,
+	server vagrant_server.Vagrant_GetJobStreamServer,
+	output *jobStreamOutput,
+	buffered bool,
+) error {
+	stored, err := s.state.JobOutputList(jobId, output.offset)
+	if err != nil {
+		return err
+	}
+
+	stored = output.filter(stored)
+	for len(stored) > 0 {
+		n := len(stored)
+		if n > jobStreamBatchSize {
+			n = jobStreamBatchSize
+		}
+
+		if err := server.Send(&vagrant_server.GetJobStreamResponse{
+			Event: &vagrant_server.GetJobStreamResponse_Terminal_{
+				Terminal: &vagrant_server.GetJobStreamResponse_Terminal{
+					Events:   stored[:n],
+					Buffered: buffered,
+				},
+			},
+		}); err != nil {
+			return err
+		}
+
+		stored = stored[n:]
+	}
+
+	return nil
+}
+
+// jobStreamOutput tracks the terminal output sent on a job stream so
+// that events are only sent once.
+type jobStreamOutput struct {
+	// offset is the offset of the last event sent
+	offset uint64
+
+	// since if set skips events with a timestamp before this time
+	since time.Time
+}
+
+// filter returns the events which have not been sent. Events which were
+// not stored do not have an offset and are always sent.
+func (o *jobStreamOutput) filter(
+	events []*vagrant_server.GetJobStreamResponse_Terminal_Event,
+) []*vagrant_server.GetJobStreamResponse_Terminal_Event {
+	var result []*vagrant_server.GetJobStreamResponse_Terminal_Event
+	for _, ev := range events {
+		if ev.Offset != 0 {
+			if ev.Offset <= o.offset {
+				continue
+			}
+			o.offset = ev.Offset
+		}
+		if !o.since.IsZero() && ev.Timestamp != nil && ev.Timestamp.AsTime().Before(o.since) {
+			continue
+		}
+
+		result = append(result, ev)
+	}
+
+	return result
+}
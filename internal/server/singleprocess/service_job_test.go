@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/hashicorp/vagrant/internal/server"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
@@ -105,6 +106,28 @@ func TestServiceValidateJob(t *testing.T) {
 		require.False(resp.Valid)
 		require.False(resp.Assignable)
 	})
+
+	t.Run("no matching runner", func(t *testing.T) {
+		require := require.New(t)
+
+		// Register a runner without the label
+		TestRunner(t, client, &vagrant_server.Runner{
+			Labels: map[string]string{"provider": "virtualbox"},
+		})
+
+		job := serverptypes.TestJobNew(t, nil)
+		job.TargetRunner.GetAny().Selectors = []*vagrant_server.Ref_RunnerLabelSelector{
+			{Key: "provider", Values: []string{"libvirt"}},
+		}
+		resp, err := client.ValidateJob(ctx, &Req{
+			Job: job,
+		})
+		require.NoError(err)
+		require.True(resp.Valid)
+		require.False(resp.Assignable)
+		require.NotNil(resp.AssignableError)
+		require.Contains(resp.AssignableError.Message, "no matching runner")
+	})
 }
 
 func TestServiceGetJobStream_complete(t *testing.T) {
@@ -347,6 +370,213 @@ func TestServiceGetJobStream_bufferedData(t *testing.T) {
 	}
 }
 
+func TestServiceGetJobStream_resume(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(err)
+	client := server.TestServer(t, impl)
+
+	// Initialize our basis
+	TestBasis(t, client, serverptypes.TestBasis(t, nil))
+
+	// Create a job
+	queueResp, err := client.QueueJob(ctx, &vagrant_server.QueueJobRequest{Job: serverptypes.TestJobNew(t, nil)})
+	require.NoError(err)
+	require.NotEmpty(queueResp.JobId)
+
+	// Register our runner
+	id, _ := TestRunner(t, client, nil)
+
+	// Start a job request
+	runnerStream, err := client.RunnerJobStream(ctx)
+	require.NoError(err)
+	require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+		Event: &vagrant_server.RunnerJobStreamRequest_Request_{
+			Request: &vagrant_server.RunnerJobStreamRequest_Request{
+				RunnerId: id,
+			},
+		},
+	}))
+
+	// Wait for assignment and ack
+	{
+		resp, err := runnerStream.Recv()
+		require.NoError(err)
+		_, ok := resp.Event.(*vagrant_server.RunnerJobStreamResponse_Assignment)
+		require.True(ok, "should be an assignment")
+
+		require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+			Event: &vagrant_server.RunnerJobStreamRequest_Ack_{
+				Ack: &vagrant_server.RunnerJobStreamRequest_Ack{},
+			},
+		}))
+	}
+
+	// Send some output, each line a second apart
+	start := time.Now().Add(-time.Hour)
+	for i, msg := range []string{"one", "two", "three"} {
+		require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+			Event: &vagrant_server.RunnerJobStreamRequest_Terminal{
+				Terminal: &vagrant_server.GetJobStreamResponse_Terminal{
+					Events: []*vagrant_server.GetJobStreamResponse_Terminal_Event{
+						{
+							Timestamp: timestamppb.New(start.Add(time.Duration(i) * time.Second)),
+							Event: &vagrant_server.GetJobStreamResponse_Terminal_Event_Line_{
+								Line: &vagrant_server.GetJobStreamResponse_Terminal_Event_Line{
+									Msg: msg,
+								},
+							},
+						},
+					},
+				},
+			},
+		}))
+	}
+
+	// Complete the job
+	require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+		Event: &vagrant_server.RunnerJobStreamRequest_Complete_{
+			Complete: &vagrant_server.RunnerJobStreamRequest_Complete{},
+		},
+	}))
+
+	// Should be done
+	_, err = runnerStream.Recv()
+	require.Equal(io.EOF, err)
+
+	// streamLines returns the lines streamed for the request
+	streamLines := func(req *vagrant_server.GetJobStreamRequest) ([]string, []uint64) {
+		stream, err := client.GetJobStream(ctx, req)
+		require.NoError(err)
+
+		var lines []string
+		var offsets []uint64
+		for {
+			resp, err := stream.Recv()
+			require.NoError(err)
+
+			switch event := resp.Event.(type) {
+			case *vagrant_server.GetJobStreamResponse_Terminal_:
+				for _, ev := range event.Terminal.Events {
+					lines = append(lines, ev.GetLine().Msg)
+					offsets = append(offsets, ev.Offset)
+				}
+
+			case *vagrant_server.GetJobStreamResponse_Complete_:
+				require.Nil(event.Complete.Error)
+				return lines, offsets
+			}
+		}
+	}
+
+	// All of the output is replayed
+	lines, offsets := streamLines(&vagrant_server.GetJobStreamRequest{JobId: queueResp.JobId})
+	require.Equal([]string{"one", "two", "three"}, lines)
+	require.Equal([]uint64{1, 2, 3}, offsets)
+
+	// Resume after the first event
+	lines, _ = streamLines(&vagrant_server.GetJobStreamRequest{
+		JobId:  queueResp.JobId,
+		Offset: 1,
+	})
+	require.Equal([]string{"two", "three"}, lines)
+
+	// Resume from a timestamp
+	lines, _ = streamLines(&vagrant_server.GetJobStreamRequest{
+		JobId: queueResp.JobId,
+		Since: timestamppb.New(start.Add(2 * time.Second)),
+	})
+	require.Equal([]string{"three"}, lines)
+}
+
+func TestServiceGetJobStream_blocked(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(err)
+	client := server.TestServer(t, impl)
+
+	// Initialize our basis
+	TestBasis(t, client, serverptypes.TestBasis(t, nil))
+
+	// Create two conflicting jobs for the same target
+	var jobIds []string
+	for i := 0; i < 2; i++ {
+		queueResp, err := client.QueueJob(ctx, &vagrant_server.QueueJobRequest{
+			Job: serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Operation: &vagrant_server.Job_Run{
+					Run: &vagrant_server.Job_RunOp{
+						Task: &vagrant_server.Task{Task: "up", CommandName: "up"},
+					},
+				},
+			}),
+		})
+		require.NoError(err)
+		jobIds = append(jobIds, queueResp.JobId)
+	}
+
+	// Register our runner
+	id, _ := TestRunner(t, client, nil)
+
+	// Start a job request
+	runnerStream, err := client.RunnerJobStream(ctx)
+	require.NoError(err)
+	require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+		Event: &vagrant_server.RunnerJobStreamRequest_Request_{
+			Request: &vagrant_server.RunnerJobStreamRequest_Request{
+				RunnerId: id,
+			},
+		},
+	}))
+
+	// Wait for assignment and ack
+	{
+		resp, err := runnerStream.Recv()
+		require.NoError(err)
+		assignment, ok := resp.Event.(*vagrant_server.RunnerJobStreamResponse_Assignment)
+		require.True(ok, "should be an assignment")
+		require.Equal(jobIds[0], assignment.Assignment.Job.Id)
+
+		require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+			Event: &vagrant_server.RunnerJobStreamRequest_Ack_{
+				Ack: &vagrant_server.RunnerJobStreamRequest_Ack{},
+			},
+		}))
+	}
+
+	// The second job should be blocked on the first
+	stream, err := client.GetJobStream(ctx, &vagrant_server.GetJobStreamRequest{JobId: jobIds[1]})
+	require.NoError(err)
+	{
+		resp := jobStreamRecv(t, stream, (*vagrant_server.GetJobStreamResponse_State_)(nil))
+		state := resp.Event.(*vagrant_server.GetJobStreamResponse_State_).State
+		require.Equal(vagrant_server.Job_QUEUED, state.Current)
+		require.True(state.Blocked)
+		require.Contains(state.BlockedMessage, jobIds[0])
+	}
+
+	// Complete the first job
+	require.NoError(runnerStream.Send(&vagrant_server.RunnerJobStreamRequest{
+		Event: &vagrant_server.RunnerJobStreamRequest_Complete_{
+			Complete: &vagrant_server.RunnerJobStreamRequest_Complete{},
+		},
+	}))
+
+	// The second job is no longer blocked
+	{
+		resp := jobStreamRecv(t, stream, (*vagrant_server.GetJobStreamResponse_State_)(nil))
+		state := resp.Event.(*vagrant_server.GetJobStreamResponse_State_).State
+		require.Equal(vagrant_server.Job_QUEUED, state.Current)
+		require.False(state.Blocked)
+		require.Empty(state.BlockedMessage)
+	}
+}
+
 func TestServiceGetJobStream_expired(t *testing.T) {
 	ctx := context.Background()
 	require := require.New(t)
@@ -378,6 +608,54 @@ func TestServiceGetJobStream_expired(t *testing.T) {
 	}
 }
 
+func TestServiceGetJobStream_retry(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(err)
+	client := server.TestServer(t, impl)
+
+	// Initialize our basis
+	TestBasis(t, client, serverptypes.TestBasis(t, nil))
+
+	// Create a job that is retried once when it expires
+	queueResp, err := client.QueueJob(ctx, &vagrant_server.QueueJobRequest{
+		Job:       serverptypes.TestJobNew(t, nil),
+		ExpiresIn: "50ms",
+		RetryPolicy: &vagrant_server.Job_RetryPolicy{
+			MaxAttempts: 2,
+			Backoff:     "1ms",
+			RetryableErrors: []vagrant_server.Job_RetryPolicy_ErrorClass{
+				vagrant_server.Job_RetryPolicy_EXPIRED,
+			},
+		},
+	})
+	require.NoError(err)
+	require.NotEmpty(queueResp.JobId)
+
+	// Get our job stream
+	stream, err := client.GetJobStream(ctx, &vagrant_server.GetJobStreamRequest{JobId: queueResp.JobId})
+	require.NoError(err)
+
+	// We should be told about the retry
+	{
+		resp := jobStreamRecv(t, stream, (*vagrant_server.GetJobStreamResponse_Retry_)(nil))
+		event := resp.Event.(*vagrant_server.GetJobStreamResponse_Retry_)
+		require.Equal(uint32(1), event.Retry.Attempt)
+		require.Equal(int32(codes.DeadlineExceeded), event.Retry.Error.Code)
+		require.NotNil(event.Retry.RetryTime)
+	}
+
+	// The second attempt expires too
+	{
+		resp := jobStreamRecv(t, stream, (*vagrant_server.GetJobStreamResponse_Complete_)(nil))
+		event := resp.Event.(*vagrant_server.GetJobStreamResponse_Complete_)
+		require.Equal(int32(codes.Canceled), event.Complete.Error.Code)
+	}
+}
+
 // jobStreamRecv receives on the stream until an event of the given
 // type is matched.
 func jobStreamRecv(
@@ -1,142 +1,205 @@
 package singleprocess
 
 import (
-	//	"sync"
+	"io"
+	"sync"
 
-	//"github.com/hashicorp/go-hclog"
-	//	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-memdb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/logbuffer"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
-	//	"github.com/hashicorp/vagrant/internal/server/singleprocess/state"
 )
 
 // defaultLogLimitBacklog is the default backlog amount to send down.
-const defaultLogLimitBacklog = 100
+const defaultLogLimitBacklog = 50
 
-// TODO: test
 func (s *service) GetLogStream(
 	req *vagrant_server.GetLogStreamRequest,
 	srv vagrant_server.Vagrant_GetLogStreamServer,
 ) error {
-	//log := hclog.FromContext(srv.Context())
+	log := hclog.FromContext(srv.Context())
 
 	// Default the limit
 	if req.LimitBacklog == 0 {
 		req.LimitBacklog = defaultLogLimitBacklog
 	}
 
-	//	var instanceFunc func(ws memdb.WatchSet) ([]*state.Instance, error)
-	switch scope := req.Scope.(type) {
-	// case *vagrant_server.GetLogStreamRequest_DeploymentId:
-	// 	log = log.With("deployment_id", scope.DeploymentId)
-	// 	instanceFunc = func(ws memdb.WatchSet) ([]*state.Instance, error) {
-	// 		return s.state.InstancesByDeployment(scope.DeploymentId, ws)
-	// 	}
-
-	// case *vagrant_server.GetLogStreamRequest_Application_:
-	// 	if scope.Application == nil ||
-	// 		scope.Application.Application == nil ||
-	// 		scope.Application.Workspace == nil {
-	// 		return status.Errorf(
-	// 			codes.FailedPrecondition,
-	// 			"application scope requires the application and workspace fields to be set",
-	// 		)
-	// 	}
-
-	// 	log = log.With(
-	// 		"project", scope.Application.Application.Project,
-	// 		"application", scope.Application.Application.Application,
-	// 		"workspace", scope.Application.Workspace.Workspace,
-	// 	)
-	// 	instanceFunc = func(ws memdb.WatchSet) ([]*state.Instance, error) {
-	// 		return s.state.InstancesByApp(
-	// 			scope.Application.Application,
-	// 			scope.Application.Workspace,
-	// 			ws,
-	// 		)
-	// 	}
-
+	var scope interface{}
+	switch r := req.Scope.(type) {
+	case *vagrant_server.GetLogStreamRequest_Basis:
+		scope = r.Basis
+	case *vagrant_server.GetLogStreamRequest_Project:
+		scope = r.Project
+	case *vagrant_server.GetLogStreamRequest_Target:
+		scope = r.Target
 	default:
 		return status.Errorf(
 			codes.FailedPrecondition,
-			"invalid scope supplied: %T - %T",
-			req.Scope, scope,
+			"invalid scope supplied: %T",
+			req.Scope,
 		)
 	}
 
-	// We keep track of what instances we already have readers for here.
-	//	var instanceSetLock sync.Mutex
-	//instanceSet := make(map[string]struct{})
-
-	// // We loop forever so that we can automatically get any new instances that
-	// // join as we have an open log stream.
-	// for {
-	// 	// Get all our records
-	// 	ws := memdb.NewWatchSet()
-	// 	records, err := instanceFunc(ws)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	log.Trace("instances loaded", "len", len(records))
-
-	// 	// For each record, start a goroutine that reads the log entries and sends them.
-	// 	for _, record := range records {
-	// 		instanceId := record.Id
-	// 		deploymentId := record.DeploymentId
-
-	// 		// If we already have a reader for this, then do nothing.
-	// 		instanceSetLock.Lock()
-	// 		_, exit := instanceSet[instanceId]
-	// 		instanceSet[instanceId] = struct{}{}
-	// 		instanceSetLock.Unlock()
-	// 		if exit {
-	// 			continue
-	// 		}
-
-	// 		// Start our reader up
-	// 		r := record.LogBuffer.Reader(req.LimitBacklog)
-	// 		instanceLog := log.With("instance_id", instanceId)
-	// 		instanceLog.Trace("instance log stream starting")
-	// 		go r.CloseContext(srv.Context())
-	// 		go func() {
-	// 			defer instanceLog.Debug("instance log stream ending")
-	// 			defer func() {
-	// 				instanceSetLock.Lock()
-	// 				defer instanceSetLock.Unlock()
-	// 				delete(instanceSet, instanceId)
-	// 			}()
-
-	// 			for {
-	// 				entries := r.Read(64, true)
-	// 				if entries == nil {
-	// 					return
-	// 				}
-
-	// 				lines := make([]*vagrant_server.LogBatch_Entry, len(entries))
-	// 				for i, v := range entries {
-	// 					lines[i] = v.(*vagrant_server.LogBatch_Entry)
-	// 				}
-
-	// 				instanceLog.Trace("sending instance log data", "entries", len(entries))
-	// 				srv.Send(&vagrant_server.LogBatch{
-	// 					DeploymentId: deploymentId,
-	// 					InstanceId:   instanceId,
-	// 					Lines:        lines,
-	// 				})
-	// 			}
-	// 		}()
-	// 	}
-
-	// // Wait for changes or to be done
-	// if err := ws.WatchCtx(srv.Context()); err != nil {
-	// 	// If our context ended, exit with that
-	// 	if err := srv.Context().Err(); err != nil {
-	// 		return err
-	// 	}
-
-	// 	return err
-	// }
-	//	}
+	// Multiple goroutines send on the stream so sends must be serialized.
+	var sendLock sync.Mutex
+
+	// We keep track of what log streams we already have readers for here.
+	var streamSetLock sync.Mutex
+	streamSet := make(map[string]struct{})
+
+	// We loop forever so that we can automatically get any new log streams
+	// that are opened while we have an open log stream.
+	for {
+		// Get all our records
+		ws := memdb.NewWatchSet()
+		records, err := s.state.LogStreamsByScope(scope, ws)
+		if err != nil {
+			return err
+		}
+		log.Trace("log streams loaded", "len", len(records))
+
+		// For each record, start a goroutine that reads the log entries and sends them.
+		for _, record := range records {
+			streamId := record.Id
+
+			// If we already have a reader for this, then do nothing.
+			streamSetLock.Lock()
+			_, exit := streamSet[streamId]
+			streamSet[streamId] = struct{}{}
+			streamSetLock.Unlock()
+			if exit {
+				continue
+			}
+
+			// Build the batch template sent with each set of lines
+			batch := &vagrant_server.LogBatch{InstanceId: record.InstanceId}
+			switch r := record.Scope.(type) {
+			case *vagrant_plugin_sdk.Ref_Basis:
+				batch.Scope = &vagrant_server.LogBatch_Basis{Basis: r}
+			case *vagrant_plugin_sdk.Ref_Project:
+				batch.Scope = &vagrant_server.LogBatch_Project{Project: r}
+			case *vagrant_plugin_sdk.Ref_Target:
+				batch.Scope = &vagrant_server.LogBatch_Target{Target: r}
+			}
+
+			// Start our reader up
+			r := record.LogBuffer.Reader(req.LimitBacklog)
+			streamLog := log.With("log_stream_id", streamId)
+			streamLog.Trace("log stream reader starting")
+			go r.CloseContext(srv.Context())
+			go func() {
+				defer streamLog.Debug("log stream reader ending")
+				defer func() {
+					streamSetLock.Lock()
+					defer streamSetLock.Unlock()
+					delete(streamSet, streamId)
+				}()
+
+				for {
+					entries := r.Read(64, true)
+					if entries == nil {
+						return
+					}
+					if len(entries) == 0 {
+						continue
+					}
+
+					lines := make([]*vagrant_server.LogBatch_Entry, len(entries))
+					for i, v := range entries {
+						lines[i] = v.(*vagrant_server.LogBatch_Entry)
+					}
+
+					streamLog.Trace("sending log data", "entries", len(entries))
+					sendLock.Lock()
+					err := srv.Send(&vagrant_server.LogBatch{
+						InstanceId: batch.InstanceId,
+						Scope:      batch.Scope,
+						Lines:      lines,
+					})
+					sendLock.Unlock()
+					if err != nil {
+						streamLog.Warn("error sending log data", "error", err)
+						r.Close()
+						return
+					}
+				}
+			}()
+		}
+
+		// Wait for changes or to be done
+		if err := ws.WatchCtx(srv.Context()); err != nil {
+			// If our context ended, exit with that
+			if err := srv.Context().Err(); err != nil {
+				return err
+			}
+
+			return err
+		}
+	}
+}
+
+func (s *service) SendLogStream(
+	srv vagrant_server.Vagrant_SendLogStreamServer,
+) error {
+	log := hclog.FromContext(srv.Context())
+
+	// Read our first message which must set the scope
+	req, err := srv.Recv()
+	if err != nil {
+		return err
+	}
+
+	var scope interface{}
+	switch r := req.Scope.(type) {
+	case *vagrant_server.SendLogStreamRequest_Basis:
+		scope = r.Basis
+	case *vagrant_server.SendLogStreamRequest_Project:
+		scope = r.Project
+	case *vagrant_server.SendLogStreamRequest_Target:
+		scope = r.Target
+	default:
+		return status.Errorf(codes.FailedPrecondition,
+			"first message must set the log stream scope")
+	}
+
+	record, err := s.state.LogStreamOpen(scope, req.InstanceId)
+	if err != nil {
+		return err
+	}
+	defer s.state.LogStreamClose(record.Id)
+
+	log = log.With("log_stream_id", record.Id)
+	log.Debug("log stream writer starting")
+	defer log.Debug("log stream writer ending")
+
+	for {
+		logBatchWrite(record.LogBuffer, req.Lines)
+
+		req, err = srv.Recv()
+		if err == io.EOF {
+			return srv.SendAndClose(&emptypb.Empty{})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// logBatchWrite writes the lines to the log buffer.
+func logBatchWrite(buf *logbuffer.Buffer, lines []*vagrant_server.LogBatch_Entry) {
+	if len(lines) == 0 {
+		return
+	}
+
+	entries := make([]logbuffer.Entry, len(lines))
+	for i, l := range lines {
+		entries[i] = l
+	}
+
+	buf.Write(entries...)
 }
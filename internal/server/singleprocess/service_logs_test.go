@@ -1,160 +1,174 @@
 package singleprocess
 
-// import (
-// 	"context"
-// 	"strconv"
-// 	"testing"
-// 	"time"
-
-// 	"github.com/stretchr/testify/require"
-
-// 	"github.com/hashicorp/vagrant/internal/server"
-// 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
-// 	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
-// )
-
-// func TestServiceGetLogStream(t *testing.T) {
-// 	ctx := context.Background()
-
-// 	// Create our server
-// 	impl, err := New(WithDB(testDB(t)))
-// 	require.NoError(t, err)
-// 	client := server.TestServer(t, impl)
-
-// 	// Register our instances
-// 	resp, err := client.UpsertDeployment(ctx, &vagrant_server.UpsertDeploymentRequest{
-// 		Deployment: serverptypes.TestValidDeployment(t, &vagrant_server.Deployment{
-// 			Component: &vagrant_server.Component{
-// 				Name: "testapp",
-// 			},
-// 		}),
-// 	})
-
-// 	require.NoError(t, err)
-
-// 	dep := resp.Deployment
-// 	configClient, err := client.EntrypointConfig(ctx, &vagrant_server.EntrypointConfigRequest{
-// 		DeploymentId: dep.Id,
-// 		InstanceId:   "1",
-// 	})
-// 	require.NoError(t, err)
-// 	_, err = configClient.Recv()
-// 	require.NoError(t, err)
-
-// 	// Simplify writing tests
-// 	type Req = vagrant_server.UpsertDeploymentRequest
-
-// 	require := require.New(t)
-
-// 	// Create the stream and send some log messages
-// 	logSendClient, err := client.EntrypointLogStream(ctx)
-// 	require.NoError(err)
-// 	for i := 0; i < 5; i++ {
-// 		var entries []*vagrant_server.LogBatch_Entry
-// 		for j := 0; j < 5; j++ {
-// 			entries = append(entries, &vagrant_server.LogBatch_Entry{
-// 				Line: strconv.Itoa(5*i + j),
-// 			})
-// 		}
-
-// 		logSendClient.Send(&vagrant_server.EntrypointLogBatch{
-// 			InstanceId: "1",
-// 			Lines:      entries,
-// 		})
-// 	}
-// 	time.Sleep(100 * time.Millisecond)
-
-// 	// Connect to the stream and download the logs
-// 	logRecvClient, err := client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
-// 		Scope: &vagrant_server.GetLogStreamRequest_DeploymentId{
-// 			DeploymentId: dep.Id,
-// 		},
-// 	})
-// 	require.NoError(err)
-
-// 	// Get a batch
-// 	batch, err := logRecvClient.Recv()
-// 	require.NoError(err)
-// 	require.NotEmpty(batch.Lines)
-// 	require.Len(batch.Lines, 25)
-// }
-
-// func TestServiceGetLogStream_byApp(t *testing.T) {
-// 	ctx := context.Background()
-
-// 	// Create our server
-// 	impl, err := New(WithDB(testDB(t)))
-// 	require.NoError(t, err)
-// 	client := server.TestServer(t, impl)
-
-// 	// Setup our references
-// 	refApp := &vagrant_server.Ref_Application{
-// 		Project:     "test",
-// 		Application: "app",
-// 	}
-// 	refWs := &vagrant_server.Ref_Workspace{
-// 		Workspace: "ws",
-// 	}
-
-// 	// Register our instances
-// 	resp, err := client.UpsertDeployment(ctx, &vagrant_server.UpsertDeploymentRequest{
-// 		Deployment: serverptypes.TestValidDeployment(t, &vagrant_server.Deployment{
-// 			Application: refApp,
-// 			Workspace:   refWs,
-// 			Component: &vagrant_server.Component{
-// 				Name: "testapp",
-// 			},
-// 		}),
-// 	})
-
-// 	require.NoError(t, err)
-
-// 	dep := resp.Deployment
-// 	configClient, err := client.EntrypointConfig(ctx, &vagrant_server.EntrypointConfigRequest{
-// 		DeploymentId: dep.Id,
-// 		InstanceId:   "1",
-// 	})
-// 	require.NoError(t, err)
-// 	_, err = configClient.Recv()
-// 	require.NoError(t, err)
-
-// 	// Simplify writing tests
-// 	type Req = vagrant_server.UpsertDeploymentRequest
-
-// 	require := require.New(t)
-
-// 	// Create the stream and send some log messages
-// 	logSendClient, err := client.EntrypointLogStream(ctx)
-// 	require.NoError(err)
-// 	for i := 0; i < 5; i++ {
-// 		var entries []*vagrant_server.LogBatch_Entry
-// 		for j := 0; j < 5; j++ {
-// 			entries = append(entries, &vagrant_server.LogBatch_Entry{
-// 				Line: strconv.Itoa(5*i + j),
-// 			})
-// 		}
-
-// 		logSendClient.Send(&vagrant_server.EntrypointLogBatch{
-// 			InstanceId: "1",
-// 			Lines:      entries,
-// 		})
-// 	}
-// 	time.Sleep(100 * time.Millisecond)
-
-// 	// Connect to the stream and download the logs
-// 	logRecvClient, err := client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
-// 		Scope: &vagrant_server.GetLogStreamRequest_Application_{
-// 			Application: &vagrant_server.GetLogStreamRequest_Application{
-// 				Application: refApp,
-// 				Workspace:   refWs,
-// 			},
-// 		},
-// 	})
-// 	require.NoError(err)
-
-// 	// Get a batch
-// 	batch, err := logRecvClient.Recv()
-// 	require.NoError(err)
-// 	require.NotEmpty(batch.Lines)
-// 	require.Len(batch.Lines, 25)
-// }
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestServiceGetLogStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(t, err)
+	client := server.TestServer(t, impl)
+
+	// Create our target
+	basisResp, err := client.UpsertBasis(ctx, &vagrant_server.UpsertBasisRequest{
+		Basis: &vagrant_server.Basis{Name: "mybasis"},
+	})
+	require.NoError(t, err)
+	projectResp, err := client.UpsertProject(ctx, &vagrant_server.UpsertProjectRequest{
+		Project: &vagrant_server.Project{
+			Name:  "myproject",
+			Basis: &vagrant_plugin_sdk.Ref_Basis{ResourceId: basisResp.Basis.ResourceId},
+		},
+	})
+	require.NoError(t, err)
+	targetResp, err := client.UpsertTarget(ctx, &vagrant_server.UpsertTargetRequest{
+		Target: &vagrant_server.Target{
+			Name:    "mytarget",
+			Project: &vagrant_plugin_sdk.Ref_Project{ResourceId: projectResp.Project.ResourceId},
+		},
+	})
+	require.NoError(t, err)
+
+	// Send some log lines for the target
+	logSendClient, err := client.SendLogStream(ctx)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		var entries []*vagrant_server.LogBatch_Entry
+		for j := 0; j < 5; j++ {
+			entries = append(entries, &vagrant_server.LogBatch_Entry{
+				Line: strconv.Itoa(5*i + j),
+			})
+		}
+
+		req := &vagrant_server.SendLogStreamRequest{Lines: entries}
+		if i == 0 {
+			req.InstanceId = "runner"
+			req.Scope = &vagrant_server.SendLogStreamRequest_Target{
+				Target: &vagrant_plugin_sdk.Ref_Target{
+					ResourceId: targetResp.Target.ResourceId,
+				},
+			}
+		}
+		require.NoError(t, logSendClient.Send(req))
+	}
+	_, err = logSendClient.CloseAndRecv()
+	require.NoError(t, err)
+
+	t.Run("target backlog", func(t *testing.T) {
+		require := require.New(t)
+
+		logRecvClient, err := client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
+			Scope: &vagrant_server.GetLogStreamRequest_Target{
+				Target: &vagrant_plugin_sdk.Ref_Target{
+					ResourceId: targetResp.Target.ResourceId,
+				},
+			},
+		})
+		require.NoError(err)
+
+		batch, err := logRecvClient.Recv()
+		require.NoError(err)
+		require.Len(batch.Lines, 25)
+		require.Equal("runner", batch.InstanceId)
+		require.Equal("mytarget", batch.GetTarget().Name)
+	})
+
+	t.Run("project backlog is limited", func(t *testing.T) {
+		require := require.New(t)
+
+		logRecvClient, err := client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
+			Scope: &vagrant_server.GetLogStreamRequest_Project{
+				Project: &vagrant_plugin_sdk.Ref_Project{
+					ResourceId: projectResp.Project.ResourceId,
+				},
+			},
+			LimitBacklog: 5,
+		})
+		require.NoError(err)
+
+		batch, err := logRecvClient.Recv()
+		require.NoError(err)
+		require.Len(batch.Lines, 5)
+		require.Equal("24", batch.Lines[4].Line)
+	})
+
+	t.Run("basis receives new log streams", func(t *testing.T) {
+		require := require.New(t)
+
+		logRecvClient, err := client.GetLogStream(ctx, &vagrant_server.GetLogStreamRequest{
+			Scope: &vagrant_server.GetLogStreamRequest_Basis{
+				Basis: &vagrant_plugin_sdk.Ref_Basis{Name: "mybasis"},
+			},
+			LimitBacklog: -1,
+		})
+		require.NoError(err)
+
+		// Get the backlog
+		batch, err := logRecvClient.Recv()
+		require.NoError(err)
+		require.Len(batch.Lines, 25)
+
+		// Send lines for the project from a new source
+		logSendClient, err := client.SendLogStream(ctx)
+		require.NoError(err)
+		require.NoError(logSendClient.Send(&vagrant_server.SendLogStreamRequest{
+			InstanceId: "plugin",
+			Scope: &vagrant_server.SendLogStreamRequest_Project{
+				Project: &vagrant_plugin_sdk.Ref_Project{
+					ResourceId: projectResp.Project.ResourceId,
+				},
+			},
+			Lines: []*vagrant_server.LogBatch_Entry{{Line: "hello"}},
+		}))
+
+		recvCh := make(chan *vagrant_server.LogBatch, 1)
+		go func() {
+			batch, err := logRecvClient.Recv()
+			if err == nil {
+				recvCh <- batch
+			}
+		}()
+
+		select {
+		case batch := <-recvCh:
+			require.Equal("plugin", batch.InstanceId)
+			require.Equal("myproject", batch.GetProject().Name)
+			require.Len(batch.Lines, 1)
+			require.Equal("hello", batch.Lines[0].Line)
+
+		case <-time.After(2 * time.Second):
+			t.Fatal("should receive new log lines")
+		}
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		require := require.New(t)
+
+		logSendClient, err := client.SendLogStream(ctx)
+		require.NoError(err)
+		require.NoError(logSendClient.Send(&vagrant_server.SendLogStreamRequest{
+			InstanceId: "runner",
+			Scope: &vagrant_server.SendLogStreamRequest_Target{
+				Target: &vagrant_plugin_sdk.Ref_Target{ResourceId: "nope"},
+			},
+		}))
+		_, err = logSendClient.CloseAndRecv()
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+}
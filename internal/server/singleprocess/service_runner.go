@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/go-memdb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/hashicorp/vagrant/internal/server/logbuffer"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
@@ -22,6 +23,29 @@ func (s *service) GetRunner(
 	return s.state.RunnerById(req.RunnerId)
 }
 
+func (s *service) ListRunners(
+	ctx context.Context,
+	req *vagrant_server.ListRunnersRequest,
+) (*vagrant_server.ListRunnersResponse, error) {
+	runners, err := s.state.RunnerList()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.ListRunnersResponse{Runners: runners}, nil
+}
+
+func (s *service) DrainRunner(
+	ctx context.Context,
+	req *vagrant_server.DrainRunnerRequest,
+) (*emptypb.Empty, error) {
+	if err := s.state.RunnerDrain(req.RunnerId); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
 // TODO: test
 func (s *service) RunnerConfig(
 	srv vagrant_server.Vagrant_RunnerConfigServer,
@@ -49,13 +73,12 @@ func (s *service) RunnerConfig(
 		return err
 	}
 
-	// Defer deleting this.
-	// TODO(mitchellh): this is too aggressive and we want to have some grace
-	// period for reconnecting clients. We should clean this up.
+	// Defer marking the runner offline. The runner is deleted after a
+	// grace period if it doesn't reconnect.
 	defer func() {
-		log.Trace("deleting runner")
-		if err := s.state.RunnerDelete(record.Id); err != nil {
-			log.Error("failed to delete runner data. This should not happen.", "err", err)
+		log.Trace("marking runner offline")
+		if err := s.state.RunnerOffline(record.Id); err != nil {
+			log.Error("failed to mark runner offline. This should not happen.", "err", err)
 		}
 	}()
 
@@ -206,10 +229,10 @@ func (s *service) RunnerJobStream(
 	// Start a goroutine that watches for job changes
 	jobCh := make(chan *state.Job, 1)
 	errCh := make(chan error, 1)
-	go func() {
+	go func(id string) {
 		for {
 			ws := memdb.NewWatchSet()
-			job, err = s.state.JobById(job.Id, ws)
+			job, err := s.state.JobById(id, ws)
 			if err != nil {
 				errCh <- err
 				return
@@ -235,7 +258,7 @@ func (s *service) RunnerJobStream(
 				return
 			}
 		}
-	}()
+	}(job.Id)
 
 	// Create a goroutine that just waits for events. We have to do this
 	// so we can exit properly on client side close.
@@ -358,9 +381,21 @@ func (s *service) handleJobStreamRequest(
 		return s.state.JobComplete(job.Id, nil, status.FromProto(event.Error.Error).Err())
 
 	case *vagrant_server.RunnerJobStreamRequest_Heartbeat_:
+		if err := s.state.RunnerHeartbeat(job.AssignedRunner.GetId()); err != nil {
+			log.Warn("error recording runner heartbeat", "error", err)
+		}
+
 		return s.state.JobHeartbeat(job.Id)
 
 	case *vagrant_server.RunnerJobStreamRequest_Terminal:
+		// Store the events so the output is available after the job is
+		// complete. This also sets the offset of each event. The events
+		// are written in batches, so an error here means the offsets
+		// couldn't be assigned.
+		if err := s.state.JobOutputAppend(job.Id, event.Terminal.Events); err != nil {
+			log.Warn("error storing job output", "error", err)
+		}
+
 		// This shouldn't happen but we want to protect against it to prevent
 		// a panic.
 		if job.OutputBuffer == nil {
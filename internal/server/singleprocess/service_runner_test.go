@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
@@ -14,6 +15,42 @@ import (
 	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
 )
 
+func TestServiceListRunners(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)), WithRunnerGracePeriod(50*time.Millisecond))
+	require.NoError(err)
+	client := server.TestServer(t, impl)
+
+	// Register our runners
+	id, closeA := TestRunner(t, client, &vagrant_server.Runner{Hostname: "host-a"})
+	TestRunner(t, client, nil)
+
+	resp, err := client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+	require.NoError(err)
+	require.Len(resp.Runners, 2)
+
+	// Drain the first runner
+	_, err = client.DrainRunner(ctx, &vagrant_server.DrainRunnerRequest{RunnerId: id})
+	require.NoError(err)
+	runner, err := client.GetRunner(ctx, &vagrant_server.GetRunnerRequest{RunnerId: id})
+	require.NoError(err)
+	require.Equal("host-a", runner.Hostname)
+	require.True(runner.Online)
+	require.True(runner.Draining)
+	require.NotNil(runner.LastHeartbeat)
+
+	// Disconnect the runner, it is removed after the grace period
+	closeA()
+	require.Eventually(func() bool {
+		resp, err := client.ListRunners(ctx, &vagrant_server.ListRunnersRequest{})
+		require.NoError(err)
+		return len(resp.Runners) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
 // Complete happy path job stream
 func TestServiceRunnerJobStream_complete(t *testing.T) {
 	ctx := context.Background()
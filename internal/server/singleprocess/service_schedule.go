@@ -0,0 +1,76 @@
+package singleprocess
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func (s *service) UpsertSchedule(
+	ctx context.Context,
+	req *vagrant_server.UpsertScheduleRequest,
+) (*vagrant_server.UpsertScheduleResponse, error) {
+	result := req.Schedule
+	if result == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "schedule must be set")
+	}
+	if err := serverptypes.ValidateSchedule(result); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+
+	// Generate an ID for new schedules
+	if result.Id == "" {
+		id, err := server.Id()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "uuid generation failed: %s", err)
+		}
+		result.Id = id
+	}
+
+	if err := s.state.SchedulePut(result); err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.UpsertScheduleResponse{Schedule: result}, nil
+}
+
+func (s *service) GetSchedule(
+	ctx context.Context,
+	req *vagrant_server.GetScheduleRequest,
+) (*vagrant_server.GetScheduleResponse, error) {
+	result, err := s.state.ScheduleGet(req.ScheduleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.GetScheduleResponse{Schedule: result}, nil
+}
+
+func (s *service) ListSchedules(
+	ctx context.Context,
+	req *emptypb.Empty,
+) (*vagrant_server.ListSchedulesResponse, error) {
+	result, err := s.state.ScheduleList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.ListSchedulesResponse{Schedules: result}, nil
+}
+
+func (s *service) DeleteSchedule(
+	ctx context.Context,
+	req *vagrant_server.DeleteScheduleRequest,
+) (*emptypb.Empty, error) {
+	if err := s.state.ScheduleDelete(req.ScheduleId); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,90 @@
+package singleprocess
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestServiceSchedule(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(err)
+	client := server.TestServer(t, impl)
+
+	// Create a schedule which runs every second
+	resp, err := client.UpsertSchedule(ctx, &vagrant_server.UpsertScheduleRequest{
+		Schedule: serverptypes.TestSchedule(t, &vagrant_server.Schedule{
+			Cron: "* * * * * * *",
+		}),
+	})
+	require.NoError(err)
+	id := resp.Schedule.Id
+	require.NotEmpty(id)
+	require.NotNil(resp.Schedule.NextRunTime)
+
+	// The job should be queued
+	var sched *vagrant_server.Schedule
+	require.Eventually(func() bool {
+		resp, err := client.GetSchedule(ctx, &vagrant_server.GetScheduleRequest{ScheduleId: id})
+		require.NoError(err)
+		sched = resp.Schedule
+		return sched.LastJobId != ""
+	}, 5*time.Second, 50*time.Millisecond)
+
+	job, err := client.GetJob(ctx, &vagrant_server.GetJobRequest{JobId: sched.LastJobId})
+	require.NoError(err)
+	require.Equal(vagrant_server.Job_QUEUED, job.State)
+
+	// Without a runner the job stays queued so the next run is skipped
+	require.Eventually(func() bool {
+		resp, err := client.GetSchedule(ctx, &vagrant_server.GetScheduleRequest{ScheduleId: id})
+		require.NoError(err)
+		return resp.Schedule.NextRunTime.AsTime().After(sched.NextRunTime.AsTime())
+	}, 5*time.Second, 50*time.Millisecond)
+
+	getResp, err := client.GetSchedule(ctx, &vagrant_server.GetScheduleRequest{ScheduleId: id})
+	require.NoError(err)
+	require.Equal(sched.LastJobId, getResp.Schedule.LastJobId)
+
+	// List and delete
+	listResp, err := client.ListSchedules(ctx, &emptypb.Empty{})
+	require.NoError(err)
+	require.Len(listResp.Schedules, 1)
+
+	_, err = client.DeleteSchedule(ctx, &vagrant_server.DeleteScheduleRequest{ScheduleId: id})
+	require.NoError(err)
+	_, err = client.GetSchedule(ctx, &vagrant_server.GetScheduleRequest{ScheduleId: id})
+	require.Error(err)
+	require.Equal(codes.NotFound, status.Code(err))
+}
+
+func TestServiceUpsertSchedule_invalid(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// Create our server
+	impl, err := New(WithDB(testDB(t)))
+	require.NoError(err)
+	client := server.TestServer(t, impl)
+
+	_, err = client.UpsertSchedule(ctx, &vagrant_server.UpsertScheduleRequest{
+		Schedule: serverptypes.TestSchedule(t, &vagrant_server.Schedule{
+			Cron: "never",
+		}),
+	})
+	require.Error(err)
+	require.Equal(codes.FailedPrecondition, status.Code(err))
+}
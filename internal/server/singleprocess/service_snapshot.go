@@ -16,7 +16,7 @@ import (
 )
 
 func (s *service) CreateSnapshot(
-	req *emptypb.Empty,
+	req *vagrant_server.CreateSnapshotRequest,
 	srv vagrant_server.Vagrant_CreateSnapshotServer,
 ) error {
 	// Always send the open message. In the future we'll send some metadata here.
@@ -30,7 +30,7 @@ func (s *service) CreateSnapshot(
 
 	// Create the snapshot and write the data
 	bw := bufio.NewWriter(&snapshotWriter{srv: srv})
-	if err := s.state.CreateSnapshot(bw); err != nil {
+	if err := s.state.CreateSnapshot(bw, req.IncludeHmacKeys); err != nil {
 		return err
 	}
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -52,7 +53,7 @@ func TestServiceRestoreSnapshot_full(t *testing.T) {
 	// Take a snapshot and write the contents to a buf
 	var snapshotBuf bytes.Buffer
 	{
-		stream, err := client.CreateSnapshot(ctx, &emptypb.Empty{})
+		stream, err := client.CreateSnapshot(ctx, &vagrant_server.CreateSnapshotRequest{})
 		require.NoError(err)
 
 		// Should get the open message
@@ -109,3 +110,49 @@ func TestServiceRestoreSnapshot_full(t *testing.T) {
 	require.NoError(err)
 	require.NotNil(resp)
 }
+
+func TestServiceRestoreSnapshot_hmacKeys(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	db := testDB(t)
+	impl, err := New(WithDB(db))
+	require.NoError(err)
+	s := impl.(*service)
+
+	token, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{User: DefaultUser})
+	require.NoError(err)
+	require.NoError(s.Authenticate(ctx, token, "test", nil))
+
+	// Snapshot without the keys and restart the server to restore it
+	var buf bytes.Buffer
+	require.NoError(s.state.CreateSnapshot(&buf, false))
+	require.NoError(s.state.StageRestoreSnapshot(bytes.NewReader(buf.Bytes())))
+	path := db.Path()
+	require.NoError(s.state.Close())
+	db, err = bolt.Open(path, 0600, nil)
+	require.NoError(err)
+	t.Cleanup(func() { db.Close() })
+
+	impl, err = New(WithDB(db))
+	require.NoError(err)
+	s = impl.(*service)
+
+	// The key was rotated so the token is rejected, and the server
+	// can't be bootstrapped again.
+	require.Error(s.Authenticate(ctx, token, "test", nil))
+	_, err = s.BootstrapToken(ctx, &emptypb.Empty{})
+	require.Error(err)
+
+	keys, err := s.ListHMACKeys(ctx, &emptypb.Empty{})
+	require.NoError(err)
+	require.Len(keys.Keys, 2)
+	require.Equal("k2", keys.Keys[1].Id)
+	require.True(keys.Keys[1].Active)
+	require.True(keys.Keys[0].Retired)
+
+	// New tokens are signed with the new key
+	token, err = s.newLoginToken(keys.Keys[1].Id, "", nil, &vagrant_server.Token{User: DefaultUser})
+	require.NoError(err)
+	require.NoError(s.Authenticate(ctx, token, "test", nil))
+}
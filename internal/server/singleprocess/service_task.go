@@ -28,6 +28,28 @@ func (s *service) UpsertTask(
 
 		// Specify the id
 		result.Id = id
+
+		// Tasks are upserted by the runner, so they were requested by
+		// whoever queued their job rather than the caller.
+		result.RequestedBy = s.requestUser(ctx)
+		if result.JobId != "" {
+			job, err := s.state.JobById(result.JobId, nil)
+			if err != nil {
+				return nil, err
+			}
+			if job != nil {
+				result.RequestedBy = job.RequestedBy
+			}
+		}
+	} else {
+		// The user that requested a task can't be changed
+		existing, err := s.state.TaskGet(&vagrant_server.Ref_Operation{
+			Target: &vagrant_server.Ref_Operation_Id{Id: result.Id},
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.RequestedBy = existing.RequestedBy
 	}
 
 	if err := s.state.TaskPut(!insert, result); err != nil {
@@ -37,12 +59,16 @@ func (s *service) UpsertTask(
 	return &vagrant_server.UpsertTaskResponse{Task: result}, nil
 }
 
-// TODO: test
 func (s *service) ListTasks(
 	ctx context.Context,
 	req *vagrant_server.ListTasksRequest,
 ) (*vagrant_server.ListTasksResponse, error) {
-	result, err := s.state.TaskList(req.Scope,
+	scope, err := taskScopeRef(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.state.TaskList(scope,
 		state.ListWithStatusFilter(req.Status...),
 		state.ListWithOrder(req.Order),
 		state.ListWithPhysicalState(req.PhysicalState),
@@ -55,12 +81,16 @@ func (s *service) ListTasks(
 	return &vagrant_server.ListTasksResponse{Tasks: result}, nil
 }
 
-// TODO: test
 func (s *service) GetLatestTask(
 	ctx context.Context,
 	req *vagrant_server.GetLatestTaskRequest,
 ) (*vagrant_server.Task, error) {
-	return s.state.TaskLatest(req.Scope)
+	scope, err := taskScopeRef(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.state.TaskLatest(scope)
 }
 
 // GetTask returns a Task based on ID
@@ -70,3 +100,25 @@ func (s *service) GetTask(
 ) (*vagrant_server.Task, error) {
 	return s.state.TaskGet(req.Ref)
 }
+
+// taskScopeRef returns the target, project, or basis reference from
+// the scope of a task request.
+func taskScopeRef(scope interface{}) (interface{}, error) {
+	switch s := scope.(type) {
+	case *vagrant_server.ListTasksRequest_Target:
+		return s.Target, nil
+	case *vagrant_server.ListTasksRequest_Project:
+		return s.Project, nil
+	case *vagrant_server.ListTasksRequest_Basis:
+		return s.Basis, nil
+	case *vagrant_server.GetLatestTaskRequest_Target:
+		return s.Target, nil
+	case *vagrant_server.GetLatestTaskRequest_Project:
+		return s.Project, nil
+	case *vagrant_server.GetLatestTaskRequest_Basis:
+		return s.Basis, nil
+	}
+
+	return nil, status.Errorf(codes.InvalidArgument,
+		"a target, project, or basis scope is required")
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
 	"github.com/hashicorp/vagrant/internal/server"
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,7 +16,6 @@ func TestServiceTask(t *testing.T) {
 
 	t.Run("set and get", func(t *testing.T) {
 		require := require.New(t)
-		t.Skip("We are leaving the Task Set/Get operations broken for now; see TODO in state.TaskPut")
 		db := testDB(t)
 		impl, err := New(WithDB(db))
 		require.NoError(err)
@@ -38,7 +38,7 @@ func TestServiceTask(t *testing.T) {
 		require.NoError(err)
 		require.NotNil(resp)
 		require.NotEmpty(resp.Task.Id)
-		require.Equal("mybasis", resp.Task.Task)
+		require.Equal("mytask", resp.Task.Task)
 
 		getResp, err := client.GetTask(ctx, &vagrant_server.GetTaskRequest{
 			Ref: &vagrant_server.Ref_Operation{Target: &vagrant_server.Ref_Operation_Id{Id: resp.Task.Id}},
@@ -47,4 +47,114 @@ func TestServiceTask(t *testing.T) {
 		require.NotNil(getResp)
 		require.Equal("mytask", getResp.Task)
 	})
+
+	t.Run("list and get latest", func(t *testing.T) {
+		require := require.New(t)
+		db := testDB(t)
+		impl, err := New(WithDB(db))
+		require.NoError(err)
+		client := server.TestServer(t, impl)
+
+		_, err = client.UpsertBasis(ctx, &vagrant_server.UpsertBasisRequest{
+			Basis: &vagrant_server.Basis{
+				Name: "mybasis",
+			},
+		})
+		require.NoError(err)
+
+		basisRef := &vagrant_plugin_sdk.Ref_Basis{Name: "mybasis"}
+		for _, st := range []vagrant_server.Status_State{
+			vagrant_server.Status_SUCCESS,
+			vagrant_server.Status_ERROR,
+		} {
+			_, err := client.UpsertTask(ctx, &vagrant_server.UpsertTaskRequest{
+				Task: &vagrant_server.Task{
+					Scope:  &vagrant_server.Task_Basis{Basis: basisRef},
+					Task:   "destroy",
+					Status: server.NewStatus(st),
+				},
+			})
+			require.NoError(err)
+		}
+
+		listResp, err := client.ListTasks(ctx, &vagrant_server.ListTasksRequest{
+			Scope: &vagrant_server.ListTasksRequest_Basis{Basis: basisRef},
+		})
+		require.NoError(err)
+		require.Len(listResp.Tasks, 2)
+
+		latest, err := client.GetLatestTask(ctx, &vagrant_server.GetLatestTaskRequest{
+			Scope: &vagrant_server.GetLatestTaskRequest_Basis{Basis: basisRef},
+		})
+		require.NoError(err)
+		require.Equal(uint64(1), latest.Sequence)
+
+		// A scope is required
+		_, err = client.ListTasks(ctx, &vagrant_server.ListTasksRequest{})
+		require.Error(err)
+	})
+
+	t.Run("records the requester", func(t *testing.T) {
+		require := require.New(t)
+		impl, err := New(WithDB(testDB(t)))
+		require.NoError(err)
+		s := impl.(*service)
+
+		_, err = s.UpsertBasis(ctx, &vagrant_server.UpsertBasisRequest{
+			Basis: &vagrant_server.Basis{
+				Name: "mybasis",
+			},
+		})
+		require.NoError(err)
+		basisRef := &vagrant_plugin_sdk.Ref_Basis{Name: "mybasis"}
+
+		token, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{User: DefaultUser})
+		require.NoError(err)
+		runnerToken, err := s.newLoginToken(DefaultKeyId, "", nil, &vagrant_server.Token{User: "runner"})
+		require.NoError(err)
+
+		// The requester of a task is the authenticated user, and can't
+		// be set by the caller
+		resp, err := s.UpsertTask(tokenContext(token), &vagrant_server.UpsertTaskRequest{
+			Task: &vagrant_server.Task{
+				Scope:       &vagrant_server.Task_Basis{Basis: basisRef},
+				Task:        "destroy",
+				RequestedBy: "someone",
+			},
+		})
+		require.NoError(err)
+		require.Equal(DefaultUser, resp.Task.RequestedBy)
+
+		resp.Task.RequestedBy = "someone"
+		resp, err = s.UpsertTask(tokenContext(token), &vagrant_server.UpsertTaskRequest{
+			Task: resp.Task,
+		})
+		require.NoError(err)
+		require.Equal(DefaultUser, resp.Task.RequestedBy)
+
+		// Tasks of a job were requested by whoever queued the job, not
+		// the runner upserting the task
+		jobResp, err := s.QueueJob(tokenContext(token), &vagrant_server.QueueJobRequest{
+			Job: serverptypes.TestJobNew(t, nil),
+		})
+		require.NoError(err)
+		resp, err = s.UpsertTask(tokenContext(runnerToken), &vagrant_server.UpsertTaskRequest{
+			Task: &vagrant_server.Task{
+				Scope: &vagrant_server.Task_Basis{Basis: basisRef},
+				Task:  "up",
+				JobId: jobResp.JobId,
+			},
+		})
+		require.NoError(err)
+		require.Equal(DefaultUser, resp.Task.RequestedBy)
+
+		list, err := s.ListTasks(ctx, &vagrant_server.ListTasksRequest{
+			Scope: &vagrant_server.ListTasksRequest_Basis{Basis: basisRef},
+		})
+		require.NoError(err)
+		require.Len(list.Tasks, 2)
+		for _, task := range list.Tasks {
+			require.Equal(DefaultUser, task.RequestedBy)
+		}
+	})
 }
@@ -0,0 +1,105 @@
+package singleprocess
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func (s *service) UpsertUser(
+	ctx context.Context,
+	req *vagrant_server.UpsertUserRequest,
+) (*vagrant_server.UpsertUserResponse, error) {
+	if req.User == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "user must be set")
+	}
+
+	if err := s.state.UserPut(req.User); err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("upserted user", "user", req.User.Username)
+	return &vagrant_server.UpsertUserResponse{User: req.User}, nil
+}
+
+func (s *service) DeleteUser(
+	ctx context.Context,
+	req *vagrant_server.DeleteUserRequest,
+) (*emptypb.Empty, error) {
+	if req.Username == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "username must be set")
+	}
+
+	if err := s.state.UserDelete(req.Username); err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("deleted user", "user", req.Username)
+	return &emptypb.Empty{}, nil
+}
+
+func (s *service) ListUsers(
+	ctx context.Context,
+	req *emptypb.Empty,
+) (*vagrant_server.ListUsersResponse, error) {
+	users, err := s.state.UserList()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.ListUsersResponse{Users: users}, nil
+}
+
+func (s *service) UpsertRole(
+	ctx context.Context,
+	req *vagrant_server.UpsertRoleRequest,
+) (*vagrant_server.UpsertRoleResponse, error) {
+	if req.Role == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "role must be set")
+	}
+
+	if err := s.state.RolePut(req.Role); err != nil {
+		return nil, err
+	}
+
+	role, err := s.state.RoleGet(req.Role.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("upserted role", "role", role.Name)
+	return &vagrant_server.UpsertRoleResponse{Role: role}, nil
+}
+
+func (s *service) DeleteRole(
+	ctx context.Context,
+	req *vagrant_server.DeleteRoleRequest,
+) (*emptypb.Empty, error) {
+	if req.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "role name must be set")
+	}
+
+	if err := s.state.RoleDelete(req.Name); err != nil {
+		return nil, err
+	}
+
+	hclog.FromContext(ctx).Info("deleted role", "role", req.Name)
+	return &emptypb.Empty{}, nil
+}
+
+func (s *service) ListRoles(
+	ctx context.Context,
+	req *emptypb.Empty,
+) (*vagrant_server.ListRolesResponse, error) {
+	roles, err := s.state.RoleList()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vagrant_server.ListRolesResponse{Roles: roles}, nil
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+var archiveUploadBucket = []byte("archive_upload")
+
+func init() {
+	dbBuckets = append(dbBuckets, archiveUploadBucket)
+}
+
+// ArchiveUploadPut stores the information about an archive uploaded to
+// the server. The archive itself is stored outside of the database.
+func (s *State) ArchiveUploadPut(upload *vagrant_server.ArchiveUpload) error {
+	if upload.Id == "" {
+		return status.Errorf(codes.InvalidArgument, "archive upload id must be set")
+	}
+
+	return s.db.Update(func(dbTxn *bolt.Tx) error {
+		return dbPut(dbTxn.Bucket(archiveUploadBucket), []byte(upload.Id), upload)
+	})
+}
+
+// ArchiveUploadGet gets the information about an uploaded archive by ID.
+func (s *State) ArchiveUploadGet(id string) (*vagrant_server.ArchiveUpload, error) {
+	var result vagrant_server.ArchiveUpload
+	err := s.db.View(func(dbTxn *bolt.Tx) error {
+		return dbGet(dbTxn.Bucket(archiveUploadBucket), []byte(id), &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,35 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestArchiveUpload(t *testing.T) {
+	require := require.New(t)
+
+	s := TestState(t)
+	defer s.Close()
+
+	_, err := s.ArchiveUploadGet("A")
+	require.Error(err)
+	require.Equal(codes.NotFound, status.Code(err))
+
+	require.NoError(s.ArchiveUploadPut(&vagrant_server.ArchiveUpload{
+		Id:       "A",
+		Checksum: "abc",
+		Size:     3,
+	}))
+
+	upload, err := s.ArchiveUploadGet("A")
+	require.NoError(err)
+	require.Equal("abc", upload.Checksum)
+	require.Equal(int64(3), upload.Size)
+
+	require.Error(s.ArchiveUploadPut(&vagrant_server.ArchiveUpload{}))
+}
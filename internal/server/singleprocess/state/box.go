@@ -68,13 +68,20 @@ func (s *State) BoxPut(box *vagrant_server.Box) error {
 }
 
 func (s *State) BoxFind(b *vagrant_plugin_sdk.Ref_Box) (*vagrant_server.Box, error) {
+	return s.BoxFindArchitecture(b, "")
+}
+
+// BoxFindArchitecture finds the highest version of the box matching the
+// reference that can be used on the given architecture. Boxes built for
+// the architecture are preferred over boxes without an architecture.
+func (s *State) BoxFindArchitecture(b *vagrant_plugin_sdk.Ref_Box, architecture string) (*vagrant_server.Box, error) {
 	memTxn := s.inmem.Txn(false)
 	defer memTxn.Abort()
 
 	var result *vagrant_server.Box
 	err := s.db.View(func(dbTxn *bolt.Tx) error {
 		var err error
-		result, err = s.boxFind(dbTxn, memTxn, b)
+		result, err = s.boxFind(dbTxn, memTxn, b, architecture)
 		return err
 	})
 
@@ -163,6 +170,7 @@ func (s *State) boxFind(
 	dbTxn *bolt.Tx,
 	memTxn *memdb.Txn,
 	ref *vagrant_plugin_sdk.Ref_Box,
+	architecture string,
 ) (r *vagrant_server.Box, err error) {
 	var match *boxIndexRecord
 	highestVersion, _ := version.NewVersion("0.0.0")
@@ -198,6 +206,10 @@ func (s *State) boxFind(
 					continue
 				}
 			}
+			if architecture != "" && boxIndexEntry.Architecture != "" &&
+				boxIndexEntry.Architecture != architecture {
+				continue
+			}
 			// Set first match
 			if match == nil {
 				match = boxIndexEntry
@@ -206,6 +218,10 @@ func (s *State) boxFind(
 			if v.GreaterThan(highestVersion) {
 				highestVersion = v
 				match = boxIndexEntry
+			} else if v.Equal(highestVersion) && architecture != "" &&
+				boxIndexEntry.Architecture == architecture {
+				// Prefer the box built for the architecture
+				match = boxIndexEntry
 			}
 		}
 
@@ -226,19 +242,24 @@ const (
 )
 
 type boxIndexRecord struct {
-	Id       string // Resource ID
-	Name     string // Box Name
-	Version  string // Box Version
-	Provider string // Box Provider
+	Id           string // Resource ID
+	Name         string // Box Name
+	Version      string // Box Version
+	Provider     string // Box Provider
+	Architecture string // Box Architecture
 }
 
 func (s *State) newBoxIndexRecord(b *vagrant_server.Box) *boxIndexRecord {
 	id := b.Name + "-" + b.Version + "-" + b.Provider
+	if b.Architecture != "" {
+		id += "-" + b.Architecture
+	}
 	return &boxIndexRecord{
-		Id:       id,
-		Name:     b.Name,
-		Version:  b.Version,
-		Provider: b.Provider,
+		Id:           id,
+		Name:         b.Name,
+		Version:      b.Version,
+		Provider:     b.Provider,
+		Architecture: b.Architecture,
 	}
 }
 
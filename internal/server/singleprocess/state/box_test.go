@@ -232,4 +232,71 @@ func TestBox(t *testing.T) {
 		require.Equal(b11.Name, "box")
 		require.Equal(b11.Version, "0")
 	})
+
+	t.Run("Find by architecture", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		for _, b := range []*vagrant_server.Box{
+			{
+				Id:       "hashicorp/bionic-1.2.3-virtualbox",
+				Name:     "hashicorp/bionic",
+				Version:  "1.2.3",
+				Provider: "virtualbox",
+			},
+			{
+				Id:           "hashicorp/bionic-1.2.3-virtualbox-arm64",
+				Name:         "hashicorp/bionic",
+				Version:      "1.2.3",
+				Provider:     "virtualbox",
+				Architecture: "arm64",
+			},
+			{
+				Id:           "hashicorp/bionic-1.2.4-virtualbox-amd64",
+				Name:         "hashicorp/bionic",
+				Version:      "1.2.4",
+				Provider:     "virtualbox",
+				Architecture: "amd64",
+			},
+		} {
+			require.NoError(s.BoxPut(b))
+		}
+
+		// Boxes for the architecture are preferred
+		b, err := s.BoxFindArchitecture(&vagrant_plugin_sdk.Ref_Box{
+			Name: "hashicorp/bionic",
+		}, "arm64")
+		require.NoError(err)
+		require.Equal("1.2.3", b.Version)
+		require.Equal("arm64", b.Architecture)
+
+		b, err = s.BoxFindArchitecture(&vagrant_plugin_sdk.Ref_Box{
+			Name: "hashicorp/bionic",
+		}, "amd64")
+		require.NoError(err)
+		require.Equal("1.2.4", b.Version)
+		require.Equal("amd64", b.Architecture)
+
+		// Boxes without an architecture match any architecture
+		b, err = s.BoxFindArchitecture(&vagrant_plugin_sdk.Ref_Box{
+			Name: "hashicorp/bionic",
+		}, "i386")
+		require.NoError(err)
+		require.Equal("1.2.3", b.Version)
+		require.Empty(b.Architecture)
+
+		// Architecture is ignored when not provided
+		b, err = s.BoxFind(&vagrant_plugin_sdk.Ref_Box{
+			Name: "hashicorp/bionic",
+		})
+		require.NoError(err)
+		require.Equal("1.2.4", b.Version)
+
+		// Architectures are indexed separately
+		list, err := s.BoxList()
+		require.NoError(err)
+		require.Len(list, 3)
+	})
 }
@@ -0,0 +1,208 @@
+package state
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// Status of a box version published to the catalog
+const CatalogBoxVersionActive = "active"
+
+var catalogBoxBucket = []byte("catalog_box")
+
+func init() {
+	dbBuckets = append(dbBuckets, catalogBoxBucket)
+}
+
+// CatalogBoxGet gets a box from the box catalog by name.
+func (s *State) CatalogBoxGet(name string) (*vagrant_server.CatalogBox, error) {
+	var result *vagrant_server.CatalogBox
+	err := s.db.View(func(dbTxn *bolt.Tx) error {
+		var err error
+		result, err = s.catalogBoxGet(dbTxn, name)
+		return err
+	})
+
+	return result, err
+}
+
+// CatalogBoxList returns all boxes in the box catalog.
+func (s *State) CatalogBoxList() ([]*vagrant_server.CatalogBox, error) {
+	var result []*vagrant_server.CatalogBox
+	err := s.db.View(func(dbTxn *bolt.Tx) error {
+		return dbTxn.Bucket(catalogBoxBucket).ForEach(func(k, v []byte) error {
+			var box vagrant_server.CatalogBox
+			if err := proto.Unmarshal(v, &box); err != nil {
+				return err
+			}
+			result = append(result, &box)
+			return nil
+		})
+	})
+
+	return result, err
+}
+
+// CatalogBoxPublish adds the box provider to the box catalog, creating
+// the box and version if they do not exist. If the provider has already
+// been published an AlreadyExists error is returned unless force is set.
+func (s *State) CatalogBoxPublish(
+	req *vagrant_server.PublishCatalogBoxRequest_Open,
+	provider *vagrant_server.CatalogBox_Provider,
+) (*vagrant_server.CatalogBox, error) {
+	var result *vagrant_server.CatalogBox
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		var err error
+		result, err = s.catalogBoxPublish(dbTxn, req, provider)
+		return err
+	})
+
+	return result, err
+}
+
+// CatalogBoxDelete removes the box provider from the box catalog. If
+// the provider is empty all providers of the version are removed and
+// if the version is empty the entire box is removed. Versions without
+// any providers and boxes without any versions are removed.
+func (s *State) CatalogBoxDelete(name, ver, provider string) error {
+	return s.db.Update(func(dbTxn *bolt.Tx) error {
+		return s.catalogBoxDelete(dbTxn, name, ver, provider)
+	})
+}
+
+func (s *State) catalogBoxGet(
+	dbTxn *bolt.Tx,
+	name string,
+) (*vagrant_server.CatalogBox, error) {
+	var result vagrant_server.CatalogBox
+	b := dbTxn.Bucket(catalogBoxBucket)
+	return &result, dbGet(b, s.catalogBoxId(name), &result)
+}
+
+func (s *State) catalogBoxPublish(
+	dbTxn *bolt.Tx,
+	req *vagrant_server.PublishCatalogBoxRequest_Open,
+	provider *vagrant_server.CatalogBox_Provider,
+) (*vagrant_server.CatalogBox, error) {
+	box, err := s.catalogBoxGet(dbTxn, req.Name)
+	if status.Code(err) == codes.NotFound {
+		box, err = &vagrant_server.CatalogBox{Name: req.Name}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.Description != "" {
+		box.Description = req.Description
+	}
+
+	var boxVersion *vagrant_server.CatalogBox_Version
+	for _, v := range box.Versions {
+		if v.Version == req.Version {
+			boxVersion = v
+			break
+		}
+	}
+	if boxVersion == nil {
+		boxVersion = &vagrant_server.CatalogBox_Version{
+			Version: req.Version,
+			Status:  CatalogBoxVersionActive,
+		}
+		box.Versions = append(box.Versions, boxVersion)
+	}
+	if req.VersionDescription != "" {
+		boxVersion.Description = req.VersionDescription
+	}
+
+	replaced := false
+	for i, p := range boxVersion.Providers {
+		if p.Name != provider.Name {
+			continue
+		}
+		if !req.Force {
+			return nil, status.Errorf(codes.AlreadyExists,
+				"box %s version %s already has provider %s", req.Name, req.Version, provider.Name)
+		}
+		boxVersion.Providers[i] = provider
+		replaced = true
+	}
+	if !replaced {
+		boxVersion.Providers = append(boxVersion.Providers, provider)
+	}
+
+	box.LastUpdate = timestamppb.Now()
+	sortCatalogBox(box)
+
+	return box, dbPut(dbTxn.Bucket(catalogBoxBucket), s.catalogBoxId(box.Name), box)
+}
+
+func (s *State) catalogBoxDelete(
+	dbTxn *bolt.Tx,
+	name, ver, provider string,
+) error {
+	b := dbTxn.Bucket(catalogBoxBucket)
+	box, err := s.catalogBoxGet(dbTxn, name)
+	if err != nil {
+		return err
+	}
+
+	if ver != "" {
+		versions := box.Versions[:0]
+		for _, v := range box.Versions {
+			if v.Version == ver {
+				if provider == "" {
+					continue
+				}
+				providers := v.Providers[:0]
+				for _, p := range v.Providers {
+					if p.Name != provider {
+						providers = append(providers, p)
+					}
+				}
+				v.Providers = providers
+				if len(v.Providers) == 0 {
+					continue
+				}
+			}
+			versions = append(versions, v)
+		}
+		box.Versions = versions
+	}
+
+	if ver == "" || len(box.Versions) == 0 {
+		return b.Delete(s.catalogBoxId(name))
+	}
+
+	box.LastUpdate = timestamppb.Now()
+	return dbPut(b, s.catalogBoxId(name), box)
+}
+
+// Box names are matched without regard to case
+func (s *State) catalogBoxId(name string) []byte {
+	return []byte(strings.ToLower(name))
+}
+
+// Sorts versions from newest to oldest and providers by name
+func sortCatalogBox(box *vagrant_server.CatalogBox) {
+	sort.SliceStable(box.Versions, func(i, j int) bool {
+		vi, erri := version.NewVersion(box.Versions[i].Version)
+		vj, errj := version.NewVersion(box.Versions[j].Version)
+		if erri != nil || errj != nil {
+			return box.Versions[i].Version > box.Versions[j].Version
+		}
+		return vi.GreaterThan(vj)
+	})
+	for _, v := range box.Versions {
+		sort.Slice(v.Providers, func(i, j int) bool {
+			return v.Providers[i].Name < v.Providers[j].Name
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCatalogBox(t *testing.T) {
+	open := func(version, provider string) *vagrant_server.PublishCatalogBoxRequest_Open {
+		return &vagrant_server.PublishCatalogBoxRequest_Open{
+			Name:     "hashicorp/bionic",
+			Version:  version,
+			Provider: provider,
+		}
+	}
+
+	t.Run("Get returns error if not exist", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.CatalogBoxGet("nothing")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Publish and Get", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		req := open("1.0.0", "virtualbox")
+		req.Description = "Ubuntu"
+		_, err := s.CatalogBoxPublish(req, &vagrant_server.CatalogBox_Provider{
+			Name:         "virtualbox",
+			Checksum:     "abc",
+			ChecksumType: "sha256",
+		})
+		require.NoError(err)
+		_, err = s.CatalogBoxPublish(open("1.10.0", "virtualbox"),
+			&vagrant_server.CatalogBox_Provider{Name: "virtualbox"})
+		require.NoError(err)
+		_, err = s.CatalogBoxPublish(open("1.10.0", "libvirt"),
+			&vagrant_server.CatalogBox_Provider{Name: "libvirt"})
+		require.NoError(err)
+
+		box, err := s.CatalogBoxGet("HashiCorp/Bionic")
+		require.NoError(err)
+		require.Equal("Ubuntu", box.Description)
+		require.Len(box.Versions, 2)
+		require.Equal("1.10.0", box.Versions[0].Version)
+		require.Equal(CatalogBoxVersionActive, box.Versions[0].Status)
+		require.Len(box.Versions[0].Providers, 2)
+		require.Equal("libvirt", box.Versions[0].Providers[0].Name)
+		require.Equal("abc", box.Versions[1].Providers[0].Checksum)
+
+		list, err := s.CatalogBoxList()
+		require.NoError(err)
+		require.Len(list, 1)
+	})
+
+	t.Run("Publish existing provider requires force", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.CatalogBoxPublish(open("1.0.0", "virtualbox"),
+			&vagrant_server.CatalogBox_Provider{Name: "virtualbox", Checksum: "abc"})
+		require.NoError(err)
+		_, err = s.CatalogBoxPublish(open("1.0.0", "virtualbox"),
+			&vagrant_server.CatalogBox_Provider{Name: "virtualbox", Checksum: "def"})
+		require.Equal(codes.AlreadyExists, status.Code(err))
+
+		req := open("1.0.0", "virtualbox")
+		req.Force = true
+		box, err := s.CatalogBoxPublish(req,
+			&vagrant_server.CatalogBox_Provider{Name: "virtualbox", Checksum: "def"})
+		require.NoError(err)
+		require.Len(box.Versions[0].Providers, 1)
+		require.Equal("def", box.Versions[0].Providers[0].Checksum)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		for _, v := range []string{"1.0.0", "2.0.0"} {
+			for _, p := range []string{"virtualbox", "libvirt"} {
+				_, err := s.CatalogBoxPublish(open(v, p),
+					&vagrant_server.CatalogBox_Provider{Name: p})
+				require.NoError(err)
+			}
+		}
+
+		// Delete a provider
+		require.NoError(s.CatalogBoxDelete("hashicorp/bionic", "1.0.0", "libvirt"))
+		box, err := s.CatalogBoxGet("hashicorp/bionic")
+		require.NoError(err)
+		require.Len(box.Versions, 2)
+		require.Len(box.Versions[1].Providers, 1)
+
+		// Deleting the last provider removes the version
+		require.NoError(s.CatalogBoxDelete("hashicorp/bionic", "1.0.0", "virtualbox"))
+		box, err = s.CatalogBoxGet("hashicorp/bionic")
+		require.NoError(err)
+		require.Len(box.Versions, 1)
+
+		// Delete the whole box
+		require.NoError(s.CatalogBoxDelete("hashicorp/bionic", "", ""))
+		_, err = s.CatalogBoxGet("hashicorp/bionic")
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/go-hclog"
 	"google.golang.org/protobuf/proto"
 	bolt "go.etcd.io/bbolt"
 	"google.golang.org/grpc/codes"
@@ -23,13 +24,21 @@ var (
 //
 // DB Version
 //
-// THIS SHOULD BE CHANGED WITH EXTREME CAUTION. Changing this will force users
-// to perform a db upgrade when they upgrade their Vagrant version.
+// THIS SHOULD BE CHANGED WITH EXTREME CAUTION. The version of the data is
+// determined by the migrations registered in migrate.go. Any change to the
+// format of persisted data must add a migration so existing data is
+// upgraded when users upgrade their Vagrant version.
 //
 //!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
-const (
-	dbVersion int64 = 1
-)
+
+// dbVersion returns the data version of this server. This is the
+// version of the last registered migration.
+func dbVersion() int64 {
+	if len(dbMigrations) == 0 {
+		return 0
+	}
+	return dbMigrations[len(dbMigrations)-1].Version
+}
 
 func init() {
 	dbBuckets = append(dbBuckets, sysBucket)
@@ -37,35 +46,54 @@ func init() {
 
 // dbInit sets up the database. This should be called once on all new
 // DB handles before accepting API calls. It is safe to be called multiple
-// times.
-func dbInit(db *bolt.DB) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		// Create all our buckets
-		for _, b := range dbBuckets {
-			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
-				return err
-			}
-		}
-
-		// Check our data version
-		sys := tx.Bucket(sysBucket)
-		vsnRaw := sys.Get(sysVersionKey)
-
-		// Initialize the version with our current version if it isn't set.
-		if len(vsnRaw) == 0 {
-			if err := sys.Put(sysVersionKey, []byte(strconv.FormatInt(dbVersion, 10))); err != nil {
-				return status.Errorf(codes.Internal,
-					"failed to write initial database version: %s", err)
-			}
-		} else {
-			vsn, err := strconv.ParseInt(string(vsnRaw), 10, 64)
-			if err != nil {
-				return status.Errorf(codes.Internal,
-					"failed to read database version: %s", err)
-			}
-
-			if vsn != dbVersion {
-				return status.Errorf(codes.FailedPrecondition, strings.TrimSpace(`
+// times. If the data on disk is from an older version, a snapshot of
+// the database is created and the data is migrated to the current version.
+func dbInit(log hclog.Logger, db *bolt.DB) error {
+	result, err := Migrate(log, db, nil)
+	if err != nil {
+		return err
+	}
+	if len(result.Applied) > 0 {
+		log.Info("database migrated",
+			"from", result.From, "to", result.To, "snapshot", result.Snapshot)
+	}
+
+	return nil
+}
+
+// dbReadVersion reads the data version from the database. If the version
+// has not been set, zero is returned.
+func dbReadVersion(tx *bolt.Tx) (int64, error) {
+	sys := tx.Bucket(sysBucket)
+	if sys == nil {
+		return 0, nil
+	}
+	vsnRaw := sys.Get(sysVersionKey)
+	if len(vsnRaw) == 0 {
+		return 0, nil
+	}
+
+	vsn, err := strconv.ParseInt(string(vsnRaw), 10, 64)
+	if err != nil {
+		return 0, status.Errorf(codes.Internal,
+			"failed to read database version: %s", err)
+	}
+	return vsn, nil
+}
+
+// dbWriteVersion writes the data version to the database.
+func dbWriteVersion(tx *bolt.Tx, vsn int64) error {
+	if err := tx.Bucket(sysBucket).Put(sysVersionKey, []byte(strconv.FormatInt(vsn, 10))); err != nil {
+		return status.Errorf(codes.Internal,
+			"failed to write database version: %s", err)
+	}
+	return nil
+}
+
+// dbVersionError is returned when the data on disk was written by a newer
+// server and cannot be read.
+func dbVersionError(vsn int64) error {
+	return status.Errorf(codes.FailedPrecondition, strings.TrimSpace(`
 The database version on disk does not match the server version.
 
 The server cannot safely read this data. Please upgrade or downgrade your server
@@ -75,12 +103,7 @@ information on the Vagrant website.
 On-disk data version: %d
  Server data version: %d
 
-`), vsn, dbVersion)
-			}
-		}
-
-		return nil
-	})
+`), vsn, dbVersion())
 }
 
 // dbPut is a helper to insert a proto.Message into a bucket for the given id.
@@ -2,8 +2,8 @@ package state
 
 import (
 	"errors"
-	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -31,6 +31,8 @@ type genericOperation struct {
 	//
 	//   - required: Id string
 	//   - required: Status *vagrant_server.Status
+	//   - required: one of Machine, Project, Basis, or a Scope oneof
+	//     holding a target, project, or basis reference
 	//
 	// It may also have the special field "Preload". If this field exists,
 	// it is automatically set to nil on disk and set to empty on read. This
@@ -41,19 +43,6 @@ type genericOperation struct {
 
 	// Bucket is the global bucket for all records of this operation.
 	Bucket []byte
-
-	// seq is the previous sequence number to set. This is initialized by the
-	// index init on server boot and `sync/atomic` should be used to increment
-	// it on each use.
-	//
-	// NOTE: Currently in waypoint the sequence is defined via app + seq number.
-	// Since our operations can be based on the basis, project, or machine we
-	// can't follow the same format. Instead, we will track a sequence against
-	// the basis and against the project. For the machine based operations, it
-	// will still just use project.
-	// NOTE(spox): These need to be pruned when a project is deleted
-	seqBasis   map[string]*uint64
-	seqProject map[string]*uint64
 }
 
 // Test validates that the operation struct is setup properly. This
@@ -99,19 +88,19 @@ func (op *genericOperation) Get(s *State, ref *vagrant_server.Ref_Operation) (in
 
 		case *vagrant_server.Ref_Operation_TargetSequence:
 			var err error
-			id, err = op.getIdForSeq(s, tx, memTxn, t.TargetSequence.Number)
+			id, err = op.getIdForSeq(s, tx, memTxn, t.TargetSequence)
 			if err != nil {
 				return err
 			}
 		case *vagrant_server.Ref_Operation_ProjectSequence:
 			var err error
-			id, err = op.getIdForSeq(s, tx, memTxn, t.ProjectSequence.Number)
+			id, err = op.getIdForSeq(s, tx, memTxn, t.ProjectSequence)
 			if err != nil {
 				return err
 			}
 		case *vagrant_server.Ref_Operation_BasisSequence:
 			var err error
-			id, err = op.getIdForSeq(s, tx, memTxn, t.BasisSequence.Number)
+			id, err = op.getIdForSeq(s, tx, memTxn, t.BasisSequence)
 			if err != nil {
 				return err
 			}
@@ -136,37 +125,25 @@ func (op *genericOperation) getIdForSeq(
 	memTxn *memdb.Txn,
 	ref interface{},
 ) (string, error) {
-	var args []interface{}
+	var scope interface{}
 	var number uint64
 
 	if r, ok := ref.(*vagrant_server.Ref_TargetOperationSeq); ok {
-		args = []interface{}{
-			r.Target.Project.Basis.ResourceId,
-			r.Target.Project.ResourceId,
-			r.Target.ResourceId,
-			r.Number,
-		}
-		number = r.Number
+		scope, number = r.Target, r.Number
 	} else if r, ok := ref.(*vagrant_server.Ref_ProjectOperationSeq); ok {
-		args = []interface{}{
-			r.Project.Basis.ResourceId,
-			r.Project.ResourceId,
-			"",
-			r.Number,
-		}
-		number = r.Number
+		scope, number = r.Project, r.Number
 	} else if r, ok := ref.(*vagrant_server.Ref_BasisOperationSeq); ok {
-		args = []interface{}{
-			r.Basis.ResourceId,
-			"",
-			"",
-			r.Number,
-		}
-		number = r.Number
+		scope, number = r.Basis, r.Number
 	} else {
 		return "", status.Errorf(codes.Internal,
-			"unknown reference type provided for sequence number %d", number)
+			"unknown reference type provided for sequence number")
+	}
+
+	scope, err := s.operationRef(dbTxn, memTxn, scope)
+	if err != nil {
+		return "", err
 	}
+	args := append(operationRefArgs(scope), number)
 
 	raw, err := memTxn.First(
 		op.memTableName(),
@@ -200,49 +177,40 @@ func (op *genericOperation) List(s *State, opts *listOperationsOptions) ([]inter
 	}
 
 	var ref interface{}
-	var args []interface{}
-
 	if opts.Machine != nil {
-		args = []interface{}{
-			opts.Machine.Project.Basis.ResourceId,
-			opts.Machine.Project.ResourceId,
-			opts.Machine.ResourceId,
-			indexTimeLatest{},
-		}
 		ref = opts.Machine
 	} else if opts.Project != nil {
-		args = []interface{}{
-			opts.Project.Basis.ResourceId,
-			opts.Project.ResourceId,
-			"",
-			indexTimeLatest{},
-		}
 		ref = opts.Project
 	} else if opts.Basis != nil {
-		args = []interface{}{
-			opts.Basis.ResourceId,
-			"",
-			"",
-			indexTimeLatest{},
-		}
 		ref = opts.Basis
 	} else {
 		return nil, errors.New("must provide a Basis.Ref, Project.Ref, or Machine.Ref to List")
 	}
 
-	// Get the iterator for lower-bound based querying
-	iter, err := memTxn.LowerBound(
-		op.memTableName(),
-		idx,
-		args...,
-	)
-
-	if err != nil {
-		return nil, err
-	}
+	// Operations of a basis or project include the operations of the
+	// projects and targets within it. These are indexed separately so
+	// all results are collected and sorted before applying the limit.
+	_, nested := ref.(*vagrant_plugin_sdk.Ref_Target)
+	nested = !nested
 
 	var result []interface{}
-	s.db.View(func(tx *bolt.Tx) error {
+	var times []time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		ref, err := s.operationRef(tx, memTxn, ref)
+		if err != nil {
+			return err
+		}
+
+		// Get the iterator for lower-bound based querying
+		iter, err := memTxn.LowerBound(
+			op.memTableName(),
+			idx,
+			append(operationRefArgs(ref), indexTimeLatest{})...,
+		)
+		if err != nil {
+			return err
+		}
+
 		for {
 			current := iter.Next()
 			if current == nil {
@@ -269,8 +237,12 @@ func (op *genericOperation) List(s *State, opts *listOperationsOptions) ([]inter
 			}
 
 			if len(opts.Status) > 0 {
-				// Get our status field
+				// Get our status field. Operations without a status
+				// never match a filter.
 				status := op.valueField(value, "Status").(*vagrant_server.Status)
+				if status == nil {
+					continue
+				}
 
 				// Filter. If we don't match the filter, then ignore this result.
 				if !statusFilterMatch(opts.Status, status) {
@@ -279,17 +251,45 @@ func (op *genericOperation) List(s *State, opts *listOperationsOptions) ([]inter
 			}
 
 			result = append(result, value)
+			if idx == opCompleteTimeIndexName {
+				times = append(times, record.CompleteTime)
+			} else {
+				times = append(times, record.StartTime)
+			}
 
 			// If we have a limit, check that now
-			if o := opts.Order; o != nil && o.Limit > 0 && len(result) >= int(o.Limit) {
+			if o := opts.Order; !nested && o != nil && o.Limit > 0 && len(result) >= int(o.Limit) {
 				return nil
 			}
 		}
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if nested {
+		sort.Stable(&operationsByTime{values: result, times: times})
+		if o := opts.Order; o != nil && o.Limit > 0 && len(result) > int(o.Limit) {
+			result = result[:o.Limit]
+		}
+	}
 
 	return result, nil
 }
 
+// operationsByTime sorts operations from newest to oldest.
+type operationsByTime struct {
+	values []interface{}
+	times  []time.Time
+}
+
+func (o *operationsByTime) Len() int           { return len(o.values) }
+func (o *operationsByTime) Less(i, j int) bool { return o.times[i].After(o.times[j]) }
+func (o *operationsByTime) Swap(i, j int) {
+	o.values[i], o.values[j] = o.values[j], o.values[i]
+	o.times[i], o.times[j] = o.times[j], o.times[i]
+}
+
 // Latest gets the latest operation that was completed successfully.
 func (op *genericOperation) Latest(
 	s *State,
@@ -298,42 +298,32 @@ func (op *genericOperation) Latest(
 	memTxn := s.inmem.Txn(false)
 	defer memTxn.Abort()
 
-	var args []interface{}
-
-	if r, ok := ref.(*vagrant_plugin_sdk.Ref_Target); ok {
-		args = []interface{}{
-			r.Project.Basis.ResourceId,
-			r.Project.ResourceId,
-			r.ResourceId,
-			indexTimeLatest{},
-		}
-	} else if r, ok := ref.(*vagrant_plugin_sdk.Ref_Project); ok {
-		args = []interface{}{
-			r.Basis.ResourceId,
-			r.ResourceId,
-			"",
-			indexTimeLatest{},
-		}
-	} else if r, ok := ref.(*vagrant_plugin_sdk.Ref_Basis); ok {
-		args = []interface{}{
-			r.ResourceId,
-			"",
-			"",
-			indexTimeLatest{},
-		}
-	} else {
-		return nil, status.Error(codes.Internal, "unknown reference type")
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		ref, err = s.operationRef(tx, memTxn, ref)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	iter, err := memTxn.LowerBound(
 		op.memTableName(),
 		opCompleteTimeIndexName,
-		args...,
+		append(operationRefArgs(ref), indexTimeLatest{})...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Operations of a basis or project include the operations of the
+	// projects and targets within it which are indexed separately, so
+	// the newest successful operation of every group must be compared.
+	_, nested := ref.(*vagrant_plugin_sdk.Ref_Target)
+	nested = !nested
+
+	var latest interface{}
+	var latestTime time.Time
 	for {
 		raw := iter.Next()
 		if raw == nil {
@@ -344,6 +334,9 @@ func (op *genericOperation) Latest(
 		if !record.MatchRef(ref) {
 			break
 		}
+		if latest != nil && !record.CompleteTime.After(latestTime) {
+			continue
+		}
 
 		v, err := op.Get(s, &vagrant_server.Ref_Operation{
 			Target: &vagrant_server.Ref_Operation_Id{Id: record.Id},
@@ -352,20 +345,27 @@ func (op *genericOperation) Latest(
 			return nil, err
 		}
 
-		// Shouldn't happen but if it does, return nothing.
+		// Operations without a status have not completed.
 		st := op.valueField(v, "Status")
-		if st == nil {
-			break
+		if st == nil || st.(*vagrant_server.Status) == nil {
+			continue
 		}
 
 		// State must be success.
 		switch st.(*vagrant_server.Status).State {
 		case vagrant_server.Status_SUCCESS:
-			return v, nil
+			if !nested {
+				return v, nil
+			}
+			latest, latestTime = v, record.CompleteTime
 		}
 	}
 
-	return nil, status.Error(codes.NotFound, "none available")
+	if latest == nil {
+		return nil, status.Error(codes.NotFound, "none available")
+	}
+
+	return latest, nil
 }
 
 // dbGet reads the value from the database.
@@ -397,34 +397,19 @@ func (op *genericOperation) dbPut(
 	value proto.Message,
 ) (err error) {
 	// Get our ref and ensure that it's created
-	var ref interface{}
-	for _, k := range []string{"Machine", "Project", "Basis"} {
-		ref = op.valueField(value, k)
-		if ref != nil {
-			break
-		}
-	}
-
+	ref := op.valueRef(value)
 	if ref == nil {
 		return status.Errorf(codes.Internal,
-			"state: Machine, Project, or Basis must be set on value %T", value)
-	}
-
-	// Determine the type so we can default the put
-	if r, ok := ref.(*vagrant_plugin_sdk.Ref_Target); ok {
-		_, err = s.targetGet(dbTxn, memTxn, r)
-	} else if r, ok := ref.(*vagrant_plugin_sdk.Ref_Project); ok {
-		_, err = s.projectGet(dbTxn, memTxn, r)
-	} else if r, ok := ref.(*vagrant_plugin_sdk.Ref_Basis); ok {
-		_, err = s.basisGet(dbTxn, memTxn, r)
-	} else {
-		err = status.Error(codes.Internal,
-			fmt.Sprintf("state: Unable to default ref on value %T", value))
+			"state: Machine, Project, Basis, or Scope must be set on value %T", value)
 	}
 
+	// Resolve the ref so the stored value always references the
+	// resource ids of the basis, project, and target.
+	ref, err = s.operationRef(dbTxn, memTxn, ref)
 	if err != nil {
 		return
 	}
+	op.setValueRef(value, ref)
 
 	// Get the global bucket and write the value to it.
 	b := dbTxn.Bucket(op.Bucket)
@@ -463,7 +448,7 @@ func (op *genericOperation) dbPut(
 	// If we're not updating, then set the sequence number up if we have one.
 	if !update {
 		if f := op.valueFieldReflect(value, "Sequence"); f.IsValid() {
-			seq := atomic.AddUint64(op.getSeq(ref), 1)
+			seq := atomic.AddUint64(op.getSeq(s, ref), 1)
 			f.Set(reflect.ValueOf(seq))
 		}
 	}
@@ -483,49 +468,40 @@ func (op *genericOperation) dbPut(
 
 // getSeq gets the pointer to the sequence number for the given reference.
 // This can only safely be called while holding the memdb write transaction.
-func (op *genericOperation) getSeq(ref interface{}) *uint64 {
+//
+// The sequence numbers are initialized by the index init on server boot and
+// `sync/atomic` should be used to increment them on each use.
+//
+// NOTE: Currently in waypoint the sequence is defined via app + seq number.
+// Since our operations can be based on the basis, project, or machine we
+// can't follow the same format. Instead, we track a sequence against
+// the basis, the project, and the machine the operation is scoped to.
+// NOTE(spox): These need to be pruned when a project is deleted
+func (op *genericOperation) getSeq(s *State, ref interface{}) *uint64 {
 	// Our ref can be a machine, project, or basis. Determine type and then
 	// find sequence
+	var k string
 	if r, ok := ref.(*vagrant_plugin_sdk.Ref_Target); ok {
-		// Machine operations are scoped to the project
-		if op.seqProject == nil {
-			op.seqProject = map[string]*uint64{}
-		}
-		k := strings.ToLower(r.ResourceId)
-		seq, ok := op.seqProject[k]
-		if !ok {
-			var value uint64
-			seq = &value
-			op.seqProject[k] = seq
-		}
-		return seq
+		k = "target/" + r.ResourceId
 	} else if r, ok := ref.(*vagrant_plugin_sdk.Ref_Project); ok {
-		if op.seqProject == nil {
-			op.seqProject = map[string]*uint64{}
-		}
-		k := strings.ToLower(r.ResourceId)
-		seq, ok := op.seqProject[k]
-		if !ok {
-			var value uint64
-			seq = &value
-			op.seqProject[k] = seq
-		}
-		return seq
+		k = "project/" + r.ResourceId
 	} else if r, ok := ref.(*vagrant_plugin_sdk.Ref_Basis); ok {
-		if op.seqBasis == nil {
-			op.seqBasis = map[string]*uint64{}
-		}
-		k := strings.ToLower(r.ResourceId)
-		seq, ok := op.seqBasis[k]
-		if !ok {
-			var value uint64
-			seq = &value
-			op.seqBasis[k] = seq
-		}
-		return seq
+		k = "basis/" + r.ResourceId
+	} else {
+		return nil
 	}
+	k = op.memTableName() + "/" + strings.ToLower(k)
 
-	return nil
+	if s.opSeqs == nil {
+		s.opSeqs = map[string]*uint64{}
+	}
+	seq, ok := s.opSeqs[k]
+	if !ok {
+		var value uint64
+		seq = &value
+		s.opSeqs[k] = seq
+	}
+	return seq
 }
 
 // indexInit initializes the index table in memdb from all the records
@@ -545,17 +521,7 @@ func (op *genericOperation) indexInit(s *State, dbTxn *bolt.Tx, memTxn *memdb.Tx
 		if v := op.valueField(result, "Sequence"); v != nil {
 			seq := v.(uint64)
 
-			var current *uint64
-			for _, k := range []string{"Machine", "Project", "Basis"} {
-				ref := op.valueField(result, k)
-				if ref == nil {
-					continue
-				}
-				current = op.getSeq(ref)
-				if current != nil {
-					break
-				}
-			}
+			current := op.getSeq(s, op.valueRef(result))
 			if current != nil && seq > *current {
 				*current = seq
 			}
@@ -599,31 +565,77 @@ func (op *genericOperation) indexPut(s *State, txn *memdb.Txn, value proto.Messa
 	}
 
 	// Get any reference information we can extract from the operation
-	var basis, project, machine string
-
-	if ref := op.valueField(value, "Machine").(*vagrant_plugin_sdk.Ref_Target); ref != nil {
-		basis = ref.Project.Basis.ResourceId
-		project = ref.Project.ResourceId
-		machine = ref.ResourceId
-	} else if ref := op.valueField(value, "Project").(*vagrant_plugin_sdk.Ref_Project); ref != nil {
-		basis = ref.Basis.ResourceId
-		project = ref.ResourceId
-	} else {
-		ref := op.valueField(value, "Basis").(*vagrant_plugin_sdk.Ref_Basis)
-		basis = ref.ResourceId
+	args := operationRefArgs(op.valueRef(value))
+	if args == nil {
+		return status.Errorf(codes.Internal,
+			"state: Machine, Project, Basis, or Scope must be set on value %T", value)
 	}
 
 	return txn.Insert(op.memTableName(), &operationIndexRecord{
 		Id:           op.valueField(value, "Id").(string),
-		Basis:        basis,
-		Project:      project,
-		Machine:      machine,
+		Basis:        args[0].(string),
+		Project:      args[1].(string),
+		Machine:      args[2].(string),
 		Sequence:     sequence,
 		StartTime:    startTime,
 		CompleteTime: completeTime,
 	})
 }
 
+// valueRef returns the target, project, or basis reference the operation
+// is scoped to. This is read from the Machine, Project, or Basis fields or
+// from the Scope oneof field. If no reference is set nil is returned.
+func (op *genericOperation) valueRef(value interface{}) interface{} {
+	for _, k := range []string{"Machine", "Project", "Basis"} {
+		if f := op.valueFieldReflect(value, k); f.IsValid() && !f.IsNil() {
+			return f.Interface()
+		}
+	}
+
+	if f := op.scopeFieldReflect(value); f.IsValid() && !f.IsNil() {
+		return f.Interface()
+	}
+
+	return nil
+}
+
+// setValueRef replaces the reference the operation is scoped to with ref.
+// The reference must be of the same type as the existing reference.
+func (op *genericOperation) setValueRef(value interface{}, ref interface{}) {
+	rv := reflect.ValueOf(ref)
+	for _, k := range []string{"Machine", "Project", "Basis"} {
+		if f := op.valueFieldReflect(value, k); f.IsValid() && !f.IsNil() && f.Type() == rv.Type() {
+			f.Set(rv)
+			return
+		}
+	}
+
+	if f := op.scopeFieldReflect(value); f.IsValid() && f.Type() == rv.Type() {
+		f.Set(rv)
+	}
+}
+
+// scopeFieldReflect returns the reference stored within the Scope oneof
+// field of the value. Oneof fields are stored as an interface holding a
+// pointer to a wrapper struct with a single field.
+func (op *genericOperation) scopeFieldReflect(value interface{}) reflect.Value {
+	f := op.valueFieldReflect(value, "Scope")
+	if !f.IsValid() || f.Kind() != reflect.Interface || f.IsNil() {
+		return reflect.Value{}
+	}
+
+	w := f.Elem()
+	if w.Kind() != reflect.Ptr || w.IsNil() {
+		return reflect.Value{}
+	}
+	w = w.Elem()
+	if w.Kind() != reflect.Struct || w.NumField() == 0 {
+		return reflect.Value{}
+	}
+
+	return w.Field(0)
+}
+
 func (op *genericOperation) valueField(value interface{}, field string) interface{} {
 	fv := op.valueFieldReflect(value, field)
 	if !fv.IsValid() {
@@ -751,6 +763,120 @@ func (op *genericOperation) memSchema() *memdb.TableSchema {
 	}
 }
 
+// operationRef looks up the target, project, or basis referenced by ref
+// and returns a reference including the resource ids of the target,
+// its project, and its basis. This allows operations to be referenced
+// by name or path in addition to resource id.
+func (s *State) operationRef(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	ref interface{},
+) (interface{}, error) {
+	switch r := ref.(type) {
+	case *vagrant_plugin_sdk.Ref_Target:
+		if r == nil {
+			break
+		}
+		var project *vagrant_plugin_sdk.Ref_Project
+		if r.Project != nil {
+			raw, err := s.operationRef(dbTxn, memTxn, r.Project)
+			if err != nil {
+				return nil, err
+			}
+			project = raw.(*vagrant_plugin_sdk.Ref_Project)
+		}
+		t, err := s.targetFind(dbTxn, memTxn, &vagrant_server.Target{
+			ResourceId: r.ResourceId,
+			Name:       r.Name,
+			Project:    project,
+		})
+		if err != nil {
+			return nil, err
+		}
+		raw, err := s.operationRef(dbTxn, memTxn, t.Project)
+		if err != nil {
+			return nil, err
+		}
+		return &vagrant_plugin_sdk.Ref_Target{
+			ResourceId: t.ResourceId,
+			Name:       t.Name,
+			Project:    raw.(*vagrant_plugin_sdk.Ref_Project),
+		}, nil
+
+	case *vagrant_plugin_sdk.Ref_Project:
+		if r == nil {
+			break
+		}
+		p, err := s.projectFind(dbTxn, memTxn, &vagrant_server.Project{
+			ResourceId: r.ResourceId,
+			Name:       r.Name,
+			Path:       r.Path,
+		})
+		if err != nil {
+			return nil, err
+		}
+		raw, err := s.operationRef(dbTxn, memTxn, p.Basis)
+		if err != nil {
+			return nil, err
+		}
+		return &vagrant_plugin_sdk.Ref_Project{
+			ResourceId: p.ResourceId,
+			Name:       p.Name,
+			Path:       p.Path,
+			Basis:      raw.(*vagrant_plugin_sdk.Ref_Basis),
+		}, nil
+
+	case *vagrant_plugin_sdk.Ref_Basis:
+		if r == nil {
+			break
+		}
+		b, err := s.basisFind(dbTxn, memTxn, &vagrant_server.Basis{
+			ResourceId: r.ResourceId,
+			Name:       r.Name,
+			Path:       r.Path,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &vagrant_plugin_sdk.Ref_Basis{
+			ResourceId: b.ResourceId,
+			Name:       b.Name,
+			Path:       b.Path,
+		}, nil
+	}
+
+	return nil, status.Errorf(codes.InvalidArgument,
+		"a target, project, or basis reference is required")
+}
+
+// operationRefArgs returns the basis, project, and target resource ids
+// of the reference used for index lookups. The reference must have been
+// resolved with operationRef. If the reference is unknown nil is returned.
+func operationRefArgs(ref interface{}) []interface{} {
+	switch r := ref.(type) {
+	case *vagrant_plugin_sdk.Ref_Target:
+		return []interface{}{
+			r.Project.Basis.ResourceId,
+			r.Project.ResourceId,
+			r.ResourceId,
+		}
+	case *vagrant_plugin_sdk.Ref_Project:
+		return []interface{}{
+			r.Basis.ResourceId,
+			r.ResourceId,
+			opScopeNone,
+		}
+	case *vagrant_plugin_sdk.Ref_Basis:
+		return []interface{}{
+			r.ResourceId,
+			opScopeNone,
+			opScopeNone,
+		}
+	}
+
+	return nil
+}
+
 // operationIndexRecord is the record we store in MemDB to perform
 // indexed lookup operations by project, app, time, etc.
 type operationIndexRecord struct {
@@ -787,6 +913,11 @@ const (
 	opStartTimeIndexName    = "start-time"    // start time index
 	opCompleteTimeIndexName = "complete-time" // complete time index
 	opSeqIndexName          = "seq"           // sequence number index
+
+	// opScopeNone is indexed in place of the project and machine of
+	// operations that are not scoped to a project or machine since
+	// memdb does not index empty strings.
+	opScopeNone = "-"
 )
 
 // listOperationsOptions are options that can be set for List calls on
@@ -2,12 +2,19 @@ package state
 
 import (
 	"crypto/rand"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 	"github.com/hashicorp/go-memdb"
 	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 )
@@ -73,6 +80,7 @@ func (s *State) hmacKeyCreate(
 	var key vagrant_server.HMACKey
 	key.Id = id
 	key.Key = raw
+	key.CreateTime = timestamppb.Now()
 
 	// Persist our data
 	if err := dbPut(b, []byte(id), &key); err != nil {
@@ -110,6 +118,231 @@ func (s *State) hmacKeyGet(
 	return idx.Key, nil
 }
 
+// HMACKeyList returns all the HMAC keys, including retired keys.
+func (s *State) HMACKeyList() ([]*vagrant_server.HMACKey, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+	return s.hmacKeyList(memTxn)
+}
+
+// HMACKeyActive returns the key new tokens are signed with. This is the
+// most recently created key which isn't retired. This returns nil if
+// there is no such key.
+func (s *State) HMACKeyActive() (*vagrant_server.HMACKey, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+	return s.hmacKeyActive(memTxn)
+}
+
+// HMACKeyRotate creates a new HMAC key with the given size which becomes
+// the active key. If retireAfter is non-zero, the previously active key is
+// retired after that duration. Otherwise it remains valid until it is
+// retired with HMACKeyRetire.
+func (s *State) HMACKeyRotate(size int, retireAfter time.Duration) (*vagrant_server.HMACKey, error) {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	var result *vagrant_server.HMACKey
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		keys, err := s.hmacKeyList(memTxn)
+		if err != nil {
+			return err
+		}
+		active, err := s.hmacKeyActive(memTxn)
+		if err != nil {
+			return err
+		}
+
+		result, err = s.hmacKeyCreate(dbTxn, memTxn, hmacKeyNextId(keys), size)
+		if err != nil {
+			return err
+		}
+
+		// Schedule the retirement of the previously active key
+		if active != nil && retireAfter > 0 {
+			active = proto.Clone(active).(*vagrant_server.HMACKey)
+			return s.hmacKeyRetire(dbTxn, memTxn, active, time.Now().Add(retireAfter))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	memTxn.Commit()
+	return result, nil
+}
+
+// HMACKeyRetire sets the HMAC key with the given ID to be retired at the
+// given time. The active key can't be retired, a new key must be created
+// with HMACKeyRotate first.
+func (s *State) HMACKeyRetire(id string, at time.Time) (*vagrant_server.HMACKey, error) {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	var result *vagrant_server.HMACKey
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		key, err := s.hmacKeyGet(dbTxn, memTxn, id)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return status.Errorf(codes.NotFound, "HMAC key not found: %s", id)
+		}
+
+		active, err := s.hmacKeyActive(memTxn)
+		if err != nil {
+			return err
+		}
+		if active != nil && active.Id == key.Id {
+			return status.Errorf(codes.FailedPrecondition,
+				"HMAC key %s is used to sign new tokens, rotate the key before retiring it", id)
+		}
+
+		key = proto.Clone(key).(*vagrant_server.HMACKey)
+		result = key
+		return s.hmacKeyRetire(dbTxn, memTxn, key, at)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	memTxn.Commit()
+	return result, nil
+}
+
+// HMACKeyRotateStripped forces a key rotation if the database was
+// restored from a snapshot without HMAC key material. The keys without
+// material are retired so tokens signed with them are rejected, and a
+// new active key of the given size is created. This returns true if
+// the keys were rotated.
+func (s *State) HMACKeyRotateStripped(size int) (bool, error) {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	var rotated bool
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		keys, err := s.hmacKeyList(memTxn)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, key := range keys {
+			if len(key.Key) > 0 || HMACKeyRetired(key, now) {
+				continue
+			}
+
+			key = proto.Clone(key).(*vagrant_server.HMACKey)
+			if err := s.hmacKeyRetire(dbTxn, memTxn, key, now); err != nil {
+				return err
+			}
+			rotated = true
+		}
+		if !rotated {
+			return nil
+		}
+
+		_, err = s.hmacKeyCreate(dbTxn, memTxn, hmacKeyNextId(keys), size)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	memTxn.Commit()
+	return rotated, nil
+}
+
+func (s *State) hmacKeyRetire(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	key *vagrant_server.HMACKey,
+	at time.Time,
+) error {
+	key.RetireTime = timestamppb.New(at)
+	if err := dbPut(dbTxn.Bucket(hmacKeyBucket), []byte(key.Id), key); err != nil {
+		return err
+	}
+
+	return s.hmacKeyIndexSet(memTxn, key)
+}
+
+func (s *State) hmacKeyList(memTxn *memdb.Txn) ([]*vagrant_server.HMACKey, error) {
+	iter, err := memTxn.Get(hmacKeyIndexTableName, hmacKeyIndexIdIndexName+"_prefix", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*vagrant_server.HMACKey
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		result = append(result, raw.(*hmacKeyIndexRecord).Key)
+	}
+
+	return result, nil
+}
+
+func (s *State) hmacKeyActive(memTxn *memdb.Txn) (*vagrant_server.HMACKey, error) {
+	keys, err := s.hmacKeyList(memTxn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys which are retired or scheduled to retire are never active. Keys
+	// created before keys were rotated have no create time and sort before
+	// any other key.
+	var result *vagrant_server.HMACKey
+	for _, key := range keys {
+		if key.RetireTime != nil {
+			continue
+		}
+
+		if result == nil {
+			result = key
+			continue
+		}
+
+		// Keys created at the same time are ordered by their number
+		created, resultCreated := key.CreateTime.AsTime(), result.CreateTime.AsTime()
+		if created.After(resultCreated) ||
+			(created.Equal(resultCreated) && hmacKeyNumber(key.Id) > hmacKeyNumber(result.Id)) {
+			result = key
+		}
+	}
+
+	return result, nil
+}
+
+// hmacKeyNextId returns the ID of the next key to create. Key IDs are
+// numbered in order of creation: k1, k2, etc.
+func hmacKeyNextId(keys []*vagrant_server.HMACKey) string {
+	next := 1
+	for _, key := range keys {
+		if n := hmacKeyNumber(key.Id); n >= next {
+			next = n + 1
+		}
+	}
+
+	return fmt.Sprintf("k%d", next)
+}
+
+// hmacKeyNumber returns the number of a key with an ID such as "k2", or
+// zero if the ID isn't numbered.
+func hmacKeyNumber(id string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(id), "k"))
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return n
+}
+
+// HMACKeyRetired returns true if the key is retired at the given time.
+func HMACKeyRetired(key *vagrant_server.HMACKey, at time.Time) bool {
+	return key.RetireTime != nil && !key.RetireTime.AsTime().After(at)
+}
+
 // hmacKeyIndexSet writes an index record for a single HMAC key.
 func (s *State) hmacKeyIndexSet(txn *memdb.Txn, value *vagrant_server.HMACKey) error {
 	record := &hmacKeyIndexRecord{
@@ -1,9 +1,13 @@
 package state
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestHMAC(t *testing.T) {
@@ -55,4 +59,148 @@ func TestHMAC(t *testing.T) {
 			require.Equal(key2.Key, key.Key)
 		}
 	})
+
+	t.Run("Rotate and retire", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// No keys, nothing is active
+		active, err := s.HMACKeyActive()
+		require.NoError(err)
+		require.Nil(active)
+
+		k1, err := s.HMACKeyCreateIfNotExist("k1", 32)
+		require.NoError(err)
+		active, err = s.HMACKeyActive()
+		require.NoError(err)
+		require.Equal("k1", active.Id)
+
+		// Rotating creates a new active key and schedules k1 to retire
+		k2, err := s.HMACKeyRotate(32, time.Hour)
+		require.NoError(err)
+		require.Equal("k2", k2.Id)
+		require.NotEqual(k1.Key, k2.Key)
+
+		active, err = s.HMACKeyActive()
+		require.NoError(err)
+		require.Equal("k2", active.Id)
+
+		old, err := s.HMACKeyGet("k1")
+		require.NoError(err)
+		require.NotNil(old.RetireTime)
+		require.False(HMACKeyRetired(old, time.Now()))
+		require.True(HMACKeyRetired(old, time.Now().Add(2*time.Hour)))
+
+		// The active key can't be retired
+		_, err = s.HMACKeyRetire("k2", time.Now())
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+
+		_, err = s.HMACKeyRetire("nope", time.Now())
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		// Retire k1 now
+		old, err = s.HMACKeyRetire("k1", time.Now())
+		require.NoError(err)
+		require.True(HMACKeyRetired(old, time.Now()))
+
+		// Rotating without a retirement keeps k2 valid
+		k3, err := s.HMACKeyRotate(32, 0)
+		require.NoError(err)
+		require.Equal("k3", k3.Id)
+		k2, err = s.HMACKeyGet("k2")
+		require.NoError(err)
+		require.Nil(k2.RetireTime)
+
+		keys, err := s.HMACKeyList()
+		require.NoError(err)
+		require.Len(keys, 3)
+	})
+
+	t.Run("Retired keys are not snapshotted", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.HMACKeyCreateIfNotExist("k1", 32)
+		require.NoError(err)
+		_, err = s.HMACKeyRotate(32, time.Hour)
+		require.NoError(err)
+		_, err = s.HMACKeyRotate(32, 0)
+		require.NoError(err)
+		_, err = s.HMACKeyRetire("k2", time.Now())
+		require.NoError(err)
+
+		var buf bytes.Buffer
+		require.NoError(s.CreateSnapshot(&buf, true))
+		require.NoError(s.StageRestoreSnapshot(bytes.NewReader(buf.Bytes())))
+		s, err = TestStateRestart(t, s)
+		require.NoError(err)
+		defer s.Close()
+
+		// k1 retires later and k3 is active, k2 is gone
+		k1, err := s.HMACKeyGet("k1")
+		require.NoError(err)
+		require.NotNil(k1)
+		require.NotNil(k1.RetireTime)
+
+		k2, err := s.HMACKeyGet("k2")
+		require.NoError(err)
+		require.Nil(k2)
+
+		active, err := s.HMACKeyActive()
+		require.NoError(err)
+		require.Equal("k3", active.Id)
+	})
+
+	t.Run("Snapshots without key material force a rotation", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.HMACKeyCreateIfNotExist("k1", 32)
+		require.NoError(err)
+
+		// Nothing to rotate while the keys have their material
+		rotated, err := s.HMACKeyRotateStripped(32)
+		require.NoError(err)
+		require.False(rotated)
+
+		var buf bytes.Buffer
+		require.NoError(s.CreateSnapshot(&buf, false))
+		require.NoError(s.StageRestoreSnapshot(bytes.NewReader(buf.Bytes())))
+		s, err = TestStateRestart(t, s)
+		require.NoError(err)
+		defer s.Close()
+
+		// The key is restored without its material
+		k1, err := s.HMACKeyGet("k1")
+		require.NoError(err)
+		require.NotNil(k1)
+		require.Empty(k1.Key)
+		require.False(s.HMACKeyEmpty())
+
+		rotated, err = s.HMACKeyRotateStripped(32)
+		require.NoError(err)
+		require.True(rotated)
+
+		k1, err = s.HMACKeyGet("k1")
+		require.NoError(err)
+		require.True(HMACKeyRetired(k1, time.Now()))
+
+		active, err := s.HMACKeyActive()
+		require.NoError(err)
+		require.Equal("k2", active.Id)
+		require.Len(active.Key, 32)
+
+		// The rotation only happens once
+		rotated, err = s.HMACKeyRotateStripped(32)
+		require.NoError(err)
+		require.False(rotated)
+	})
 }
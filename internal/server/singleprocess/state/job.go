@@ -3,7 +3,6 @@ package state
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sort"
 	"time"
 
@@ -103,6 +102,15 @@ func jobSchema() *memdb.TableSchema {
 					},
 				},
 			},
+
+			jobDependsOnIndexName: {
+				Name:         jobDependsOnIndexName,
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.StringSliceFieldIndex{
+					Field: "DependsOn",
+				},
+			},
 		},
 	}
 }
@@ -110,8 +118,9 @@ func jobSchema() *memdb.TableSchema {
 type jobIndex struct {
 	Id string
 
-	// OpType is the operation type for the job.
-	OpType reflect.Type
+	// Command is the name of the command the job runs. This is used
+	// to determine if the job can run in parallel with other jobs.
+	Command string
 
 	// The basis/project/machine that this job is part of. This is used
 	// to determine if the job is blocked. See job_assigned.go for more details.
@@ -125,9 +134,34 @@ type jobIndex struct {
 	// TargetAny will be true if this job targets anything
 	TargetAny bool
 
+	// TargetSelectors are the label selectors a runner must match when
+	// the job targets any runner.
+	TargetSelectors []*vagrant_server.Ref_RunnerLabelSelector
+
 	// TargetRunnerId is the ID of the runner to target.
 	TargetRunnerId string
 
+	// AssignedRunnerId is the ID of the runner the job is assigned to.
+	AssignedRunnerId string
+
+	// DependsOn are the IDs of the jobs that must succeed before this
+	// job can be assigned. See job_depends.go for more details.
+	DependsOn []string
+
+	// RetryTime is the earliest time a retried job can be assigned.
+	RetryTime time.Time
+
+	// ExpireTime is the time the job expires if it isn't assigned and
+	// acked. This is zero if the job doesn't expire.
+	ExpireTime time.Time
+
+	// Deadline is the time the running job is cancelled because it
+	// exceeded its timeout. This is zero if the job has no timeout.
+	Deadline time.Time
+
+	// DeadlineTimer cancels the job once the deadline passes.
+	DeadlineTimer *time.Timer
+
 	// State is the current state of this job.
 	State vagrant_server.Job_State
 
@@ -162,8 +196,12 @@ type Job struct {
 	OutputBuffer *logbuffer.Buffer
 
 	// Blocked is true if this job is blocked on another job for the same
-	// project/app/workspace.
+	// project/target or on a job it depends on.
 	Blocked bool
+
+	// BlockedMessage describes the job this job is blocked on. This is
+	// empty if the job is not blocked.
+	BlockedMessage string
 }
 
 // JobCreate queues the given job.
@@ -231,9 +269,15 @@ func (s *State) JobById(id string, ws memdb.WatchSet) (*Job, error) {
 	jobIdx := raw.(*jobIndex)
 
 	// Get blocked status if it is queued.
-	var blocked bool
+	var blockedBy *jobAssignedIndex
+	var dependsOn *jobIndex
 	if jobIdx.State == vagrant_server.Job_QUEUED {
-		blocked, err = s.jobIsBlocked(memTxn, jobIdx, ws)
+		dependsOn, err = s.jobDependsPending(memTxn, jobIdx, ws)
+		if err != nil {
+			return nil, err
+		}
+
+		blockedBy, err = s.jobBlockedBy(memTxn, jobIdx, ws)
 		if err != nil {
 			return nil, err
 		}
@@ -246,7 +290,15 @@ func (s *State) JobById(id string, ws memdb.WatchSet) (*Job, error) {
 	})
 
 	result := jobIdx.Job(job)
-	result.Blocked = blocked
+	switch {
+	case dependsOn != nil:
+		result.Blocked = true
+		result.BlockedMessage = jobDependsMessage(dependsOn)
+
+	case blockedBy != nil:
+		result.Blocked = true
+		result.BlockedMessage = blockedBy.Message()
+	}
 
 	return result, err
 }
@@ -282,9 +334,19 @@ RETRY_ASSIGN:
 		candidateQuery = []candidateFunc{s.jobCandidateById}
 	}
 
+	// Draining runners are not assigned new jobs. We watch the runner so
+	// we retry if the runner stops draining.
+	ws := memdb.NewWatchSet()
+	draining, err := s.runnerDraining(txn, ws, r.Id)
+	if err != nil {
+		return nil, err
+	}
+	if draining {
+		candidateQuery = nil
+	}
+
 	// Build the list of candidates
 	var candidates []*jobIndex
-	ws := memdb.NewWatchSet()
 	for _, f := range candidateQuery {
 		job, err := f(txn, ws, runnerRec)
 		if err != nil {
@@ -350,7 +412,7 @@ RETRY_ASSIGN:
 		// We need to verify that in the time between our candidate search
 		// and our write lock acquisition, that this job hasn't been assigned,
 		// canceled, etc. If so, this is an invalid candidate.
-		job := raw.(*jobIndex)
+		job := raw.(*jobIndex).Copy()
 		if job == nil || job.State != vagrant_server.Job_QUEUED {
 			continue
 		}
@@ -363,11 +425,21 @@ RETRY_ASSIGN:
 			return nil, err
 		}
 
+		// Another job may have been assigned to the runner in the
+		// meantime so we recheck the capacity of the runner.
+		if ok, err := s.runnerHasCapacity(txn, nil, r, job); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
 		// Update our state and update our on-disk job
 		job.State = vagrant_server.Job_WAITING
+		job.AssignedRunnerId = r.Id
 		result, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
 			jobpb.State = job.State
 			jobpb.AssignTime = timestamppb.New(time.Now())
+			jobpb.AssignedRunner = &vagrant_server.Ref_RunnerId{Id: r.Id}
 			return nil
 		})
 		if err != nil {
@@ -414,7 +486,7 @@ func (s *State) JobAck(id string, ack bool) (*Job, error) {
 	if raw == nil {
 		return nil, status.Errorf(codes.NotFound, "job not found: %s", id)
 	}
-	job := raw.(*jobIndex)
+	job := raw.(*jobIndex).Copy()
 
 	// If the job is not in the assigned state, then this is an error.
 	if job.State != vagrant_server.Job_WAITING {
@@ -430,14 +502,25 @@ func (s *State) JobAck(id string, ack bool) (*Job, error) {
 			jobpb.State = job.State
 			jobpb.AckTime = timestamppb.New(time.Now())
 
+			// Start the clock on the execution timeout
+			if d := s.jobTimeout(jobpb, job.Command); d > 0 {
+				job.Deadline = jobpb.AckTime.AsTime().Add(d)
+				jobpb.Deadline = timestamppb.New(job.Deadline)
+			}
+
 			// We also initialize the output buffer here because we can
-			// expect output to begin streaming in.
-			job.OutputBuffer = logbuffer.New()
+			// expect output to begin streaming in. A retried job keeps
+			// its buffer so streams receive the output of every attempt.
+			if job.OutputBuffer == nil {
+				job.OutputBuffer = logbuffer.New()
+			}
 		} else {
 			// Set to queued
 			job.State = vagrant_server.Job_QUEUED
+			job.AssignedRunnerId = ""
 			jobpb.State = job.State
 			jobpb.AssignTime = nil
+			jobpb.AssignedRunner = nil
 		}
 
 		return nil
@@ -455,16 +538,16 @@ func (s *State) JobAck(id string, ack bool) (*Job, error) {
 	// Create a new timer that we'll use for our heartbeat. After this
 	// timer expires, the job will immediately move to an error state.
 	job.StateTimer = time.AfterFunc(jobHeartbeatTimeout, func() {
-		s.log.Info("canceling job due to heartbeat timeout", "job", job.Id)
-		// Force cancel
-		err := s.JobCancel(job.Id, true)
-		if err != nil {
-			s.log.Error("error canceling job due to heartbeat failure", "error", err, "job", job.Id)
-		}
+		s.jobHeartbeatFailed(job.Id)
 	})
 
 	s.log.Debug("heartbeat timer set", "job", job.Id, "timeout", jobHeartbeatTimeout)
 
+	// Start the timer for the execution timeout if we have one
+	if ack {
+		s.jobDeadlineSet(job)
+	}
+
 	// Insert to update
 	if err := txn.Insert(jobTableName, job); err != nil {
 		return nil, err
@@ -496,7 +579,7 @@ func (s *State) JobComplete(id string, result *vagrant_server.Job_Result, cerr e
 	if raw == nil {
 		return status.Errorf(codes.NotFound, "job not found: %s", id)
 	}
-	job := raw.(*jobIndex)
+	job := raw.(*jobIndex).Copy()
 
 	// Update our assigned state
 	if err := s.jobAssignedSet(txn, job, false); err != nil {
@@ -510,7 +593,19 @@ func (s *State) JobComplete(id string, result *vagrant_server.Job_Result, cerr e
 			job.State.String())
 	}
 
-	_, err = s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
+	// If the job failed, it may be retried rather than completed.
+	if cerr != nil {
+		retried, err := s.jobRetry(txn, job, cerr, vagrant_server.Job_RetryPolicy_UNSPECIFIED)
+		if err != nil {
+			return err
+		}
+		if retried {
+			txn.Commit()
+			return nil
+		}
+	}
+
+	jobpb, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
 		// Set to complete, assume success for now
 		job.State = vagrant_server.Job_SUCCESS
 		jobpb.State = job.State
@@ -521,6 +616,12 @@ func (s *State) JobComplete(id string, result *vagrant_server.Job_Result, cerr e
 			job.State = vagrant_server.Job_ERROR
 			jobpb.State = job.State
 
+			// A job cancelled for exceeding its timeout reports that
+			// rather than the error from the cancellation.
+			if err := jobTimeoutError(jobpb); err != nil {
+				cerr = err
+			}
+
 			st, _ := status.FromError(cerr)
 			jobpb.Error = st.Proto()
 		}
@@ -531,6 +632,12 @@ func (s *State) JobComplete(id string, result *vagrant_server.Job_Result, cerr e
 		return err
 	}
 
+	// Record the task in the task history. This is best effort since
+	// the job itself has completed.
+	if err := s.jobTaskPut(txn, jobpb); err != nil {
+		s.log.Warn("failed to record job task", "job", job.Id, "error", err)
+	}
+
 	// End the job
 	job.End()
 
@@ -539,7 +646,21 @@ func (s *State) JobComplete(id string, result *vagrant_server.Job_Result, cerr e
 		return err
 	}
 
+	// Jobs depending on a failed job can never run
+	if job.State == vagrant_server.Job_ERROR {
+		if err := s.jobDependentsFail(txn, job); err != nil {
+			return err
+		}
+	}
+
 	txn.Commit()
+
+	// Store the buffered output now that the job has no more output. This
+	// is best effort since the output is stored again later on failure.
+	if err := s.JobOutputFlush(); err != nil {
+		s.log.Warn("error storing job output", "job", job.Id, "error", err)
+	}
+
 	return nil
 }
 
@@ -558,7 +679,7 @@ func (s *State) JobCancel(id string, force bool) error {
 	if raw == nil {
 		return status.Errorf(codes.NotFound, "job not found: %s", id)
 	}
-	job := raw.(*jobIndex)
+	job := raw.(*jobIndex).Copy()
 
 	if err := s.jobCancel(txn, job, force); err != nil {
 		return err
@@ -604,7 +725,7 @@ func (s *State) jobCancel(txn *memdb.Txn, job *jobIndex, force bool) error {
 	}
 
 	// Persist the on-disk data
-	_, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
+	jobpb, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
 		jobpb.State = job.State
 		jobpb.CancelTime = timestamppb.New(time.Now())
 
@@ -613,6 +734,9 @@ func (s *State) jobCancel(txn *memdb.Txn, job *jobIndex, force bool) error {
 		// since otherwise we would've returned early.
 		if jobpb.State == vagrant_server.Job_ERROR {
 			jobpb.Error = status.New(codes.Canceled, "canceled").Proto()
+			if err := jobTimeoutError(jobpb); err != nil {
+				jobpb.Error = status.Convert(err).Proto()
+			}
 		}
 
 		return nil
@@ -621,6 +745,13 @@ func (s *State) jobCancel(txn *memdb.Txn, job *jobIndex, force bool) error {
 		return err
 	}
 
+	// Record the task of a running job that was force cancelled
+	if job.State == vagrant_server.Job_ERROR {
+		if err := s.jobTaskPut(txn, jobpb); err != nil {
+			s.log.Warn("failed to record job task", "job", job.Id, "error", err)
+		}
+	}
+
 	// Store the inmem data
 	// This will be seen by a currently running RunnerJobStream goroutine, which
 	// will then see that the job has been canceled and send the request to cancel
@@ -629,6 +760,13 @@ func (s *State) jobCancel(txn *memdb.Txn, job *jobIndex, force bool) error {
 		return err
 	}
 
+	// Jobs depending on a cancelled job can never run
+	if job.State == vagrant_server.Job_ERROR {
+		if err := s.jobDependentsFail(txn, job); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -690,11 +828,27 @@ func (s *State) JobExpire(id string) error {
 	if raw == nil {
 		return status.Errorf(codes.NotFound, "job not found: %s", id)
 	}
-	job := raw.(*jobIndex)
+	job := raw.(*jobIndex).Copy()
+
+	// A retried job gets a new expiry so this may be the timer of a
+	// previous attempt.
+	if job.ExpireTime.After(time.Now()) {
+		return nil
+	}
 
 	// How we handle depends on the state
 	switch job.State {
 	case vagrant_server.Job_QUEUED, vagrant_server.Job_WAITING:
+		retried, err := s.jobRetry(txn, job,
+			status.Error(codes.DeadlineExceeded, "job expired before it was assigned"),
+			vagrant_server.Job_RetryPolicy_EXPIRED)
+		if err != nil {
+			return err
+		}
+		if retried {
+			break
+		}
+
 		if err := s.jobCancel(txn, job, false); err != nil {
 			return err
 		}
@@ -735,8 +889,9 @@ func (s *State) JobIsAssignable(ctx context.Context, jobpb *vagrant_server.Job)
 	switch v := jobpb.TargetRunner.Target.(type) {
 	case *vagrant_server.Ref_Runner_Any:
 		// We need a special target check that disallows by ID only
+		// and requires the runner labels to match the selectors.
 		targetCheck = func(r *vagrant_server.Runner) (bool, error) {
-			return !r.ByIdOnly, nil
+			return !r.ByIdOnly && runnerMatchesSelectors(r, v.Any.GetSelectors()), nil
 		}
 
 		iter, err = memTxn.LowerBound(runnerTableName, runnerIdIndexName, "")
@@ -778,7 +933,7 @@ func (s *State) JobIsAssignable(ctx context.Context, jobpb *vagrant_server.Job)
 // jobIndexInit initializes the config index from persisted data.
 func (s *State) jobIndexInit(dbTxn *bolt.Tx, memTxn *memdb.Txn) error {
 	bucket := dbTxn.Bucket(jobBucket)
-	return bucket.ForEach(func(k, v []byte) error {
+	err := bucket.ForEach(func(k, v []byte) error {
 		var value vagrant_server.Job
 		if err := proto.Unmarshal(v, &value); err != nil {
 			return err
@@ -798,6 +953,12 @@ func (s *State) jobIndexInit(dbTxn *bolt.Tx, memTxn *memdb.Txn) error {
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// Dependencies are checked once all the jobs are indexed
+	return s.jobDependsInit(memTxn)
 }
 
 // jobIndexSet writes an index record for a single job.
@@ -808,7 +969,10 @@ func (s *State) jobIndexSet(txn *memdb.Txn, id []byte, jobpb *vagrant_server.Job
 		Basis:   jobpb.Basis,
 		Project: jobpb.Project,
 		Target:  jobpb.Target,
-		OpType:  reflect.TypeOf(jobpb.Operation),
+		Command: jobCommandName(jobpb),
+
+		AssignedRunnerId: jobpb.AssignedRunner.GetId(),
+		DependsOn:        jobpb.DependsOn,
 	}
 
 	// Target
@@ -818,6 +982,7 @@ func (s *State) jobIndexSet(txn *memdb.Txn, id []byte, jobpb *vagrant_server.Job
 	switch v := jobpb.TargetRunner.Target.(type) {
 	case *vagrant_server.Ref_Runner_Any:
 		rec.TargetAny = true
+		rec.TargetSelectors = v.Any.GetSelectors()
 
 	case *vagrant_server.Ref_Runner_Id:
 		rec.TargetRunnerId = v.Id.Id
@@ -855,8 +1020,22 @@ func (s *State) jobIndexSet(txn *memdb.Txn, id []byte, jobpb *vagrant_server.Job
 	// This should only happen on reinit. This is tested.
 	if rec.State == vagrant_server.Job_RUNNING {
 		rec.StateTimer = time.AfterFunc(jobHeartbeatTimeout, func() {
-			// Force cancel
-			s.JobCancel(rec.Id, true)
+			s.jobHeartbeatFailed(rec.Id)
+		})
+
+		// Restart the execution timeout as well
+		if jobpb.Deadline != nil {
+			rec.Deadline = jobpb.Deadline.AsTime()
+			s.jobDeadlineSet(rec)
+		}
+	}
+
+	// If this job is waiting to be retried, we need to wake up anything
+	// waiting for a job to assign once the backoff has passed.
+	if rec.State == vagrant_server.Job_QUEUED && jobpb.RetryTime != nil {
+		rec.RetryTime = jobpb.RetryTime.AsTime()
+		rec.StateTimer = time.AfterFunc(time.Until(rec.RetryTime), func() {
+			s.jobRetryReady(rec.Id)
 		})
 	}
 
@@ -868,6 +1047,7 @@ func (s *State) jobIndexSet(txn *memdb.Txn, id []byte, jobpb *vagrant_server.Job
 		if err != nil {
 			return nil, err
 		}
+		rec.ExpireTime = jobpb.ExpireTime.AsTime()
 
 		dur := jobpb.ExpireTime.AsTime().Sub(now)
 		if dur < 0 {
@@ -886,6 +1066,12 @@ func (s *State) jobCreate(dbTxn *bolt.Tx, memTxn *memdb.Txn, jobpb *vagrant_serv
 	var err error
 	jobpb.State = vagrant_server.Job_QUEUED
 	jobpb.QueueTime = timestamppb.New(time.Now())
+	jobpb.Attempt = 1
+
+	// Verify the jobs this job depends on
+	if err := s.jobDependsValidate(memTxn, jobpb); err != nil {
+		return err
+	}
 
 	id := []byte(jobpb.Id)
 
@@ -912,8 +1098,8 @@ func (s *State) jobById(dbTxn *bolt.Tx, id string) (*vagrant_server.Job, error)
 
 func (s *State) jobReadAndUpdate(id string, f func(*vagrant_server.Job) error) (*vagrant_server.Job, error) {
 	var result *vagrant_server.Job
-	var err error
-	return result, s.db.Update(func(dbTxn *bolt.Tx) error {
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		var err error
 		result, err = s.jobById(dbTxn, id)
 		if err != nil {
 			return err
@@ -927,6 +1113,8 @@ func (s *State) jobReadAndUpdate(id string, f func(*vagrant_server.Job) error) (
 		// Commit
 		return dbPut(dbTxn.Bucket(jobBucket), []byte(id), result)
 	})
+
+	return result, err
 }
 
 // jobCandidateById returns the most promising candidate job to assign
@@ -954,6 +1142,18 @@ func (s *State) jobCandidateById(memTxn *memdb.Txn, ws memdb.WatchSet, r *runner
 			continue
 		}
 
+		// If this job is waiting to be retried, it is not a candidate.
+		if job.RetryTime.After(time.Now()) {
+			continue
+		}
+
+		// If this job is waiting on a dependency, it is not a candidate.
+		if dep, err := s.jobDependsPending(memTxn, job, ws); err != nil {
+			return nil, err
+		} else if dep != nil {
+			continue
+		}
+
 		// If this job is blocked, it is not a candidate.
 		if blocked, err := s.jobIsBlocked(memTxn, job, ws); err != nil {
 			return nil, err
@@ -961,6 +1161,14 @@ func (s *State) jobCandidateById(memTxn *memdb.Txn, ws memdb.WatchSet, r *runner
 			continue
 		}
 
+		// If the runner has no capacity left for this job, it is not
+		// a candidate.
+		if ok, err := s.runnerHasCapacity(memTxn, ws, r.Runner, job); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
 		return job, nil
 	}
 
@@ -990,6 +1198,24 @@ func (s *State) jobCandidateAny(memTxn *memdb.Txn, ws memdb.WatchSet, r *runnerR
 			continue
 		}
 
+		// If this job is waiting to be retried, it is not a candidate.
+		if job.RetryTime.After(time.Now()) {
+			continue
+		}
+
+		// If this job is waiting on a dependency, it is not a candidate.
+		if dep, err := s.jobDependsPending(memTxn, job, ws); err != nil {
+			return nil, err
+		} else if dep != nil {
+			continue
+		}
+
+		// If the runner doesn't have the labels the job requires, it
+		// is not a candidate.
+		if !runnerMatchesSelectors(r.Runner, job.TargetSelectors) {
+			continue
+		}
+
 		// If this job is blocked, it is not a candidate.
 		if blocked, err := s.jobIsBlocked(memTxn, job, ws); err != nil {
 			return nil, err
@@ -997,12 +1223,100 @@ func (s *State) jobCandidateAny(memTxn *memdb.Txn, ws memdb.WatchSet, r *runnerR
 			continue
 		}
 
+		// If the runner has no capacity left for this job, it is not
+		// a candidate.
+		if ok, err := s.runnerHasCapacity(memTxn, ws, r.Runner, job); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
 		return job, nil
 	}
 
 	return nil, nil
 }
 
+// jobsAssignedToRunner returns all the jobs assigned to the runner which
+// have not completed. If ws is set, a watch is added for changes to the
+// jobs.
+func (s *State) jobsAssignedToRunner(
+	memTxn *memdb.Txn,
+	ws memdb.WatchSet,
+	runnerId string,
+) ([]*jobIndex, error) {
+	// Jobs are updated in place so the state index may still have entries
+	// for jobs which changed state since. This means we must check the
+	// state of each job and watch the whole table rather than the states.
+	if ws != nil {
+		iter, err := memTxn.Get(jobTableName, jobIdIndexName+"_prefix", "")
+		if err != nil {
+			return nil, err
+		}
+		ws.Add(iter.WatchCh())
+	}
+
+	var result []*jobIndex
+	for _, state := range []vagrant_server.Job_State{
+		vagrant_server.Job_WAITING,
+		vagrant_server.Job_RUNNING,
+	} {
+		iter, err := memTxn.Get(jobTableName, jobStateIndexName, state)
+		if err != nil {
+			return nil, err
+		}
+
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			job := raw.(*jobIndex)
+			if job.State == state && job.AssignedRunnerId == runnerId {
+				result = append(result, job)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// jobsRequeueForRunner moves all the jobs assigned to the runner that
+// have not completed back to the queued state so they can be assigned
+// to another runner.
+func (s *State) jobsRequeueForRunner(memTxn *memdb.Txn, runnerId string) error {
+	jobs, err := s.jobsAssignedToRunner(memTxn, nil, runnerId)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		s.log.Info("requeueing job of removed runner", "job", job.Id, "runner", runnerId)
+
+		job = job.Copy()
+		job.End()
+		job.State = vagrant_server.Job_QUEUED
+		job.AssignedRunnerId = ""
+		_, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
+			jobpb.State = job.State
+			jobpb.AssignTime = nil
+			jobpb.AckTime = nil
+			jobpb.AssignedRunner = nil
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := memTxn.Insert(jobTableName, job); err != nil {
+			return err
+		}
+
+		// Unblock the jobs waiting on this job
+		if err := s.jobAssignedSet(memTxn, job, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *State) jobsPruneOld(memTxn *memdb.Txn, max int) (int, error) {
 	// Prune from memdb
 	return pruneOld(memTxn, pruneOp{
@@ -1019,37 +1333,67 @@ func (s *State) jobsPruneOld(memTxn *memdb.Txn, max int) (int, error) {
 	})
 }
 
+// JobsDBPruneOld deletes the oldest completed jobs and their output from
+// the database until at most max jobs are stored. Jobs that have not
+// completed are never pruned, so more than max jobs may remain.
 func (s *State) JobsDBPruneOld(max int) (int, error) {
 	cnt := dbCount(s.db, jobTableName)
 	toDelete := cnt - max
-	var deleted int
+	if toDelete <= 0 {
+		return 0, nil
+	}
+
+	// Hold the output lock so buffered output of pruned jobs isn't
+	// written after the jobs are deleted.
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
 
 	// Prune jobs from boltDB
-	s.db.Update(func(tx *bolt.Tx) error {
+	var deleted int
+	var pruned [][]byte
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(jobTableName))
+
+		// Collect the keys first since deleting while iterating
+		// the cursor will skip keys.
+		var keys [][]byte
 		cur := bucket.Cursor()
-		key, _ := cur.First()
-		for {
-			if key == nil {
-				break
+		for key, _ := cur.First(); key != nil && len(keys) < toDelete; key, _ = cur.Next() {
+			var job vagrant_server.Job
+			if err := dbGet(bucket, key, &job); err != nil {
+				return err
+			}
+			if !jobIsCompleted(job.State) {
+				continue
 			}
-			// otherwise, prune this job! Once we've pruned enough jobs to get back
-			// to the maximum, we stop pruning.
-			toDelete--
 
-			err := bucket.Delete(key)
-			if err != nil {
+			keys = append(keys, key)
+		}
+
+		// Prune the jobs along with their output. Once we've pruned enough
+		// jobs to get back to the maximum, we stop pruning.
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			if err := jobOutputDelete(tx, key); err != nil {
 				return err
 			}
 
 			deleted++
-			if toDelete <= 0 {
-				break
-			}
-			key, _ = cur.Next()
 		}
+
+		pruned = keys
 		return nil
 	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range pruned {
+		s.jobOutputDiscard(string(key))
+	}
+
 	return deleted, nil
 }
 
@@ -1061,10 +1405,22 @@ func (idx *jobIndex) Job(jobpb *vagrant_server.Job) *Job {
 	}
 }
 
+// Copy returns a copy of the index. Records in memdb must not be
+// modified in place since readers may hold them, so a record is copied
+// before it is changed and the copy is inserted.
+func (idx *jobIndex) Copy() *jobIndex {
+	result := *idx
+	return &result
+}
+
 // End notes this job is complete and performs any cleanup on the index.
 func (idx *jobIndex) End() {
 	if idx.StateTimer != nil {
 		idx.StateTimer.Stop()
 		idx.StateTimer = nil
 	}
+	if idx.DeadlineTimer != nil {
+		idx.DeadlineTimer.Stop()
+		idx.DeadlineTimer = nil
+	}
 }
@@ -1,21 +1,32 @@
 package state
 
 import (
-	"reflect"
+	"fmt"
+	"strings"
 
 	"github.com/hashicorp/go-memdb"
-	//	pb "github.com/hashicorp/vagrant/internal/server/gen"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 )
 
 // This file has the methods related to tracking assigned jobs. I pulled this
 // out into a separate file since the job queueing logic is already quite long.
 
-// blockOps are the operation types that can NOT be run in parallel. Anything
-// in this list will block if any other operation in this list is running
-// for the app and workspace.
-// TODO(spox): can we make this dynamic based on metadata provided via action request?
-var blockOps = map[reflect.Type]struct{}{
-	// reflect.TypeOf((*pb.Job_Destroy)(nil)): {},
+// DefaultExclusiveCommands are the commands that can NOT be run in parallel.
+// A job running any of these commands will block if any other job running
+// one of these commands is assigned for the same target. Jobs which are not
+// scoped to a target lock their entire project.
+var DefaultExclusiveCommands = []string{
+	"destroy",
+	"halt",
+	"init",
+	"package",
+	"provision",
+	"reload",
+	"resume",
+	"snapshot",
+	"suspend",
+	"up",
 }
 
 func init() {
@@ -62,6 +73,63 @@ type jobAssignedIndex struct {
 	Basis   string
 	Project string
 	Machine string
+
+	// JobId is the ID of the job holding the lock
+	JobId string
+
+	// Command is the command the job holding the lock is running
+	Command string
+}
+
+// Message returns a message describing the job holding the lock
+// that can be shown to users waiting on the lock.
+func (rec *jobAssignedIndex) Message() string {
+	scope := "target"
+	if rec.Machine == opScopeNone {
+		scope = "project"
+	}
+
+	return fmt.Sprintf("Waiting for job %s (%s) to complete on this %s",
+		rec.JobId, rec.Command, scope)
+}
+
+// SetExclusiveCommands sets the commands which can not be run in parallel
+// on the same target. If cmds is nil, DefaultExclusiveCommands is used.
+func (s *State) SetExclusiveCommands(cmds []string) {
+	if cmds == nil {
+		cmds = DefaultExclusiveCommands
+	}
+
+	exclusive := map[string]struct{}{}
+	for _, c := range cmds {
+		exclusive[strings.ToLower(c)] = struct{}{}
+	}
+
+	s.exclusiveMu.Lock()
+	defer s.exclusiveMu.Unlock()
+	s.exclusiveCmds = exclusive
+}
+
+// jobIsExclusive returns true if the job can not run in parallel with
+// other exclusive jobs for the same target.
+func (s *State) jobIsExclusive(idx *jobIndex) bool {
+	if idx.Command == "" || jobAssignedIdxArgs(idx) == nil {
+		return false
+	}
+
+	s.exclusiveMu.RLock()
+	defer s.exclusiveMu.RUnlock()
+	if s.exclusiveCmds == nil {
+		for _, c := range DefaultExclusiveCommands {
+			if c == idx.Command {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, ok := s.exclusiveCmds[idx.Command]
+	return ok
 }
 
 // jobIsBlocked will return true if the given job is currently blocked because
@@ -71,58 +139,148 @@ type jobAssignedIndex struct {
 // Note that this trigger doesn't mean that the blocking is necessarily gone
 // but something changed to warrant rechecking.
 func (s *State) jobIsBlocked(memTxn *memdb.Txn, idx *jobIndex, ws memdb.WatchSet) (bool, error) {
+	rec, err := s.jobBlockedBy(memTxn, idx, ws)
+	return rec != nil, err
+}
+
+// jobBlockedBy returns the assignment record of the job blocking the
+// given job. If the job is not blocked, nil is returned. Jobs for a target
+// are blocked by jobs for the same target or for the project of the target.
+// Jobs for a project are blocked by any job within the project.
+func (s *State) jobBlockedBy(memTxn *memdb.Txn, idx *jobIndex, ws memdb.WatchSet) (*jobAssignedIndex, error) {
 	// If this job represents a parallelizable operation type, then allow it.
-	if _, ok := blockOps[idx.OpType]; !ok {
-		return false, nil
+	if !s.jobIsExclusive(idx) {
+		return nil, nil
 	}
 
-	// Look for this project/app/ws combo
-	watchCh, value, err := memTxn.FirstWatch(
-		jobAssignedTableName,
-		jobAssignedIdIndexName,
-		s.jobAssignedIdxArgs(idx)...,
-	)
-	if err != nil {
-		return false, err
+	args := jobAssignedIdxArgs(idx)
+	if args[2] == opScopeNone {
+		iter, err := memTxn.Get(
+			jobAssignedTableName,
+			jobAssignedIdIndexName+"_prefix",
+			args[0], args[1],
+		)
+		if err != nil {
+			return nil, err
+		}
+		if ws != nil {
+			ws.Add(iter.WatchCh())
+		}
+
+		// The prefix lookup may match other projects that share the
+		// prefix of the project id so the project must be checked.
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			rec := raw.(*jobAssignedIndex)
+			if rec.JobId != idx.Id && strings.EqualFold(rec.Project, args[1].(string)) {
+				return rec, nil
+			}
+		}
+
+		return nil, nil
 	}
-	if ws != nil {
-		ws.Add(watchCh)
+
+	for _, lookup := range [][]interface{}{args, {args[0], args[1], opScopeNone}} {
+		watchCh, value, err := memTxn.FirstWatch(
+			jobAssignedTableName,
+			jobAssignedIdIndexName,
+			lookup...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if ws != nil {
+			ws.Add(watchCh)
+		}
+
+		// Blocked if we have a record
+		if rec, ok := value.(*jobAssignedIndex); ok && rec.JobId != idx.Id {
+			return rec, nil
+		}
 	}
 
-	// Blocked if we have a record
-	return value != nil, nil
+	return nil, nil
 }
 
 // jobAssignedSet records the given job as assigned.
 func (s *State) jobAssignedSet(memTxn *memdb.Txn, idx *jobIndex, assigned bool) error {
+	args := jobAssignedIdxArgs(idx)
+	if args == nil {
+		return nil
+	}
+
+	if !assigned {
+		// Release any lock held by the job. The lock is looked up rather
+		// than checking if the job is exclusive since the exclusive
+		// commands may have changed since the job was assigned.
+		raw, err := memTxn.First(jobAssignedTableName, jobAssignedIdIndexName, args...)
+		if err != nil {
+			return err
+		}
+		if rec, ok := raw.(*jobAssignedIndex); ok && rec.JobId == idx.Id {
+			return memTxn.Delete(jobAssignedTableName, rec)
+		}
+
+		return nil
+	}
+
 	// If this job represents a parallelizable operation type, then do nothing.
-	if _, ok := blockOps[idx.OpType]; !ok {
+	if !s.jobIsExclusive(idx) {
 		return nil
 	}
 
-	args := s.jobAssignedIdxArgs(idx)
-	rec := &jobAssignedIndex{
+	return memTxn.Insert(jobAssignedTableName, &jobAssignedIndex{
 		Basis:   args[0].(string),
 		Project: args[1].(string),
 		Machine: args[2].(string),
+		JobId:   idx.Id,
+		Command: idx.Command,
+	})
+}
+
+// jobAssignedIdxArgs returns the basis, project, and machine the job
+// locks when assigned. Jobs which are not scoped to a target or project
+// do not lock anything and nil is returned.
+func jobAssignedIdxArgs(idx *jobIndex) []interface{} {
+	project, machine := idx.Project, opScopeNone
+	if idx.Target.GetResourceId() != "" {
+		project, machine = idx.Target.Project, idx.Target.ResourceId
+	}
+	if project.GetResourceId() == "" {
+		return nil
 	}
 
-	if assigned {
-		return memTxn.Insert(jobAssignedTableName, rec)
+	basis := project.Basis.GetResourceId()
+	if basis == "" {
+		basis = opScopeNone
 	}
 
-	return memTxn.Delete(jobAssignedTableName, rec)
+	return []interface{}{basis, project.ResourceId, machine}
 }
 
-func (s *State) jobAssignedIdxArgs(idx *jobIndex) []interface{} {
-	if idx.Target != nil {
-		return []interface{}{
-			idx.Target.Project.Basis.ResourceId, idx.Target.Project.ResourceId, idx.Target.ResourceId,
-		}
-	} else if idx.Project != nil {
-		return []interface{}{
-			idx.Project.Basis.ResourceId, idx.Project.ResourceId, "",
+// jobCommandName returns the name of the command the job runs. This is
+// used to determine if the job can run in parallel with other jobs.
+func jobCommandName(jobpb *vagrant_server.Job) string {
+	var name string
+	switch op := jobpb.Operation.(type) {
+	case *vagrant_server.Job_Run:
+		if t := op.Run.GetTask(); t != nil {
+			name = t.CommandName
+			if name == "" {
+				name = t.Component.GetName()
+			}
+			if name == "" {
+				name = t.Task
+			}
 		}
+	case *vagrant_server.Job_Init:
+		name = "init"
+	case *vagrant_server.Job_Validate:
+		name = "validate"
+	case *vagrant_server.Job_Auth:
+		name = "auth"
+	case *vagrant_server.Job_Docs:
+		name = "docs"
 	}
-	return []interface{}{idx.Basis.ResourceId, "", ""}
+
+	return strings.ToLower(name)
 }
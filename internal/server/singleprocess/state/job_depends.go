@@ -0,0 +1,213 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// This file has the methods related to job dependencies. A job that
+// depends on other jobs stays queued until all of them succeed, and fails
+// without running if any of them fail.
+
+const (
+	jobDependsOnIndexName = "depends-on"
+)
+
+// jobDependsValidate verifies the dependencies of a new job. Every
+// dependency must exist and must not have failed already.
+func (s *State) jobDependsValidate(memTxn *memdb.Txn, jobpb *vagrant_server.Job) error {
+	for _, id := range jobpb.DependsOn {
+		if id == jobpb.Id {
+			return status.Errorf(codes.InvalidArgument,
+				"job can't depend on itself: %s", id)
+		}
+
+		raw, err := memTxn.First(jobTableName, jobIdIndexName, id)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			return status.Errorf(codes.NotFound,
+				"dependency job not found: %s", id)
+		}
+
+		if dep := raw.(*jobIndex); dep.State == vagrant_server.Job_ERROR {
+			return status.Errorf(codes.FailedPrecondition,
+				"dependency job %s has already failed", id)
+		}
+	}
+
+	return nil
+}
+
+// jobDependsPending returns the first dependency of the job that hasn't
+// succeeded yet. If all dependencies succeeded, nil is returned.
+//
+// Dependencies that no longer exist were pruned after completing. A failed
+// dependency fails its dependents immediately so a job that is still queued
+// must only have had successful dependencies pruned.
+//
+// If ws is set then a watch will be added for any changes to the
+// dependencies of the job.
+func (s *State) jobDependsPending(memTxn *memdb.Txn, idx *jobIndex, ws memdb.WatchSet) (*jobIndex, error) {
+	for _, id := range idx.DependsOn {
+		watchCh, raw, err := memTxn.FirstWatch(jobTableName, jobIdIndexName, id)
+		if err != nil {
+			return nil, err
+		}
+		if ws != nil {
+			ws.Add(watchCh)
+		}
+
+		if dep, ok := raw.(*jobIndex); ok && dep.State != vagrant_server.Job_SUCCESS {
+			return dep, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// jobDependsMessage returns a message describing the dependency a job is
+// waiting on that can be shown to users.
+func jobDependsMessage(dep *jobIndex) string {
+	msg := fmt.Sprintf("Waiting for job %s to complete successfully", dep.Id)
+	if dep.Command != "" {
+		msg = fmt.Sprintf("Waiting for job %s (%s) to complete successfully", dep.Id, dep.Command)
+	}
+
+	return msg
+}
+
+// jobDependentsFail fails all the queued jobs that depend on the given
+// job. This is called when the job fails. Jobs depending on the failed
+// dependents are failed as well.
+func (s *State) jobDependentsFail(memTxn *memdb.Txn, job *jobIndex) error {
+	iter, err := memTxn.Get(jobTableName, jobDependsOnIndexName, job.Id)
+	if err != nil {
+		return err
+	}
+
+	// Collect copies of the dependents first since we modify the table below
+	var dependents []*jobIndex
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		if dep := raw.(*jobIndex); dep.State == vagrant_server.Job_QUEUED {
+			dependents = append(dependents, dep.Copy())
+		}
+	}
+
+	for _, dep := range dependents {
+		if err := s.jobDependFail(memTxn, dep, job.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jobDependFail fails a queued job because the dependency with the
+// given ID failed. The job must be a copy of its index record since it
+// is modified and inserted.
+func (s *State) jobDependFail(memTxn *memdb.Txn, job *jobIndex, depId string) error {
+	s.log.Info("failing job due to failed dependency", "job", job.Id, "dependency", depId)
+
+	jobpb, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
+		jobpb.State = vagrant_server.Job_ERROR
+		jobpb.CompleteTime = timestamppb.New(time.Now())
+		jobpb.Error = status.Newf(codes.FailedPrecondition,
+			"dependency job %s failed", depId).Proto()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Record the task in the task history. This is best effort.
+	if err := s.jobTaskPut(memTxn, jobpb); err != nil {
+		s.log.Warn("failed to record job task", "job", job.Id, "error", err)
+	}
+
+	job.End()
+	job.State = vagrant_server.Job_ERROR
+	if err := memTxn.Insert(jobTableName, job); err != nil {
+		return err
+	}
+
+	return s.jobDependentsFail(memTxn, job)
+}
+
+// jobDependsInit finds the queued jobs with a failed dependency while the
+// job index is initialized. This catches dependents that weren't failed if
+// the server stopped while failing them. The jobs can't be failed during
+// the index initialization since it runs in a read transaction, so they are
+// failed by jobDependsInitFail once it is done.
+func (s *State) jobDependsInit(memTxn *memdb.Txn) error {
+	iter, err := memTxn.Get(jobTableName, jobStateIndexName, vagrant_server.Job_QUEUED)
+	if err != nil {
+		return err
+	}
+
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		job := raw.(*jobIndex)
+		for _, id := range job.DependsOn {
+			depRaw, err := memTxn.First(jobTableName, jobIdIndexName, id)
+			if err != nil {
+				return err
+			}
+
+			if dep, ok := depRaw.(*jobIndex); ok && dep.State == vagrant_server.Job_ERROR {
+				s.dependsFailed = append(s.dependsFailed, jobDependFailure{
+					id:    job.Id,
+					depId: dep.Id,
+				})
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// jobDependFailure is a queued job found with a failed dependency by
+// jobDependsInit.
+type jobDependFailure struct {
+	id    string
+	depId string
+}
+
+// jobDependsInitFail fails the queued jobs with a failed dependency found
+// while the job index was initialized.
+func (s *State) jobDependsInitFail() error {
+	txn := s.inmem.Txn(true)
+	defer txn.Abort()
+
+	for _, f := range s.dependsFailed {
+		raw, err := txn.First(jobTableName, jobIdIndexName, f.id)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+
+		// The job may have been failed already as a dependent of
+		// another job in the list
+		job := raw.(*jobIndex).Copy()
+		if job.State != vagrant_server.Job_QUEUED {
+			continue
+		}
+
+		if err := s.jobDependFail(txn, job, f.depId); err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+	return nil
+}
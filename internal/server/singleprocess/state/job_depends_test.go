@@ -0,0 +1,261 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestJobDepends(t *testing.T) {
+	t.Run("waits for dependencies to succeed", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+
+		// B is blocked on A
+		job, err := s.JobById("B", nil)
+		require.NoError(err)
+		require.True(job.Blocked)
+		require.Contains(job.BlockedMessage, "A")
+
+		testJobRun(t, s, "A")
+
+		// B can't be assigned while A is running
+		{
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
+			require.Error(err)
+			require.Equal(ctx.Err(), err)
+		}
+
+		// Complete A, then B can be assigned
+		require.NoError(s.JobComplete("A", nil, nil))
+		testJobRun(t, s, "B")
+	})
+
+	t.Run("wakes up waiting runners", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+		testJobRun(t, s, "A")
+
+		// Wait for assignment in the background
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		doneCh := make(chan *Job, 1)
+		go func() {
+			job, err := s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
+			require.NoError(err)
+			doneCh <- job
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		select {
+		case job := <-doneCh:
+			require.Equal("B", job.Id)
+		case <-ctx.Done():
+			t.Fatal("job should be assigned")
+		}
+	})
+
+	t.Run("fails dependents when a dependency fails", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "C",
+			DependsOn: []string{"B"},
+		})))
+
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Internal, "boom")))
+
+		for _, id := range []string{"B", "C"} {
+			job, err := s.JobById(id, nil)
+			require.NoError(err)
+			require.Equal(vagrant_server.Job_ERROR, job.State)
+			require.Equal(int32(codes.FailedPrecondition), job.Error.Code)
+			require.Contains(job.Error.Message, "failed")
+		}
+	})
+
+	t.Run("doesn't modify index records in place", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+
+		// Hold the record of B as a reader would
+		txn := s.inmem.Txn(false)
+		raw, err := txn.First(jobTableName, jobIdIndexName, "B")
+		txn.Abort()
+		require.NoError(err)
+		old := raw.(*jobIndex)
+
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Internal, "boom")))
+
+		job, err := s.JobById("B", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_ERROR, job.State)
+		require.Equal(vagrant_server.Job_QUEUED, old.State)
+	})
+
+	t.Run("fails dependents when a dependency is cancelled", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+		require.NoError(s.JobCancel("A", false))
+
+		job, err := s.JobById("B", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_ERROR, job.State)
+		require.Equal(int32(codes.FailedPrecondition), job.Error.Code)
+	})
+
+	t.Run("validates dependencies", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Unknown dependency
+		err := s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		}))
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		// Failed dependency
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCancel("A", false))
+		err = s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		}))
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("survives a restart", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+
+		s = TestStateReinit(t, s)
+		defer s.Close()
+
+		// B is still blocked on A
+		testJobRun(t, s, "A")
+		job, err := s.JobById("B", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_QUEUED, job.State)
+		require.True(job.Blocked)
+
+		require.NoError(s.JobComplete("A", nil, nil))
+		testJobRun(t, s, "B")
+	})
+
+	t.Run("fails dependents of failed jobs on restart", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			DependsOn: []string{"A"},
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "C",
+			DependsOn: []string{"B"},
+		})))
+
+		// Fail A without failing its dependents, as if the server stopped
+		// before failing them
+		_, err := s.jobReadAndUpdate("A", func(jobpb *vagrant_server.Job) error {
+			jobpb.State = vagrant_server.Job_ERROR
+			return nil
+		})
+		require.NoError(err)
+
+		s = TestStateReinit(t, s)
+		defer s.Close()
+
+		// The dependents are failed once the state is initialized
+		for _, id := range []string{"B", "C"} {
+			job, err := s.JobById(id, nil)
+			require.NoError(err)
+			require.Equal(vagrant_server.Job_ERROR, job.State, id)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package state
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// jobOutputBucket stores the terminal output of jobs. Each job has a
+// nested bucket keyed by the job id. The events of a job are stored with
+// their offset as the key.
+var jobOutputBucket = []byte("job-output")
+
+// jobOutputMaxEvents is the maximum number of terminal events stored for
+// a single job. Once a job has more events, the oldest are removed.
+const jobOutputMaxEvents = 10000
+
+func init() {
+	dbBuckets = append(dbBuckets, jobOutputBucket)
+}
+
+// jobOutputFlushInterval is how long terminal events are buffered before
+// they are written to the database.
+var jobOutputFlushInterval = time.Second
+
+// jobOutputFlushEvents is the number of buffered terminal events at which
+// the events are written to the database without waiting.
+const jobOutputFlushEvents = 1000
+
+// jobOutputPending are the buffered terminal events of a job.
+type jobOutputPending struct {
+	// seq is the offset of the last event assigned for the job.
+	seq    uint64
+	events []*vagrant_server.GetJobStreamResponse_Terminal_Event
+}
+
+// JobOutputAppend buffers the terminal events for the job. Each event is
+// assigned the next offset for the job. The buffered events of all jobs
+// are written to the database together after jobOutputFlushInterval or
+// once a job completes. The output of a job is removed when the job is
+// pruned.
+func (s *State) JobOutputAppend(jobId string, events []*vagrant_server.GetJobStreamResponse_Terminal_Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	p, ok := s.outputPending[jobId]
+	if !ok {
+		// Offsets continue after the stored events of the job.
+		p = &jobOutputPending{}
+		err := s.db.View(func(dbTxn *bolt.Tx) error {
+			if b := dbTxn.Bucket(jobOutputBucket).Bucket([]byte(jobId)); b != nil {
+				p.seq = b.Sequence()
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if s.outputPending == nil {
+			s.outputPending = make(map[string]*jobOutputPending)
+		}
+		s.outputPending[jobId] = p
+	}
+
+	for _, ev := range events {
+		p.seq++
+		ev.Offset = p.seq
+	}
+	p.events = append(p.events, events...)
+	s.outputCount += len(events)
+
+	// The events are kept if writing fails, so this doesn't fail the
+	// append once offsets are assigned.
+	if s.outputCount >= jobOutputFlushEvents {
+		if err := s.jobOutputFlush(); err != nil {
+			s.log.Warn("error storing job output", "error", err)
+		}
+
+		return nil
+	}
+	if s.outputTimer == nil {
+		s.outputTimer = time.AfterFunc(jobOutputFlushInterval, s.jobOutputFlushTimer)
+	}
+
+	return nil
+}
+
+// JobOutputFlush writes the buffered terminal events of all jobs to the
+// database. If writing fails, the events are kept and written later.
+func (s *State) JobOutputFlush() error {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	return s.jobOutputFlush()
+}
+
+// jobOutputFlushTimer flushes the buffered terminal events when the flush
+// timer fires.
+func (s *State) jobOutputFlushTimer() {
+	if err := s.JobOutputFlush(); err != nil {
+		s.log.Warn("error storing job output", "error", err)
+	}
+}
+
+// jobOutputFlush writes the buffered terminal events of all jobs in a
+// single transaction. This must be called with outputMu held.
+func (s *State) jobOutputFlush() error {
+	if s.outputTimer != nil {
+		s.outputTimer.Stop()
+		s.outputTimer = nil
+	}
+	if len(s.outputPending) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		for jobId, p := range s.outputPending {
+			b, err := dbTxn.Bucket(jobOutputBucket).CreateBucketIfNotExists([]byte(jobId))
+			if err != nil {
+				return err
+			}
+
+			for _, ev := range p.events {
+				if err := dbPut(b, jobOutputKey(ev.Offset), ev); err != nil {
+					return err
+				}
+			}
+			if err := b.SetSequence(p.seq); err != nil {
+				return err
+			}
+
+			if err := jobOutputTrim(b, jobOutputMaxEvents); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		// Keep the events and try again later
+		s.outputTimer = time.AfterFunc(jobOutputFlushInterval, s.jobOutputFlushTimer)
+		return err
+	}
+
+	s.outputPending = nil
+	s.outputCount = 0
+	return nil
+}
+
+// JobOutputList returns the stored terminal events for the job with an
+// offset greater than the given offset. Use an offset of zero to get all
+// stored events. This includes the events which are still buffered.
+func (s *State) JobOutputList(jobId string, offset uint64) ([]*vagrant_server.GetJobStreamResponse_Terminal_Event, error) {
+	// Hold the lock so events aren't flushed in between reading the
+	// database and the buffer.
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	var result []*vagrant_server.GetJobStreamResponse_Terminal_Event
+	err := s.db.View(func(dbTxn *bolt.Tx) error {
+		b := dbTxn.Bucket(jobOutputBucket).Bucket([]byte(jobId))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, _ := c.Seek(jobOutputKey(offset + 1)); k != nil; k, _ = c.Next() {
+			var ev vagrant_server.GetJobStreamResponse_Terminal_Event
+			if err := dbGet(b, k, &ev); err != nil {
+				return err
+			}
+
+			result = append(result, &ev)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p, ok := s.outputPending[jobId]; ok {
+		for _, ev := range p.events {
+			if ev.Offset > offset {
+				result = append(result, ev)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// jobOutputDiscard removes the buffered terminal events of the job. This
+// must be called with outputMu held.
+func (s *State) jobOutputDiscard(jobId string) {
+	if p, ok := s.outputPending[jobId]; ok {
+		s.outputCount -= len(p.events)
+		delete(s.outputPending, jobId)
+	}
+}
+
+// jobOutputDelete removes the stored output of the job.
+func jobOutputDelete(dbTxn *bolt.Tx, jobId []byte) error {
+	b := dbTxn.Bucket(jobOutputBucket)
+	if b.Bucket(jobId) == nil {
+		return nil
+	}
+
+	return b.DeleteBucket(jobId)
+}
+
+// jobOutputTrim removes the oldest events of the job output bucket so
+// that at most max events are stored.
+func jobOutputTrim(b *bolt.Bucket, max int) error {
+	c := b.Cursor()
+	k, _ := c.First()
+	if k == nil {
+		return nil
+	}
+
+	// Offsets are sequential so the number of events is the difference
+	// between the first and last offset.
+	first := binary.BigEndian.Uint64(k)
+	count := b.Sequence() - first + 1
+	for offset := first; count > uint64(max); offset++ {
+		if err := b.Delete(jobOutputKey(offset)); err != nil {
+			return err
+		}
+		count--
+	}
+
+	return nil
+}
+
+// jobOutputKey returns the key of the event with the given offset. The
+// key is big endian so the events are ordered by offset.
+func jobOutputKey(offset uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, offset)
+	return key
+}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestJobOutput(t *testing.T) {
+	// testEvents returns line events for the messages
+	testEvents := func(msgs ...string) []*vagrant_server.GetJobStreamResponse_Terminal_Event {
+		var result []*vagrant_server.GetJobStreamResponse_Terminal_Event
+		for _, msg := range msgs {
+			result = append(result, &vagrant_server.GetJobStreamResponse_Terminal_Event{
+				Event: &vagrant_server.GetJobStreamResponse_Terminal_Event_Line_{
+					Line: &vagrant_server.GetJobStreamResponse_Terminal_Event_Line{
+						Msg: msg,
+					},
+				},
+			})
+		}
+		return result
+	}
+
+	// testLines returns the messages of the line events
+	testLines := func(events []*vagrant_server.GetJobStreamResponse_Terminal_Event) []string {
+		var result []string
+		for _, ev := range events {
+			result = append(result, ev.GetLine().Msg)
+		}
+		return result
+	}
+
+	t.Run("append and list", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		events := testEvents("one", "two")
+		require.NoError(s.JobOutputAppend("A", events))
+		require.Equal(uint64(1), events[0].Offset)
+		require.Equal(uint64(2), events[1].Offset)
+		require.NoError(s.JobOutputAppend("A", testEvents("three")))
+
+		result, err := s.JobOutputList("A", 0)
+		require.NoError(err)
+		require.Equal([]string{"one", "two", "three"}, testLines(result))
+
+		// After an offset
+		result, err = s.JobOutputList("A", 2)
+		require.NoError(err)
+		require.Equal([]string{"three"}, testLines(result))
+		require.Equal(uint64(3), result[0].Offset)
+
+		// Unknown job
+		result, err = s.JobOutputList("B", 0)
+		require.NoError(err)
+		require.Empty(result)
+	})
+
+	t.Run("buffered until flushed", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// testStored returns the number of stored events of the job
+		testStored := func(jobId string) int {
+			var count int
+			require.NoError(s.db.View(func(dbTxn *bolt.Tx) error {
+				if b := dbTxn.Bucket(jobOutputBucket).Bucket([]byte(jobId)); b != nil {
+					count = b.Stats().KeyN
+				}
+				return nil
+			}))
+			return count
+		}
+
+		require.NoError(s.JobOutputAppend("A", testEvents("one", "two")))
+		require.NoError(s.JobOutputAppend("B", testEvents("three")))
+		require.Equal(0, testStored("A"))
+
+		// Buffered events are listed
+		result, err := s.JobOutputList("A", 1)
+		require.NoError(err)
+		require.Equal([]string{"two"}, testLines(result))
+
+		// All jobs are written together
+		require.NoError(s.JobOutputFlush())
+		require.Equal(2, testStored("A"))
+		require.Equal(1, testStored("B"))
+
+		// Offsets continue after the stored events
+		events := testEvents("four")
+		require.NoError(s.JobOutputAppend("A", events))
+		require.Equal(uint64(3), events[0].Offset)
+		result, err = s.JobOutputList("A", 0)
+		require.NoError(err)
+		require.Equal([]string{"one", "two", "four"}, testLines(result))
+	})
+
+	t.Run("flushed after an interval", func(t *testing.T) {
+		require := require.New(t)
+
+		defer func(v time.Duration) { jobOutputFlushInterval = v }(jobOutputFlushInterval)
+		jobOutputFlushInterval = 10 * time.Millisecond
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobOutputAppend("A", testEvents("one")))
+		require.Eventually(func() bool {
+			s.outputMu.Lock()
+			defer s.outputMu.Unlock()
+			return len(s.outputPending) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		var seq uint64
+		require.NoError(s.db.View(func(dbTxn *bolt.Tx) error {
+			seq = dbTxn.Bucket(jobOutputBucket).Bucket([]byte("A")).Sequence()
+			return nil
+		}))
+		require.Equal(uint64(1), seq)
+	})
+
+	t.Run("flushed when a job completes", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		testJobRun(t, s, "A")
+		require.NoError(s.JobOutputAppend("A", testEvents("one")))
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		s.outputMu.Lock()
+		defer s.outputMu.Unlock()
+		require.Empty(s.outputPending)
+	})
+
+	t.Run("trim oldest events", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobOutputAppend("A", testEvents("one", "two", "three", "four")))
+		require.NoError(s.JobOutputFlush())
+		require.NoError(s.db.Update(func(dbTxn *bolt.Tx) error {
+			return jobOutputTrim(dbTxn.Bucket(jobOutputBucket).Bucket([]byte("A")), 2)
+		}))
+
+		result, err := s.JobOutputList("A", 0)
+		require.NoError(err)
+		require.Equal([]string{"three", "four"}, testLines(result))
+
+		// Offsets continue after trimming
+		require.NoError(s.JobOutputAppend("A", testEvents("five")))
+		result, err = s.JobOutputList("A", 4)
+		require.NoError(err)
+		require.Equal([]string{"five"}, testLines(result))
+		require.Equal(uint64(5), result[0].Offset)
+	})
+
+	t.Run("pruned with the job", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "B",
+		})))
+		require.NoError(s.JobOutputAppend("A", testEvents("one")))
+		require.NoError(s.JobOutputAppend("B", testEvents("two")))
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		deleted, err := s.JobsDBPruneOld(1)
+		require.NoError(err)
+		require.Equal(1, deleted)
+
+		result, err := s.JobOutputList("A", 0)
+		require.NoError(err)
+		require.Empty(result)
+
+		result, err = s.JobOutputList("B", 0)
+		require.NoError(err)
+		require.Equal([]string{"two"}, testLines(result))
+	})
+}
@@ -0,0 +1,260 @@
+package state
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// This file has the methods related to retrying failed jobs. A job with a
+// retry policy that fails with a retryable error is moved back to the
+// queued state rather than completing, and is assigned again once the
+// backoff for the attempt has passed.
+
+const (
+	// jobRetryDefaultBackoff is the delay before the first retry if the
+	// retry policy doesn't set one.
+	jobRetryDefaultBackoff = 1 * time.Second
+
+	// jobRetryDefaultMaxBackoff is the maximum delay between retries if
+	// the retry policy doesn't set one.
+	jobRetryDefaultMaxBackoff = 5 * time.Minute
+)
+
+// jobRetryDefaultErrors are the classes of errors retried if the retry
+// policy doesn't specify what is retryable.
+var jobRetryDefaultErrors = []vagrant_server.Job_RetryPolicy_ErrorClass{
+	vagrant_server.Job_RetryPolicy_RUNNER_LOST,
+	vagrant_server.Job_RetryPolicy_TRANSIENT,
+}
+
+// JobRetry requeues the job if its retry policy allows retrying the
+// error. The class is the class of the error if it is known, such as
+// RUNNER_LOST for a job whose runner stopped heartbeating. This returns
+// true if the job was requeued.
+func (s *State) JobRetry(
+	id string,
+	cerr error,
+	class vagrant_server.Job_RetryPolicy_ErrorClass,
+) (bool, error) {
+	txn := s.inmem.Txn(true)
+	defer txn.Abort()
+
+	// Get the job
+	raw, err := txn.First(jobTableName, jobIdIndexName, id)
+	if err != nil {
+		return false, err
+	}
+	if raw == nil {
+		return false, status.Errorf(codes.NotFound, "job not found: %s", id)
+	}
+	job := raw.(*jobIndex).Copy()
+
+	// Only jobs which haven't completed can be retried
+	if jobIsCompleted(job.State) {
+		return false, nil
+	}
+
+	retried, err := s.jobRetry(txn, job, cerr, class)
+	if err != nil || !retried {
+		return false, err
+	}
+
+	txn.Commit()
+	return true, nil
+}
+
+// jobRetry requeues the job if its retry policy allows retrying the error.
+// The failed attempt is recorded on the job and the job is not assigned
+// again until the backoff has passed.
+func (s *State) jobRetry(
+	txn *memdb.Txn,
+	job *jobIndex,
+	cerr error,
+	class vagrant_server.Job_RetryPolicy_ErrorClass,
+) (bool, error) {
+	st, _ := status.FromError(cerr)
+
+	var retried bool
+	jobpb, err := s.jobReadAndUpdate(job.Id, func(jobpb *vagrant_server.Job) error {
+		if !jobRetryAllowed(jobpb, st, class) {
+			return nil
+		}
+		retried = true
+
+		now := time.Now()
+		jobpb.Attempts = append(jobpb.Attempts, &vagrant_server.Job_Attempt{
+			Number:       jobpb.Attempt,
+			AssignTime:   jobpb.AssignTime,
+			CompleteTime: timestamppb.New(now),
+			Error:        st.Proto(),
+		})
+
+		// Each attempt gets the same amount of time to be assigned as
+		// the first attempt did. The window is measured from when the
+		// previous attempt became assignable.
+		retryTime := now.Add(jobRetryBackoff(jobpb.RetryPolicy, jobpb.Attempt))
+		if jobpb.ExpireTime != nil {
+			start := jobpb.QueueTime
+			if jobpb.RetryTime != nil {
+				start = jobpb.RetryTime
+			}
+			expiresIn := jobpb.ExpireTime.AsTime().Sub(start.AsTime())
+			jobpb.ExpireTime = timestamppb.New(retryTime.Add(expiresIn))
+		}
+
+		jobpb.Attempt++
+		jobpb.State = vagrant_server.Job_QUEUED
+		jobpb.RetryTime = timestamppb.New(retryTime)
+		jobpb.AssignedRunner = nil
+		jobpb.AssignTime = nil
+		jobpb.AckTime = nil
+		jobpb.Deadline = nil
+		return nil
+	})
+	if err != nil || !retried {
+		return false, err
+	}
+
+	s.log.Info("retrying job", "job", job.Id, "attempt", jobpb.Attempt,
+		"retry_time", jobpb.RetryTime.AsTime(), "error", cerr)
+
+	// Unblock the jobs waiting on this job
+	if err := s.jobAssignedSet(txn, job, false); err != nil {
+		return false, err
+	}
+
+	// Requeue the job. When the backoff has passed, the job is updated
+	// so that anything waiting for a job to assign is woken up.
+	job.End()
+	job.State = vagrant_server.Job_QUEUED
+	job.AssignedRunnerId = ""
+	job.Deadline = time.Time{}
+	job.RetryTime = jobpb.RetryTime.AsTime()
+	job.StateTimer = time.AfterFunc(time.Until(job.RetryTime), func() {
+		s.jobRetryReady(job.Id)
+	})
+	if jobpb.ExpireTime != nil {
+		job.ExpireTime = jobpb.ExpireTime.AsTime()
+		time.AfterFunc(time.Until(job.ExpireTime), func() { s.JobExpire(job.Id) })
+	}
+
+	if err := txn.Insert(jobTableName, job); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// jobRetryReady updates the index of a retried job once its backoff has
+// passed. This wakes up anything waiting for a job to assign.
+func (s *State) jobRetryReady(id string) {
+	txn := s.inmem.Txn(true)
+	defer txn.Abort()
+
+	raw, err := txn.First(jobTableName, jobIdIndexName, id)
+	if err != nil || raw == nil {
+		return
+	}
+
+	job := raw.(*jobIndex).Copy()
+	if job.State != vagrant_server.Job_QUEUED {
+		return
+	}
+
+	job.StateTimer = nil
+	if err := txn.Insert(jobTableName, job); err != nil {
+		s.log.Warn("error updating retried job", "job", id, "error", err)
+		return
+	}
+
+	txn.Commit()
+}
+
+// jobHeartbeatFailed is called when a running job stopped receiving
+// heartbeats. The job is retried if its retry policy allows, otherwise
+// it is force cancelled.
+func (s *State) jobHeartbeatFailed(id string) {
+	retried, err := s.JobRetry(id,
+		status.Error(codes.Unavailable, "runner heartbeat timed out"),
+		vagrant_server.Job_RetryPolicy_RUNNER_LOST)
+	if err != nil {
+		s.log.Error("error retrying job after heartbeat timeout", "error", err, "job", id)
+	}
+	if retried {
+		return
+	}
+
+	s.log.Info("canceling job due to heartbeat timeout", "job", id)
+	if err := s.JobCancel(id, true); err != nil {
+		s.log.Error("error canceling job due to heartbeat failure", "error", err, "job", id)
+	}
+}
+
+// jobRetryAllowed returns true if the job has attempts left and the
+// error is retryable according to the retry policy of the job. Jobs which
+// were cancelled, including jobs which exceeded their timeout, are never
+// retried.
+func jobRetryAllowed(
+	jobpb *vagrant_server.Job,
+	st *status.Status,
+	class vagrant_server.Job_RetryPolicy_ErrorClass,
+) bool {
+	policy := jobpb.RetryPolicy
+	if policy == nil || jobpb.Attempt >= policy.MaxAttempts || jobpb.CancelTime != nil {
+		return false
+	}
+
+	for _, c := range policy.RetryableCodes {
+		if codes.Code(c) == st.Code() {
+			return true
+		}
+	}
+
+	classes := policy.RetryableErrors
+	if len(classes) == 0 && len(policy.RetryableCodes) == 0 {
+		classes = jobRetryDefaultErrors
+	}
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+
+		// Errors from the job itself are transient based on the code
+		if c == vagrant_server.Job_RetryPolicy_TRANSIENT &&
+			class == vagrant_server.Job_RetryPolicy_UNSPECIFIED {
+			switch st.Code() {
+			case codes.Unavailable, codes.Aborted, codes.ResourceExhausted, codes.DeadlineExceeded:
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// jobRetryBackoff returns the delay before retrying the given attempt.
+// The delay doubles for each attempt up to the maximum backoff.
+func jobRetryBackoff(policy *vagrant_server.Job_RetryPolicy, attempt uint32) time.Duration {
+	backoff, max := jobRetryDefaultBackoff, jobRetryDefaultMaxBackoff
+	if d, err := time.ParseDuration(policy.GetBackoff()); err == nil {
+		backoff = d
+	}
+	if d, err := time.ParseDuration(policy.GetMaxBackoff()); err == nil {
+		max = d
+	}
+
+	for i := uint32(1); i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+
+	return backoff
+}
@@ -0,0 +1,219 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestJobRetry(t *testing.T) {
+	t.Run("retries a retryable error", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+			RetryPolicy: &vagrant_server.Job_RetryPolicy{
+				MaxAttempts:    3,
+				Backoff:        "10ms",
+				RetryableCodes: []uint32{uint32(codes.Internal)},
+			},
+		})))
+
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Internal, "boom")))
+
+		// The job should be queued again with the failed attempt
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_QUEUED, job.State)
+		require.Equal(uint32(2), job.Attempt)
+		require.Len(job.Attempts, 1)
+		require.Equal(uint32(1), job.Attempts[0].Number)
+		require.Equal(int32(codes.Internal), job.Attempts[0].Error.Code)
+		require.NotNil(job.RetryTime)
+		require.Nil(job.AssignedRunner)
+
+		// It is assigned again after the backoff
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		job, err = s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_SUCCESS, job.State)
+		require.Equal(uint32(2), job.Attempt)
+	})
+
+	t.Run("waits for the backoff", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+			RetryPolicy: &vagrant_server.Job_RetryPolicy{
+				MaxAttempts: 2,
+				Backoff:     "1h",
+			},
+		})))
+
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Unavailable, "boom")))
+
+		// The job shouldn't be assigned before the backoff
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
+		require.Error(err)
+		require.Equal(ctx.Err(), err)
+	})
+
+	t.Run("respects max attempts", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+			RetryPolicy: &vagrant_server.Job_RetryPolicy{
+				MaxAttempts: 2,
+				Backoff:     "1ms",
+			},
+		})))
+
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Unavailable, "boom")))
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Unavailable, "boom")))
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_ERROR, job.State)
+		require.Equal(uint32(2), job.Attempt)
+		require.Len(job.Attempts, 1)
+	})
+
+	t.Run("doesn't retry other errors", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+			RetryPolicy: &vagrant_server.Job_RetryPolicy{
+				MaxAttempts: 3,
+			},
+		})))
+
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.InvalidArgument, "bad")))
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_ERROR, job.State)
+		require.Empty(job.Attempts)
+	})
+
+	t.Run("keeps the expiry window of each attempt", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:         "A",
+			ExpireTime: timestamppb.New(time.Now().Add(1 * time.Hour)),
+			RetryPolicy: &vagrant_server.Job_RetryPolicy{
+				MaxAttempts:    3,
+				Backoff:        "10ms",
+				RetryableCodes: []uint32{uint32(codes.Internal)},
+			},
+		})))
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		expiresIn := job.ExpireTime.AsTime().Sub(job.QueueTime.AsTime())
+
+		for i := 0; i < 2; i++ {
+			testJobRun(t, s, "A")
+			require.NoError(s.JobComplete("A", nil, status.Error(codes.Internal, "boom")))
+		}
+
+		job, err = s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(uint32(3), job.Attempt)
+		require.Equal(job.RetryTime.AsTime().Add(expiresIn), job.ExpireTime.AsTime())
+	})
+
+	t.Run("retries when the runner is lost", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Set a short timeout
+		old := jobHeartbeatTimeout
+		defer func() { jobHeartbeatTimeout = old }()
+		jobHeartbeatTimeout = 5 * time.Millisecond
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+			RetryPolicy: &vagrant_server.Job_RetryPolicy{
+				MaxAttempts: 2,
+				Backoff:     "1h",
+			},
+		})))
+
+		testJobRun(t, s, "A")
+
+		require.Eventually(func() bool {
+			job, err := s.JobById("A", nil)
+			require.NoError(err)
+			return job.State == vagrant_server.Job_QUEUED
+		}, 1*time.Second, 10*time.Millisecond)
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.Len(job.Attempts, 1)
+		require.Equal(int32(codes.Unavailable), job.Attempts[0].Error.Code)
+	})
+}
+
+func TestJobRetryBackoff(t *testing.T) {
+	policy := &vagrant_server.Job_RetryPolicy{
+		Backoff:    "1s",
+		MaxBackoff: "5s",
+	}
+
+	require.Equal(t, 1*time.Second, jobRetryBackoff(policy, 1))
+	require.Equal(t, 2*time.Second, jobRetryBackoff(policy, 2))
+	require.Equal(t, 4*time.Second, jobRetryBackoff(policy, 3))
+	require.Equal(t, 5*time.Second, jobRetryBackoff(policy, 4))
+	require.Equal(t, jobRetryDefaultBackoff, jobRetryBackoff(nil, 1))
+}
+
+// testJobRun assigns and acks the next job and verifies it has the given ID.
+func testJobRun(t *testing.T, s *State, id string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
+	require.NoError(t, err)
+	require.Equal(t, id, job.Id)
+	_, err = s.JobAck(job.Id, true)
+	require.NoError(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -111,155 +112,270 @@ func TestJobAssign(t *testing.T) {
 		}
 	})
 
-	// t.Run("blocking on matching basis and project", func(t *testing.T) {
-	// 	require := require.New(t)
-
-	// 	s := TestState(t)
-	// 	defer s.Close()
-
-	// 	// Create two builds for the same project
-	// 	require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
-	// 		Id: "A",
-	// 		Project: &vagrant_plugin_sdk.Ref_Project{
-	// 			ResourceId: "project1",
-	// 		},
-	// 		Operation: &vagrant_server.Job_Run{
-	// 			Run: &vagrant_server.Job_RunOp{},
-	// 		},
-	// 	})))
-	// 	require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
-	// 		Id: "B",
-	// 		Project: &vagrant_plugin_sdk.Ref_Project{
-	// 			ResourceId: "project1",
-	// 		},
-	// 		Operation: &vagrant_server.Job_Run{
-	// 			Run: &vagrant_server.Job_RunOp{},
-	// 		},
-	// 	})))
-
-	// 	// Assign it, we should get this build
-	// 	{
-	// 		job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
-	// 		require.NoError(err)
-	// 		require.NotNil(job)
-	// 		require.Equal("A", job.Id)
-	// 	}
-
-	// 	// Get the next value in a goroutine
-	// 	{
-	// 		ctx, cancel := context.WithCancel(context.Background())
-	// 		defer cancel()
-	// 		var job *Job
-	// 		var jerr error
-	// 		doneCh := make(chan struct{})
-	// 		go func() {
-	// 			defer close(doneCh)
-	// 			job, jerr = s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
-	// 		}()
-
-	// 		// We should be blocking
-	// 		select {
-	// 		case <-doneCh:
-	// 			t.Fatal("should wait")
-
-	// 		case <-time.After(500 * time.Millisecond):
-	// 		}
-
-	// 		// Insert another job for a different workspace
-	// 		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
-	// 			Id: "C",
-	// 			Project: &vagrant_plugin_sdk.Ref_Project{
-	// 				ResourceId: "project2",
-	// 			},
-	// 			Operation: &vagrant_server.Job_Run{
-	// 				Run: &vagrant_server.Job_RunOp{},
-	// 			},
-	// 		})))
-
-	// 		// We should get a result
-	// 		select {
-	// 		case <-doneCh:
-
-	// 		case <-time.After(500 * time.Millisecond):
-	// 			t.Fatal("should have a result")
-	// 		}
-
-	// 		require.NoError(jerr)
-	// 		require.NotNil(job)
-	// 		require.Equal("C", job.Id)
-	// 	}
-	// })
-
-	// t.Run("blocking on matching basis and project (sequential)", func(t *testing.T) {
-	// 	require := require.New(t)
-
-	// 	s := TestState(t)
-	// 	defer s.Close()
-
-	// 	// Create two builds for the same app/workspace
-	// 	require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
-	// 		Id: "A",
-	// 		Project: &vagrant_plugin_sdk.Ref_Project{
-	// 			ResourceId: "project1",
-	// 		},
-	// 		Operation: &vagrant_server.Job_Run{
-	// 			Run: &vagrant_server.Job_RunOp{},
-	// 		},
-	// 	})))
-	// 	require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
-	// 		Id: "B",
-	// 		Project: &vagrant_plugin_sdk.Ref_Project{
-	// 			ResourceId: "project1",
-	// 		},
-	// 		Operation: &vagrant_server.Job_Run{
-	// 			Run: &vagrant_server.Job_RunOp{},
-	// 		},
-	// 	})))
-
-	// 	// Assign it, we should get this build
-	// 	job1, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
-	// 	require.NoError(err)
-	// 	require.NotNil(job1)
-	// 	require.Equal("A", job1.Id)
-
-	// 	// Get the next value in a goroutine
-	// 	{
-	// 		ctx, cancel := context.WithCancel(context.Background())
-	// 		defer cancel()
-	// 		var job *Job
-	// 		var jerr error
-	// 		doneCh := make(chan struct{})
-	// 		go func() {
-	// 			defer close(doneCh)
-	// 			job, jerr = s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
-	// 		}()
-
-	// 		// We should be blocking
-	// 		select {
-	// 		case <-doneCh:
-	// 			t.Fatal("should wait")
-
-	// 		case <-time.After(500 * time.Millisecond):
-	// 		}
-
-	// 		// Complete the job
-	// 		_, err = s.JobAck(job1.Id, true)
-	// 		require.NoError(err)
-	// 		require.NoError(s.JobComplete(job1.Id, nil, nil))
-
-	// 		// We should get a result
-	// 		select {
-	// 		case <-doneCh:
-
-	// 		case <-time.After(500 * time.Millisecond):
-	// 			t.Fatal("should have a result")
-	// 		}
-
-	// 		require.NoError(jerr)
-	// 		require.NotNil(job)
-	// 		require.Equal("B", job.Id)
-	// 	}
-	// })
+	t.Run("blocking on matching target", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Create two ups for the same target
+		for _, id := range []string{"A", "B"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id:        id,
+				Target:    testJobTarget("project1", "target1"),
+				Operation: testJobRunOp("up"),
+			})))
+		}
+
+		// Assign it, we should get this build
+		{
+			job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+			require.NoError(err)
+			require.NotNil(job)
+			require.Equal("A", job.Id)
+		}
+
+		// The second job is blocked
+		job, err := s.JobById("B", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_QUEUED, job.State)
+		require.True(job.Blocked)
+		require.Contains(job.BlockedMessage, "A (up)")
+
+		// Get the next value in a goroutine
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var job *Job
+			var jerr error
+			doneCh := make(chan struct{})
+			go func() {
+				defer close(doneCh)
+				job, jerr = s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
+			}()
+
+			// We should be blocking
+			select {
+			case <-doneCh:
+				t.Fatal("should wait")
+
+			case <-time.After(500 * time.Millisecond):
+			}
+
+			// Insert another job for a different target
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id:        "C",
+				Target:    testJobTarget("project1", "target2"),
+				Operation: testJobRunOp("up"),
+			})))
+
+			// We should get a result
+			select {
+			case <-doneCh:
+
+			case <-time.After(500 * time.Millisecond):
+				t.Fatal("should have a result")
+			}
+
+			require.NoError(jerr)
+			require.NotNil(job)
+			require.Equal("C", job.Id)
+		}
+	})
+
+	t.Run("blocking on matching target (sequential)", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Create two conflicting jobs for the same target
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "A",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("up"),
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("destroy"),
+		})))
+
+		// Assign it, we should get this build
+		job1, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+		require.NoError(err)
+		require.NotNil(job1)
+		require.Equal("A", job1.Id)
+
+		// Get the next value in a goroutine
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var job *Job
+			var jerr error
+			doneCh := make(chan struct{})
+			go func() {
+				defer close(doneCh)
+				job, jerr = s.JobAssignForRunner(ctx, &vagrant_server.Runner{Id: "R_A"})
+			}()
+
+			// We should be blocking
+			select {
+			case <-doneCh:
+				t.Fatal("should wait")
+
+			case <-time.After(500 * time.Millisecond):
+			}
+
+			// Complete the job
+			_, err = s.JobAck(job1.Id, true)
+			require.NoError(err)
+			require.NoError(s.JobComplete(job1.Id, nil, nil))
+
+			// We should get a result
+			select {
+			case <-doneCh:
+
+			case <-time.After(500 * time.Millisecond):
+				t.Fatal("should have a result")
+			}
+
+			require.NoError(jerr)
+			require.NotNil(job)
+			require.Equal("B", job.Id)
+		}
+	})
+
+	t.Run("blocking on matching project", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// A project wide job blocks jobs for targets in the project
+		require.NoError(s.JobCreate(testProjectJob(t, "A", "project1")))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("up"),
+		})))
+
+		job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+		require.NoError(err)
+		require.Equal("A", job.Id)
+
+		job, err = s.JobById("B", nil)
+		require.NoError(err)
+		require.True(job.Blocked)
+		require.Contains(job.BlockedMessage, "project")
+
+		// Target jobs block project wide jobs
+		_, err = s.JobAck("A", true)
+		require.NoError(err)
+		require.NoError(s.JobComplete("A", nil, nil))
+		job, err = s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+		require.NoError(err)
+		require.Equal("B", job.Id)
+
+		require.NoError(s.JobCreate(testProjectJob(t, "C", "project1")))
+		job, err = s.JobById("C", nil)
+		require.NoError(err)
+		require.True(job.Blocked)
+
+		// Other projects are not blocked
+		require.NoError(s.JobCreate(testProjectJob(t, "D", "project10")))
+		job, err = s.JobById("D", nil)
+		require.NoError(err)
+		require.False(job.Blocked)
+	})
+
+	t.Run("non-exclusive commands are not blocked", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "A",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("up"),
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("status"),
+		})))
+
+		for _, id := range []string{"A", "B"} {
+			job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+			require.NoError(err)
+			require.Equal(id, job.Id)
+		}
+	})
+
+	t.Run("exclusive commands are configurable", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		s.SetExclusiveCommands([]string{"Status"})
+
+		for _, id := range []string{"A", "B"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id:        id,
+				Target:    testJobTarget("project1", "target1"),
+				Operation: testJobRunOp("up"),
+			})))
+		}
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "C",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("status"),
+		})))
+
+		for _, id := range []string{"A", "B", "C"} {
+			job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+			require.NoError(err)
+			require.Equal(id, job.Id)
+		}
+
+		// A second status is blocked
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "D",
+			Target:    testJobTarget("project1", "target1"),
+			Operation: testJobRunOp("status"),
+		})))
+		job, err := s.JobById("D", nil)
+		require.NoError(err)
+		require.True(job.Blocked)
+	})
+
+	t.Run("assigned jobs block after restart", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		for _, id := range []string{"A", "B"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id:        id,
+				Target:    testJobTarget("project1", "target1"),
+				Operation: testJobRunOp("up"),
+			})))
+		}
+		job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+		require.NoError(err)
+		require.Equal("A", job.Id)
+		_, err = s.JobAck("A", true)
+		require.NoError(err)
+
+		s = TestStateReinit(t, s)
+		defer s.Close()
+
+		job, err = s.JobById("B", nil)
+		require.NoError(err)
+		require.True(job.Blocked)
+	})
 
 	t.Run("basic assignment with two", func(t *testing.T) {
 		require := require.New(t)
@@ -427,6 +543,47 @@ func TestJobAssign(t *testing.T) {
 		require.NotNil(job)
 		require.Equal("B", job.Id)
 	})
+
+	t.Run("any with selectors requires matching labels", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Create a job that requires the libvirt provider
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+			TargetRunner: &vagrant_server.Ref_Runner{
+				Target: &vagrant_server.Ref_Runner_Any{
+					Any: &vagrant_server.Ref_RunnerAny{
+						Selectors: []*vagrant_server.Ref_RunnerLabelSelector{
+							{Key: "provider", Values: []string{"libvirt"}},
+						},
+					},
+				},
+			},
+		})))
+
+		// A runner without the label should block
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		job, err := s.JobAssignForRunner(ctx, &vagrant_server.Runner{
+			Id:     "R_A",
+			Labels: map[string]string{"provider": "virtualbox"},
+		})
+		require.Error(err)
+		require.Nil(job)
+		require.Equal(ctx.Err(), err)
+
+		// A matching runner should get the job
+		job, err = s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{
+			Id:     "R_B",
+			Labels: map[string]string{"provider": "libvirt"},
+		})
+		require.NoError(err)
+		require.NotNil(job)
+		require.Equal("A", job.Id)
+	})
 }
 
 func TestJobAck(t *testing.T) {
@@ -662,6 +819,76 @@ func TestJobIsAssignable(t *testing.T) {
 		require.False(result)
 	})
 
+	t.Run("any target, selectors", func(t *testing.T) {
+		require := require.New(t)
+		ctx := context.Background()
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Register a runner
+		require.NoError(s.RunnerCreate(serverptypes.TestRunner(t, &vagrant_server.Runner{
+			Labels: map[string]string{"provider": "virtualbox", "arch": "amd64"},
+		})))
+
+		jobWithSelectors := func(selectors ...*vagrant_server.Ref_RunnerLabelSelector) *vagrant_server.Job {
+			return serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id: "A",
+				TargetRunner: &vagrant_server.Ref_Runner{
+					Target: &vagrant_server.Ref_Runner_Any{
+						Any: &vagrant_server.Ref_RunnerAny{Selectors: selectors},
+					},
+				},
+			})
+		}
+
+		// Matching selectors should be assignable
+		result, err := s.JobIsAssignable(ctx, jobWithSelectors(
+			&vagrant_server.Ref_RunnerLabelSelector{
+				Key:    "provider",
+				Values: []string{"virtualbox"},
+			},
+			&vagrant_server.Ref_RunnerLabelSelector{
+				Key:      "arch",
+				Operator: vagrant_server.Ref_RunnerLabelSelector_IN,
+				Values:   []string{"amd64", "arm64"},
+			},
+			&vagrant_server.Ref_RunnerLabelSelector{
+				Key:      "gpu",
+				Operator: vagrant_server.Ref_RunnerLabelSelector_NOT_EQUALS,
+				Values:   []string{"none"},
+			},
+		))
+		require.NoError(err)
+		require.True(result)
+
+		// Any selector not matching should not be assignable
+		result, err = s.JobIsAssignable(ctx, jobWithSelectors(
+			&vagrant_server.Ref_RunnerLabelSelector{
+				Key:    "provider",
+				Values: []string{"virtualbox"},
+			},
+			&vagrant_server.Ref_RunnerLabelSelector{
+				Key:      "arch",
+				Operator: vagrant_server.Ref_RunnerLabelSelector_NOT_IN,
+				Values:   []string{"amd64"},
+			},
+		))
+		require.NoError(err)
+		require.False(result)
+
+		// A missing label should not match
+		result, err = s.JobIsAssignable(ctx, jobWithSelectors(
+			&vagrant_server.Ref_RunnerLabelSelector{
+				Key:      "gpu",
+				Operator: vagrant_server.Ref_RunnerLabelSelector_IN,
+				Values:   []string{"nvidia"},
+			},
+		))
+		require.NoError(err)
+		require.False(result)
+	})
+
 	t.Run("ID target, no match", func(t *testing.T) {
 		require := require.New(t)
 		ctx := context.Background()
@@ -945,7 +1172,7 @@ func TestJobHeartbeat(t *testing.T) {
 			cancel()
 			<-doneCh
 		}()
-		go func() {
+		go func(id string) {
 			defer close(doneCh)
 
 			tick := time.NewTicker(20 * time.Millisecond)
@@ -954,13 +1181,13 @@ func TestJobHeartbeat(t *testing.T) {
 			for {
 				select {
 				case <-tick.C:
-					s.JobHeartbeat(job.Id)
+					s.JobHeartbeat(id)
 
 				case <-ctx.Done():
 					return
 				}
 			}
-		}()
+		}(job.Id)
 
 		// Sleep for a bit
 		time.Sleep(1 * time.Second)
@@ -1008,7 +1235,7 @@ func TestJobHeartbeat(t *testing.T) {
 			cancel()
 			<-doneCh
 		}()
-		go func() {
+		go func(id string) {
 			defer close(doneCh)
 
 			tick := time.NewTicker(20 * time.Millisecond)
@@ -1017,13 +1244,13 @@ func TestJobHeartbeat(t *testing.T) {
 			for {
 				select {
 				case <-tick.C:
-					s.JobHeartbeat(job.Id)
+					s.JobHeartbeat(id)
 
 				case <-ctx.Done():
 					return
 				}
 			}
-		}()
+		}(job.Id)
 
 		// Sleep for a bit
 		time.Sleep(1 * time.Second)
@@ -1079,7 +1306,7 @@ func TestJobHeartbeat(t *testing.T) {
 			cancel()
 			<-doneCh
 		}()
-		go func(s *State) {
+		go func(s *State, id string) {
 			defer close(doneCh)
 
 			tick := time.NewTicker(20 * time.Millisecond)
@@ -1088,13 +1315,13 @@ func TestJobHeartbeat(t *testing.T) {
 			for {
 				select {
 				case <-tick.C:
-					s.JobHeartbeat(job.Id)
+					s.JobHeartbeat(id)
 
 				case <-ctx.Done():
 					return
 				}
 			}
-		}(s)
+		}(s, job.Id)
 
 		// Reinit the state as if we crashed
 		s = TestStateReinit(t, s)
@@ -1114,3 +1341,65 @@ func TestJobHeartbeat(t *testing.T) {
 		}, 2*time.Second, 10*time.Millisecond)
 	})
 }
+
+// testJobTarget returns a reference to a target within the project.
+func testJobTarget(project, target string) *vagrant_plugin_sdk.Ref_Target {
+	return &vagrant_plugin_sdk.Ref_Target{
+		ResourceId: target,
+		Project: &vagrant_plugin_sdk.Ref_Project{
+			ResourceId: project,
+			Basis:      &vagrant_plugin_sdk.Ref_Basis{ResourceId: "basis1"},
+		},
+	}
+}
+
+// testProjectJob returns an init job which is not scoped to a target.
+func testProjectJob(t *testing.T, id, project string) *vagrant_server.Job {
+	job := serverptypes.TestJobNew(t, &vagrant_server.Job{
+		Id:        id,
+		Operation: &vagrant_server.Job_Init{},
+	})
+	job.Project = testJobTarget(project, "").Project
+	job.Target = nil
+	return job
+}
+
+func TestJobsDBPruneOld(t *testing.T) {
+	t.Run("keeps jobs that have not completed", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// A is the oldest job and is still running
+		for _, id := range []string{"A", "B", "C"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id: id,
+			})))
+		}
+		testJobRun(t, s, "A")
+		testJobRun(t, s, "B")
+		require.NoError(s.JobComplete("B", nil, nil))
+
+		deleted, err := s.JobsDBPruneOld(1)
+		require.NoError(err)
+		require.Equal(1, deleted)
+
+		require.NoError(s.db.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(jobBucket)
+			require.NotNil(bucket.Get([]byte("A")))
+			require.Nil(bucket.Get([]byte("B")))
+			require.NotNil(bucket.Get([]byte("C")))
+			return nil
+		}))
+	})
+}
+
+// testJobRunOp returns a run operation for the command.
+func testJobRunOp(cmd string) *vagrant_server.Job_Run {
+	return &vagrant_server.Job_Run{
+		Run: &vagrant_server.Job_RunOp{
+			Task: &vagrant_server.Task{Task: cmd, CommandName: cmd},
+		},
+	}
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// This file has the methods related to the execution timeout of jobs. When
+// a running job exceeds its timeout, the job is cancelled. If the job is
+// still running after a grace period, it is force cancelled.
+
+// jobTimeoutGrace is how long a job which exceeded its timeout has to
+// complete after it is cancelled before it is force cancelled. This is a
+// var so that tests can change it.
+var jobTimeoutGrace = 1 * time.Minute
+
+// SetJobTimeouts sets the default timeouts of jobs by the name of the
+// command they run. Jobs which set their own timeout are not affected.
+func (s *State) SetJobTimeouts(timeouts map[string]time.Duration) {
+	result := map[string]time.Duration{}
+	for cmd, d := range timeouts {
+		result[strings.ToLower(cmd)] = d
+	}
+
+	s.jobTimeoutsMu.Lock()
+	defer s.jobTimeoutsMu.Unlock()
+	s.jobTimeouts = result
+}
+
+// jobTimeout returns the timeout of the job. If the job doesn't set a
+// timeout, the default for the command is used. Zero is returned if the
+// job has no timeout.
+func (s *State) jobTimeout(jobpb *vagrant_server.Job, cmd string) time.Duration {
+	if jobpb.Timeout != "" {
+		d, err := time.ParseDuration(jobpb.Timeout)
+		if err == nil {
+			return d
+		}
+
+		s.log.Warn("ignoring invalid job timeout", "job", jobpb.Id, "timeout", jobpb.Timeout)
+	}
+
+	s.jobTimeoutsMu.RLock()
+	defer s.jobTimeoutsMu.RUnlock()
+	return s.jobTimeouts[cmd]
+}
+
+// jobDeadlineSet starts the timer which cancels the job once its
+// deadline passes.
+func (s *State) jobDeadlineSet(job *jobIndex) {
+	if job.DeadlineTimer != nil {
+		job.DeadlineTimer.Stop()
+		job.DeadlineTimer = nil
+	}
+	if job.Deadline.IsZero() {
+		return
+	}
+
+	dur := time.Until(job.Deadline)
+	if dur < 0 {
+		dur = 1
+	}
+
+	id, deadline := job.Id, job.Deadline
+	job.DeadlineTimer = time.AfterFunc(dur, func() {
+		s.jobDeadlineExceeded(id, deadline)
+	})
+}
+
+// jobDeadlineExceeded cancels a job which exceeded its timeout. If the job
+// hasn't completed after the grace period, it is force cancelled.
+func (s *State) jobDeadlineExceeded(id string, deadline time.Time) {
+	s.log.Info("canceling job due to timeout", "job", id)
+	if err := s.JobCancel(id, false); err != nil {
+		s.log.Error("error canceling job due to timeout", "error", err, "job", id)
+	}
+
+	time.AfterFunc(jobTimeoutGrace, func() {
+		s.jobDeadlineForce(id, deadline)
+	})
+}
+
+// jobDeadlineForce force cancels a job which exceeded its timeout and
+// didn't complete within the grace period. If the job was retried since,
+// the deadline no longer matches and nothing is done.
+func (s *State) jobDeadlineForce(id string, deadline time.Time) {
+	txn := s.inmem.Txn(false)
+	raw, err := txn.First(jobTableName, jobIdIndexName, id)
+	txn.Abort()
+	if err != nil || raw == nil || !raw.(*jobIndex).Deadline.Equal(deadline) {
+		return
+	}
+
+	s.log.Info("force canceling job due to timeout", "job", id)
+	if err := s.JobCancel(id, true); err != nil {
+		s.log.Error("error force canceling job due to timeout", "error", err, "job", id)
+	}
+}
+
+// jobTimeoutError returns the error of a job which was cancelled because
+// it exceeded its timeout. If the job didn't exceed its timeout, nil is
+// returned.
+func jobTimeoutError(jobpb *vagrant_server.Job) error {
+	if jobpb.Deadline == nil || jobpb.CancelTime == nil {
+		return nil
+	}
+
+	deadline := jobpb.Deadline.AsTime()
+	if jobpb.CancelTime.AsTime().Before(deadline) {
+		return nil
+	}
+
+	timeout := deadline.Sub(jobpb.AckTime.AsTime()).Round(time.Second)
+	return status.Errorf(codes.DeadlineExceeded,
+		"job exceeded its timeout of %s", timeout)
+}
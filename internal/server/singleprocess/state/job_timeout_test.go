@@ -0,0 +1,158 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestJobTimeout(t *testing.T) {
+	t.Run("cancels and then force cancels", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Set a short grace period
+		old := jobTimeoutGrace
+		defer func() { jobTimeoutGrace = old }()
+		jobTimeoutGrace = 100 * time.Millisecond
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:      "A",
+			Timeout: "10ms",
+		})))
+		testJobRun(t, s, "A")
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.NotNil(job.Deadline)
+
+		// The job is cancelled first
+		require.Eventually(func() bool {
+			job, err := s.JobById("A", nil)
+			require.NoError(err)
+			return job.CancelTime != nil
+		}, 1*time.Second, 5*time.Millisecond)
+
+		job, err = s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_RUNNING, job.State)
+
+		// Then it is force cancelled
+		require.Eventually(func() bool {
+			job, err := s.JobById("A", nil)
+			require.NoError(err)
+			return job.State == vagrant_server.Job_ERROR
+		}, 1*time.Second, 10*time.Millisecond)
+
+		job, err = s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(int32(codes.DeadlineExceeded), job.Error.Code)
+	})
+
+	t.Run("reports the timeout when the runner cancels", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:      "A",
+			Timeout: "10ms",
+		})))
+		testJobRun(t, s, "A")
+
+		require.Eventually(func() bool {
+			job, err := s.JobById("A", nil)
+			require.NoError(err)
+			return job.CancelTime != nil
+		}, 1*time.Second, 5*time.Millisecond)
+
+		// The runner completes the job because it was cancelled
+		require.NoError(s.JobComplete("A", nil, status.Error(codes.Canceled, "canceled")))
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_ERROR, job.State)
+		require.Equal(int32(codes.DeadlineExceeded), job.Error.Code)
+	})
+
+	t.Run("uses the default for the command", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		s.SetJobTimeouts(map[string]time.Duration{"UP": time.Hour})
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "A",
+			Operation: testJobRunOp("up"),
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			Operation: testJobRunOp("status"),
+		})))
+
+		testJobRun(t, s, "A")
+		testJobRun(t, s, "B")
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.NotNil(job.Deadline)
+		require.WithinDuration(job.AckTime.AsTime().Add(time.Hour), job.Deadline.AsTime(), time.Second)
+
+		job, err = s.JobById("B", nil)
+		require.NoError(err)
+		require.Nil(job.Deadline)
+	})
+
+	t.Run("doesn't cancel jobs which complete in time", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:      "A",
+			Timeout: "50ms",
+		})))
+		testJobRun(t, s, "A")
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		time.Sleep(100 * time.Millisecond)
+
+		job, err := s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_SUCCESS, job.State)
+		require.Nil(job.CancelTime)
+	})
+
+	t.Run("survives a restart", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:      "A",
+			Timeout: "100ms",
+		})))
+		testJobRun(t, s, "A")
+
+		s2 := TestStateReinit(t, s)
+		defer s2.Close()
+
+		require.Eventually(func() bool {
+			job, err := s2.JobById("A", nil)
+			require.NoError(err)
+			return job.CancelTime != nil
+		}, 1*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,300 @@
+package state
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/logbuffer"
+)
+
+const (
+	logStreamTableName        = "log-streams"
+	logStreamIdIndexName      = "id"
+	logStreamBasisIndexName   = "basis"
+	logStreamProjectIndexName = "project"
+	logStreamTargetIndexName  = "target"
+
+	// logStreamIdleTimeout is how long a log stream is kept after the
+	// last writer disconnects before it is removed by Prune.
+	logStreamIdleTimeout = 1 * time.Hour
+)
+
+func init() {
+	schemas = append(schemas, logStreamSchema)
+}
+
+func logStreamSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: logStreamTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			logStreamIdIndexName: {
+				Name:         logStreamIdIndexName,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Id",
+					Lowercase: true,
+				},
+			},
+
+			logStreamBasisIndexName: {
+				Name:         logStreamBasisIndexName,
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Basis",
+					Lowercase: true,
+				},
+			},
+
+			logStreamProjectIndexName: {
+				Name:         logStreamProjectIndexName,
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Project",
+					Lowercase: true,
+				},
+			},
+
+			logStreamTargetIndexName: {
+				Name:         logStreamTargetIndexName,
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Target",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
+// LogStream is a buffer of log lines sent for a basis, project, or target
+// by a single source such as a runner or plugin. Log streams are only
+// kept in memory and are lost when the server restarts.
+type LogStream struct {
+	// Id is the unique id of the log stream. This is derived from the
+	// scope and the instance id.
+	Id string
+
+	// InstanceId identifies the source of the lines.
+	InstanceId string
+
+	// The resource ids of the basis, project, and target the lines were
+	// sent for. Project and Target are empty for a basis scoped log stream.
+	Basis   string
+	Project string
+	Target  string
+
+	// Scope is the reference to the basis, project, or target the lines
+	// were sent for. This is one of *vagrant_plugin_sdk.Ref_Basis,
+	// *vagrant_plugin_sdk.Ref_Project, or *vagrant_plugin_sdk.Ref_Target.
+	Scope interface{}
+
+	// LogBuffer is the buffer of log entries. Entries are of type
+	// *vagrant_server.LogBatch_Entry.
+	LogBuffer *logbuffer.Buffer
+
+	// Writers is the number of open writers for this log stream.
+	Writers int
+
+	// IdleTime is the time the last writer was closed.
+	IdleTime time.Time
+}
+
+// LogStreamOpen returns the log stream for the scope and instance id,
+// creating it if it doesn't exist. The scope may reference the basis,
+// project, or target by name. Callers must call LogStreamClose when they
+// are done writing to the log stream.
+func (s *State) LogStreamOpen(scope interface{}, instanceId string) (*LogStream, error) {
+	if instanceId == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"log stream instance id is required")
+	}
+
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	ref, err := s.logStreamRef(memTxn, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := newLogStream(ref, instanceId)
+	raw, err := memTxn.First(logStreamTableName, logStreamIdIndexName, rec.Id)
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		// Copy the record so we don't modify the record in memdb
+		existing := *raw.(*LogStream)
+		rec = &existing
+	}
+	rec.Writers++
+
+	if err := memTxn.Insert(logStreamTableName, rec); err != nil {
+		return nil, err
+	}
+	memTxn.Commit()
+
+	return rec, nil
+}
+
+// LogStreamClose closes a writer of the log stream opened with
+// LogStreamOpen. The buffered lines remain available to readers until
+// the log stream is pruned.
+func (s *State) LogStreamClose(id string) error {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	raw, err := memTxn.First(logStreamTableName, logStreamIdIndexName, id)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return status.Errorf(codes.NotFound, "log stream not found: %s", id)
+	}
+
+	rec := *raw.(*LogStream)
+	if rec.Writers > 0 {
+		rec.Writers--
+	}
+	if rec.Writers == 0 {
+		rec.IdleTime = time.Now()
+	}
+
+	if err := memTxn.Insert(logStreamTableName, &rec); err != nil {
+		return err
+	}
+	memTxn.Commit()
+
+	return nil
+}
+
+// LogStreamsByScope returns all the log streams for the basis, project,
+// or target. The log streams for a basis or project include the log
+// streams of all projects and targets within it.
+//
+// If ws is set, a watch is added for any new log streams within the scope.
+func (s *State) LogStreamsByScope(scope interface{}, ws memdb.WatchSet) ([]*LogStream, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	ref, err := s.logStreamRef(memTxn, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var index, id string
+	switch r := ref.(type) {
+	case *vagrant_plugin_sdk.Ref_Basis:
+		index, id = logStreamBasisIndexName, r.ResourceId
+	case *vagrant_plugin_sdk.Ref_Project:
+		index, id = logStreamProjectIndexName, r.ResourceId
+	case *vagrant_plugin_sdk.Ref_Target:
+		index, id = logStreamTargetIndexName, r.ResourceId
+	}
+
+	iter, err := memTxn.Get(logStreamTableName, index, id)
+	if err != nil {
+		return nil, err
+	}
+	if ws != nil {
+		ws.Add(iter.WatchCh())
+	}
+
+	var result []*LogStream
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		result = append(result, raw.(*LogStream))
+	}
+
+	return result, nil
+}
+
+// logStreamsPruneIdle removes the log streams which have not had a writer
+// for longer than logStreamIdleTimeout. Any readers of the removed log
+// streams are closed.
+func (s *State) logStreamsPruneIdle(memTxn *memdb.Txn) (int, error) {
+	iter, err := memTxn.Get(logStreamTableName, logStreamIdIndexName+"_prefix", "")
+	if err != nil {
+		return 0, err
+	}
+
+	var idle []*LogStream
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		rec := raw.(*LogStream)
+		if rec.Writers == 0 && time.Since(rec.IdleTime) > logStreamIdleTimeout {
+			idle = append(idle, rec)
+		}
+	}
+
+	for _, rec := range idle {
+		if err := memTxn.Delete(logStreamTableName, rec); err != nil {
+			return 0, err
+		}
+		rec.LogBuffer.Close()
+	}
+
+	return len(idle), nil
+}
+
+// logStreamRef resolves the scope of a log stream.
+func (s *State) logStreamRef(memTxn *memdb.Txn, scope interface{}) (interface{}, error) {
+	var valid bool
+	switch r := scope.(type) {
+	case *vagrant_plugin_sdk.Ref_Basis:
+		valid = r != nil
+	case *vagrant_plugin_sdk.Ref_Project:
+		valid = r != nil
+	case *vagrant_plugin_sdk.Ref_Target:
+		valid = r != nil
+	}
+	if !valid {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"log stream scope must be a basis, project, or target")
+	}
+
+	var ref interface{}
+	err := s.db.View(func(dbTxn *bolt.Tx) error {
+		var err error
+		ref, err = s.operationRef(dbTxn, memTxn, scope)
+		return err
+	})
+
+	return ref, err
+}
+
+// newLogStream creates a new log stream for the resolved scope.
+func newLogStream(ref interface{}, instanceId string) *LogStream {
+	rec := &LogStream{
+		InstanceId: instanceId,
+		Scope:      ref,
+		LogBuffer:  logbuffer.New(),
+	}
+
+	var kind, id string
+	switch r := ref.(type) {
+	case *vagrant_plugin_sdk.Ref_Basis:
+		rec.Basis = r.ResourceId
+		kind, id = "basis", r.ResourceId
+	case *vagrant_plugin_sdk.Ref_Project:
+		rec.Basis = r.Basis.ResourceId
+		rec.Project = r.ResourceId
+		kind, id = "project", r.ResourceId
+	case *vagrant_plugin_sdk.Ref_Target:
+		rec.Basis = r.Project.Basis.ResourceId
+		rec.Project = r.Project.ResourceId
+		rec.Target = r.ResourceId
+		kind, id = "target", r.ResourceId
+	}
+	rec.Id = strings.Join([]string{kind, id, instanceId}, "/")
+
+	return rec
+}
@@ -0,0 +1,122 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestLogStream(t *testing.T) {
+	// Creates a target within the test project
+	testTarget := func(t *testing.T, s *State) *vagrant_plugin_sdk.Ref_Target {
+		projectRef := testProject(t, s)
+		require.NoError(t, s.TargetPut(serverptypes.TestTarget(t, &vagrant_server.Target{
+			ResourceId: "test-target",
+			Project:    projectRef,
+			Name:       "default",
+		})))
+		return &vagrant_plugin_sdk.Ref_Target{
+			ResourceId: "test-target",
+			Project:    projectRef,
+		}
+	}
+
+	t.Run("Open requires a scope and instance", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s)
+
+		_, err := s.LogStreamOpen(nil, "runner")
+		require.Equal(codes.InvalidArgument, status.Code(err))
+
+		_, err = s.LogStreamOpen(target, "")
+		require.Equal(codes.InvalidArgument, status.Code(err))
+
+		_, err = s.LogStreamOpen(&vagrant_plugin_sdk.Ref_Target{ResourceId: "nope"}, "runner")
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Open and list by scope", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s)
+
+		rec, err := s.LogStreamOpen(target, "runner")
+		require.NoError(err)
+		require.Equal(1, rec.Writers)
+		_, err = s.LogStreamOpen(target.Project, "plugin")
+		require.NoError(err)
+
+		// Opening again uses the same buffer
+		again, err := s.LogStreamOpen(target, "runner")
+		require.NoError(err)
+		require.Equal(rec.Id, again.Id)
+		require.Equal(rec.LogBuffer, again.LogBuffer)
+		require.Equal(2, again.Writers)
+
+		result, err := s.LogStreamsByScope(target, nil)
+		require.NoError(err)
+		require.Len(result, 1)
+
+		result, err = s.LogStreamsByScope(target.Project, nil)
+		require.NoError(err)
+		require.Len(result, 2)
+
+		// Look up the basis by name
+		ws := memdb.NewWatchSet()
+		result, err = s.LogStreamsByScope(&vagrant_plugin_sdk.Ref_Basis{Name: "test-basis"}, ws)
+		require.NoError(err)
+		require.Len(result, 2)
+
+		// New log streams trigger the watch
+		_, err = s.LogStreamOpen(target.Project.Basis, "runner")
+		require.NoError(err)
+		require.False(ws.Watch(time.After(50 * time.Millisecond)))
+	})
+
+	t.Run("Prune removes idle log streams", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s)
+
+		rec, err := s.LogStreamOpen(target, "runner")
+		require.NoError(err)
+		_, err = s.LogStreamOpen(target, "plugin")
+		require.NoError(err)
+		require.NoError(s.LogStreamClose(rec.Id))
+
+		// Nothing is idle long enough yet
+		require.NoError(s.Prune())
+		result, err := s.LogStreamsByScope(target, nil)
+		require.NoError(err)
+		require.Len(result, 2)
+
+		// Move the idle time back
+		memTxn := s.inmem.Txn(true)
+		idle := *rec
+		idle.Writers = 0
+		idle.IdleTime = time.Now().Add(-2 * logStreamIdleTimeout)
+		require.NoError(memTxn.Insert(logStreamTableName, &idle))
+		memTxn.Commit()
+
+		require.NoError(s.Prune())
+		result, err = s.LogStreamsByScope(target, nil)
+		require.NoError(err)
+		require.Len(result, 1)
+		require.Equal("plugin", result[0].InstanceId)
+	})
+}
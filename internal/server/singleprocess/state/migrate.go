@@ -0,0 +1,248 @@
+package state
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// dbMigration is a single change to the format of the persisted data.
+// Migrations are run in order of their version inside a single bolt
+// transaction. All buckets registered in dbBuckets exist when the
+// migration is run.
+type dbMigration struct {
+	// Version is the data version after the migration has been applied.
+	// This must be exactly one more than the version of the previous
+	// migration. The first migration is version 1.
+	Version int64
+
+	// Description is a short description of the migration used for logging.
+	Description string
+
+	// Migrate applies the migration. Any error will abort the transaction
+	// and no migrations will be persisted.
+	Migrate func(log hclog.Logger, tx *bolt.Tx) error
+}
+
+// dbMigrations is the ordered list of registered migrations. The last
+// migration determines the current data version. New migrations must
+// be appended to the end of this list and must never be modified once
+// they are released.
+var dbMigrations = []*dbMigration{
+	{
+		// The data written before migrations were introduced. There is
+		// nothing to change since all buckets are created before the
+		// migrations are run.
+		Version:     1,
+		Description: "initial schema",
+		Migrate:     func(hclog.Logger, *bolt.Tx) error { return nil },
+	},
+	{
+		Version:     2,
+		Description: "number the attempts of existing jobs",
+		Migrate:     migrateJobAttempts,
+	},
+}
+
+// migrateJobAttempts sets the attempt of jobs that were persisted before
+// retries were supported. These jobs are on their first attempt.
+func migrateJobAttempts(log hclog.Logger, tx *bolt.Tx) error {
+	b := tx.Bucket(jobBucket)
+	return b.ForEach(func(k, v []byte) error {
+		var job vagrant_server.Job
+		if err := dbGet(b, k, &job); err != nil {
+			return err
+		}
+		if job.Attempt != 0 {
+			return nil
+		}
+
+		log.Trace("setting job attempt", "job", job.Id)
+		job.Attempt = 1
+		return dbPut(b, k, &job)
+	})
+}
+
+// MigrateOptions are the options for Migrate.
+type MigrateOptions struct {
+	// DryRun will run all pending migrations but roll back the
+	// transaction instead of committing it. This can be used to verify
+	// that the data can be migrated. No snapshot is created.
+	DryRun bool
+
+	// SnapshotDir is the directory the pre-migration snapshot is written
+	// to. This defaults to the directory of the database.
+	SnapshotDir string
+}
+
+// MigrateResult is the result of Migrate.
+type MigrateResult struct {
+	// From is the data version before migrating. This is zero for
+	// a new database.
+	From int64
+
+	// To is the data version after migrating.
+	To int64
+
+	// Applied is the description of each migration that was applied.
+	// For a dry run these are the migrations that would be applied.
+	Applied []string
+
+	// Snapshot is the path of the snapshot that was created before
+	// migrating. This is empty if no migrations were applied or if
+	// this was a dry run.
+	Snapshot string
+}
+
+// Migrate upgrades the data in db to the current data version. Before
+// any migrations are applied a snapshot of the database is written so the
+// previous data can be restored with RestoreSnapshot if necessary. All
+// migrations are applied in a single transaction so a failure leaves the
+// data unchanged.
+//
+// If the data was written by a newer server a FailedPrecondition error is
+// returned since the data cannot be safely read.
+func Migrate(log hclog.Logger, db *bolt.DB, opts *MigrateOptions) (*MigrateResult, error) {
+	if opts == nil {
+		opts = &MigrateOptions{}
+	}
+	if err := validateMigrations(dbMigrations); err != nil {
+		return nil, err
+	}
+
+	result := &MigrateResult{To: dbVersion()}
+	if err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		result.From, err = dbReadVersion(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if result.From > result.To {
+		return nil, dbVersionError(result.From)
+	}
+
+	// Take a snapshot of existing data before changing it. This must be
+	// done outside of the write transaction below.
+	if result.From != 0 && result.From < result.To && !opts.DryRun {
+		path, err := migrateSnapshot(log, db, opts.SnapshotDir, result.From)
+		if err != nil {
+			return nil, err
+		}
+		result.Snapshot = path
+	}
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := migrateTx(log, tx, result); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	return result, tx.Commit()
+}
+
+// migrateTx creates all buckets and applies the pending migrations
+// within the transaction.
+func migrateTx(log hclog.Logger, tx *bolt.Tx, result *MigrateResult) error {
+	for _, b := range dbBuckets {
+		if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+			return err
+		}
+	}
+
+	// A new database has no data to migrate
+	if result.From == 0 {
+		return dbWriteVersion(tx, result.To)
+	}
+
+	for _, m := range dbMigrations {
+		if m.Version <= result.From {
+			continue
+		}
+
+		log.Info("applying database migration",
+			"version", m.Version, "description", m.Description)
+		if err := m.Migrate(log.Named("migrate"), tx); err != nil {
+			return status.Errorf(codes.Internal,
+				"database migration to version %d (%s) failed: %s",
+				m.Version, m.Description, err)
+		}
+		result.Applied = append(result.Applied, m.Description)
+	}
+
+	return dbWriteVersion(tx, result.To)
+}
+
+// migrateSnapshot writes a snapshot of the database to dir and returns
+// the path of the snapshot.
+func migrateSnapshot(log hclog.Logger, db *bolt.DB, dir string, from int64) (string, error) {
+	if dir == "" {
+		dir = filepath.Dir(db.Path())
+	}
+	path := filepath.Join(dir, fmt.Sprintf(
+		"vagrant-premigrate-v%d-%d.snap", from, time.Now().Unix()))
+
+	log.Info("creating snapshot before database migration", "path", path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	bw := bufio.NewWriter(f)
+	err = (&State{db: db, log: log}).CreateSnapshot(bw, true)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// validateMigrations verifies the migrations are ordered and do not
+// skip any versions.
+func validateMigrations(ms []*dbMigration) error {
+	if len(ms) == 0 {
+		return status.Errorf(codes.Internal, "no database migrations registered")
+	}
+
+	expected := int64(1)
+	for _, m := range ms {
+		if m.Version != expected {
+			return status.Errorf(codes.Internal,
+				"invalid database migration %q: expected version %d, got %d",
+				m.Description, expected, m.Version)
+		}
+		if m.Migrate == nil {
+			return status.Errorf(codes.Internal,
+				"invalid database migration %q: no migrate function", m.Description)
+		}
+		expected++
+	}
+
+	return nil
+}
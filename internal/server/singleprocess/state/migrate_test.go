@@ -0,0 +1,213 @@
+package state
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestMigrate(t *testing.T) {
+	// Sets the architecture of all boxes that do not have one
+	boxArchMigration := &dbMigration{
+		Version:     2,
+		Description: "set box architecture",
+		Migrate: func(log hclog.Logger, tx *bolt.Tx) error {
+			b := tx.Bucket(boxBucket)
+			return b.ForEach(func(k, v []byte) error {
+				var box vagrant_server.Box
+				if err := dbGet(b, k, &box); err != nil {
+					return err
+				}
+				if box.Architecture == "" {
+					box.Architecture = "amd64"
+				}
+				return dbPut(b, k, &box)
+			})
+		},
+	}
+
+	t.Run("upgrades fixture database", func(t *testing.T) {
+		require := require.New(t)
+		testMigrations(t, boxArchMigration)
+		db := testFixtureDB(t, "migrate-v1.db")
+		dir := filepath.Dir(db.Path())
+
+		s, err := New(hclog.L(), db)
+		require.NoError(err)
+
+		box, err := s.BoxGet(&vagrant_plugin_sdk.Ref_Box{
+			ResourceId: "hashicorp-bionic-1.0.0-virtualbox",
+		})
+		require.NoError(err)
+		require.Equal("amd64", box.Architecture)
+		require.Equal(int64(2), testReadVersion(t, db))
+
+		// A snapshot of the previous data was created
+		matches, err := filepath.Glob(filepath.Join(dir, "vagrant-premigrate-v1-*.snap"))
+		require.NoError(err)
+		require.Len(matches, 1)
+		r, closer, err := snapshotReader(matches[0], sha256.New())
+		require.NoError(err)
+		require.NoError(closer())
+		require.NotNil(r)
+
+		// Restarting does not migrate again
+		s, err = TestStateRestart(t, s)
+		require.NoError(err)
+		defer s.Close()
+		matches, err = filepath.Glob(filepath.Join(dir, "vagrant-premigrate-*.snap"))
+		require.NoError(err)
+		require.Len(matches, 1)
+	})
+
+	t.Run("dry run leaves data unchanged", func(t *testing.T) {
+		require := require.New(t)
+		testMigrations(t, boxArchMigration)
+		db := testFixtureDB(t, "migrate-v1.db")
+
+		result, err := Migrate(hclog.L(), db, &MigrateOptions{DryRun: true})
+		require.NoError(err)
+		require.Equal(int64(1), result.From)
+		require.Equal(int64(2), result.To)
+		require.Equal([]string{"set box architecture"}, result.Applied)
+		require.Empty(result.Snapshot)
+		require.Equal(int64(1), testReadVersion(t, db))
+
+		require.NoError(db.View(func(tx *bolt.Tx) error {
+			var box vagrant_server.Box
+			require.NoError(dbGet(tx.Bucket(boxBucket),
+				[]byte("hashicorp-bionic-1.0.0-virtualbox"), &box))
+			require.Empty(box.Architecture)
+			return nil
+		}))
+	})
+
+	t.Run("failed migration rolls back", func(t *testing.T) {
+		require := require.New(t)
+		testMigrations(t, boxArchMigration, &dbMigration{
+			Version:     3,
+			Description: "fail",
+			Migrate: func(log hclog.Logger, tx *bolt.Tx) error {
+				return errors.New("failed")
+			},
+		})
+		db := testFixtureDB(t, "migrate-v1.db")
+
+		_, err := New(hclog.L(), db)
+		require.Error(err)
+		require.Equal(codes.Internal, status.Code(err))
+		require.Equal(int64(1), testReadVersion(t, db))
+	})
+
+	t.Run("newer data version is rejected", func(t *testing.T) {
+		require := require.New(t)
+		testMigrations(t, boxArchMigration)
+		db := testFixtureDB(t, "migrate-v1.db")
+		require.NoError(db.Update(func(tx *bolt.Tx) error {
+			return dbWriteVersion(tx, 3)
+		}))
+
+		_, err := New(hclog.L(), db)
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("invalid migration order", func(t *testing.T) {
+		require := require.New(t)
+		testMigrations(t, &dbMigration{
+			Version:     3,
+			Description: "skips a version",
+			Migrate:     boxArchMigration.Migrate,
+		})
+
+		_, err := Migrate(hclog.L(), testDB(t), nil)
+		require.Error(err)
+	})
+
+	t.Run("new database is not migrated", func(t *testing.T) {
+		require := require.New(t)
+		testMigrations(t, boxArchMigration)
+		db := testDB(t)
+
+		result, err := Migrate(hclog.L(), db, nil)
+		require.NoError(err)
+		require.Equal(int64(0), result.From)
+		require.Empty(result.Applied)
+		require.Empty(result.Snapshot)
+		require.Equal(int64(2), testReadVersion(t, db))
+	})
+}
+
+func TestMigrate_jobAttempts(t *testing.T) {
+	require := require.New(t)
+	db := testFixtureDB(t, "migrate-v1.db")
+
+	// Jobs written by a version 1 server have no attempt
+	require.NoError(db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(jobBucket)
+		require.NoError(err)
+		job := serverptypes.TestJobNew(t, &vagrant_server.Job{Id: "A"})
+		job.State = vagrant_server.Job_SUCCESS
+		job.QueueTime = timestamppb.Now()
+		return dbPut(b, []byte(job.Id), job)
+	}))
+
+	result, err := Migrate(hclog.L(), db, &MigrateOptions{DryRun: true})
+	require.NoError(err)
+	require.Equal(int64(1), result.From)
+	require.Equal([]string{"number the attempts of existing jobs"}, result.Applied)
+
+	s, err := New(hclog.L(), db)
+	require.NoError(err)
+	defer s.Close()
+
+	job, err := s.JobById("A", nil)
+	require.NoError(err)
+	require.Equal(uint32(1), job.Attempt)
+	require.Equal(dbVersion(), testReadVersion(t, db))
+}
+
+// testMigrations replaces the registered migrations after the initial
+// schema for the test.
+func testMigrations(t *testing.T, ms ...*dbMigration) {
+	old := dbMigrations
+	dbMigrations = append([]*dbMigration{old[0]}, ms...)
+	t.Cleanup(func() { dbMigrations = old })
+}
+
+// testFixtureDB opens a copy of the fixture database in testdata.
+func testFixtureDB(t *testing.T, name string) *bolt.DB {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testReadVersion(t *testing.T, db *bolt.DB) int64 {
+	var vsn int64
+	require.NoError(t, db.View(func(tx *bolt.Tx) error {
+		var err error
+		vsn, err = dbReadVersion(tx)
+		return err
+	}))
+	return vsn
+}
@@ -21,7 +21,7 @@ func (p *pruneOp) Validate() error {
 		validation.Field(&p.lock, validation.Required),
 		validation.Field(&p.table, validation.Required),
 		validation.Field(&p.index, validation.Required),
-		validation.Field(&p.cur, validation.NilOrNotEmpty),
+		validation.Field(&p.cur, validation.NotNil),
 	)
 }
 
@@ -0,0 +1,302 @@
+package state
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-memdb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+var roleBucket = []byte("roles")
+
+func init() {
+	dbBuckets = append(dbBuckets, roleBucket)
+	dbIndexers = append(dbIndexers, (*State).roleIndexInit)
+	schemas = append(schemas, roleIndexSchema)
+}
+
+// The names of the built-in roles.
+const (
+	RoleReadOnly = "readonly"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// builtinRoles are always present and can't be changed or deleted.
+var builtinRoles = map[string]*vagrant_server.Role{
+	// readonly can read everything except secrets and the server's
+	// credentials.
+	RoleReadOnly: {
+		Name:    RoleReadOnly,
+		Builtin: true,
+		Permissions: []string{
+			"Get*",
+			"List*",
+			"Find*",
+			"Download*",
+			"ValidateJob",
+			"_ListJobs",
+			"!GetConfig",
+			"!ListTokens",
+			"!ListHMACKeys",
+		},
+	},
+
+	// operator can manage projects and run jobs, but can't change the
+	// server itself or manage its users and credentials. Endpoints are
+	// listed explicitly so that new endpoints aren't permitted by default.
+	RoleOperator: {
+		Name:    RoleOperator,
+		Builtin: true,
+		Permissions: []string{
+			"Get*",
+			"List*",
+			"Find*",
+			"Download*",
+			"ValidateJob",
+			"_ListJobs",
+			"!ListTokens",
+			"!ListHMACKeys",
+			"!ListUsers",
+
+			// Projects and their configuration
+			"UpsertBasis",
+			"UpsertProject",
+			"UpsertTarget",
+			"DeleteTarget",
+			"SetConfig",
+
+			// Boxes
+			"UpsertBox",
+			"DeleteBox",
+			"PublishCatalogBox",
+			"DeleteCatalogBox",
+
+			// Jobs and the runners executing them
+			"QueueJob",
+			"CancelJob",
+			"UploadArchive",
+			"UpsertTask",
+			"UpsertSchedule",
+			"DeleteSchedule",
+			"RunnerConfig",
+			"RunnerJobStream",
+			"DrainRunner",
+			"SendLogStream",
+
+			// Tokens for themselves and invites with their roles
+			"GenerateLoginToken",
+			"GenerateInviteToken",
+		},
+	},
+
+	// admin can do everything.
+	RoleAdmin: {
+		Name:        RoleAdmin,
+		Builtin:     true,
+		Permissions: []string{"*"},
+	},
+}
+
+// RolePut creates or updates a role. Built-in roles can't be changed.
+func (s *State) RolePut(role *vagrant_server.Role) error {
+	if role.Name == "" {
+		return status.Errorf(codes.InvalidArgument, "role name must be set")
+	}
+	if len(role.Permissions) == 0 {
+		return status.Errorf(codes.InvalidArgument, "role must have at least one permission")
+	}
+	if _, ok := builtinRoles[strings.ToLower(role.Name)]; ok {
+		return status.Errorf(codes.FailedPrecondition,
+			"built-in role %q can't be changed", role.Name)
+	}
+
+	// Only the built-in roles are marked as built-in
+	role = proto.Clone(role).(*vagrant_server.Role)
+	role.Builtin = false
+
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		return s.rolePut(dbTxn, memTxn, role)
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+// RoleGet gets a role by name, including the built-in roles.
+func (s *State) RoleGet(name string) (*vagrant_server.Role, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	result, err := s.roleGet(memTxn, name)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, status.Errorf(codes.NotFound, "role not found: %s", name)
+	}
+
+	return result, nil
+}
+
+// RoleList returns all the roles, including the built-in roles.
+func (s *State) RoleList() ([]*vagrant_server.Role, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	var result []*vagrant_server.Role
+	for _, role := range builtinRoles {
+		result = append(result, role)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	iter, err := memTxn.Get(roleIndexTableName, roleIndexIdIndexName+"_prefix", "")
+	if err != nil {
+		return nil, err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		result = append(result, raw.(*roleIndexRecord).Role)
+	}
+
+	return result, nil
+}
+
+// RoleDelete deletes a role. Built-in roles and roles which are bound to a
+// user can't be deleted.
+func (s *State) RoleDelete(name string) error {
+	if _, ok := builtinRoles[strings.ToLower(name)]; ok {
+		return status.Errorf(codes.FailedPrecondition,
+			"built-in role %q can't be deleted", name)
+	}
+
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		role, err := s.roleGet(memTxn, name)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return status.Errorf(codes.NotFound, "role not found: %s", name)
+		}
+
+		users, err := s.userList(memTxn)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			for _, binding := range user.Roles {
+				if strings.EqualFold(binding.Role, role.Name) {
+					return status.Errorf(codes.FailedPrecondition,
+						"role %q is bound to user %q", role.Name, user.Username)
+				}
+			}
+		}
+
+		id := strings.ToLower(role.Name)
+		if err := dbTxn.Bucket(roleBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		return memTxn.Delete(roleIndexTableName, &roleIndexRecord{Id: id})
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+func (s *State) rolePut(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	role *vagrant_server.Role,
+) error {
+	id := strings.ToLower(role.Name)
+	if err := dbPut(dbTxn.Bucket(roleBucket), []byte(id), role); err != nil {
+		return err
+	}
+
+	return s.roleIndexSet(memTxn, role)
+}
+
+func (s *State) roleGet(
+	memTxn *memdb.Txn,
+	name string,
+) (*vagrant_server.Role, error) {
+	if role, ok := builtinRoles[strings.ToLower(name)]; ok {
+		return role, nil
+	}
+
+	raw, err := memTxn.First(roleIndexTableName, roleIndexIdIndexName, strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	return raw.(*roleIndexRecord).Role, nil
+}
+
+// roleIndexSet writes an index record for a single role.
+func (s *State) roleIndexSet(txn *memdb.Txn, value *vagrant_server.Role) error {
+	return txn.Insert(roleIndexTableName, &roleIndexRecord{
+		Id:   strings.ToLower(value.Name),
+		Role: value,
+	})
+}
+
+// roleIndexInit initializes the role index from persisted data.
+func (s *State) roleIndexInit(dbTxn *bolt.Tx, memTxn *memdb.Txn) error {
+	bucket := dbTxn.Bucket(roleBucket)
+	return bucket.ForEach(func(k, v []byte) error {
+		var value vagrant_server.Role
+		if err := proto.Unmarshal(v, &value); err != nil {
+			return err
+		}
+
+		return s.roleIndexSet(memTxn, &value)
+	})
+}
+
+func roleIndexSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: roleIndexTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			roleIndexIdIndexName: {
+				Name:         roleIndexIdIndexName,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Id",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
+const (
+	roleIndexTableName   = "role-index"
+	roleIndexIdIndexName = "id"
+)
+
+type roleIndexRecord struct {
+	Id   string
+	Role *vagrant_server.Role
+}
@@ -0,0 +1,91 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestRole(t *testing.T) {
+	t.Run("built-in roles", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		roles, err := s.RoleList()
+		require.NoError(err)
+		require.Len(roles, 3)
+
+		role, err := s.RoleGet("Admin")
+		require.NoError(err)
+		require.True(role.Builtin)
+
+		err = s.RolePut(&vagrant_server.Role{Name: RoleAdmin, Permissions: []string{"Get*"}})
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+
+		err = s.RoleDelete(RoleReadOnly)
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("put, get and delete", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.RoleGet("deployer")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		require.Error(s.RolePut(&vagrant_server.Role{Name: "deployer"}))
+		require.NoError(s.RolePut(&vagrant_server.Role{
+			Name:        "deployer",
+			Permissions: []string{"QueueJob"},
+			Builtin:     true,
+		}))
+
+		role, err := s.RoleGet("deployer")
+		require.NoError(err)
+		require.Equal([]string{"QueueJob"}, role.Permissions)
+		require.False(role.Builtin)
+
+		roles, err := s.RoleList()
+		require.NoError(err)
+		require.Len(roles, 4)
+
+		require.NoError(s.RoleDelete("deployer"))
+		_, err = s.RoleGet("deployer")
+		require.Error(err)
+
+		err = s.RoleDelete("deployer")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("can't delete a role bound to a user", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.RolePut(&vagrant_server.Role{
+			Name:        "deployer",
+			Permissions: []string{"QueueJob"},
+		}))
+		require.NoError(s.UserPut(&vagrant_server.User{
+			Username: "alice",
+			Roles:    []*vagrant_server.RoleBinding{{Role: "deployer"}},
+		}))
+
+		err := s.RoleDelete("deployer")
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+}
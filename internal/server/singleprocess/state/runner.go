@@ -1,9 +1,13 @@
 package state
 
 import (
+	"time"
+
 	"github.com/hashicorp/go-memdb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 )
@@ -34,18 +38,50 @@ func runnerSchema() *memdb.TableSchema {
 	}
 }
 
+// DefaultRunnerGracePeriod is how long a runner which disconnected is
+// kept before it is removed and the jobs assigned to it are requeued.
+const DefaultRunnerGracePeriod = 1 * time.Minute
+
 type runnerRecord struct {
 	// The full Runner. All other fiels are derivatives of this.
 	Runner *vagrant_server.Runner
 
 	// Id of the runner
 	Id string
+
+	// DeleteTimer removes the runner once the grace period after the
+	// runner disconnected has passed. This is nil if the runner is online.
+	DeleteTimer *time.Timer
 }
 
+// RunnerCreate registers a runner. If the runner is already registered,
+// such as a runner reconnecting within the grace period, the runner is
+// marked online again and keeps its draining state.
 func (s *State) RunnerCreate(r *vagrant_server.Runner) error {
 	txn := s.inmem.Txn(true)
 	defer txn.Abort()
 
+	now := timestamppb.Now()
+	r.Online = true
+	r.Draining = false
+	r.CurrentJobId = ""
+	r.FirstSeen = now
+	r.LastHeartbeat = now
+
+	raw, err := txn.First(runnerTableName, runnerIdIndexName, r.Id)
+	if err != nil {
+		return status.Errorf(codes.Aborted, err.Error())
+	}
+	if raw != nil {
+		existing := raw.(*runnerRecord)
+		if existing.DeleteTimer != nil {
+			existing.DeleteTimer.Stop()
+		}
+
+		r.Draining = existing.Runner.Draining
+		r.FirstSeen = existing.Runner.FirstSeen
+	}
+
 	// Create our runner
 	if err := txn.Insert(runnerTableName, newRunnerRecord(r)); err != nil {
 		return status.Errorf(codes.Aborted, err.Error())
@@ -56,21 +92,78 @@ func (s *State) RunnerCreate(r *vagrant_server.Runner) error {
 	return nil
 }
 
+// RunnerDelete removes the runner. Any jobs assigned to the runner that
+// have not completed are requeued.
 func (s *State) RunnerDelete(id string) error {
 	txn := s.inmem.Txn(true)
 	defer txn.Abort()
-	if _, err := txn.DeleteAll(runnerTableName, runnerIdIndexName, id); err != nil {
-		return status.Errorf(codes.Aborted, err.Error())
+	if err := s.runnerDelete(txn, id); err != nil {
+		return err
 	}
 	txn.Commit()
 
 	return nil
 }
 
+// RunnerOffline marks the runner as disconnected. The runner is removed
+// with RunnerDelete once the grace period has passed unless it registers
+// again with RunnerCreate before then.
+func (s *State) RunnerOffline(id string) error {
+	txn := s.inmem.Txn(true)
+	defer txn.Abort()
+
+	raw, err := txn.First(runnerTableName, runnerIdIndexName, id)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+
+	grace := s.runnerGracePeriod()
+	if grace <= 0 {
+		if err := s.runnerDelete(txn, id); err != nil {
+			return err
+		}
+		txn.Commit()
+		return nil
+	}
+
+	rec := *raw.(*runnerRecord)
+	rec.Runner = proto.Clone(rec.Runner).(*vagrant_server.Runner)
+	rec.Runner.Online = false
+	rec.DeleteTimer = time.AfterFunc(grace, func() {
+		if err := s.runnerExpire(id); err != nil {
+			s.log.Error("error removing disconnected runner", "runner", id, "error", err)
+		}
+	})
+	if err := txn.Insert(runnerTableName, &rec); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// RunnerHeartbeat records that the runner is alive.
+func (s *State) RunnerHeartbeat(id string) error {
+	return s.runnerUpdate(id, func(r *vagrant_server.Runner) {
+		r.LastHeartbeat = timestamppb.Now()
+	})
+}
+
+// RunnerDrain marks the runner as draining. Draining runners are not
+// assigned new jobs but any job already assigned is allowed to complete.
+func (s *State) RunnerDrain(id string) error {
+	return s.runnerUpdate(id, func(r *vagrant_server.Runner) {
+		r.Draining = true
+	})
+}
+
 func (s *State) RunnerById(id string) (*vagrant_server.Runner, error) {
 	txn := s.inmem.Txn(false)
+	defer txn.Abort()
 	raw, err := txn.First(runnerTableName, runnerIdIndexName, id)
-	txn.Abort()
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +171,146 @@ func (s *State) RunnerById(id string) (*vagrant_server.Runner, error) {
 		return nil, status.Errorf(codes.NotFound, "runner ID not found")
 	}
 
-	return raw.(*runnerRecord).Runner, nil
+	return s.runnerWithJob(txn, raw.(*runnerRecord))
+}
+
+// RunnerList returns all the registered runners sorted by ID.
+func (s *State) RunnerList() ([]*vagrant_server.Runner, error) {
+	txn := s.inmem.Txn(false)
+	defer txn.Abort()
+
+	iter, err := txn.LowerBound(runnerTableName, runnerIdIndexName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*vagrant_server.Runner
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		r, err := s.runnerWithJob(txn, raw.(*runnerRecord))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+// SetRunnerGracePeriod sets how long a disconnected runner is kept before
+// it is removed. If the grace period is zero, runners are removed as soon
+// as they disconnect.
+func (s *State) SetRunnerGracePeriod(d time.Duration) {
+	s.runnerGraceMu.Lock()
+	defer s.runnerGraceMu.Unlock()
+	s.runnerGrace = d
+}
+
+func (s *State) runnerGracePeriod() time.Duration {
+	s.runnerGraceMu.RLock()
+	defer s.runnerGraceMu.RUnlock()
+	return s.runnerGrace
+}
+
+// runnerExpire removes the runner if it is still offline. This is called
+// once the grace period of a disconnected runner has passed.
+func (s *State) runnerExpire(id string) error {
+	txn := s.inmem.Txn(true)
+	defer txn.Abort()
+
+	raw, err := txn.First(runnerTableName, runnerIdIndexName, id)
+	if err != nil {
+		return err
+	}
+	if raw == nil || raw.(*runnerRecord).Runner.Online {
+		// The runner reconnected.
+		return nil
+	}
+
+	s.log.Info("removing runner after disconnect grace period", "runner", id)
+	if err := s.runnerDelete(txn, id); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+func (s *State) runnerDelete(txn *memdb.Txn, id string) error {
+	raw, err := txn.First(runnerTableName, runnerIdIndexName, id)
+	if err != nil {
+		return status.Errorf(codes.Aborted, err.Error())
+	}
+	if raw == nil {
+		return nil
+	}
+	if rec := raw.(*runnerRecord); rec.DeleteTimer != nil {
+		rec.DeleteTimer.Stop()
+	}
+
+	if _, err := txn.DeleteAll(runnerTableName, runnerIdIndexName, id); err != nil {
+		return status.Errorf(codes.Aborted, err.Error())
+	}
+
+	// Requeue the jobs the runner didn't complete
+	return s.jobsRequeueForRunner(txn, id)
+}
+
+// runnerUpdate modifies a copy of the registered runner.
+func (s *State) runnerUpdate(id string, f func(*vagrant_server.Runner)) error {
+	txn := s.inmem.Txn(true)
+	defer txn.Abort()
+
+	raw, err := txn.First(runnerTableName, runnerIdIndexName, id)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return status.Errorf(codes.NotFound, "runner ID not found")
+	}
+
+	rec := *raw.(*runnerRecord)
+	rec.Runner = proto.Clone(rec.Runner).(*vagrant_server.Runner)
+	f(rec.Runner)
+	if err := txn.Insert(runnerTableName, &rec); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// runnerWithJob returns the runner with the current jobs set. The runner
+// is only copied if it has a current job.
+func (s *State) runnerWithJob(txn *memdb.Txn, rec *runnerRecord) (*vagrant_server.Runner, error) {
+	jobs, err := s.jobsAssignedToRunner(txn, nil, rec.Id)
+	if err != nil || len(jobs) == 0 {
+		return rec.Runner, err
+	}
+
+	r := proto.Clone(rec.Runner).(*vagrant_server.Runner)
+	r.CurrentJobId = jobs[0].Id
+	for _, job := range jobs {
+		r.JobIds = append(r.JobIds, job.Id)
+	}
+	return r, nil
+}
+
+// runnerDraining returns true if the runner is registered and draining.
+// If ws is set, a watch is added for changes to the runner.
+func (s *State) runnerDraining(txn *memdb.Txn, ws memdb.WatchSet, id string) (bool, error) {
+	watchCh, raw, err := txn.FirstWatch(runnerTableName, runnerIdIndexName, id)
+	if err != nil {
+		return false, err
+	}
+	if ws != nil {
+		ws.Add(watchCh)
+	}
+	if raw == nil {
+		return false, nil
+	}
+
+	return raw.(*runnerRecord).Runner.Draining, nil
 }
 
 // runnerEmpty returns true if there are no runners registered.
@@ -91,6 +323,45 @@ func (s *State) runnerEmpty(memTxn *memdb.Txn) (bool, error) {
 	return iter.Next() == nil, nil
 }
 
+// runnerMatchesSelectors returns true if the labels of the runner match
+// all of the selectors. A runner always matches when there are no selectors.
+func runnerMatchesSelectors(r *vagrant_server.Runner, selectors []*vagrant_server.Ref_RunnerLabelSelector) bool {
+	for _, sel := range selectors {
+		value, ok := r.Labels[sel.Key]
+
+		var in bool
+		if ok {
+			for _, v := range sel.Values {
+				if v == value {
+					in = true
+					break
+				}
+			}
+		}
+
+		switch sel.Operator {
+		case vagrant_server.Ref_RunnerLabelSelector_EQUALS,
+			vagrant_server.Ref_RunnerLabelSelector_IN:
+			if !in {
+				return false
+			}
+
+		case vagrant_server.Ref_RunnerLabelSelector_NOT_EQUALS,
+			vagrant_server.Ref_RunnerLabelSelector_NOT_IN:
+			if in {
+				return false
+			}
+
+		default:
+			// Unknown operators never match so that jobs aren't assigned
+			// to runners that may not meet their requirements.
+			return false
+		}
+	}
+
+	return true
+}
+
 // newRunnerRecord creates a runnerRecord from a runner.
 func newRunnerRecord(r *vagrant_server.Runner) *runnerRecord {
 	rec := &runnerRecord{
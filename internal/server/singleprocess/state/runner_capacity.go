@@ -0,0 +1,81 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/hashicorp/go-memdb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+// This file has the methods related to the capacity of runners. A runner
+// advertises the maximum number of jobs it runs at the same time and
+// optionally resource slots which limit the number of jobs running
+// specific commands. A job is only assigned to a runner with capacity
+// left for it.
+
+// runnerIsLimited returns true if the runner limits the jobs assigned to it.
+func runnerIsLimited(r *vagrant_server.Runner) bool {
+	return r.Concurrency > 0 || len(r.Slots) > 0
+}
+
+// runnerSlotFor returns the resource slot of the runner used by jobs
+// running the given command. This returns nil if the command doesn't use
+// any slot.
+func runnerSlotFor(r *vagrant_server.Runner, cmd string) *vagrant_server.Runner_ResourceSlot {
+	var wildcard *vagrant_server.Runner_ResourceSlot
+	for _, slot := range r.Slots {
+		for _, c := range slot.Commands {
+			if c == "*" {
+				if wildcard == nil {
+					wildcard = slot
+				}
+				continue
+			}
+
+			if strings.EqualFold(c, cmd) {
+				return slot
+			}
+		}
+	}
+
+	return wildcard
+}
+
+// runnerHasCapacity returns true if the job can be assigned to the runner
+// without exceeding its concurrency or the capacity of the slot used by
+// the job. If ws is set, a watch is added for changes to the jobs assigned
+// to the runner.
+func (s *State) runnerHasCapacity(
+	txn *memdb.Txn,
+	ws memdb.WatchSet,
+	r *vagrant_server.Runner,
+	job *jobIndex,
+) (bool, error) {
+	if !runnerIsLimited(r) {
+		return true, nil
+	}
+
+	assigned, err := s.jobsAssignedToRunner(txn, ws, r.Id)
+	if err != nil {
+		return false, err
+	}
+
+	if r.Concurrency > 0 && len(assigned) >= int(r.Concurrency) {
+		return false, nil
+	}
+
+	slot := runnerSlotFor(r, job.Command)
+	if slot == nil {
+		return true, nil
+	}
+
+	used := 0
+	for _, other := range assigned {
+		if runnerSlotFor(r, other.Command) == slot {
+			used++
+		}
+	}
+
+	return used < int(slot.Capacity), nil
+}
@@ -0,0 +1,180 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestRunnerCapacity(t *testing.T) {
+	t.Run("respects the concurrency of the runner", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		r := &vagrant_server.Runner{Id: "R_A", Concurrency: 2}
+		for _, id := range []string{"A", "B", "C"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id: id,
+			})))
+		}
+
+		// Two jobs can be assigned
+		for _, id := range []string{"A", "B"} {
+			job, err := s.JobAssignForRunner(context.Background(), r)
+			require.NoError(err)
+			require.Equal(id, job.Id)
+		}
+
+		// The third job can't be assigned while the others are running
+		{
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := s.JobAssignForRunner(ctx, r)
+			require.Error(err)
+			require.Equal(ctx.Err(), err)
+		}
+
+		// Another runner gets the job
+		job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_B"})
+		require.NoError(err)
+		require.Equal("C", job.Id)
+	})
+
+	t.Run("wakes up when a job completes", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		r := &vagrant_server.Runner{Id: "R_A", Concurrency: 1}
+		for _, id := range []string{"A", "B"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id: id,
+			})))
+		}
+
+		job, err := s.JobAssignForRunner(context.Background(), r)
+		require.NoError(err)
+		require.Equal("A", job.Id)
+		_, err = s.JobAck(job.Id, true)
+		require.NoError(err)
+
+		// Wait for assignment in the background
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		doneCh := make(chan *Job, 1)
+		go func() {
+			job, err := s.JobAssignForRunner(ctx, r)
+			require.NoError(err)
+			doneCh <- job
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case <-doneCh:
+			t.Fatal("job should not be assigned")
+		default:
+		}
+
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		select {
+		case job := <-doneCh:
+			require.Equal("B", job.Id)
+		case <-ctx.Done():
+			t.Fatal("job should be assigned")
+		}
+	})
+
+	t.Run("respects the capacity of slots", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// The jobs have the same target so let them run in parallel
+		s.SetExclusiveCommands([]string{})
+
+		r := &vagrant_server.Runner{
+			Id: "R_A",
+			Slots: []*vagrant_server.Runner_ResourceSlot{
+				{Name: "vm", Capacity: 1, Commands: []string{"up", "reload"}},
+				{Name: "other", Capacity: 8, Commands: []string{"*"}},
+			},
+		}
+
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "A",
+			Operation: testJobRunOp("up"),
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "B",
+			Operation: testJobRunOp("reload"),
+		})))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id:        "C",
+			Operation: testJobRunOp("status"),
+		})))
+
+		job, err := s.JobAssignForRunner(context.Background(), r)
+		require.NoError(err)
+		require.Equal("A", job.Id)
+
+		// B uses the same slot so the status job is assigned instead
+		job, err = s.JobAssignForRunner(context.Background(), r)
+		require.NoError(err)
+		require.Equal("C", job.Id)
+
+		// B is assigned once A completes
+		_, err = s.JobAck("A", true)
+		require.NoError(err)
+		require.NoError(s.JobComplete("A", nil, nil))
+
+		job, err = s.JobAssignForRunner(context.Background(), r)
+		require.NoError(err)
+		require.Equal("B", job.Id)
+	})
+
+	t.Run("lists the jobs of the runner", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		r := serverptypes.TestRunner(t, &vagrant_server.Runner{Concurrency: 2})
+		require.NoError(s.RunnerCreate(r))
+		for _, id := range []string{"A", "B"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id: id,
+			})))
+
+			_, err := s.JobAssignForRunner(context.Background(), r)
+			require.NoError(err)
+		}
+
+		found, err := s.RunnerById(r.Id)
+		require.NoError(err)
+		require.Equal("A", found.CurrentJobId)
+		require.Equal([]string{"A", "B"}, found.JobIds)
+	})
+}
+
+func TestRunnerSlotFor(t *testing.T) {
+	r := &vagrant_server.Runner{
+		Slots: []*vagrant_server.Runner_ResourceSlot{
+			{Name: "vm", Commands: []string{"up"}},
+			{Name: "other", Commands: []string{"*"}},
+		},
+	}
+
+	require.Equal(t, "vm", runnerSlotFor(r, "UP").Name)
+	require.Equal(t, "other", runnerSlotFor(r, "status").Name)
+	require.Nil(t, runnerSlotFor(&vagrant_server.Runner{}, "up"))
+}
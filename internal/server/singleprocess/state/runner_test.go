@@ -1,9 +1,12 @@
 package state
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -49,3 +52,123 @@ func TestRunnerById_notFound(t *testing.T) {
 	require.Nil(found)
 	require.Equal(codes.NotFound, status.Code(err))
 }
+
+func TestRunnerOffline(t *testing.T) {
+	t.Run("removed after grace period", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		s.SetRunnerGracePeriod(50 * time.Millisecond)
+
+		// Register a runner and assign it a job
+		rec := &vagrant_server.Runner{Id: "R_A"}
+		require.NoError(s.RunnerCreate(rec))
+		require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+			Id: "A",
+		})))
+		job, err := s.JobAssignForRunner(context.Background(), rec)
+		require.NoError(err)
+		_, err = s.JobAck(job.Id, true)
+		require.NoError(err)
+
+		found, err := s.RunnerById(rec.Id)
+		require.NoError(err)
+		require.True(found.Online)
+		require.Equal("A", found.CurrentJobId)
+
+		// Disconnect, the runner should be kept for the grace period
+		require.NoError(s.RunnerOffline(rec.Id))
+		found, err = s.RunnerById(rec.Id)
+		require.NoError(err)
+		require.False(found.Online)
+
+		// The runner should be removed and the job requeued
+		require.Eventually(func() bool {
+			_, err := s.RunnerById(rec.Id)
+			return status.Code(err) == codes.NotFound
+		}, 2*time.Second, 10*time.Millisecond)
+
+		job, err = s.JobById("A", nil)
+		require.NoError(err)
+		require.Equal(vagrant_server.Job_QUEUED, job.State)
+		require.Nil(job.AssignedRunner)
+	})
+
+	t.Run("reconnect within grace period", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		s.SetRunnerGracePeriod(50 * time.Millisecond)
+
+		rec := &vagrant_server.Runner{Id: "R_A"}
+		require.NoError(s.RunnerCreate(rec))
+		require.NoError(s.RunnerDrain(rec.Id))
+		require.NoError(s.RunnerOffline(rec.Id))
+
+		// Reconnect
+		require.NoError(s.RunnerCreate(&vagrant_server.Runner{Id: "R_A"}))
+		time.Sleep(100 * time.Millisecond)
+
+		found, err := s.RunnerById(rec.Id)
+		require.NoError(err)
+		require.True(found.Online)
+		require.True(found.Draining)
+	})
+
+	t.Run("no grace period", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		s.SetRunnerGracePeriod(0)
+
+		require.NoError(s.RunnerCreate(&vagrant_server.Runner{Id: "R_A"}))
+		require.NoError(s.RunnerOffline("R_A"))
+
+		_, err := s.RunnerById("R_A")
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+}
+
+func TestRunnerDrain(t *testing.T) {
+	require := require.New(t)
+
+	s := TestState(t)
+	defer s.Close()
+
+	rec := &vagrant_server.Runner{Id: "R_A"}
+	require.NoError(s.RunnerCreate(rec))
+	require.NoError(s.RunnerCreate(&vagrant_server.Runner{Id: "R_B"}))
+	require.NoError(s.RunnerDrain(rec.Id))
+	require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+		Id: "A",
+	})))
+
+	// A draining runner should not be assigned the job
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	job, err := s.JobAssignForRunner(ctx, rec)
+	require.Error(err)
+	require.Nil(job)
+	require.Equal(ctx.Err(), err)
+
+	// Other runners can still be assigned the job
+	job, err = s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_B"})
+	require.NoError(err)
+	require.Equal("A", job.Id)
+
+	// Both runners are listed
+	runners, err := s.RunnerList()
+	require.NoError(err)
+	require.Len(runners, 2)
+	require.Equal("R_A", runners[0].Id)
+	require.True(runners[0].Draining)
+	require.Empty(runners[0].CurrentJobId)
+	require.Equal("R_B", runners[1].Id)
+	require.Equal("A", runners[1].CurrentJobId)
+
+	// Draining an unknown runner is an error
+	require.Equal(codes.NotFound, status.Code(s.RunnerDrain("nope")))
+}
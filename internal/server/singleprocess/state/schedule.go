@@ -0,0 +1,279 @@
+package state
+
+import (
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+	"github.com/hashicorp/go-memdb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+var scheduleBucket = []byte("schedule")
+
+func init() {
+	dbBuckets = append(dbBuckets, scheduleBucket)
+	dbIndexers = append(dbIndexers, (*State).scheduleIndexInit)
+	schemas = append(schemas, scheduleIndexSchema)
+}
+
+const (
+	scheduleIndexTableName   = "schedule-index"
+	scheduleIndexIdIndexName = "id"
+)
+
+// SchedulePut creates or updates a schedule. The fields set by the server
+// are kept from the existing schedule. The next run time is calculated from
+// the cron expression if the schedule is new, the expression changed, or
+// the schedule was enabled or disabled.
+func (s *State) SchedulePut(sched *vagrant_server.Schedule) error {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		return s.schedulePut(dbTxn, memTxn, sched)
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+// ScheduleGet gets a schedule by ID.
+func (s *State) ScheduleGet(id string) (*vagrant_server.Schedule, error) {
+	var result *vagrant_server.Schedule
+	err := s.db.View(func(dbTxn *bolt.Tx) error {
+		var err error
+		result, err = s.scheduleGet(dbTxn, id)
+		return err
+	})
+
+	return result, err
+}
+
+// ScheduleList returns all the schedules. If ws is set then a watch will
+// be added for any changes to the schedules.
+func (s *State) ScheduleList(ws memdb.WatchSet) ([]*vagrant_server.Schedule, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	iter, err := memTxn.Get(scheduleIndexTableName, scheduleIndexIdIndexName+"_prefix", "")
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+
+	var result []*vagrant_server.Schedule
+	err = s.db.View(func(dbTxn *bolt.Tx) error {
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			sched, err := s.scheduleGet(dbTxn, raw.(*scheduleIndexRecord).Id)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, sched)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// ScheduleDelete deletes a schedule by ID. Deleting a schedule that
+// doesn't exist is not an error.
+func (s *State) ScheduleDelete(id string) error {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		if err := dbTxn.Bucket(scheduleBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		_, err := memTxn.DeleteAll(scheduleIndexTableName, scheduleIndexIdIndexName, id)
+		return err
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+// ScheduleRecordRun records a run of the schedule and calculates the
+// next run time. The jobId is the ID of the queued job and is empty if no
+// job was queued, either because runErr is set or because the run was
+// skipped.
+func (s *State) ScheduleRecordRun(id, jobId string, runErr error) error {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		sched, err := s.scheduleGet(dbTxn, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		sched.LastError = nil
+		if jobId != "" {
+			sched.LastJobId = jobId
+			sched.LastRunTime = timestamppb.New(now)
+		}
+		if runErr != nil {
+			sched.LastError = status.Convert(runErr).Proto()
+		}
+
+		// A schedule that doesn't match again has no next run but the
+		// run is still recorded.
+		next, err := scheduleNext(sched.Cron, now)
+		if err != nil {
+			return err
+		}
+		sched.NextRunTime = nil
+		if !next.IsZero() {
+			sched.NextRunTime = timestamppb.New(next)
+		}
+
+		return s.scheduleWrite(dbTxn, memTxn, sched)
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+func (s *State) schedulePut(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	sched *vagrant_server.Schedule,
+) error {
+	if sched.Id == "" {
+		return status.Errorf(codes.InvalidArgument, "schedule id must be set")
+	}
+
+	existing, err := s.scheduleGet(dbTxn, sched.Id)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			return err
+		}
+		existing = nil
+	}
+
+	// Keep the fields set by the server
+	if existing != nil {
+		sched.LastJobId = existing.LastJobId
+		sched.LastRunTime = existing.LastRunTime
+		sched.LastError = existing.LastError
+		if existing.Cron == sched.Cron && existing.Disabled == sched.Disabled {
+			sched.NextRunTime = existing.NextRunTime
+		} else {
+			sched.NextRunTime = nil
+		}
+	} else {
+		sched.LastJobId = ""
+		sched.LastRunTime = nil
+		sched.LastError = nil
+		sched.NextRunTime = nil
+	}
+
+	if sched.NextRunTime == nil {
+		next, err := scheduleNext(sched.Cron, time.Now())
+		if err != nil {
+			return err
+		}
+		if next.IsZero() {
+			return status.Errorf(codes.InvalidArgument,
+				"cron expression %q never matches", sched.Cron)
+		}
+		sched.NextRunTime = timestamppb.New(next)
+	}
+
+	return s.scheduleWrite(dbTxn, memTxn, sched)
+}
+
+func (s *State) scheduleWrite(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	sched *vagrant_server.Schedule,
+) error {
+	id := []byte(sched.Id)
+	if err := dbPut(dbTxn.Bucket(scheduleBucket), id, sched); err != nil {
+		return err
+	}
+
+	return s.scheduleIndexSet(memTxn, id, sched)
+}
+
+func (s *State) scheduleGet(dbTxn *bolt.Tx, id string) (*vagrant_server.Schedule, error) {
+	var result vagrant_server.Schedule
+	b := dbTxn.Bucket(scheduleBucket)
+	return &result, dbGet(b, []byte(id), &result)
+}
+
+// scheduleNext returns the next time after from that the cron expression
+// matches. The expression is evaluated in UTC. If the expression never
+// matches after from, the zero time is returned.
+func scheduleNext(expr string, from time.Time) (time.Time, error) {
+	cron, err := cronexpr.Parse(expr)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument,
+			"invalid cron expression %q: %s", expr, err)
+	}
+
+	return cron.Next(from.UTC()), nil
+}
+
+// scheduleIndexSchema is the memdb schema for schedules. This is only used
+// to watch for changes to the schedules.
+func scheduleIndexSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: scheduleIndexTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			scheduleIndexIdIndexName: {
+				Name:         scheduleIndexIdIndexName,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Id",
+				},
+			},
+		},
+	}
+}
+
+type scheduleIndexRecord struct {
+	Id string
+
+	// NextRunTime is the next time the schedule runs. This changes on
+	// every run so that watchers are notified.
+	NextRunTime time.Time
+}
+
+// scheduleIndexInit initializes the schedule index from persisted data.
+func (s *State) scheduleIndexInit(dbTxn *bolt.Tx, memTxn *memdb.Txn) error {
+	bucket := dbTxn.Bucket(scheduleBucket)
+	return bucket.ForEach(func(k, v []byte) error {
+		var value vagrant_server.Schedule
+		if err := proto.Unmarshal(v, &value); err != nil {
+			return err
+		}
+
+		return s.scheduleIndexSet(memTxn, k, &value)
+	})
+}
+
+func (s *State) scheduleIndexSet(txn *memdb.Txn, id []byte, value *vagrant_server.Schedule) error {
+	return txn.Insert(scheduleIndexTableName, &scheduleIndexRecord{
+		Id:          string(id),
+		NextRunTime: value.NextRunTime.AsTime(),
+	})
+}
@@ -0,0 +1,158 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("CRUD operations", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		// Get not found
+		_, err := s.ScheduleGet("A")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		// Put
+		sched := serverptypes.TestSchedule(t, nil)
+		sched.Id = "A"
+		require.NoError(s.SchedulePut(sched))
+		require.NotNil(sched.NextRunTime)
+		require.True(sched.NextRunTime.AsTime().After(time.Now()))
+
+		// Get
+		resp, err := s.ScheduleGet("A")
+		require.NoError(err)
+		require.Equal("test", resp.Name)
+		require.Equal(sched.NextRunTime.AsTime(), resp.NextRunTime.AsTime())
+
+		// List
+		list, err := s.ScheduleList(nil)
+		require.NoError(err)
+		require.Len(list, 1)
+
+		// Delete
+		require.NoError(s.ScheduleDelete("A"))
+		list, err = s.ScheduleList(nil)
+		require.NoError(err)
+		require.Empty(list)
+	})
+
+	t.Run("invalid cron expression", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		sched := serverptypes.TestSchedule(t, nil)
+		sched.Id = "A"
+		sched.Cron = "every day"
+		err := s.SchedulePut(sched)
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("records runs", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		sched := serverptypes.TestSchedule(t, nil)
+		sched.Id = "A"
+		require.NoError(s.SchedulePut(sched))
+
+		ws := memdb.NewWatchSet()
+		_, err := s.ScheduleList(ws)
+		require.NoError(err)
+
+		require.NoError(s.ScheduleRecordRun("A", "J", nil))
+		require.False(ws.Watch(time.After(time.Second)), "watch should fire")
+
+		resp, err := s.ScheduleGet("A")
+		require.NoError(err)
+		require.Equal("J", resp.LastJobId)
+		require.NotNil(resp.LastRunTime)
+		require.Nil(resp.LastError)
+
+		// Updating the schedule keeps the server fields
+		sched = serverptypes.TestSchedule(t, nil)
+		sched.Id = "A"
+		sched.Name = "updated"
+		require.NoError(s.SchedulePut(sched))
+		require.Equal("J", sched.LastJobId)
+
+		// Failed runs record the error but keep the last job
+		require.NoError(s.ScheduleRecordRun("A", "", status.Error(codes.Internal, "boom")))
+		resp, err = s.ScheduleGet("A")
+		require.NoError(err)
+		require.Equal("updated", resp.Name)
+		require.Equal("J", resp.LastJobId)
+		require.Equal(int32(codes.Internal), resp.LastError.Code)
+	})
+
+	t.Run("no next run once the expression stops matching", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		sched := serverptypes.TestSchedule(t, nil)
+		sched.Id = "A"
+		sched.Cron = fmt.Sprintf("* * * * * %d", time.Now().UTC().Year())
+		require.NoError(s.SchedulePut(sched))
+
+		// Move the schedule to a year that has passed, as if the
+		// schedule was created then
+		require.NoError(s.db.Update(func(dbTxn *bolt.Tx) error {
+			sched.Cron = "* * * * * 2020"
+			return dbPut(dbTxn.Bucket(scheduleBucket), []byte(sched.Id), sched)
+		}))
+
+		require.NoError(s.ScheduleRecordRun("A", "J", nil))
+		resp, err := s.ScheduleGet("A")
+		require.NoError(err)
+		require.Equal("J", resp.LastJobId)
+		require.Nil(resp.NextRunTime)
+
+		// A new schedule that never matches is rejected
+		sched = serverptypes.TestSchedule(t, nil)
+		sched.Id = "B"
+		sched.Cron = "* * * * * 2020"
+		err = s.SchedulePut(sched)
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("survives a restart", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		sched := serverptypes.TestSchedule(t, nil)
+		sched.Id = "A"
+		require.NoError(s.SchedulePut(sched))
+
+		s2 := TestStateReinit(t, s)
+		defer s2.Close()
+
+		list, err := s2.ScheduleList(nil)
+		require.NoError(err)
+		require.Len(list, 1)
+		require.Equal("A", list[0].Id)
+	})
+}
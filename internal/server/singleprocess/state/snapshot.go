@@ -2,6 +2,7 @@ package state
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
@@ -19,6 +20,7 @@ import (
 	bolt "go.etcd.io/bbolt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/hashicorp/vagrant/internal/pkg/protowriter"
 	"github.com/hashicorp/vagrant/internal/protocolversion"
@@ -27,9 +29,13 @@ import (
 
 // CreateSnapshot creates a database snapshot and writes it to the given writer.
 //
+// The material of the HMAC keys is only written if includeHMACKeys is true.
+// Otherwise the keys are written without it, and a new key is created once
+// the snapshot is restored. See hmacKeyRotateStripped.
+//
 // This will NOT buffer data to w, so you should wrap w in a bufio.Writer
 // if you want buffering.
-func (s *State) CreateSnapshot(w io.Writer) error {
+func (s *State) CreateSnapshot(w io.Writer, includeHMACKeys bool) error {
 	// We build up the checksum using a multiwriter from the protowriter.
 	// This lets us figure out the checksum after the proto bytes are marshalled
 	// but before gzip.
@@ -48,6 +54,7 @@ func (s *State) CreateSnapshot(w io.Writer) error {
 		return err
 	}
 
+	now := time.Now()
 	return s.db.View(func(dbTxn *bolt.Tx) error {
 		if err := dbTxn.ForEach(func(name []byte, b *bolt.Bucket) error {
 			const chunkLenMax = 1024 * 1024 // 1 MB
@@ -70,6 +77,27 @@ func (s *State) CreateSnapshot(w io.Writer) error {
 
 			// Iterate and write the data
 			if err := b.ForEach(func(k, v []byte) error {
+				// Retired HMAC keys no longer validate any token so we
+				// don't carry their key material into snapshots.
+				if bytes.Equal(name, hmacKeyBucket) {
+					var key vagrant_server.HMACKey
+					if err := proto.Unmarshal(v, &key); err != nil {
+						return err
+					}
+					if HMACKeyRetired(&key, now) {
+						return nil
+					}
+
+					if !includeHMACKeys {
+						key.Key = nil
+						raw, err := proto.Marshal(&key)
+						if err != nil {
+							return err
+						}
+						v = raw
+					}
+				}
+
 				if len(v)+chunkLen > chunkLenMax {
 					if err := flushChunk(); err != nil {
 						return err
@@ -36,7 +36,7 @@ func TestSnapshotRestore(t *testing.T) {
 
 	// Snapshot
 	var buf bytes.Buffer
-	require.NoError(s.CreateSnapshot(&buf))
+	require.NoError(s.CreateSnapshot(&buf, true))
 
 	// Create more data that isn't in the snapshot
 	err = s.ProjectPut(serverptypes.TestProject(t, &vagrant_server.Project{
@@ -56,6 +56,11 @@ type State struct {
 	// initialized during New and set to nil at the end of New.
 	indexers map[uintptr]struct{}
 
+	// dependsFailed are the queued jobs with a failed dependency found
+	// while initializing the job index. This is set to nil at the end of
+	// New once the jobs are failed.
+	dependsFailed []jobDependFailure
+
 	// Where to log to
 	log hclog.Logger
 
@@ -64,6 +69,34 @@ type State struct {
 
 	// Used to track prune records
 	pruneMu sync.Mutex
+
+	// opSeqs tracks the latest sequence number of operations for each
+	// basis, project, and target. See genericOperation.getSeq.
+	opSeqs map[string]*uint64
+
+	// exclusiveCmds are the commands which can not be run in parallel
+	// on the same target. If nil, DefaultExclusiveCommands is used.
+	exclusiveMu   sync.RWMutex
+	exclusiveCmds map[string]struct{}
+
+	// runnerGrace is how long a disconnected runner is kept before it
+	// is removed. See SetRunnerGracePeriod.
+	runnerGraceMu sync.RWMutex
+	runnerGrace   time.Duration
+
+	// jobTimeouts are the default timeouts of jobs by command name.
+	// See SetJobTimeouts.
+	jobTimeoutsMu sync.RWMutex
+	jobTimeouts   map[string]time.Duration
+
+	// outputPending are the terminal events of jobs which are buffered
+	// until they are written to the database. outputCount is the number
+	// of buffered events and outputTimer flushes them. See
+	// JobOutputAppend.
+	outputMu      sync.Mutex
+	outputPending map[string]*jobOutputPending
+	outputCount   int
+	outputTimer   *time.Timer
 }
 
 // New initializes a new State store.
@@ -83,12 +116,17 @@ func New(log hclog.Logger, db *bolt.DB) (*State, error) {
 	}
 
 	// Initialize and validate our on-disk format.
-	if err := dbInit(db); err != nil {
+	if err := dbInit(log, db); err != nil {
 		log.Error("failed to initialize and validate on-disk format", "error", err)
 		return nil, err
 	}
 
-	s := &State{inmem: inmem, db: db, log: log}
+	s := &State{
+		inmem:       inmem,
+		db:          db,
+		log:         log,
+		runnerGrace: DefaultRunnerGracePeriod,
+	}
 
 	// Initialize our set that'll track what memdb indexers we call.
 	// When we're done we always clear this out since it is never used
@@ -116,6 +154,14 @@ func New(log hclog.Logger, db *bolt.DB) (*State, error) {
 	}
 	memTxn.Commit()
 
+	// Fail the jobs found with a failed dependency now that we can write
+	err = s.jobDependsInitFail()
+	s.dependsFailed = nil
+	if err != nil {
+		log.Error("failed to fail jobs with failed dependencies", "error", err)
+		return nil, err
+	}
+
 	return s, nil
 }
 
@@ -134,6 +180,16 @@ func (s *State) callIndexer(fn indexFn, dbTxn *bolt.Tx, memTxn *memdb.Txn) error
 
 // Close should be called to gracefully close any resources.
 func (s *State) Close() error {
+	s.outputMu.Lock()
+	if err := s.jobOutputFlush(); err != nil {
+		s.log.Warn("error storing job output", "error", err)
+	}
+	if s.outputTimer != nil {
+		s.outputTimer.Stop()
+		s.outputTimer = nil
+	}
+	s.outputMu.Unlock()
+
 	return s.db.Close()
 }
 
@@ -148,8 +204,15 @@ func (s *State) Prune() error {
 	if err != nil {
 		return err
 	}
+
+	// Prune log streams that are no longer written to
+	logStreams, err := s.logStreamsPruneIdle(memTxn)
+	if err != nil {
+		return err
+	}
 	s.log.Debug("Finished pruning data",
 		"removed-jobs", jobs,
+		"removed-log-streams", logStreams,
 	)
 	memTxn.Commit()
 
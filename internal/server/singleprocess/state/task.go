@@ -1,6 +1,10 @@
 package state
 
 import (
+	"github.com/hashicorp/go-memdb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+
 	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
 )
 
@@ -13,18 +17,12 @@ func init() {
 	taskOp.register()
 }
 
-// TaskPut inserts or updates a task record.
+// TaskPut inserts or updates a task record. The task must be scoped to
+// an existing target, project, or basis. The scope may reference the
+// resource by name or path and will be replaced with a reference that
+// includes the resource ids. New tasks are assigned the next sequence
+// number of their scope.
 func (s *State) TaskPut(update bool, t *vagrant_server.Task) error {
-	// TODO(phinze): This Put operation will always fail because the Task
-	// struct has neither a Basis, a Project, nor a Target set. This is ok for
-	// now because nobody is using Task operations directly - Tasks seem to
-	// enter state only from being nested in Jobs. At some point we'll need to
-	// swing around and decide if we want to fix this wiring or if it's
-	// unnecessary and okay to delete.
-	//
-	// If we do decide to fix it... the thing to sort out here will be how to
-	// transform the basis, project, or target referenced by t.Scope into the
-	// form expected by taskOp.Put().
 	return taskOp.Put(s, update, t)
 }
 
@@ -37,6 +35,8 @@ func (s *State) TaskGet(ref *vagrant_server.Ref_Operation) (*vagrant_server.Task
 	return result.(*vagrant_server.Task), nil
 }
 
+// TaskList returns the tasks run within the scope of the given target,
+// project, or basis reference.
 func (s *State) TaskList(
 	ref interface{},
 	opts ...ListOperationOption,
@@ -54,6 +54,8 @@ func (s *State) TaskList(
 	return
 }
 
+// TaskLatest returns the most recently completed successful task run
+// within the scope of the given target, project, or basis reference.
 func (s *State) TaskLatest(
 	ref interface{},
 ) (*vagrant_server.Task, error) {
@@ -64,3 +66,67 @@ func (s *State) TaskLatest(
 
 	return result.(*vagrant_server.Task), nil
 }
+
+// jobTaskPut records the task run by a completed job in the task history.
+// Jobs which did not run a task or which never started are ignored.
+func (s *State) jobTaskPut(memTxn *memdb.Txn, jobpb *vagrant_server.Job) error {
+	run, ok := jobpb.Operation.(*vagrant_server.Job_Run)
+	if !ok || run.Run.Task == nil || jobpb.AckTime == nil {
+		return nil
+	}
+
+	// Prefer the task returned by the runner since it may have been updated
+	task := run.Run.Task
+	if r := jobpb.Result.GetRun(); r != nil && r.Task != nil {
+		task = r.Task
+	}
+	task = proto.Clone(task).(*vagrant_server.Task)
+
+	// Default the scope to the scope of the job
+	if task.Scope == nil {
+		switch {
+		case jobpb.Target != nil:
+			task.Scope = &vagrant_server.Task_Target{Target: jobpb.Target}
+		case jobpb.Project != nil:
+			task.Scope = &vagrant_server.Task_Project{Project: jobpb.Project}
+		case jobpb.Basis != nil:
+			task.Scope = &vagrant_server.Task_Basis{Basis: jobpb.Basis}
+		}
+	}
+
+	id, err := s.newResourceId()
+	if err != nil {
+		return err
+	}
+	task.Id = id
+	task.JobId = jobpb.Id
+	task.RequestedBy = jobpb.RequestedBy
+	if len(jobpb.Labels) > 0 {
+		labels := map[string]string{}
+		for k, v := range jobpb.Labels {
+			labels[k] = v
+		}
+		for k, v := range task.Labels {
+			labels[k] = v
+		}
+		task.Labels = labels
+	}
+
+	completeTime := jobpb.CompleteTime
+	if completeTime == nil {
+		completeTime = jobpb.CancelTime
+	}
+	task.Status = &vagrant_server.Status{
+		State:        vagrant_server.Status_SUCCESS,
+		StartTime:    jobpb.AckTime,
+		CompleteTime: completeTime,
+	}
+	if jobpb.Error != nil {
+		task.Status.State = vagrant_server.Status_ERROR
+		task.Status.Error = jobpb.Error
+	}
+
+	return s.db.Update(func(dbTxn *bolt.Tx) error {
+		return taskOp.dbPut(s, dbTxn, memTxn, false, task)
+	})
+}
@@ -0,0 +1,283 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+	serverptypes "github.com/hashicorp/vagrant/internal/server/ptypes"
+)
+
+func TestTask(t *testing.T) {
+	// Creates a target within the test project
+	testTarget := func(t *testing.T, s *State, name string) *vagrant_plugin_sdk.Ref_Target {
+		projectRef := testProject(t, s)
+		require.NoError(t, s.TargetPut(serverptypes.TestTarget(t, &vagrant_server.Target{
+			ResourceId: "target-" + name,
+			Project:    projectRef,
+			Name:       name,
+		})))
+		return &vagrant_plugin_sdk.Ref_Target{
+			ResourceId: "target-" + name,
+			Name:       name,
+			Project:    projectRef,
+		}
+	}
+
+	// Creates a completed task
+	testTask := func(id, name string, state vagrant_server.Status_State, scope interface{}) *vagrant_server.Task {
+		task := &vagrant_server.Task{
+			Id:          id,
+			Task:        name,
+			CommandName: name,
+			Status: &vagrant_server.Status{
+				State:        state,
+				StartTime:    timestamppb.Now(),
+				CompleteTime: timestamppb.Now(),
+			},
+		}
+		switch r := scope.(type) {
+		case *vagrant_plugin_sdk.Ref_Target:
+			task.Scope = &vagrant_server.Task_Target{Target: r}
+		case *vagrant_plugin_sdk.Ref_Project:
+			task.Scope = &vagrant_server.Task_Project{Project: r}
+		case *vagrant_plugin_sdk.Ref_Basis:
+			task.Scope = &vagrant_server.Task_Basis{Basis: r}
+		}
+		return task
+	}
+
+	t.Run("Put requires a scope", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		err := s.TaskPut(false, &vagrant_server.Task{Id: "A", Task: "up"})
+		require.Error(err)
+	})
+
+	t.Run("Put and Get by basis name", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		testBasis(t, s)
+
+		task := testTask("A", "up", vagrant_server.Status_SUCCESS,
+			&vagrant_plugin_sdk.Ref_Basis{Name: "test-basis"})
+		require.NoError(s.TaskPut(false, task))
+		require.Equal(uint64(1), task.Sequence)
+		require.Equal("test-basis", task.GetBasis().ResourceId)
+
+		result, err := s.TaskGet(&vagrant_server.Ref_Operation{
+			Target: &vagrant_server.Ref_Operation_Id{Id: "A"},
+		})
+		require.NoError(err)
+		require.Equal("up", result.Task)
+		require.Equal("test-basis", result.GetBasis().ResourceId)
+
+		// Get by sequence
+		result, err = s.TaskGet(&vagrant_server.Ref_Operation{
+			Target: &vagrant_server.Ref_Operation_BasisSequence{
+				BasisSequence: &vagrant_server.Ref_BasisOperationSeq{
+					Basis:  &vagrant_plugin_sdk.Ref_Basis{Name: "test-basis"},
+					Number: 1,
+				},
+			},
+		})
+		require.NoError(err)
+		require.Equal("A", result.Id)
+	})
+
+	t.Run("Put with unknown scope", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		err := s.TaskPut(false, testTask("A", "up", vagrant_server.Status_SUCCESS,
+			&vagrant_plugin_sdk.Ref_Basis{Name: "nothing"}))
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Update keeps sequence", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s, "default")
+
+		task := testTask("A", "up", vagrant_server.Status_RUNNING, target)
+		require.NoError(s.TaskPut(false, task))
+
+		task.Sequence = 10
+		task.Status.State = vagrant_server.Status_SUCCESS
+		require.NoError(s.TaskPut(true, task))
+		require.Equal(uint64(1), task.Sequence)
+
+		// Updating a missing task fails
+		err := s.TaskPut(true, testTask("B", "up", vagrant_server.Status_SUCCESS, target))
+		require.Equal(codes.NotFound, status.Code(err))
+	})
+
+	t.Run("List and Latest by target", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s, "default")
+		other := testTarget(t, s, "other")
+
+		require.NoError(s.TaskPut(false,
+			testTask("A", "up", vagrant_server.Status_SUCCESS, target)))
+		time.Sleep(time.Millisecond)
+		require.NoError(s.TaskPut(false,
+			testTask("B", "destroy", vagrant_server.Status_SUCCESS, target)))
+		time.Sleep(time.Millisecond)
+		require.NoError(s.TaskPut(false,
+			testTask("C", "up", vagrant_server.Status_ERROR, target)))
+		require.NoError(s.TaskPut(false,
+			testTask("D", "up", vagrant_server.Status_SUCCESS, other)))
+
+		// Sequence numbers are per target
+		otherTask, err := s.TaskGet(&vagrant_server.Ref_Operation{
+			Target: &vagrant_server.Ref_Operation_Id{Id: "D"},
+		})
+		require.NoError(err)
+		require.Equal(uint64(1), otherTask.Sequence)
+
+		// Look up the target by name
+		byName := &vagrant_plugin_sdk.Ref_Target{
+			Name:    "default",
+			Project: &vagrant_plugin_sdk.Ref_Project{Name: "test-project"},
+		}
+		result, err := s.TaskList(byName)
+		require.NoError(err)
+		require.Len(result, 3)
+		require.Equal("C", result[0].Id)
+		require.Equal(uint64(3), result[0].Sequence)
+
+		// Filter by status
+		result, err = s.TaskList(byName, ListWithStatusFilter(&vagrant_server.StatusFilter{
+			Filters: []*vagrant_server.StatusFilter_Filter{{
+				Filter: &vagrant_server.StatusFilter_Filter_State{
+					State: vagrant_server.Status_SUCCESS,
+				},
+			}},
+		}))
+		require.NoError(err)
+		require.Len(result, 2)
+
+		// Latest successful task
+		latest, err := s.TaskLatest(byName)
+		require.NoError(err)
+		require.Equal("destroy", latest.Task)
+	})
+
+	t.Run("List by project includes targets", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s, "default")
+
+		require.NoError(s.TaskPut(false,
+			testTask("A", "up", vagrant_server.Status_SUCCESS, target)))
+		time.Sleep(time.Millisecond)
+		require.NoError(s.TaskPut(false,
+			testTask("B", "validate", vagrant_server.Status_SUCCESS, target.Project)))
+		time.Sleep(time.Millisecond)
+		require.NoError(s.TaskPut(false,
+			testTask("C", "destroy", vagrant_server.Status_SUCCESS, target)))
+
+		result, err := s.TaskList(target.Project, ListWithOrder(&vagrant_server.OperationOrder{
+			Limit: 2,
+		}))
+		require.NoError(err)
+		require.Len(result, 2)
+		require.Equal("C", result[0].Id)
+		require.Equal("B", result[1].Id)
+
+		latest, err := s.TaskLatest(target.Project.Basis)
+		require.NoError(err)
+		require.Equal("C", latest.Id)
+	})
+
+	t.Run("Index is rebuilt on restart", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s, "default")
+
+		require.NoError(s.TaskPut(false,
+			testTask("A", "up", vagrant_server.Status_SUCCESS, target)))
+
+		s = TestStateReinit(t, s)
+		defer s.Close()
+
+		result, err := s.TaskList(target)
+		require.NoError(err)
+		require.Len(result, 1)
+
+		// Sequence numbers continue
+		task := testTask("B", "up", vagrant_server.Status_SUCCESS, target)
+		require.NoError(s.TaskPut(false, task))
+		require.Equal(uint64(2), task.Sequence)
+	})
+
+	t.Run("Completed jobs are recorded", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+		target := testTarget(t, s, "default")
+
+		for _, id := range []string{"A", "B"} {
+			require.NoError(s.JobCreate(serverptypes.TestJobNew(t, &vagrant_server.Job{
+				Id:          id,
+				Target:      target,
+				Labels:      map[string]string{"user": "alice"},
+				RequestedBy: "alice",
+				Operation: &vagrant_server.Job_Run{
+					Run: &vagrant_server.Job_RunOp{
+						Task: &vagrant_server.Task{Task: "destroy", CommandName: "destroy"},
+					},
+				},
+			})))
+			job, err := s.JobAssignForRunner(context.Background(), &vagrant_server.Runner{Id: "R_A"})
+			require.NoError(err)
+			_, err = s.JobAck(job.Id, true)
+			require.NoError(err)
+
+			var jobErr error
+			if id == "B" {
+				jobErr = errors.New("failed")
+			}
+			require.NoError(s.JobComplete(id, nil, jobErr))
+		}
+
+		result, err := s.TaskList(target)
+		require.NoError(err)
+		require.Len(result, 2)
+
+		latest, err := s.TaskLatest(target)
+		require.NoError(err)
+		require.Equal("destroy", latest.CommandName)
+		require.Equal("A", latest.JobId)
+		require.Equal("alice", latest.Labels["user"])
+		require.Equal("alice", latest.RequestedBy)
+		require.NotNil(latest.Status.StartTime)
+		require.NotNil(latest.Status.CompleteTime)
+	})
+}
@@ -0,0 +1,209 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/hashicorp/go-memdb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+var issuedTokenBucket = []byte("issued_tokens")
+
+func init() {
+	dbBuckets = append(dbBuckets, issuedTokenBucket)
+	dbIndexers = append(dbIndexers, (*State).issuedTokenIndexInit)
+	schemas = append(schemas, issuedTokenIndexSchema)
+}
+
+// TokenPut records a token issued by the server.
+func (s *State) TokenPut(token *vagrant_server.IssuedToken) error {
+	if token.Id == "" {
+		return status.Errorf(codes.InvalidArgument, "token id must be set")
+	}
+
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		return s.issuedTokenPut(dbTxn, memTxn, token)
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+// TokenGet gets the record of an issued token by ID.
+func (s *State) TokenGet(id string) (*vagrant_server.IssuedToken, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	result, err := s.issuedTokenGet(memTxn, nil, id)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, status.Errorf(codes.NotFound, "token not found: %s", id)
+	}
+
+	return result, nil
+}
+
+// TokenList returns the records of all the issued tokens. Revoked tokens
+// are only included if includeRevoked is true.
+func (s *State) TokenList(includeRevoked bool) ([]*vagrant_server.IssuedToken, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	iter, err := memTxn.Get(issuedTokenIndexTableName, issuedTokenIndexIdIndexName+"_prefix", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*vagrant_server.IssuedToken
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		token := raw.(*issuedTokenIndexRecord).Token
+		if token.Revoked && !includeRevoked {
+			continue
+		}
+
+		result = append(result, token)
+	}
+
+	return result, nil
+}
+
+// TokenRevoke revokes the issued token with the given ID. Revoking a token
+// which is already revoked doesn't change it.
+func (s *State) TokenRevoke(id string) (*vagrant_server.IssuedToken, error) {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	var result *vagrant_server.IssuedToken
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		token, err := s.issuedTokenGet(memTxn, nil, id)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return status.Errorf(codes.NotFound, "token not found: %s", id)
+		}
+		if token.Revoked {
+			result = token
+			return nil
+		}
+
+		// Copy so readers of the current record are not affected
+		result = proto.Clone(token).(*vagrant_server.IssuedToken)
+		result.Revoked = true
+		result.RevokeTime = timestamppb.Now()
+		return s.issuedTokenPut(dbTxn, memTxn, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	memTxn.Commit()
+	return result, nil
+}
+
+// TokenRevoked returns true if the token with the given ID is revoked.
+// Tokens without a record were issued before tokens were recorded and
+// are never revoked. If ws is given, it is notified of any change to the
+// record of the token.
+func (s *State) TokenRevoked(id string, ws memdb.WatchSet) (bool, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	token, err := s.issuedTokenGet(memTxn, ws, id)
+	if err != nil {
+		return false, err
+	}
+
+	return token != nil && token.Revoked, nil
+}
+
+func (s *State) issuedTokenPut(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	token *vagrant_server.IssuedToken,
+) error {
+	if err := dbPut(dbTxn.Bucket(issuedTokenBucket), []byte(token.Id), token); err != nil {
+		return err
+	}
+
+	return s.issuedTokenIndexSet(memTxn, token)
+}
+
+func (s *State) issuedTokenGet(
+	memTxn *memdb.Txn,
+	ws memdb.WatchSet,
+	id string,
+) (*vagrant_server.IssuedToken, error) {
+	watchCh, raw, err := memTxn.FirstWatch(
+		issuedTokenIndexTableName, issuedTokenIndexIdIndexName, strings.ToLower(id))
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(watchCh)
+	if raw == nil {
+		return nil, nil
+	}
+
+	return raw.(*issuedTokenIndexRecord).Token, nil
+}
+
+// issuedTokenIndexSet writes an index record for a single issued token.
+func (s *State) issuedTokenIndexSet(txn *memdb.Txn, value *vagrant_server.IssuedToken) error {
+	return txn.Insert(issuedTokenIndexTableName, &issuedTokenIndexRecord{
+		Id:    value.Id,
+		Token: value,
+	})
+}
+
+// issuedTokenIndexInit initializes the issued token index from persisted data.
+func (s *State) issuedTokenIndexInit(dbTxn *bolt.Tx, memTxn *memdb.Txn) error {
+	bucket := dbTxn.Bucket(issuedTokenBucket)
+	return bucket.ForEach(func(k, v []byte) error {
+		var value vagrant_server.IssuedToken
+		if err := proto.Unmarshal(v, &value); err != nil {
+			return err
+		}
+
+		return s.issuedTokenIndexSet(memTxn, &value)
+	})
+}
+
+func issuedTokenIndexSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: issuedTokenIndexTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			issuedTokenIndexIdIndexName: {
+				Name:         issuedTokenIndexIdIndexName,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Id",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
+const (
+	issuedTokenIndexTableName   = "issued-token-index"
+	issuedTokenIndexIdIndexName = "id"
+)
+
+type issuedTokenIndexRecord struct {
+	Id    string
+	Token *vagrant_server.IssuedToken
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestToken(t *testing.T) {
+	t.Run("put, list and revoke", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.TokenPut(&vagrant_server.IssuedToken{
+			Id:      "A",
+			Purpose: vagrant_server.IssuedToken_LOGIN,
+		}))
+		require.NoError(s.TokenPut(&vagrant_server.IssuedToken{
+			Id:      "B",
+			Purpose: vagrant_server.IssuedToken_INVITE,
+		}))
+
+		tokens, err := s.TokenList(false)
+		require.NoError(err)
+		require.Len(tokens, 2)
+
+		ws := memdb.NewWatchSet()
+		revoked, err := s.TokenRevoked("A", ws)
+		require.NoError(err)
+		require.False(revoked)
+
+		token, err := s.TokenRevoke("A")
+		require.NoError(err)
+		require.True(token.Revoked)
+		require.NotNil(token.RevokeTime)
+
+		// The watch fires and the token is revoked
+		require.False(ws.Watch(time.After(time.Second)))
+		revoked, err = s.TokenRevoked("A", nil)
+		require.NoError(err)
+		require.True(revoked)
+
+		tokens, err = s.TokenList(false)
+		require.NoError(err)
+		require.Len(tokens, 1)
+		require.Equal("B", tokens[0].Id)
+
+		tokens, err = s.TokenList(true)
+		require.NoError(err)
+		require.Len(tokens, 2)
+
+		// Revoking again doesn't change the revocation
+		again, err := s.TokenRevoke("A")
+		require.NoError(err)
+		require.Equal(token.RevokeTime.AsTime(), again.RevokeTime.AsTime())
+	})
+
+	t.Run("unknown tokens", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.TokenGet("A")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		_, err = s.TokenRevoke("A")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		revoked, err := s.TokenRevoked("A", nil)
+		require.NoError(err)
+		require.False(revoked)
+	})
+
+	t.Run("revocation is persisted", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.TokenPut(&vagrant_server.IssuedToken{Id: "A"}))
+		_, err := s.TokenRevoke("A")
+		require.NoError(err)
+
+		s = TestStateReinit(t, s)
+		defer s.Close()
+
+		token, err := s.TokenGet("A")
+		require.NoError(err)
+		require.True(token.Revoked)
+	})
+}
@@ -0,0 +1,248 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/hashicorp/go-memdb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+var userBucket = []byte("users")
+
+func init() {
+	dbBuckets = append(dbBuckets, userBucket)
+	dbIndexers = append(dbIndexers, (*State).userIndexInit)
+	schemas = append(schemas, userIndexSchema)
+}
+
+// UserPut creates or updates a user. All the roles bound to the user must
+// exist.
+func (s *State) UserPut(user *vagrant_server.User) error {
+	if user.Username == "" {
+		return status.Errorf(codes.InvalidArgument, "username must be set")
+	}
+
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		for _, binding := range user.Roles {
+			if err := s.roleBindingValidate(memTxn, binding); err != nil {
+				return err
+			}
+		}
+
+		return s.userPut(dbTxn, memTxn, user)
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+// UserGet gets a user by name.
+func (s *State) UserGet(username string) (*vagrant_server.User, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	result, err := s.userGet(memTxn, username)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, status.Errorf(codes.NotFound, "user not found: %s", username)
+	}
+
+	return result, nil
+}
+
+// UserList returns all the users.
+func (s *State) UserList() ([]*vagrant_server.User, error) {
+	memTxn := s.inmem.Txn(false)
+	defer memTxn.Abort()
+
+	return s.userList(memTxn)
+}
+
+// UserDelete deletes a user. The last user can't be deleted since nobody
+// could use the server anymore.
+func (s *State) UserDelete(username string) error {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		user, err := s.userGet(memTxn, username)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return status.Errorf(codes.NotFound, "user not found: %s", username)
+		}
+
+		users, err := s.userList(memTxn)
+		if err != nil {
+			return err
+		}
+		if len(users) == 1 {
+			return status.Errorf(codes.FailedPrecondition,
+				"the last user can't be deleted")
+		}
+
+		id := strings.ToLower(user.Username)
+		if err := dbTxn.Bucket(userBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		return memTxn.Delete(userIndexTableName, &userIndexRecord{Id: id})
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+// UserBootstrap creates the given user if there are no users yet. This is
+// used to create the initial user that tokens created while bootstrapping
+// the server are for.
+func (s *State) UserBootstrap(user *vagrant_server.User) error {
+	memTxn := s.inmem.Txn(true)
+	defer memTxn.Abort()
+
+	err := s.db.Update(func(dbTxn *bolt.Tx) error {
+		users, err := s.userList(memTxn)
+		if err != nil || len(users) > 0 {
+			return err
+		}
+
+		return s.userPut(dbTxn, memTxn, user)
+	})
+	if err == nil {
+		memTxn.Commit()
+	}
+
+	return err
+}
+
+func (s *State) userPut(
+	dbTxn *bolt.Tx,
+	memTxn *memdb.Txn,
+	user *vagrant_server.User,
+) error {
+	id := strings.ToLower(user.Username)
+	if err := dbPut(dbTxn.Bucket(userBucket), []byte(id), user); err != nil {
+		return err
+	}
+
+	return s.userIndexSet(memTxn, user)
+}
+
+func (s *State) userGet(
+	memTxn *memdb.Txn,
+	username string,
+) (*vagrant_server.User, error) {
+	raw, err := memTxn.First(userIndexTableName, userIndexIdIndexName, strings.ToLower(username))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	return raw.(*userIndexRecord).User, nil
+}
+
+func (s *State) userList(memTxn *memdb.Txn) ([]*vagrant_server.User, error) {
+	iter, err := memTxn.Get(userIndexTableName, userIndexIdIndexName+"_prefix", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*vagrant_server.User
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		result = append(result, raw.(*userIndexRecord).User)
+	}
+
+	return result, nil
+}
+
+// roleBindingValidate checks that the role of the binding exists and that
+// its scope, if any, identifies a basis or project.
+func (s *State) roleBindingValidate(memTxn *memdb.Txn, binding *vagrant_server.RoleBinding) error {
+	role, err := s.roleGet(memTxn, binding.Role)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return status.Errorf(codes.InvalidArgument, "role not found: %s", binding.Role)
+	}
+
+	switch scope := binding.Scope.(type) {
+	case *vagrant_server.RoleBinding_Basis:
+		if scope.Basis.GetResourceId() == "" && scope.Basis.GetName() == "" {
+			return status.Errorf(codes.InvalidArgument,
+				"basis scope of role %q must set a resource id or name", binding.Role)
+		}
+	case *vagrant_server.RoleBinding_Project:
+		if scope.Project.GetResourceId() == "" && scope.Project.GetName() == "" {
+			return status.Errorf(codes.InvalidArgument,
+				"project scope of role %q must set a resource id or name", binding.Role)
+		}
+	}
+
+	return nil
+}
+
+// userIndexSet writes an index record for a single user.
+func (s *State) userIndexSet(txn *memdb.Txn, value *vagrant_server.User) error {
+	return txn.Insert(userIndexTableName, &userIndexRecord{
+		Id:   strings.ToLower(value.Username),
+		User: value,
+	})
+}
+
+// userIndexInit initializes the user index from persisted data.
+func (s *State) userIndexInit(dbTxn *bolt.Tx, memTxn *memdb.Txn) error {
+	bucket := dbTxn.Bucket(userBucket)
+	return bucket.ForEach(func(k, v []byte) error {
+		var value vagrant_server.User
+		if err := proto.Unmarshal(v, &value); err != nil {
+			return err
+		}
+
+		return s.userIndexSet(memTxn, &value)
+	})
+}
+
+func userIndexSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: userIndexTableName,
+		Indexes: map[string]*memdb.IndexSchema{
+			userIndexIdIndexName: {
+				Name:         userIndexIdIndexName,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Id",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
+const (
+	userIndexTableName   = "user-index"
+	userIndexIdIndexName = "id"
+)
+
+type userIndexRecord struct {
+	Id   string
+	User *vagrant_server.User
+}
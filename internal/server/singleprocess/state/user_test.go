@@ -0,0 +1,116 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vagrant-plugin-sdk/proto/vagrant_plugin_sdk"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/vagrant/internal/server/proto/vagrant_server"
+)
+
+func TestUser(t *testing.T) {
+	t.Run("put, get, list and delete", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		_, err := s.UserGet("alice")
+		require.Error(err)
+		require.Equal(codes.NotFound, status.Code(err))
+
+		require.NoError(s.UserPut(&vagrant_server.User{
+			Username: "alice",
+			Roles:    []*vagrant_server.RoleBinding{{Role: RoleAdmin}},
+		}))
+		require.NoError(s.UserPut(&vagrant_server.User{
+			Username: "bob",
+			Roles: []*vagrant_server.RoleBinding{{
+				Role: RoleOperator,
+				Scope: &vagrant_server.RoleBinding_Project{
+					Project: &vagrant_plugin_sdk.Ref_Project{Name: "web"},
+				},
+			}},
+		}))
+
+		user, err := s.UserGet("Bob")
+		require.NoError(err)
+		require.Equal("web", user.Roles[0].GetProject().Name)
+
+		users, err := s.UserList()
+		require.NoError(err)
+		require.Len(users, 2)
+
+		require.NoError(s.UserDelete("bob"))
+		_, err = s.UserGet("bob")
+		require.Error(err)
+
+		// The last user can't be deleted
+		err = s.UserDelete("alice")
+		require.Error(err)
+		require.Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("validates role bindings", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		err := s.UserPut(&vagrant_server.User{
+			Username: "alice",
+			Roles:    []*vagrant_server.RoleBinding{{Role: "unknown"}},
+		})
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+
+		err = s.UserPut(&vagrant_server.User{
+			Username: "alice",
+			Roles: []*vagrant_server.RoleBinding{{
+				Role: RoleAdmin,
+				Scope: &vagrant_server.RoleBinding_Basis{
+					Basis: &vagrant_plugin_sdk.Ref_Basis{},
+				},
+			}},
+		})
+		require.Error(err)
+		require.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("bootstrap only creates the first user", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.UserBootstrap(&vagrant_server.User{Username: "vagrant"}))
+		require.NoError(s.UserBootstrap(&vagrant_server.User{Username: "other"}))
+
+		users, err := s.UserList()
+		require.NoError(err)
+		require.Len(users, 1)
+		require.Equal("vagrant", users[0].Username)
+	})
+
+	t.Run("persists across restarts", func(t *testing.T) {
+		require := require.New(t)
+
+		s := TestState(t)
+		defer s.Close()
+
+		require.NoError(s.UserPut(&vagrant_server.User{
+			Username: "alice",
+			Roles:    []*vagrant_server.RoleBinding{{Role: RoleReadOnly}},
+		}))
+
+		s = TestStateReinit(t, s)
+		defer s.Close()
+
+		user, err := s.UserGet("alice")
+		require.NoError(err)
+		require.Equal(RoleReadOnly, user.Roles[0].Role)
+	})
+}
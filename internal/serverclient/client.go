@@ -92,11 +92,23 @@ func Connect(ctx context.Context, opts ...ConnectOption) (*grpc.ClientConn, erro
 	}
 
 	if !cfg.Tls {
+		if cfg.TlsCertFile != "" {
+			return nil, fmt.Errorf("client certificates require a TLS connection to the server")
+		}
+
 		grpcOpts = append(grpcOpts, grpc.WithInsecure())
-	} else if cfg.TlsSkipVerify {
-		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(
-			credentials.NewTLS(&tls.Config{InsecureSkipVerify: true}),
-		))
+	} else {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TlsSkipVerify}
+		if cfg.TlsCertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.TlsCertFile, cfg.TlsKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("error loading client certificate: %s", err)
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	}
 
 	if cfg.Auth {
@@ -105,11 +117,14 @@ func Connect(ctx context.Context, opts ...ConnectOption) (*grpc.ClientConn, erro
 			token = v
 		}
 
-		if token == "" {
+		// The client certificate authenticates us if we have no token
+		if token == "" && cfg.TlsCertFile == "" {
 			return nil, fmt.Errorf("No token available at the VAGRANT_SERVER_TOKEN environment variable")
 		}
 
-		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(StaticToken(token)))
+		if token != "" {
+			grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(StaticToken(token)))
+		}
 	}
 
 	// Connect to this server
@@ -135,6 +150,8 @@ func ContextConfig(opts ...ConnectOption) (*clicontext.Config, error) {
 			TlsSkipVerify: cfg.TlsSkipVerify,
 			RequireAuth:   cfg.Token != "",
 			AuthToken:     cfg.Token,
+			TlsCertFile:   cfg.TlsCertFile,
+			TlsKeyFile:    cfg.TlsKeyFile,
 		},
 	}, nil
 }
@@ -143,6 +160,8 @@ type connectConfig struct {
 	Addr          string
 	Tls           bool
 	TlsSkipVerify bool
+	TlsCertFile   string
+	TlsKeyFile    string
 	Auth          bool
 	Token         string
 	Optional      bool // See Optional func
@@ -164,6 +183,8 @@ func FromEnv() ConnectOption {
 			c.Addr = v
 			c.Tls = os.Getenv(EnvServerTls) != ""
 			c.TlsSkipVerify = os.Getenv(EnvServerTlsSkipVerify) != ""
+			c.TlsCertFile = os.Getenv(EnvServerTlsCertFile)
+			c.TlsKeyFile = os.Getenv(EnvServerTlsKeyFile)
 			c.Auth = os.Getenv(EnvServerToken) != ""
 		}
 
@@ -178,6 +199,8 @@ func FromContextConfig(cfg *clicontext.Config) ConnectOption {
 			c.Addr = cfg.Server.Address
 			c.Tls = cfg.Server.Tls
 			c.TlsSkipVerify = cfg.Server.TlsSkipVerify
+			c.TlsCertFile = cfg.Server.TlsCertFile
+			c.TlsKeyFile = cfg.Server.TlsKeyFile
 			if cfg.Server.RequireAuth {
 				c.Auth = true
 				c.Token = cfg.Server.AuthToken
@@ -265,6 +288,11 @@ const (
 	EnvServerTls           = "VAGRANT_SERVER_TLS"
 	EnvServerTlsSkipVerify = "VAGRANT_SERVER_TLS_SKIP_VERIFY"
 
+	// EnvServerTlsCertFile and EnvServerTlsKeyFile are the paths to the
+	// client certificate and key to present to the server.
+	EnvServerTlsCertFile = "VAGRANT_SERVER_TLS_CERT_FILE"
+	EnvServerTlsKeyFile  = "VAGRANT_SERVER_TLS_KEY_FILE"
+
 	// EnvServerToken is the token for authenticated with the server.
 	EnvServerToken = "VAGRANT_SERVER_TOKEN"
 
@@ -21,6 +21,13 @@ type Client struct {
 	// Note this will be stored plaintext on disk. You can also use the
 	// WAYPOINT_SERVER_TOKEN env var.
 	AuthToken string `hcl:"auth_token,optional"`
+
+	// TlsCertFile and TlsKeyFile are the client certificate and key to
+	// present to the server. Servers that trust the CA of the certificate
+	// authenticate the client as the user its subject is mapped to, so
+	// no token is needed.
+	TlsCertFile string `hcl:"tls_cert_file,optional"`
+	TlsKeyFile  string `hcl:"tls_key_file,optional"`
 }
 
 // Config is the configuration for the built-in server.
@@ -34,11 +41,90 @@ type Config struct {
 	// HTTP is the listening configuration for the HTTP service for grpc-web.
 	HTTP Listener `hcl:"http,block"`
 
+	// BoxCatalogURL is the external URL of the server, such as
+	// "https://vagrant.example.com", that box files in the box catalog
+	// are linked with. If not set, the URL the box metadata is requested
+	// with is used.
+	BoxCatalogURL string `hcl:"box_catalog_url,optional"`
+
+	// TrustForwardedHeaders uses the X-Forwarded-Proto and X-Forwarded-Host
+	// headers of HTTP requests. Only enable this behind a proxy which sets
+	// these headers.
+	TrustForwardedHeaders bool `hcl:"trust_forwarded_headers,optional"`
+
 	// URL configures a server to use a URL service.
 	URL *URL `hcl:"url,block"`
 
 	// CEBConfig configures the entrypoint binary for deployments
 	CEBConfig *CEBConfig `hcl:"entrypoint_config,block"`
+
+	// ExclusiveCommands are the commands that can not be run in parallel
+	// on the same target. If not set, a default set of commands is used.
+	ExclusiveCommands []string `hcl:"exclusive_commands,optional"`
+
+	// RunnerGracePeriod is how long a runner which disconnected is kept
+	// before it is removed and its jobs are requeued, such as "5m". If
+	// not set, a default grace period is used.
+	RunnerGracePeriod string `hcl:"runner_grace_period,optional"`
+
+	// JobTimeouts are the default execution timeouts of jobs by the name
+	// of the command they run, such as {"up" = "1h"}. Jobs which set their
+	// own timeout are not affected.
+	JobTimeouts map[string]string `hcl:"job_timeouts,optional"`
+
+	// KeyRotation configures the rotation of the keys used to sign
+	// authentication tokens. If not set, keys are only rotated manually.
+	KeyRotation *KeyRotation `hcl:"key_rotation,block"`
+
+	// OIDC configures logging in with an OpenID Connect provider. ID tokens
+	// issued by the provider are accepted in addition to the tokens issued
+	// by the server.
+	OIDC *OIDC `hcl:"oidc,block"`
+
+	// ClientCertUsers maps the subjects of client certificates to the
+	// users they authenticate as. Keys are either the full subject, such
+	// as "CN=runner-1,O=Example", or only its common name. Certificates
+	// must be issued by the client CA of the listener. The local server
+	// started by the CLI doesn't use TLS, so certificate authentication
+	// isn't usable with it yet.
+	ClientCertUsers map[string]string `hcl:"client_cert_users,optional"`
+}
+
+// KeyRotation configures the rotation of the token signing keys.
+type KeyRotation struct {
+	// Interval is how long a key is used to sign new tokens before a new
+	// key is created, such as "720h".
+	Interval string `hcl:"interval,attr"`
+
+	// RetireAfter is how long the previous key keeps validating tokens
+	// once a new key is created, such as "168h". If not set, previous keys
+	// are valid until they are retired manually.
+	RetireAfter string `hcl:"retire_after,optional"`
+}
+
+// OIDC configures authentication with an OpenID Connect provider.
+type OIDC struct {
+	// Issuer is the issuer URL of the provider.
+	Issuer string `hcl:"issuer,attr"`
+
+	// ClientID is the client Vagrant is registered as with the provider.
+	// ID tokens must be issued for this client.
+	ClientID string `hcl:"client_id,attr"`
+
+	// UsernameClaim is the claim identifying the user, such as "email".
+	// If not set, the "sub" claim is used. The roles bound to the server
+	// user named "oidc:<issuer>:<claim value>" apply, so users of the
+	// provider never get the roles of other users.
+	UsernameClaim string `hcl:"username_claim,optional"`
+
+	// RolesClaim is the claim listing the groups of the user, such as
+	// "groups". Groups are mapped to roles with RoleMappings.
+	RolesClaim string `hcl:"roles_claim,optional"`
+
+	// RoleMappings maps the groups in RolesClaim to the roles of the
+	// server, such as {"vagrant-admins" = "admin"}. The roles apply to all
+	// bases and projects.
+	RoleMappings map[string]string `hcl:"role_mappings,optional"`
 }
 
 // CEBConfig is specific configuration for the entrypoint binaries
@@ -54,6 +140,13 @@ type Listener struct {
 	TLSDisable  bool   `hcl:"tls_disable,optional"`
 	TLSCertFile string `hcl:"tls_cert_file,optional"`
 	TLSKeyFile  string `hcl:"tls_key_file,optional"`
+
+	// TLSClientCAFile is the CA that client certificates are verified
+	// with. Clients presenting a certificate it issued are authenticated
+	// by ClientCertUsers. If TLSRequireClientCert is set, clients must
+	// present such a certificate to connect at all.
+	TLSClientCAFile      string `hcl:"tls_client_ca_file,optional"`
+	TLSRequireClientCert bool   `hcl:"tls_require_client_cert,optional"`
 }
 
 // URL is the configuration for the URL service.
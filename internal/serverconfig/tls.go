@@ -0,0 +1,57 @@
+package serverconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig returns the TLS configuration of the listener for use with
+// server.WithTLS. This is nil if TLS is disabled or no certificate is
+// configured. The CLI doesn't load the server config yet, so this only
+// takes effect for servers run with it by other programs.
+func (l *Listener) TLSConfig() (*tls.Config, error) {
+	if l.TLSDisable || l.TLSCertFile == "" {
+		if l.TLSClientCAFile != "" || l.TLSRequireClientCert {
+			return nil, fmt.Errorf("client certificates for %s require a TLS certificate", l.Addr)
+		}
+
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.TLSCertFile, l.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS certificate for %s: %s", l.Addr, err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if l.TLSClientCAFile == "" {
+		if l.TLSRequireClientCert {
+			return nil, fmt.Errorf("tls_require_client_cert for %s requires tls_client_ca_file", l.Addr)
+		}
+
+		return cfg, nil
+	}
+
+	pem, err := ioutil.ReadFile(l.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading client CA for %s: %s", l.Addr, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("client CA file for %s has no certificates", l.Addr)
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	if l.TLSRequireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
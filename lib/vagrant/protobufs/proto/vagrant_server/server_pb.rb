@@ -66,6 +66,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :metadata_url, :string, 6
       optional :name, :string, 7
       optional :last_update, :message, 8, "google.protobuf.Timestamp"
+      optional :architecture, :string, 9
     end
     add_message "hashicorp.vagrant.Target" do
       optional :resource_id, :string, 1
@@ -125,6 +126,18 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :id, :string, 1
     end
     add_message "hashicorp.vagrant.Ref.RunnerAny" do
+      repeated :selectors, :message, 1, "hashicorp.vagrant.Ref.RunnerLabelSelector"
+    end
+    add_message "hashicorp.vagrant.Ref.RunnerLabelSelector" do
+      optional :key, :string, 1
+      optional :operator, :enum, 2, "hashicorp.vagrant.Ref.RunnerLabelSelector.Operator"
+      repeated :values, :string, 3
+    end
+    add_enum "hashicorp.vagrant.Ref.RunnerLabelSelector.Operator" do
+      value :EQUALS, 0
+      value :NOT_EQUALS, 1
+      value :IN, 2
+      value :NOT_IN, 3
     end
     add_message "hashicorp.vagrant.Ref.Vagrantfile" do
       optional :resource_id, :string, 1
@@ -195,6 +208,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     add_message "hashicorp.vagrant.QueueJobRequest" do
       optional :job, :message, 1, "hashicorp.vagrant.Job"
       optional :expires_in, :string, 2
+      optional :retry_policy, :message, 3, "hashicorp.vagrant.Job.RetryPolicy"
     end
     add_message "hashicorp.vagrant.QueueJobResponse" do
       optional :job_id, :string, 1
@@ -210,6 +224,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :valid, :bool, 1
       optional :validation_error, :message, 2, "google.rpc.Status"
       optional :assignable, :bool, 3
+      optional :assignable_error, :message, 4, "google.rpc.Status"
     end
     add_message "hashicorp.vagrant.Job" do
       optional :id, :string, 1
@@ -220,6 +235,9 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       map :labels, :string, :string, 6
       optional :data_source, :message, 7, "hashicorp.vagrant.Job.DataSource"
       map :data_source_overrides, :string, :string, 8
+      optional :retry_policy, :message, 9, "hashicorp.vagrant.Job.RetryPolicy"
+      repeated :depends_on, :string, 10
+      optional :timeout, :string, 11
       optional :state, :enum, 100, "hashicorp.vagrant.Job.State"
       optional :assigned_runner, :message, 101, "hashicorp.vagrant.Ref.RunnerId"
       optional :queue_time, :message, 102, "google.protobuf.Timestamp"
@@ -230,6 +248,11 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :result, :message, 107, "hashicorp.vagrant.Job.Result"
       optional :cancel_time, :message, 108, "google.protobuf.Timestamp"
       optional :expire_time, :message, 109, "google.protobuf.Timestamp"
+      optional :attempt, :uint32, 110
+      repeated :attempts, :message, 111, "hashicorp.vagrant.Job.Attempt"
+      optional :retry_time, :message, 112, "google.protobuf.Timestamp"
+      optional :deadline, :message, 113, "google.protobuf.Timestamp"
+      optional :requested_by, :string, 114
       oneof :operation do
         optional :noop, :message, 50, "hashicorp.vagrant.Job.Noop"
         optional :auth, :message, 51, "hashicorp.vagrant.Job.AuthOp"
@@ -239,6 +262,25 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
         optional :init, :message, 55, "hashicorp.vagrant.Job.InitOp"
       end
     end
+    add_message "hashicorp.vagrant.Job.RetryPolicy" do
+      optional :max_attempts, :uint32, 1
+      optional :backoff, :string, 2
+      optional :max_backoff, :string, 3
+      repeated :retryable_codes, :uint32, 4
+      repeated :retryable_errors, :enum, 5, "hashicorp.vagrant.Job.RetryPolicy.ErrorClass"
+    end
+    add_enum "hashicorp.vagrant.Job.RetryPolicy.ErrorClass" do
+      value :UNSPECIFIED, 0
+      value :RUNNER_LOST, 1
+      value :TRANSIENT, 2
+      value :EXPIRED, 3
+    end
+    add_message "hashicorp.vagrant.Job.Attempt" do
+      optional :number, :uint32, 1
+      optional :assign_time, :message, 2, "google.protobuf.Timestamp"
+      optional :complete_time, :message, 3, "google.protobuf.Timestamp"
+      optional :error, :message, 4, "google.rpc.Status"
+    end
     add_message "hashicorp.vagrant.Job.Result" do
       optional :auth, :message, 1, "hashicorp.vagrant.Job.AuthResult"
       optional :docs, :message, 2, "hashicorp.vagrant.Job.DocsResult"
@@ -250,6 +292,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       oneof :source do
         optional :local, :message, 1, "hashicorp.vagrant.Job.Local"
         optional :git, :message, 2, "hashicorp.vagrant.Job.Git"
+        optional :archive, :message, 3, "hashicorp.vagrant.Job.Archive"
       end
     end
     add_message "hashicorp.vagrant.Job.Local" do
@@ -258,6 +301,17 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :url, :string, 1
       optional :ref, :string, 2
       optional :path, :string, 3
+      optional :depth, :uint32, 4
+      optional :submodules, :bool, 5
+      optional :ssh_key_var, :string, 6
+      optional :token_var, :string, 7
+      optional :username, :string, 8
+    end
+    add_message "hashicorp.vagrant.Job.Archive" do
+      optional :url, :string, 1
+      optional :upload_id, :string, 2
+      optional :checksum, :string, 3
+      optional :path, :string, 4
     end
     add_message "hashicorp.vagrant.Job.Noop" do
     end
@@ -359,6 +413,8 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     end
     add_message "hashicorp.vagrant.GetJobStreamRequest" do
       optional :job_id, :string, 1
+      optional :since, :message, 2, "google.protobuf.Timestamp"
+      optional :offset, :uint64, 3
     end
     add_message "hashicorp.vagrant.GetJobStreamResponse" do
       oneof :event do
@@ -367,8 +423,14 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
         optional :terminal, :message, 3, "hashicorp.vagrant.GetJobStreamResponse.Terminal"
         optional :error, :message, 4, "hashicorp.vagrant.GetJobStreamResponse.Error"
         optional :complete, :message, 5, "hashicorp.vagrant.GetJobStreamResponse.Complete"
+        optional :retry, :message, 6, "hashicorp.vagrant.GetJobStreamResponse.Retry"
       end
     end
+    add_message "hashicorp.vagrant.GetJobStreamResponse.Retry" do
+      optional :attempt, :uint32, 1
+      optional :error, :message, 2, "google.rpc.Status"
+      optional :retry_time, :message, 3, "google.protobuf.Timestamp"
+    end
     add_message "hashicorp.vagrant.GetJobStreamResponse.Open" do
     end
     add_message "hashicorp.vagrant.GetJobStreamResponse.State" do
@@ -376,6 +438,8 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :current, :enum, 2, "hashicorp.vagrant.Job.State"
       optional :job, :message, 3, "hashicorp.vagrant.Job"
       optional :canceling, :bool, 4
+      optional :blocked, :bool, 5
+      optional :blocked_message, :string, 6
     end
     add_message "hashicorp.vagrant.GetJobStreamResponse.Terminal" do
       repeated :events, :message, 1, "hashicorp.vagrant.GetJobStreamResponse.Terminal.Event"
@@ -383,6 +447,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     end
     add_message "hashicorp.vagrant.GetJobStreamResponse.Terminal.Event" do
       optional :timestamp, :message, 1, "google.protobuf.Timestamp"
+      optional :offset, :uint64, 9
       oneof :event do
         optional :line, :message, 2, "hashicorp.vagrant.GetJobStreamResponse.Terminal.Event.Line"
         optional :status, :message, 3, "hashicorp.vagrant.GetJobStreamResponse.Terminal.Event.Status"
@@ -447,6 +512,22 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :id, :string, 1
       optional :by_id_only, :bool, 2
       repeated :components, :message, 3, "hashicorp.vagrant.Component"
+      map :labels, :string, :string, 4
+      optional :hostname, :string, 5
+      optional :version, :string, 6
+      optional :concurrency, :uint32, 12
+      repeated :slots, :message, 13, "hashicorp.vagrant.Runner.ResourceSlot"
+      optional :online, :bool, 7
+      optional :draining, :bool, 8
+      optional :current_job_id, :string, 9
+      repeated :job_ids, :string, 14
+      optional :first_seen, :message, 10, "google.protobuf.Timestamp"
+      optional :last_heartbeat, :message, 11, "google.protobuf.Timestamp"
+    end
+    add_message "hashicorp.vagrant.Runner.ResourceSlot" do
+      optional :name, :string, 1
+      optional :capacity, :uint32, 2
+      repeated :commands, :string, 3
     end
     add_message "hashicorp.vagrant.RunnerConfigRequest" do
       oneof :event do
@@ -497,6 +578,41 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     add_message "hashicorp.vagrant.RunnerJobStreamResponse.JobCancel" do
       optional :force, :bool, 1
     end
+    add_message "hashicorp.vagrant.Schedule" do
+      optional :id, :string, 1
+      optional :name, :string, 2
+      optional :cron, :string, 3
+      optional :job, :message, 4, "hashicorp.vagrant.Job"
+      optional :overlap, :enum, 5, "hashicorp.vagrant.Schedule.OverlapPolicy"
+      optional :disabled, :bool, 6
+      optional :last_job_id, :string, 10
+      optional :last_run_time, :message, 11, "google.protobuf.Timestamp"
+      optional :next_run_time, :message, 12, "google.protobuf.Timestamp"
+      optional :last_error, :message, 13, "google.rpc.Status"
+    end
+    add_enum "hashicorp.vagrant.Schedule.OverlapPolicy" do
+      value :SKIP, 0
+      value :ALLOW, 1
+      value :CANCEL_PREVIOUS, 2
+    end
+    add_message "hashicorp.vagrant.UpsertScheduleRequest" do
+      optional :schedule, :message, 1, "hashicorp.vagrant.Schedule"
+    end
+    add_message "hashicorp.vagrant.UpsertScheduleResponse" do
+      optional :schedule, :message, 1, "hashicorp.vagrant.Schedule"
+    end
+    add_message "hashicorp.vagrant.GetScheduleRequest" do
+      optional :schedule_id, :string, 1
+    end
+    add_message "hashicorp.vagrant.GetScheduleResponse" do
+      optional :schedule, :message, 1, "hashicorp.vagrant.Schedule"
+    end
+    add_message "hashicorp.vagrant.ListSchedulesResponse" do
+      repeated :schedules, :message, 1, "hashicorp.vagrant.Schedule"
+    end
+    add_message "hashicorp.vagrant.DeleteScheduleRequest" do
+      optional :schedule_id, :string, 1
+    end
     add_message "hashicorp.vagrant.RunnerGetDeploymentConfigRequest" do
     end
     add_message "hashicorp.vagrant.RunnerGetDeploymentConfigResponse" do
@@ -507,6 +623,14 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     add_message "hashicorp.vagrant.GetRunnerRequest" do
       optional :runner_id, :string, 1
     end
+    add_message "hashicorp.vagrant.ListRunnersRequest" do
+    end
+    add_message "hashicorp.vagrant.ListRunnersResponse" do
+      repeated :runners, :message, 1, "hashicorp.vagrant.Runner"
+    end
+    add_message "hashicorp.vagrant.DrainRunnerRequest" do
+      optional :runner_id, :string, 1
+    end
     add_message "hashicorp.vagrant.SetServerConfigRequest" do
       optional :config, :message, 1, "hashicorp.vagrant.ServerConfig"
     end
@@ -610,10 +734,99 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     end
     add_message "hashicorp.vagrant.FindBoxRequest" do
       optional :box, :message, 2, "hashicorp.vagrant.sdk.Ref.Box"
+      optional :architecture, :string, 3
     end
     add_message "hashicorp.vagrant.FindBoxResponse" do
       optional :box, :message, 1, "hashicorp.vagrant.Box"
     end
+    add_message "hashicorp.vagrant.CatalogBox" do
+      optional :name, :string, 1
+      optional :description, :string, 2
+      repeated :versions, :message, 3, "hashicorp.vagrant.CatalogBox.Version"
+      optional :last_update, :message, 4, "google.protobuf.Timestamp"
+    end
+    add_message "hashicorp.vagrant.CatalogBox.Version" do
+      optional :version, :string, 1
+      optional :status, :string, 2
+      optional :description, :string, 3
+      repeated :providers, :message, 4, "hashicorp.vagrant.CatalogBox.Provider"
+    end
+    add_message "hashicorp.vagrant.CatalogBox.Provider" do
+      optional :name, :string, 1
+      optional :checksum, :string, 2
+      optional :checksum_type, :string, 3
+      optional :size, :int64, 4
+    end
+    add_message "hashicorp.vagrant.PublishCatalogBoxRequest" do
+      oneof :event do
+        optional :open, :message, 1, "hashicorp.vagrant.PublishCatalogBoxRequest.Open"
+        optional :chunk, :bytes, 2
+      end
+    end
+    add_message "hashicorp.vagrant.PublishCatalogBoxRequest.Open" do
+      optional :name, :string, 1
+      optional :description, :string, 2
+      optional :version, :string, 3
+      optional :version_description, :string, 4
+      optional :provider, :string, 5
+      optional :checksum, :string, 6
+      optional :force, :bool, 7
+    end
+    add_message "hashicorp.vagrant.PublishCatalogBoxResponse" do
+      optional :box, :message, 1, "hashicorp.vagrant.CatalogBox"
+    end
+    add_message "hashicorp.vagrant.GetCatalogBoxRequest" do
+      optional :name, :string, 1
+    end
+    add_message "hashicorp.vagrant.GetCatalogBoxResponse" do
+      optional :box, :message, 1, "hashicorp.vagrant.CatalogBox"
+    end
+    add_message "hashicorp.vagrant.ListCatalogBoxesResponse" do
+      repeated :boxes, :message, 1, "hashicorp.vagrant.CatalogBox"
+    end
+    add_message "hashicorp.vagrant.DeleteCatalogBoxRequest" do
+      optional :name, :string, 1
+      optional :version, :string, 2
+      optional :provider, :string, 3
+    end
+    add_message "hashicorp.vagrant.DownloadCatalogBoxRequest" do
+      optional :name, :string, 1
+      optional :version, :string, 2
+      optional :provider, :string, 3
+    end
+    add_message "hashicorp.vagrant.DownloadCatalogBoxResponse" do
+      oneof :event do
+        optional :open, :message, 1, "hashicorp.vagrant.CatalogBox.Provider"
+        optional :chunk, :bytes, 2
+      end
+    end
+    add_message "hashicorp.vagrant.ArchiveUpload" do
+      optional :id, :string, 1
+      optional :checksum, :string, 2
+      optional :size, :int64, 3
+      optional :upload_time, :message, 4, "google.protobuf.Timestamp"
+    end
+    add_message "hashicorp.vagrant.UploadArchiveRequest" do
+      oneof :event do
+        optional :open, :message, 1, "hashicorp.vagrant.UploadArchiveRequest.Open"
+        optional :chunk, :bytes, 2
+      end
+    end
+    add_message "hashicorp.vagrant.UploadArchiveRequest.Open" do
+      optional :checksum, :string, 1
+    end
+    add_message "hashicorp.vagrant.UploadArchiveResponse" do
+      optional :upload, :message, 1, "hashicorp.vagrant.ArchiveUpload"
+    end
+    add_message "hashicorp.vagrant.DownloadArchiveRequest" do
+      optional :upload_id, :string, 1
+    end
+    add_message "hashicorp.vagrant.DownloadArchiveResponse" do
+      oneof :event do
+        optional :open, :message, 1, "hashicorp.vagrant.ArchiveUpload"
+        optional :chunk, :bytes, 2
+      end
+    end
     add_message "hashicorp.vagrant.GetLogStreamRequest" do
       optional :limit_backlog, :int32, 4
       oneof :scope do
@@ -626,11 +839,25 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :deployment_id, :string, 1
       optional :instance_id, :string, 2
       repeated :lines, :message, 3, "hashicorp.vagrant.LogBatch.Entry"
+      oneof :scope do
+        optional :basis, :message, 4, "hashicorp.vagrant.sdk.Ref.Basis"
+        optional :project, :message, 5, "hashicorp.vagrant.sdk.Ref.Project"
+        optional :target, :message, 6, "hashicorp.vagrant.sdk.Ref.Target"
+      end
     end
     add_message "hashicorp.vagrant.LogBatch.Entry" do
       optional :timestamp, :message, 1, "google.protobuf.Timestamp"
       optional :line, :string, 2
     end
+    add_message "hashicorp.vagrant.SendLogStreamRequest" do
+      optional :instance_id, :string, 4
+      repeated :lines, :message, 5, "hashicorp.vagrant.LogBatch.Entry"
+      oneof :scope do
+        optional :basis, :message, 1, "hashicorp.vagrant.sdk.Ref.Basis"
+        optional :project, :message, 2, "hashicorp.vagrant.sdk.Ref.Project"
+        optional :target, :message, 3, "hashicorp.vagrant.sdk.Ref.Target"
+      end
+    end
     add_message "hashicorp.vagrant.ConfigVar" do
       optional :name, :string, 1
       optional :value, :string, 2
@@ -776,6 +1003,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :login, :bool, 4
       optional :invite, :bool, 5
       optional :entrypoint, :message, 6, "hashicorp.vagrant.Token.Entrypoint"
+      repeated :roles, :string, 7
     end
     add_message "hashicorp.vagrant.Token.Entrypoint" do
       optional :deployment_id, :string, 1
@@ -783,10 +1011,37 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     add_message "hashicorp.vagrant.HMACKey" do
       optional :id, :string, 1
       optional :key, :bytes, 2
+      optional :create_time, :message, 3, "google.protobuf.Timestamp"
+      optional :retire_time, :message, 4, "google.protobuf.Timestamp"
+    end
+    add_message "hashicorp.vagrant.HMACKeyInfo" do
+      optional :id, :string, 1
+      optional :create_time, :message, 2, "google.protobuf.Timestamp"
+      optional :retire_time, :message, 3, "google.protobuf.Timestamp"
+      optional :active, :bool, 4
+      optional :retired, :bool, 5
+    end
+    add_message "hashicorp.vagrant.ListHMACKeysResponse" do
+      repeated :keys, :message, 1, "hashicorp.vagrant.HMACKeyInfo"
+    end
+    add_message "hashicorp.vagrant.RotateHMACKeyRequest" do
+      optional :retire_after, :string, 1
+    end
+    add_message "hashicorp.vagrant.RotateHMACKeyResponse" do
+      optional :key, :message, 1, "hashicorp.vagrant.HMACKeyInfo"
+    end
+    add_message "hashicorp.vagrant.RetireHMACKeyRequest" do
+      optional :id, :string, 1
+      optional :retire_after, :string, 2
+    end
+    add_message "hashicorp.vagrant.RetireHMACKeyResponse" do
+      optional :key, :message, 1, "hashicorp.vagrant.HMACKeyInfo"
     end
     add_message "hashicorp.vagrant.InviteTokenRequest" do
       optional :duration, :string, 1
       optional :entrypoint, :message, 2, "hashicorp.vagrant.Token.Entrypoint"
+      optional :user, :string, 3
+      repeated :roles, :string, 4
     end
     add_message "hashicorp.vagrant.NewTokenResponse" do
       optional :token, :string, 1
@@ -794,6 +1049,78 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
     add_message "hashicorp.vagrant.ConvertInviteTokenRequest" do
       optional :token, :string, 1
     end
+    add_message "hashicorp.vagrant.IssuedToken" do
+      optional :id, :string, 1
+      optional :creator_id, :string, 2
+      optional :purpose, :enum, 3, "hashicorp.vagrant.IssuedToken.Purpose"
+      optional :key_id, :string, 4
+      optional :issue_time, :message, 5, "google.protobuf.Timestamp"
+      optional :valid_until, :message, 6, "google.protobuf.Timestamp"
+      optional :revoked, :bool, 7
+      optional :revoke_time, :message, 8, "google.protobuf.Timestamp"
+      optional :entrypoint, :bool, 9
+      optional :user, :string, 10
+    end
+    add_enum "hashicorp.vagrant.IssuedToken.Purpose" do
+      value :UNKNOWN, 0
+      value :LOGIN, 1
+      value :INVITE, 2
+    end
+    add_message "hashicorp.vagrant.ListTokensRequest" do
+      optional :include_revoked, :bool, 1
+    end
+    add_message "hashicorp.vagrant.ListTokensResponse" do
+      repeated :tokens, :message, 1, "hashicorp.vagrant.IssuedToken"
+    end
+    add_message "hashicorp.vagrant.RevokeTokenRequest" do
+      optional :id, :string, 1
+    end
+    add_message "hashicorp.vagrant.RevokeTokenResponse" do
+      optional :token, :message, 1, "hashicorp.vagrant.IssuedToken"
+    end
+    add_message "hashicorp.vagrant.User" do
+      optional :username, :string, 1
+      repeated :roles, :message, 2, "hashicorp.vagrant.RoleBinding"
+    end
+    add_message "hashicorp.vagrant.RoleBinding" do
+      optional :role, :string, 1
+      oneof :scope do
+        optional :basis, :message, 2, "hashicorp.vagrant.sdk.Ref.Basis"
+        optional :project, :message, 3, "hashicorp.vagrant.sdk.Ref.Project"
+      end
+    end
+    add_message "hashicorp.vagrant.Role" do
+      optional :name, :string, 1
+      repeated :permissions, :string, 2
+      optional :builtin, :bool, 3
+    end
+    add_message "hashicorp.vagrant.UpsertUserRequest" do
+      optional :user, :message, 1, "hashicorp.vagrant.User"
+    end
+    add_message "hashicorp.vagrant.UpsertUserResponse" do
+      optional :user, :message, 1, "hashicorp.vagrant.User"
+    end
+    add_message "hashicorp.vagrant.DeleteUserRequest" do
+      optional :username, :string, 1
+    end
+    add_message "hashicorp.vagrant.ListUsersResponse" do
+      repeated :users, :message, 1, "hashicorp.vagrant.User"
+    end
+    add_message "hashicorp.vagrant.UpsertRoleRequest" do
+      optional :role, :message, 1, "hashicorp.vagrant.Role"
+    end
+    add_message "hashicorp.vagrant.UpsertRoleResponse" do
+      optional :role, :message, 1, "hashicorp.vagrant.Role"
+    end
+    add_message "hashicorp.vagrant.DeleteRoleRequest" do
+      optional :name, :string, 1
+    end
+    add_message "hashicorp.vagrant.ListRolesResponse" do
+      repeated :roles, :message, 1, "hashicorp.vagrant.Role"
+    end
+    add_message "hashicorp.vagrant.CreateSnapshotRequest" do
+      optional :include_hmac_keys, :bool, 1
+    end
     add_message "hashicorp.vagrant.CreateSnapshotResponse" do
       oneof :event do
         optional :open, :message, 1, "hashicorp.vagrant.CreateSnapshotResponse.Open"
@@ -872,6 +1199,7 @@ Google::Protobuf::DescriptorPool.generated_pool.build do
       optional :cli_args, :message, 12, "hashicorp.vagrant.sdk.Command.Arguments"
       optional :command_name, :string, 13
       optional :vagrantfile, :message, 14, "hashicorp.vagrant.Vagrantfile"
+      optional :requested_by, :string, 15
       oneof :scope do
         optional :target, :message, 1, "hashicorp.vagrant.sdk.Ref.Target"
         optional :project, :message, 2, "hashicorp.vagrant.sdk.Ref.Project"
@@ -902,6 +1230,8 @@ module Hashicorp
     Ref::Runner = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Ref.Runner").msgclass
     Ref::RunnerId = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Ref.RunnerId").msgclass
     Ref::RunnerAny = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Ref.RunnerAny").msgclass
+    Ref::RunnerLabelSelector = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Ref.RunnerLabelSelector").msgclass
+    Ref::RunnerLabelSelector::Operator = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Ref.RunnerLabelSelector.Operator").enummodule
     Ref::Vagrantfile = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Ref.Vagrantfile").msgclass
     Component = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Component").msgclass
     Component::Type = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Component.Type").enummodule
@@ -919,10 +1249,14 @@ module Hashicorp
     ValidateJobRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ValidateJobRequest").msgclass
     ValidateJobResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ValidateJobResponse").msgclass
     Job = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job").msgclass
+    Job::RetryPolicy = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.RetryPolicy").msgclass
+    Job::RetryPolicy::ErrorClass = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.RetryPolicy.ErrorClass").enummodule
+    Job::Attempt = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.Attempt").msgclass
     Job::Result = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.Result").msgclass
     Job::DataSource = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.DataSource").msgclass
     Job::Local = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.Local").msgclass
     Job::Git = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.Git").msgclass
+    Job::Archive = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.Archive").msgclass
     Job::Noop = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.Noop").msgclass
     Job::ValidateOp = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.ValidateOp").msgclass
     Job::ValidateResult = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Job.ValidateResult").msgclass
@@ -948,6 +1282,7 @@ module Hashicorp
     ListJobsResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListJobsResponse").msgclass
     GetJobStreamRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamRequest").msgclass
     GetJobStreamResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse").msgclass
+    GetJobStreamResponse::Retry = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse.Retry").msgclass
     GetJobStreamResponse::Open = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse.Open").msgclass
     GetJobStreamResponse::State = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse.State").msgclass
     GetJobStreamResponse::Terminal = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse.Terminal").msgclass
@@ -965,6 +1300,7 @@ module Hashicorp
     GetJobStreamResponse::Error = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse.Error").msgclass
     GetJobStreamResponse::Complete = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetJobStreamResponse.Complete").msgclass
     Runner = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Runner").msgclass
+    Runner::ResourceSlot = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Runner.ResourceSlot").msgclass
     RunnerConfigRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerConfigRequest").msgclass
     RunnerConfigRequest::Open = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerConfigRequest.Open").msgclass
     RunnerConfigResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerConfigResponse").msgclass
@@ -978,9 +1314,20 @@ module Hashicorp
     RunnerJobStreamResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerJobStreamResponse").msgclass
     RunnerJobStreamResponse::JobAssignment = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerJobStreamResponse.JobAssignment").msgclass
     RunnerJobStreamResponse::JobCancel = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerJobStreamResponse.JobCancel").msgclass
+    Schedule = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Schedule").msgclass
+    Schedule::OverlapPolicy = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Schedule.OverlapPolicy").enummodule
+    UpsertScheduleRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UpsertScheduleRequest").msgclass
+    UpsertScheduleResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UpsertScheduleResponse").msgclass
+    GetScheduleRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetScheduleRequest").msgclass
+    GetScheduleResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetScheduleResponse").msgclass
+    ListSchedulesResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListSchedulesResponse").msgclass
+    DeleteScheduleRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DeleteScheduleRequest").msgclass
     RunnerGetDeploymentConfigRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerGetDeploymentConfigRequest").msgclass
     RunnerGetDeploymentConfigResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RunnerGetDeploymentConfigResponse").msgclass
     GetRunnerRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetRunnerRequest").msgclass
+    ListRunnersRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListRunnersRequest").msgclass
+    ListRunnersResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListRunnersResponse").msgclass
+    DrainRunnerRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DrainRunnerRequest").msgclass
     SetServerConfigRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.SetServerConfigRequest").msgclass
     GetServerConfigResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetServerConfigResponse").msgclass
     ServerConfig = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ServerConfig").msgclass
@@ -1015,9 +1362,28 @@ module Hashicorp
     ListBoxesResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListBoxesResponse").msgclass
     FindBoxRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.FindBoxRequest").msgclass
     FindBoxResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.FindBoxResponse").msgclass
+    CatalogBox = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.CatalogBox").msgclass
+    CatalogBox::Version = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.CatalogBox.Version").msgclass
+    CatalogBox::Provider = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.CatalogBox.Provider").msgclass
+    PublishCatalogBoxRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.PublishCatalogBoxRequest").msgclass
+    PublishCatalogBoxRequest::Open = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.PublishCatalogBoxRequest.Open").msgclass
+    PublishCatalogBoxResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.PublishCatalogBoxResponse").msgclass
+    GetCatalogBoxRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetCatalogBoxRequest").msgclass
+    GetCatalogBoxResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetCatalogBoxResponse").msgclass
+    ListCatalogBoxesResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListCatalogBoxesResponse").msgclass
+    DeleteCatalogBoxRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DeleteCatalogBoxRequest").msgclass
+    DownloadCatalogBoxRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DownloadCatalogBoxRequest").msgclass
+    DownloadCatalogBoxResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DownloadCatalogBoxResponse").msgclass
+    ArchiveUpload = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ArchiveUpload").msgclass
+    UploadArchiveRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UploadArchiveRequest").msgclass
+    UploadArchiveRequest::Open = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UploadArchiveRequest.Open").msgclass
+    UploadArchiveResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UploadArchiveResponse").msgclass
+    DownloadArchiveRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DownloadArchiveRequest").msgclass
+    DownloadArchiveResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DownloadArchiveResponse").msgclass
     GetLogStreamRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.GetLogStreamRequest").msgclass
     LogBatch = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.LogBatch").msgclass
     LogBatch::Entry = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.LogBatch.Entry").msgclass
+    SendLogStreamRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.SendLogStreamRequest").msgclass
     ConfigVar = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ConfigVar").msgclass
     ConfigSetRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ConfigSetRequest").msgclass
     ConfigSetResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ConfigSetResponse").msgclass
@@ -1050,9 +1416,33 @@ module Hashicorp
     Token = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Token").msgclass
     Token::Entrypoint = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Token.Entrypoint").msgclass
     HMACKey = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.HMACKey").msgclass
+    HMACKeyInfo = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.HMACKeyInfo").msgclass
+    ListHMACKeysResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListHMACKeysResponse").msgclass
+    RotateHMACKeyRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RotateHMACKeyRequest").msgclass
+    RotateHMACKeyResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RotateHMACKeyResponse").msgclass
+    RetireHMACKeyRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RetireHMACKeyRequest").msgclass
+    RetireHMACKeyResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RetireHMACKeyResponse").msgclass
     InviteTokenRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.InviteTokenRequest").msgclass
     NewTokenResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.NewTokenResponse").msgclass
     ConvertInviteTokenRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ConvertInviteTokenRequest").msgclass
+    IssuedToken = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.IssuedToken").msgclass
+    IssuedToken::Purpose = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.IssuedToken.Purpose").enummodule
+    ListTokensRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListTokensRequest").msgclass
+    ListTokensResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListTokensResponse").msgclass
+    RevokeTokenRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RevokeTokenRequest").msgclass
+    RevokeTokenResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RevokeTokenResponse").msgclass
+    User = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.User").msgclass
+    RoleBinding = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RoleBinding").msgclass
+    Role = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.Role").msgclass
+    UpsertUserRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UpsertUserRequest").msgclass
+    UpsertUserResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UpsertUserResponse").msgclass
+    DeleteUserRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DeleteUserRequest").msgclass
+    ListUsersResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListUsersResponse").msgclass
+    UpsertRoleRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UpsertRoleRequest").msgclass
+    UpsertRoleResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.UpsertRoleResponse").msgclass
+    DeleteRoleRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.DeleteRoleRequest").msgclass
+    ListRolesResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.ListRolesResponse").msgclass
+    CreateSnapshotRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.CreateSnapshotRequest").msgclass
     CreateSnapshotResponse = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.CreateSnapshotResponse").msgclass
     CreateSnapshotResponse::Open = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.CreateSnapshotResponse.Open").msgclass
     RestoreSnapshotRequest = ::Google::Protobuf::DescriptorPool.generated_pool.lookup("hashicorp.vagrant.RestoreSnapshotRequest").msgclass
@@ -55,11 +55,33 @@ module Hashicorp
         rpc :GetBox, ::Hashicorp::Vagrant::GetBoxRequest, ::Hashicorp::Vagrant::GetBoxResponse
         rpc :ListBoxes, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::ListBoxesResponse
         rpc :FindBox, ::Hashicorp::Vagrant::FindBoxRequest, ::Hashicorp::Vagrant::FindBoxResponse
-        # GetLogStream reads the log stream for a deployment. This will immediately
-        # send a single LogEntry with the lines we have so far. If there are no
-        # available lines this will NOT block and instead will return an error.
-        # The client can choose to retry or not.
+        # PublishCatalogBox adds a box provider to the box catalog hosted by
+        # the server. The box file is streamed in chunks after the Open message.
+        rpc :PublishCatalogBox, stream(::Hashicorp::Vagrant::PublishCatalogBoxRequest), ::Hashicorp::Vagrant::PublishCatalogBoxResponse
+        # GetCatalogBox returns the catalog entry for a box.
+        rpc :GetCatalogBox, ::Hashicorp::Vagrant::GetCatalogBoxRequest, ::Hashicorp::Vagrant::GetCatalogBoxResponse
+        # ListCatalogBoxes returns all boxes in the box catalog.
+        rpc :ListCatalogBoxes, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::ListCatalogBoxesResponse
+        # DeleteCatalogBox removes a box, box version, or box provider from
+        # the box catalog along with the box files.
+        rpc :DeleteCatalogBox, ::Hashicorp::Vagrant::DeleteCatalogBoxRequest, ::Google::Protobuf::Empty
+        # DownloadCatalogBox streams the box file of a box provider in the
+        # box catalog.
+        rpc :DownloadCatalogBox, ::Hashicorp::Vagrant::DownloadCatalogBoxRequest, stream(::Hashicorp::Vagrant::DownloadCatalogBoxResponse)
+        # UploadArchive stores an archive of project data on the server so that
+        # jobs can use it with an archive data source. The archive is streamed
+        # in chunks after the Open message.
+        rpc :UploadArchive, stream(::Hashicorp::Vagrant::UploadArchiveRequest), ::Hashicorp::Vagrant::UploadArchiveResponse
+        # DownloadArchive streams an archive previously stored with UploadArchive.
+        rpc :DownloadArchive, ::Hashicorp::Vagrant::DownloadArchiveRequest, stream(::Hashicorp::Vagrant::DownloadArchiveResponse)
+        # GetLogStream reads the log stream for a basis, project, or target. The
+        # stream for a basis or project includes the logs of all projects and
+        # targets within it. This will immediately send the backlog of lines
+        # we have so far and then stream new lines as they are received.
         rpc :GetLogStream, ::Hashicorp::Vagrant::GetLogStreamRequest, stream(::Hashicorp::Vagrant::LogBatch)
+        # SendLogStream sends log lines for a basis, project, or target. The
+        # lines are buffered by the server and can be read with GetLogStream.
+        rpc :SendLogStream, stream(::Hashicorp::Vagrant::SendLogStreamRequest), ::Google::Protobuf::Empty
         # Set a single configuration item for the application.
         rpc :SetConfig, ::Hashicorp::Vagrant::ConfigSetRequest, ::Hashicorp::Vagrant::ConfigSetResponse
         # Retrieve merged configuration values for a specific scope. You can determine
@@ -92,14 +114,30 @@ module Hashicorp
         rpc :GetJobStream, ::Hashicorp::Vagrant::GetJobStreamRequest, stream(::Hashicorp::Vagrant::GetJobStreamResponse)
         # Clean out old jobs from the job database
         rpc :PruneOldJobs, ::Google::Protobuf::Empty, ::Google::Protobuf::Empty
+        # UpsertSchedule creates or updates a schedule which queues a job
+        # periodically.
+        rpc :UpsertSchedule, ::Hashicorp::Vagrant::UpsertScheduleRequest, ::Hashicorp::Vagrant::UpsertScheduleResponse
+        # GetSchedule gets a single schedule by ID.
+        rpc :GetSchedule, ::Hashicorp::Vagrant::GetScheduleRequest, ::Hashicorp::Vagrant::GetScheduleResponse
+        # ListSchedules lists all the schedules.
+        rpc :ListSchedules, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::ListSchedulesResponse
+        # DeleteSchedule deletes a schedule. Jobs already queued by the schedule
+        # are not affected.
+        rpc :DeleteSchedule, ::Hashicorp::Vagrant::DeleteScheduleRequest, ::Google::Protobuf::Empty
         # GetRunner gets information about a single runner.
         rpc :GetRunner, ::Hashicorp::Vagrant::GetRunnerRequest, ::Hashicorp::Vagrant::Runner
+        # ListRunners lists the registered runners, including runners which
+        # recently disconnected and are within the reconnect grace period.
+        rpc :ListRunners, ::Hashicorp::Vagrant::ListRunnersRequest, ::Hashicorp::Vagrant::ListRunnersResponse
+        # DrainRunner stops assigning new jobs to a runner. Any job currently
+        # running on the runner is allowed to complete.
+        rpc :DrainRunner, ::Hashicorp::Vagrant::DrainRunnerRequest, ::Google::Protobuf::Empty
         # GetServerConfig sets configuration for the Vagrant server.
         rpc :GetServerConfig, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::GetServerConfigResponse
         # SetServerConfig sets configuration for the Vagrant server.
         rpc :SetServerConfig, ::Hashicorp::Vagrant::SetServerConfigRequest, ::Google::Protobuf::Empty
         # CreateSnapshot creates a new database snapshot.
-        rpc :CreateSnapshot, ::Google::Protobuf::Empty, stream(::Hashicorp::Vagrant::CreateSnapshotResponse)
+        rpc :CreateSnapshot, ::Hashicorp::Vagrant::CreateSnapshotRequest, stream(::Hashicorp::Vagrant::CreateSnapshotResponse)
         # RestoreSnapshot performs a database restore with the given snapshot.
         # This API doesn't do a full online restore, it only stages the restore
         # for the next server start to finalize the restore. See the arguments for
@@ -115,6 +153,34 @@ module Hashicorp
         rpc :GenerateLoginToken, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::NewTokenResponse
         # Exchange a invite token for a login token.
         rpc :ConvertInviteToken, ::Hashicorp::Vagrant::ConvertInviteTokenRequest, ::Hashicorp::Vagrant::NewTokenResponse
+        # ListTokens returns the tokens issued by the server.
+        rpc :ListTokens, ::Hashicorp::Vagrant::ListTokensRequest, ::Hashicorp::Vagrant::ListTokensResponse
+        # RevokeToken revokes a token issued by the server. The token is rejected
+        # from then on and streams authenticated with it are closed. Tokens issued
+        # before tokens were recorded have no ID and can't be revoked this way,
+        # these are rejected once the key that signed them is retired.
+        rpc :RevokeToken, ::Hashicorp::Vagrant::RevokeTokenRequest, ::Hashicorp::Vagrant::RevokeTokenResponse
+        # ListHMACKeys returns the keys used to sign tokens. The key material
+        # itself is never returned.
+        rpc :ListHMACKeys, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::ListHMACKeysResponse
+        # RotateHMACKey creates a new key that is used to sign new tokens. Tokens
+        # signed with the previous keys remain valid until those keys are retired.
+        rpc :RotateHMACKey, ::Hashicorp::Vagrant::RotateHMACKeyRequest, ::Hashicorp::Vagrant::RotateHMACKeyResponse
+        # RetireHMACKey sets when a key is retired. Tokens signed with a retired
+        # key are rejected.
+        rpc :RetireHMACKey, ::Hashicorp::Vagrant::RetireHMACKeyRequest, ::Hashicorp::Vagrant::RetireHMACKeyResponse
+        # UpsertUser creates or updates a user and the roles bound to it.
+        rpc :UpsertUser, ::Hashicorp::Vagrant::UpsertUserRequest, ::Hashicorp::Vagrant::UpsertUserResponse
+        # DeleteUser deletes a user. Tokens of the user are rejected from then on.
+        rpc :DeleteUser, ::Hashicorp::Vagrant::DeleteUserRequest, ::Google::Protobuf::Empty
+        # ListUsers returns all the users.
+        rpc :ListUsers, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::ListUsersResponse
+        # UpsertRole creates or updates a role. Built-in roles can't be changed.
+        rpc :UpsertRole, ::Hashicorp::Vagrant::UpsertRoleRequest, ::Hashicorp::Vagrant::UpsertRoleResponse
+        # DeleteRole deletes a role which isn't bound to any user.
+        rpc :DeleteRole, ::Hashicorp::Vagrant::DeleteRoleRequest, ::Google::Protobuf::Empty
+        # ListRoles returns all the roles, including the built-in roles.
+        rpc :ListRoles, ::Google::Protobuf::Empty, ::Hashicorp::Vagrant::ListRolesResponse
         # ----------------------------------------------------------------------
         # Runner endpoints. These are expected to be called only by a runner.
         # These are not meant to be public endpoints.
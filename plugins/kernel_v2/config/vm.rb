@@ -38,6 +38,7 @@ module VagrantPlugins
       attr_accessor :base_address
       attr_accessor :boot_timeout
       attr_accessor :box
+      attr_accessor :box_architecture
       attr_accessor :ignore_box_vagrantfile
       attr_accessor :box_check_update
       attr_accessor :box_url
@@ -75,6 +76,7 @@ module VagrantPlugins
         @boot_timeout                  = UNSET_VALUE
         @box                           = UNSET_VALUE
         @ignore_box_vagrantfile        = UNSET_VALUE
+        @box_architecture              = UNSET_VALUE
         @box_check_update              = UNSET_VALUE
         @box_download_ca_cert          = UNSET_VALUE
         @box_download_ca_path          = UNSET_VALUE
@@ -533,6 +535,7 @@ module VagrantPlugins
           @box_check_update = !present?(ENV["VAGRANT_BOX_UPDATE_CHECK_DISABLE"])
         end
 
+        @box_architecture = nil if @box_architecture == UNSET_VALUE
         @box_download_ca_cert = nil if @box_download_ca_cert == UNSET_VALUE
         @box_download_ca_path = nil if @box_download_ca_path == UNSET_VALUE
         @box_download_checksum = nil if @box_download_checksum == UNSET_VALUE
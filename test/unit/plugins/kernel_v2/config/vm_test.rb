@@ -153,6 +153,21 @@ describe VagrantPlugins::Kernel_V2::VMConfig do
     end
   end
 
+  context "#box_architecture" do
+    it "defaults to nil" do
+      subject.finalize!
+
+      expect(subject.box_architecture).to be_nil
+    end
+
+    it "can be set" do
+      subject.box_architecture = "arm64"
+      subject.finalize!
+
+      expect(subject.box_architecture).to eq("arm64")
+    end
+  end
+
   context "#box_version" do
     it "defaults to nil" do
       subject.finalize!